@@ -0,0 +1,30 @@
+package portsscanner
+
+import "strings"
+
+// applyALPN 记录HTTPS探测握手时协商出的应用层协议，以及Alt-Svc头声明的HTTP/3。
+// 服务端不支持ALPN时协商结果为空，字段保持为空
+func applyALPN(portInfo *PortInfo, result *httpProbeResult) {
+	if result.ALPN != "" {
+		portInfo.ALPN = result.ALPN
+		portInfo.HTTP2 = result.ALPN == "h2"
+	}
+	if altSvcHTTP3(result.AltSvc) {
+		portInfo.HTTP3 = true
+	}
+}
+
+// altSvcHTTP3 判断Alt-Svc头是否声明了HTTP/3(h3或草案版本h3-29等)，如 `h3=":443"; ma=86400, h2=":443"`
+func altSvcHTTP3(header string) bool {
+	for _, entry := range strings.Split(header, ",") {
+		protocol, _, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		protocol = strings.Trim(strings.TrimSpace(protocol), `"`)
+		if protocol == "h3" || strings.HasPrefix(protocol, "h3-") {
+			return true
+		}
+	}
+	return false
+}
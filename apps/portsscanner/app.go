@@ -2,15 +2,29 @@ package portsscanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
-
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 type App struct {
 	ctx context.Context
+
+	selfTestAddr string         // 自检出站TCP测试地址
+	scanWindow   ScanWindow     // 允许扫描的时间窗口
+	verbosity    int32          // 事件详细程度(eventVerbosity)，原子读写
+	pipeline     *scanPipeline  // SetPipeline设置的自定义扫描阶段，nil为默认流水线
+	safeMode     safeModePolicy // 安全模式设置，零值为启用
+
+	recorder     atomic.Pointer[eventRecorder] // StartEventRecording开启的事件录制，nil为未录制
+	eventContext atomic.Pointer[EventContext]  // 扫描进行中附带在每个事件上的标识
+	timeline     atomic.Pointer[scanTimeline]  // 扫描进行中记录事件的时间线
+	replaying    int32                         // 正在回放录制的事件，原子读写
+
+	alertMu   sync.Mutex // 保护lastAlert
+	lastAlert time.Time  // 最近一次(或已排队的) scan-complete-alert 的发送时间
 }
 
 // NewApp 创建新的 App 实例
@@ -21,57 +35,430 @@ func NewApp() *App {
 // Startup 在应用启动时初始化上下文
 func (a *App) Startup(ctx context.Context) {
 	a.ctx = ctx
+
+	// 打开存储后端(file后端会先升级旧格式的记录)后再加载历史
+	if err := openStorage(); err != nil {
+		fmt.Printf("打开存储后端失败: %v\n", err)
+	}
+	if err := loadStoredScans(); err != nil {
+		fmt.Printf("加载扫描记录失败: %v\n", err)
+	}
+	if _, err := pruneStore(); err != nil {
+		fmt.Printf("清理扫描记录失败: %v\n", err)
+	}
+	if err := a.loadCampaigns(); err != nil {
+		fmt.Printf("加载扫描活动失败: %v\n", err)
+	}
 }
 
 func (a *App) ScanPorts(IP string, startPort int, endPort int, maxThreads int) error {
+	return a.ScanWithConfig(ScanConfig{
+		Target:     IP,
+		StartPort:  startPort,
+		EndPort:    endPort,
+		MaxThreads: maxThreads,
+	})
+}
+
+// prepareScanConfig 填充默认值并校验扫描配置，解析客户端证书、源地址和代理等运行时状态，
+// 返回展开后的端口列表和StopOnMatch条件。只解析配置，不发送任何报文，ScanWithConfig和PreviewScan共用
+func prepareScanConfig(config *ScanConfig) ([]int, *resultMatcher, error) {
+	applyScanDefaults(config)
+	ports := config.portList()
+	if len(ports) == 0 {
+		return nil, nil, fmt.Errorf("no ports to scan")
+	}
+
+	if _, err := validateRawConfig(*config); err != nil {
+		return nil, nil, err
+	}
+	clientCert, err := loadClientCertificate(*config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.SyslogTarget != "" {
+		if _, _, err := parseSyslogTarget(config.SyslogTarget); err != nil {
+			return nil, nil, err
+		}
+	}
+	if config.FindingWebhook != "" {
+		if _, err := parseWebhookURL(config.FindingWebhook); err != nil {
+			return nil, nil, err
+		}
+	}
+	if config.PostScanCommand != "" {
+		if _, err := splitCommandLine(config.PostScanCommand); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := validateAutoExport(config); err != nil {
+		return nil, nil, err
+	}
+	if err := validateScanIDs(*config); err != nil {
+		return nil, nil, err
+	}
+	if config.VerifyOpen && config.ScanType == ScanTypeIdle {
+		return nil, nil, errVerifyIdle
+	}
+	if err := validateStatePolicy(*config); err != nil {
+		return nil, nil, err
+	}
+	if config.FollowUpProbes && config.ScanType == ScanTypeIdle {
+		return nil, nil, errFollowUpIdle
+	}
+	var stopMatch *resultMatcher
+	if config.StopOnMatch != "" {
+		if stopMatch, err = parseResultMatcher(config.StopOnMatch); err != nil {
+			return nil, nil, err
+		}
+	}
+	config.clientCert = clientCert
+	if config.gentle, err = newGentlePolicy(config.GentleHosts); err != nil {
+		return nil, nil, err
+	}
+	if config.sources, err = newSourcePool(*config); err != nil {
+		return nil, nil, err
+	}
+	if len(config.Proxies) > 0 && config.ScanType != ScanTypeConnect {
+		return nil, nil, errProxyScanType
+	}
+	if config.proxies, err = newProxyPool(config.Proxies, config.Timeout); err != nil {
+		return nil, nil, err
+	}
+	return ports, stopMatch, nil
+}
+
+// ScanWithConfig 使用完整的扫描配置启动端口扫描
+func (a *App) ScanWithConfig(config ScanConfig) error {
 	if a == nil || a.ctx == nil {
 		return fmt.Errorf("app context is not initialized")
 	}
 
+	if deferred, err := a.checkScanWindow(config); deferred || err != nil {
+		return err
+	}
+
+	ports, stopMatch, err := prepareScanConfig(&config)
+	if err != nil {
+		return err
+	}
+	startPort, endPort := ports[0], ports[len(ports)-1]
+	config.bandwidth = newBandwidthMeter(config.MaxBandwidth)
+	if config.AdaptiveThrottle || config.AutoConcurrency {
+		config.limiter = newAdaptiveLimiter(config.connectThreads(), nil)
+	}
+	config.pause = &pauseGate{}
+	if config.AdaptiveTimeout {
+		config.rtt = newRTTEstimator(config.Timeout, config.AdaptiveTimeoutFactor)
+	}
+	mode, err := selectScanType(config, rawSocketAvailable)
+	if err != nil {
+		return err
+	}
+	config.ScanType = mode.Selected
+	if mode.FellBack && validateICMPHandling(config) != nil {
+		// 回退后的扫描类型无法关联ICMP报文
+		config.ICMPHandling = ICMPIgnore
+	}
+	a.emitEvent("scan-mode-selected", mode)
+
+	// 扫描前规范化目标列表：解析主机名并合并重复/别名IP，解析过程可被StopScan中断
+	resolveCtx, cancelResolve := context.WithCancel(context.Background())
+	scanMutex.Lock()
+	resolveCancel = cancelResolve
+	config.pipeline = a.pipeline
+	scanMutex.Unlock()
+	config.phases = newPhaseTracker(config)
+
+	hosts, duplicates, unresolved, err := resolveTargets(resolveCtx, config)
+	a.reportUnresolved(config, unresolved)
+	if err == nil {
+		a.reportAddressSelections(hosts)
+		hosts, err = a.applyDenylist(config, hosts)
+	}
+	if err == nil {
+		err = a.checkSafeMode(config, hosts)
+	}
+	if err == nil && config.runsDiscovery() {
+		hosts, err = a.discoverTargets(resolveCtx, config, hosts)
+	}
+	if err == nil {
+		a.checkInterception(resolveCtx, config, hosts)
+	}
+
+	scanMutex.Lock()
+	resolveCancel = nil
+	scanMutex.Unlock()
+	cancelResolve()
+
+	if err != nil {
+		if errors.Is(err, ErrDNSTimeout) {
+			a.emitEvent("dns-timeout", map[string]interface{}{
+				"target":  config.Target,
+				"timeout": config.DNSTimeout.Seconds(),
+				"error":   err.Error(),
+			})
+		}
+		return err
+	}
+
+	var stream *jsonlWriter
+	if config.StreamToFile != "" {
+		if stream, err = openJSONLWriter(config.StreamToFile); err != nil {
+			return err
+		}
+	}
+
 	scanMutex.Lock()
 	defer scanMutex.Unlock()
 
 	ctx, cancel := context.WithCancel(context.Background())
-	totalPorts := int32(endPort - startPort + 1)
+	totalPorts := int64(len(ports)) * int64(len(hosts))
+	if len(config.endpoints) > 0 {
+		config.hostPorts, config.endpointNames = planEndpoints(hosts, config.endpoints)
+		totalPorts = int64(len(config.endpointNames))
+	}
+
+	// 按完整端口列表登记检查点，续扫时沿用原扫描的进度
+	checkpoint := newScanCheckpoint()
+	if config.resume != nil {
+		checkpoint = config.resume.checkpoint
+	}
+	for _, h := range hosts {
+		checkpoint.register(h.IP, len(config.portsFor(h.IP, ports)))
+	}
+	config.checkpoint = checkpoint
+	if config.resume != nil {
+		var skippedHosts int
+		hosts, config.hostPorts, skippedHosts = planResume(config, hosts, ports)
+		if len(hosts) == 0 {
+			cancel()
+			if stream != nil {
+				stream.Close()
+			}
+			return fmt.Errorf("scan %q has no remaining ports to resume", config.resume.from)
+		}
+		totalPorts = 0
+		for _, left := range config.hostPorts {
+			totalPorts += int64(len(left))
+		}
+		fmt.Printf("续扫 %s: 跳过 %d 台已完成主机，剩余 %d 台主机 %d 个端口\n", config.resume.from, skippedHosts, len(hosts), totalPorts)
+	}
+
+	config.hosts = hosts
+	config.registry = newHostRegistry()
+	for _, h := range hosts {
+		config.registry.register(h.IP, len(config.portsFor(h.IP, ports)))
+	}
+	config.emit = a.emitEvent
+	config.probeEvents = a.eventLevel() == verbosityVerbose
+	config.PortCount = len(ports)
+	record := createScanRecord(config)
+	record.mu.Lock()
+	record.summary.ConfigHash = configHash(config)
+	record.summary.Unresolved = unresolved
+	record.mu.Unlock()
+	config.logf = record.log.add
+	if config.proxies != nil {
+		config.proxies.onDown = func(proxy string, err error, retryAfter time.Duration) {
+			record.log.add(logWarn, "代理 %s 不可用，暂停使用 %v: %v", proxy, retryAfter, err)
+			a.emitEvent("proxy-unavailable", map[string]interface{}{
+				"proxy":       proxy,
+				"error":       err.Error(),
+				"retry_after": retryAfter.Seconds(),
+			})
+		}
+	}
+	if mode.FellBack {
+		record.log.add(logWarn, "扫描类型回退: %s", mode.Reason)
+	}
+	a.setEventContext(&EventContext{ScanID: record.summary.ID, CorrelationID: config.CorrelationID})
+	a.timeline.Store(&record.timeline)
+	record.checkpoint = checkpoint
+	var syslog *syslogForwarder
+	if config.SyslogTarget != "" {
+		syslog, _ = newSyslogForwarder(config.SyslogTarget, record.summary.ID, config.CorrelationID)
+	}
+	var webhook *findingWebhook
+	if config.FindingWebhook != "" {
+		scanID := record.summary.ID
+		webhook, _ = newFindingWebhook(config.FindingWebhook, scanID, config.CorrelationID, func(job webhookJob, err error) {
+			record.log.add(logWarn, "webhook投递 %s:%d 失败(已尝试 %d 次): %v", job.info.Host, job.info.Port, job.attempts, err)
+			a.emitEvent("webhook-failed", map[string]interface{}{
+				"scan_id":  scanID,
+				"host":     job.info.Host,
+				"port":     job.info.Port,
+				"attempts": job.attempts,
+				"error":    err.Error(),
+			})
+		})
+	}
+	if config.resume != nil {
+		record.resumeFrom(config.resume)
+		record.log.add(logInfo, "从扫描 %s 续扫，剩余 %d 台主机 %d 个端口", config.resume.from, len(hosts), totalPorts)
+	}
+
+	effectiveConfig = config
+	effectiveConfig.Ports = ports
+	if duplicates > 0 {
+		record.log.add(logInfo, "目标去重: 合并 %d 个重复项，剩余 %d 台主机", duplicates, len(hosts))
+	}
+	for _, u := range unresolved {
+		record.log.add(logWarn, "跳过无法解析的目标 %s: %s", u.Host, u.Error)
+	}
+
+	// both 扫描每个端口的TCP和UDP分别计入进度
+	totalPorts *= config.unitsPerPort()
 
 	// 创建新的 scanControl
 	newScan := &scanControl{
+		record:     record,
+		registry:   config.registry,
 		cancel:     cancel,
 		totalPorts: totalPorts,
 		scanned:    0,
+		rtt:        config.rtt,
+		limiter:    config.limiter,
+		pause:      config.pause,
+		phases:     config.phases,
+		done:       make(chan struct{}),
+	}
+	config.phases.attach(record.summary.ID, func() int64 {
+		return atomic.LoadInt64(&newScan.totalPorts) / config.unitsPerPort()
+	})
+
+	// 超时主机未派发的端口不再扫描，与CancelTarget一样从总数中扣除
+	config.onHostTimeout = func(host string, skipped int) {
+		total := atomic.AddInt64(&newScan.totalPorts, -int64(skipped)*config.unitsPerPort())
+		a.emitEvent("host-timeout", map[string]interface{}{
+			"host":          host,
+			"timeout":       config.PerHostTimeout.Seconds(),
+			"skipped_ports": skipped,
+			"total_ports":   total,
+		})
+	}
+	config.onError = func(category string) {
+		if counts, ok := newScan.countError(category); ok {
+			a.emitEvent("error-summary", counts)
+		}
 	}
 
 	// 原子性地替换 currentScan
+	clearRetainedScan()
 	currentScan = newScan
 
-	config := ScanConfig{
-		Target:     IP,
-		StartPort:  startPort,
-		EndPort:    endPort,
-		MaxThreads: maxThreads,
-		Timeout:    time.Second * 2,
-	}
+	events := newEventThrottle(config.emit, config.UnthrottledEvents)
 
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				runtime.EventsEmit(a.ctx, "scan-error", "Internal error occurred")
+				a.emitEvent("scan-error", "Internal error occurred")
+			}
+			events.close()
+			if stream != nil {
+				stream.Close()
 			}
+			if syslog != nil {
+				syslog.close()
+			}
+			if webhook != nil {
+				if left := webhook.close(); left > 0 {
+					fmt.Printf("webhook投递超时，%d 个发现未发送\n", left)
+				}
+			}
+			config.proxies.close()
 			scanMutex.Lock()
 			currentScan = nil
+			a.retainScan(newScan, config.CompletedRetention)
+			a.setEventContext(nil)
+			a.timeline.Store(nil)
 			scanMutex.Unlock()
-			runtime.EventsEmit(a.ctx, "scan-status", "idle")
+			close(newScan.done)
+		}()
+
+		// 定期保存检查点，进程意外退出后可从最近的进度续扫
+		saveTicker := time.NewTicker(checkpointSaveInterval)
+		saveDone := make(chan struct{})
+		defer close(saveDone)
+		go func() {
+			defer saveTicker.Stop()
+			for {
+				select {
+				case <-saveDone:
+					return
+				case <-saveTicker.C:
+					if err := saveScanRecord(record); err != nil {
+						fmt.Printf("保存扫描检查点失败: %v\n", err)
+					}
+				}
+			}
 		}()
 
+		a.emitEvent("scan-started", map[string]interface{}{
+			"scan_id": record.summary.ID,
+			"target":  config.Target,
+		})
+		if syslog != nil {
+			syslog.lifecycle("started", config.Target, "")
+		}
+
+		if duplicates > 0 {
+			a.emitEvent("targets-deduplicated", map[string]interface{}{
+				"duplicates": duplicates,
+				"hosts":      len(hosts),
+			})
+		}
+		a.emitHostInfo(hosts)
+		stopPhases := config.phases.run(a.emitEvent)
+		defer stopPhases()
+		if config.PauseOnNetworkChange {
+			watchCtx, stopWatch := context.WithCancel(ctx)
+			defer stopWatch()
+			go a.watchNetwork(watchCtx, config, record)
+		}
+
 		// 发送初始状态
-		runtime.EventsEmit(a.ctx, "scan-status", "running")
-		runtime.EventsEmit(a.ctx, "scan-progress", map[string]interface{}{
+		a.emitEvent("scan-status", "running")
+		a.emitEvent("scan-progress", map[string]interface{}{
 			"current_port": startPort,
-			"total_ports":  totalPorts,
+			"total_ports":  atomic.LoadInt64(&newScan.totalPorts),
 			"status":       "scanning",
 		})
 
-		err := ScanPortsCombined(ctx, config, func(portInfo PortInfo) {
+		// MaxResults 达到上限或StopOnMatch匹配时只停止端口扫描，复核等收尾步骤照常进行，扫描按完成处理
+		scanCtx, stopScan := context.WithCancel(ctx)
+		defer stopScan()
+		var limitReached, matchStopped atomic.Bool
+		found := 0
+		checkMatch := func(portInfo PortInfo) {
+			if stopMatch == nil || portInfo.State != "" || !stopMatch.matches(portInfo) {
+				return
+			}
+			stopping := !config.ContinueOnMatch && !matchStopped.Swap(true)
+			record.log.add(logInfo, "%s:%d %s %s 匹配 %q", portInfo.Host, portInfo.Port, portInfo.ProductName, portInfo.Version, stopMatch.pattern)
+			a.emitEvent("match-found", map[string]interface{}{
+				"scan_id":  record.summary.ID,
+				"pattern":  stopMatch.pattern,
+				"result":   portInfo,
+				"stopping": stopping,
+			})
+			if stopping {
+				record.log.add(logInfo, "找到匹配 %q 的服务，停止扫描", stopMatch.pattern)
+				stopScan()
+			}
+		}
+
+		// 扫描线程只负责入队，由单独的协程分发结果，分发跟不上时扫描线程降速等待
+		dedup := newResultDedup()
+		if config.resume != nil {
+			for _, r := range config.resume.results {
+				dedup.classify(r)
+				if r.State == "" {
+					found++
+				}
+			}
+		}
+		results := newResultQueue(config.ResultBuffer, func(portInfo PortInfo) {
 			scanMutex.Lock()
 			if currentScan == nil {
 				scanMutex.Unlock()
@@ -80,30 +467,102 @@ func (a *App) ScanPorts(IP string, startPort int, endPort int, maxThreads int) e
 			scanMutex.Unlock()
 
 			if portInfo.Protocol == "progress" {
-				scanned := atomic.AddInt32(&currentScan.scanned, 1)
-				runtime.EventsEmit(a.ctx, "scan-progress", map[string]interface{}{
+				if !dedup.firstProgress(portInfo) {
+					return
+				}
+				scanned := atomic.AddInt64(&currentScan.scanned, 1)
+				progress := map[string]interface{}{
 					"current_port": portInfo.Port,
-					"total_ports":  totalPorts,
+					"total_ports":  atomic.LoadInt64(&newScan.totalPorts),
 					"scanned":      scanned,
 					"status":       "scanning",
-				})
+				}
+				if timeout := newScan.effectiveTimeout(); timeout > 0 {
+					progress["effective_timeout"] = timeout
+				}
+				events.progress(progress)
 			} else {
+				switch first, updated := dedup.classify(portInfo); {
+				case updated:
+					if record.updateResult(portInfo) {
+						a.emitEvent("port-updated", portInfo)
+						checkMatch(portInfo)
+					}
+					return
+				case !first:
+					record.log.add(logDebug, "%s:%d 重复报告开放，已忽略", portInfo.Host, portInfo.Port)
+					return
+				case limitReached.Load():
+					// 停止前已在进行的探测仍会报告结果
+					record.log.add(logDebug, "%s:%d 已达到结果数上限，已忽略", portInfo.Host, portInfo.Port)
+					return
+				}
 				// 发送完整的端口信息，包括指纹识别结果
-				runtime.EventsEmit(a.ctx, "port-found", map[string]interface{}{
-					"port":             portInfo.Port,
-					"protocol":         portInfo.Protocol,
-					"service":          portInfo.Service,
-					"product_name":     portInfo.ProductName,
-					"version":          portInfo.Version,
-					"info":             portInfo.Info,
-					"hostname":         portInfo.Hostname,
-					"operating_system": portInfo.OperatingSystem,
-					"device_type":      portInfo.DeviceType,
-					"probe_name":       portInfo.ProbeName,
-					"tls":              portInfo.TLS,
-				})
+				record.addResult(portInfo)
+				if stream != nil {
+					if err := stream.Write(portInfo); err != nil {
+						fmt.Printf("写入结果流文件失败: %v\n", err)
+						record.log.add(logError, "写入结果流文件失败: %v", err)
+					}
+				}
+				events.portFound(portInfo)
+				if syslog != nil {
+					syslog.finding(portInfo)
+				}
+				if webhook != nil {
+					webhook.finding(portInfo)
+				}
+				if counts, ok := newScan.countService(portInfo.Service); ok {
+					a.emitEvent("service-counts", counts)
+				}
+				if top, ok := newScan.countTop(portInfo); ok {
+					a.emitEvent("top-n-updated", top)
+				}
+				checkMatch(portInfo)
+				if portInfo.State == "" && config.MaxResults > 0 {
+					if found++; found >= config.MaxResults && !limitReached.Swap(true) {
+						record.log.add(logInfo, "已发现 %d 个开放端口，达到结果数上限，停止扫描", found)
+						a.emitEvent("max-results-reached", map[string]interface{}{
+							"scan_id":     record.summary.ID,
+							"max_results": config.MaxResults,
+						})
+						stopScan()
+					}
+				}
 			}
+		}, func(buffered, capacity int, stalls int64) {
+			record.log.add(logWarn, "结果分发跟不上扫描速度，扫描线程等待中(缓冲 %d/%d)", buffered, capacity)
+			a.emitEvent("results-backpressure", map[string]interface{}{
+				"buffered": buffered,
+				"capacity": capacity,
+				"stalls":   stalls,
+			})
 		})
+		defer results.close()
+
+		err := ScanPortsCombined(scanCtx, config, results.push)
+
+		results.close()
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil && (limitReached.Load() || matchStopped.Load()) {
+			err = nil
+		}
+		if err == nil && config.FollowUpProbes && !limitReached.Load() && !matchStopped.Load() {
+			err = a.runFollowUps(ctx, config, record, events.portFound)
+		}
+		if err == nil && config.VerifyOpen {
+			err = a.verifyOpenPorts(ctx, config, record)
+		}
+		events.close()
+
+		switch {
+		case err == context.Canceled:
+			config.phases.finish("cancelled")
+		case err != nil:
+			config.phases.finish("error")
+		default:
+			config.phases.finish("completed")
+		}
+		stopPhases()
 
 		scanMutex.Lock()
 		defer scanMutex.Unlock()
@@ -111,80 +570,265 @@ func (a *App) ScanPorts(IP string, startPort int, endPort int, maxThreads int) e
 		if currentScan == nil {
 			return
 		}
+		a.emitEvent("service-counts", currentScan.serviceCountsSnapshot())
+		a.emitEvent("top-n-updated", currentScan.topSnapshot(defaultTopN))
+		a.emitEvent("error-summary", currentScan.errorCountsSnapshot())
 
 		if err != nil {
 			if err == context.Canceled {
-				runtime.EventsEmit(a.ctx, "scan-status", "cancelled")
-				runtime.EventsEmit(a.ctx, "scan-progress", map[string]interface{}{
-					"current_port": atomic.LoadInt32(&currentScan.scanned),
-					"total_ports":  totalPorts,
+				record.log.add(logInfo, "扫描已取消")
+				record.finish("cancelled")
+				a.alertScanComplete(config, record, err)
+				a.emitAggregate(config, record, hosts, "cancelled")
+				if syslog != nil {
+					syslog.lifecycle("cancelled", config.Target, "")
+				}
+				a.emitEvent("scan-status", "cancelled")
+				a.emitEvent("scan-progress", map[string]interface{}{
+					"current_port": atomic.LoadInt64(&currentScan.scanned),
+					"total_ports":  atomic.LoadInt64(&newScan.totalPorts),
 					"status":       "cancelled",
 				})
 			} else {
-				runtime.EventsEmit(a.ctx, "scan-error", err.Error())
-				runtime.EventsEmit(a.ctx, "scan-status", "error")
-				runtime.EventsEmit(a.ctx, "scan-progress", map[string]interface{}{
-					"current_port": atomic.LoadInt32(&currentScan.scanned),
-					"total_ports":  totalPorts,
+				record.log.add(logError, "扫描出错: %v", err)
+				record.finish("error")
+				a.alertScanComplete(config, record, err)
+				if syslog != nil {
+					syslog.lifecycle("error", config.Target, err.Error())
+				}
+				a.emitEvent("scan-error", err.Error())
+				a.emitEvent("scan-status", "error")
+				a.emitEvent("scan-progress", map[string]interface{}{
+					"current_port": atomic.LoadInt64(&currentScan.scanned),
+					"total_ports":  atomic.LoadInt64(&newScan.totalPorts),
 					"status":       "error",
 				})
 			}
 		} else {
-			runtime.EventsEmit(a.ctx, "scan-complete", map[string]interface{}{
-				"total_ports": totalPorts,
-				"scanned":     atomic.LoadInt32(&currentScan.scanned),
-			})
-			runtime.EventsEmit(a.ctx, "scan-status", "completed")
-			runtime.EventsEmit(a.ctx, "scan-progress", map[string]interface{}{
+			record.log.add(logInfo, "扫描完成，共探测 %d 个端口", atomic.LoadInt64(&currentScan.scanned))
+			if config.DetectHoneypots {
+				a.checkHoneypots(config, record, hosts)
+			}
+			if config.CollapsePortRanges {
+				a.emitPortRanges(config, record)
+			}
+			if config.DualStackCompare {
+				a.emitDualStackDiffs(record, hosts)
+			}
+			if config.MergeByIP {
+				a.mergeRecordResults(record)
+			}
+			record.finish("completed")
+			a.alertScanComplete(config, record, nil)
+			if syslog != nil {
+				summary, _, _ := record.snapshot()
+				syslog.lifecycle("completed", config.Target, fmt.Sprintf("%d open ports", summary.OpenPorts))
+			}
+			if config.EmitHostSummary {
+				_, _, results := record.snapshot()
+				for _, summary := range summarizeHosts(hosts, results) {
+					a.emitEvent("host-summary", summary)
+				}
+			}
+			a.emitAggregate(config, record, hosts, "completed")
+			complete := map[string]interface{}{
+				"scan_id":     record.summary.ID,
+				"total_ports": atomic.LoadInt64(&newScan.totalPorts),
+				"scanned":     atomic.LoadInt64(&currentScan.scanned),
+			}
+			if len(unresolved) > 0 {
+				complete["unresolved"] = unresolved
+			}
+			a.emitEvent("scan-complete", complete)
+			a.emitEvent("scan-status", "completed")
+			a.emitEvent("scan-progress", map[string]interface{}{
 				"current_port": endPort,
-				"total_ports":  totalPorts,
+				"total_ports":  atomic.LoadInt64(&newScan.totalPorts),
 				"status":       "completed",
 			})
+			if len(config.AutoExportFormats) > 0 {
+				go a.runAutoExport(config, record)
+			}
+			if config.PostScanCommand != "" {
+				go a.runPostScanCommand(config, record)
+			}
 		}
 	}()
 
 	return nil
 }
 
+// discoverTargets 扫描前探测存活主机，探测过程同样可被StopScan中断
+func (a *App) discoverTargets(ctx context.Context, config ScanConfig, hosts []scanTarget) ([]scanTarget, error) {
+	config.phases.startDiscovery(len(hosts))
+	stop := config.phases.run(a.emitEvent)
+	defer stop()
+	live := discoverHosts(ctx, hosts, config.Timeout, config.MaxThreads, config.connectDialer(), config.sources, config.phases.hostDiscovered)
+	config.phases.discoveryFinished()
+	if ctx.Err() != nil {
+		return nil, context.Canceled
+	}
+	a.emitEvent("hosts-discovered", map[string]interface{}{
+		"hosts": len(hosts),
+		"alive": len(live),
+	})
+	if len(live) == 0 {
+		return nil, fmt.Errorf("no live hosts found in %s", config.Target)
+	}
+	return live, nil
+}
+
 func (a *App) StopScan() error {
 	scanMutex.Lock()
 	defer scanMutex.Unlock()
 
+	if resolveCancel != nil {
+		resolveCancel()
+	}
+	if fingerprintCancel != nil {
+		fingerprintCancel()
+	}
+	if deferred != nil {
+		deferred.cancel()
+		deferred = nil
+		a.emitEvent("scan-status", "cancelled")
+	}
+
 	if currentScan != nil && currentScan.cancel != nil {
 		currentScan.cancel()
-		runtime.EventsEmit(a.ctx, "scan-status", "stopping")
-		runtime.EventsEmit(a.ctx, "scan-progress", map[string]interface{}{
-			"current_port": atomic.LoadInt32(&currentScan.scanned),
-			"total_ports":  currentScan.totalPorts,
+		a.emitEvent("scan-status", "stopping")
+		a.emitEvent("scan-progress", map[string]interface{}{
+			"current_port": atomic.LoadInt64(&currentScan.scanned),
+			"total_ports":  atomic.LoadInt64(&currentScan.totalPorts),
 			"status":       "stopping",
 		})
 	}
 	return nil
 }
 
+// GetEffectiveConfig 返回当前或最近一次扫描实际使用的配置，
+// 包含填充后的默认值、解析出的端口列表和端口数量，尚未扫描过时返回零值
+func (a *App) GetEffectiveConfig() ScanConfig {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+	return effectiveConfig
+}
+
+// CancelTarget 在多目标扫描中单独停止某台主机，其余主机继续扫描
+func (a *App) CancelTarget(host string) error {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+
+	if currentScan == nil {
+		return fmt.Errorf("no scan is running")
+	}
+	ip, ok := currentScan.matchHost(host)
+	if !ok {
+		return fmt.Errorf("host %s is not part of the current scan", host)
+	}
+	skipped, err := currentScan.registry.cancel(ip)
+	if err != nil {
+		return err
+	}
+
+	// 未派发的端口不再扫描，从总数中扣除以保证进度准确
+	total := atomic.AddInt64(&currentScan.totalPorts, -int64(skipped)*currentScan.record.config.unitsPerPort())
+	currentScan.record.log.add(logInfo, "用户取消主机 %s (%s)，跳过 %d 个未派发端口", ip, host, skipped)
+	a.emitEvent("target-cancelled", map[string]interface{}{
+		"host":          ip,
+		"input":         host,
+		"skipped_ports": skipped,
+		"total_ports":   total,
+	})
+	return nil
+}
+
+// retainScan 扫描结束后在保留期内保留其状态，保留期结束且没有新扫描时才发送 scan-status idle。
+// 调用方需持有scanMutex
+func (a *App) retainScan(scan *scanControl, retention time.Duration) {
+	summary, _, _ := scan.record.snapshot()
+	status := summary.Status
+	if status == "running" {
+		status = "error"
+	}
+	if retention < 0 {
+		a.emitEvent("scan-status", "idle")
+		return
+	}
+
+	clearRetainedScan()
+	entry := &finishedScan{control: scan, status: status}
+	entry.timer = time.AfterFunc(retention, func() {
+		scanMutex.Lock()
+		defer scanMutex.Unlock()
+		if lastScan != entry {
+			return
+		}
+		lastScan = nil
+		if currentScan == nil {
+			a.emitEvent("scan-status", "idle")
+		}
+	})
+	lastScan = entry
+}
+
+// GetScanStatus 返回 running、paused、刚结束扫描的结束状态(保留期内)或 idle
 func (a *App) GetScanStatus() string {
 	scanMutex.Lock()
 	defer scanMutex.Unlock()
 
 	if currentScan != nil {
-		return "running"
+		return currentScan.status()
+	}
+	if lastScan != nil {
+		return lastScan.status
 	}
 	return "idle"
 }
 
+// GetServiceCounts 返回当前扫描(或保留期内刚结束的扫描)中各服务已发现的数量
+func (a *App) GetServiceCounts() map[string]int {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+
+	switch {
+	case currentScan != nil:
+		return currentScan.serviceCountsSnapshot()
+	case lastScan != nil:
+		return lastScan.control.serviceCountsSnapshot()
+	}
+	return map[string]int{}
+}
+
 func (a *App) GetScanProgress() ScanProgress {
 	scanMutex.Lock()
 	defer scanMutex.Unlock()
 
 	if currentScan == nil {
+		if lastScan != nil {
+			scan := lastScan.control
+			return ScanProgress{
+				CurrentPort:      atomic.LoadInt64(&scan.scanned),
+				TotalPorts:       atomic.LoadInt64(&scan.totalPorts),
+				Status:           lastScan.status,
+				ScanID:           scan.record.summary.ID,
+				EffectiveTimeout: scan.effectiveTimeout(),
+				Concurrency:      scan.concurrency(),
+				Phases:           scan.phaseProgress(),
+			}
+		}
 		return ScanProgress{
 			Status: "idle",
 		}
 	}
 
 	return ScanProgress{
-		CurrentPort: atomic.LoadInt32(&currentScan.scanned),
-		TotalPorts:  currentScan.totalPorts,
-		Status:      "running",
+		CurrentPort:      atomic.LoadInt64(&currentScan.scanned),
+		TotalPorts:       atomic.LoadInt64(&currentScan.totalPorts),
+		Status:           currentScan.status(),
+		ScanID:           currentScan.record.summary.ID,
+		EffectiveTimeout: currentScan.effectiveTimeout(),
+		Concurrency:      currentScan.concurrency(),
+		Phases:           currentScan.phaseProgress(),
 	}
 }
@@ -0,0 +1,69 @@
+package portsscanner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errScanArchived 已归档的扫描不允许清理、覆盖或修改
+var errScanArchived = errors.New("scan is archived")
+
+// archivedError 返回指明扫描ID的归档错误
+func archivedError(scanID string) error {
+	return fmt.Errorf("scan %q is archived, unarchive it first: %w", scanID, errScanArchived)
+}
+
+// setArchived 修改扫描的归档状态并立即持久化，保存失败时恢复原状态
+func setArchived(scanID string, archived bool) error {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return err
+	}
+
+	record.mu.Lock()
+	if record.summary.Status == "running" {
+		record.mu.Unlock()
+		return fmt.Errorf("scan %q is still running", scanID)
+	}
+	if record.summary.Archived == archived {
+		record.mu.Unlock()
+		return nil
+	}
+	previous := record.summary.ArchivedAt
+	record.summary.Archived = archived
+	record.summary.ArchivedAt = nil
+	if archived {
+		now := time.Now()
+		record.summary.ArchivedAt = &now
+	}
+	record.mu.Unlock()
+
+	if err := saveScanRecord(record); err != nil {
+		record.mu.Lock()
+		record.summary.Archived = !archived
+		record.summary.ArchivedAt = previous
+		record.mu.Unlock()
+		return fmt.Errorf("failed to save scan %q: %w", scanID, err)
+	}
+	return nil
+}
+
+// ArchiveScan 将已结束的扫描标记为归档：结果仍可查看和导出，但不会被保留策略清理，
+// 也不能被同ID的结果文件覆盖或重新识别，直到调用UnarchiveScan
+func (a *App) ArchiveScan(scanID string) error {
+	if err := setArchived(scanID, true); err != nil {
+		return err
+	}
+	fmt.Printf("扫描 %s 已归档\n", scanID)
+	return nil
+}
+
+// UnarchiveScan 取消扫描的归档标记
+func (a *App) UnarchiveScan(scanID string) error {
+	if err := setArchived(scanID, false); err != nil {
+		return err
+	}
+	fmt.Printf("扫描 %s 已取消归档\n", scanID)
+	return nil
+}
@@ -0,0 +1,186 @@
+package portsscanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 发送完最后一个ARP请求后继续等待响应的时间
+	arpReplyWait = 2 * time.Second
+	// 相邻ARP请求的发送间隔，过快时交换机和目标主机可能丢弃请求
+	arpSendInterval = 500 * time.Microsecond
+)
+
+var errARPUnsupported = errors.New("ARP scanning is only supported on Linux")
+
+// HostInfo ARP扫描发现的本地网段主机
+type HostInfo struct {
+	IP        string `json:"ip"`
+	MAC       string `json:"mac"`
+	Vendor    string `json:"vendor,omitempty"` // 根据MAC前缀(OUI)识别的厂商，内置表未收录时为空
+	Interface string `json:"interface"`
+}
+
+// ouiVendors 常见网卡厂商的OUI前缀，只收录虚拟化平台和局域网中常见的设备
+var ouiVendors = map[string]string{
+	"00:50:56": "VMware",
+	"00:0c:29": "VMware",
+	"00:05:69": "VMware",
+	"00:1c:14": "VMware",
+	"08:00:27": "Oracle VirtualBox",
+	"52:54:00": "QEMU/KVM",
+	"00:15:5d": "Microsoft Hyper-V",
+	"00:16:3e": "Xen",
+	"b8:27:eb": "Raspberry Pi",
+	"dc:a6:32": "Raspberry Pi",
+	"e4:5f:01": "Raspberry Pi",
+	"00:00:0c": "Cisco",
+	"f0:9f:c2": "Ubiquiti",
+	"00:11:32": "Synology",
+}
+
+// macVendor 根据MAC地址前三个字节查找厂商
+func macVendor(mac net.HardwareAddr) string {
+	if len(mac) < 3 {
+		return ""
+	}
+	return ouiVendors[strings.ToLower(mac[:3].String())]
+}
+
+// arpInterface 查找直连目标网段的网卡及其IPv4地址，ARP只能在本地二层网段内使用
+func arpInterface(network *net.IPNet) (*net.Interface, net.IP, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) != 6 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.To4() == nil {
+				continue
+			}
+			if ipnet.Contains(network.IP) || network.Contains(ipnet.IP) {
+				return iface, ipnet.IP.To4(), nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("no local interface is attached to %s; ARP only works on the local network segment", network)
+}
+
+// arpTargets 展开网段中的IPv4地址，跳过网络地址、广播地址和本机地址
+func arpTargets(network *net.IPNet, self net.IP, maxHosts int) ([]net.IP, error) {
+	base := network.IP.To4()
+	if base == nil {
+		return nil, fmt.Errorf("ARP scanning only supports IPv4 networks: %s", network)
+	}
+	ones, bits := network.Mask.Size()
+	size := uint64(1) << uint(bits-ones)
+	if size > uint64(maxHosts) {
+		return nil, fmt.Errorf("%s contains %d addresses, more than the limit of %d", network, size, maxHosts)
+	}
+	start := binary.BigEndian.Uint32(base)
+	targets := make([]net.IP, 0, size)
+	for i := uint64(0); i < size; i++ {
+		if size > 2 && (i == 0 || i == size-1) {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, start+uint32(i))
+		if !ip.Equal(self) {
+			targets = append(targets, ip)
+		}
+	}
+	return targets, nil
+}
+
+// buildARPRequest 构造广播ARP请求的以太网帧
+func buildARPRequest(srcMAC net.HardwareAddr, srcIP, dstIP net.IP) []byte {
+	frame := make([]byte, 42)
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:], 0x0806)
+
+	arp := frame[14:]
+	binary.BigEndian.PutUint16(arp[0:], 1)      // 以太网
+	binary.BigEndian.PutUint16(arp[2:], 0x0800) // IPv4
+	arp[4], arp[5] = 6, 4
+	binary.BigEndian.PutUint16(arp[6:], 1) // 请求
+	copy(arp[8:14], srcMAC)
+	copy(arp[14:18], srcIP.To4())
+	copy(arp[24:28], dstIP.To4())
+	return frame
+}
+
+// parseARPReply 解析ARP应答帧，返回发送方的IP和MAC
+func parseARPReply(frame []byte) (net.IP, net.HardwareAddr, bool) {
+	if len(frame) < 42 || binary.BigEndian.Uint16(frame[12:]) != 0x0806 {
+		return nil, nil, false
+	}
+	arp := frame[14:]
+	if binary.BigEndian.Uint16(arp[6:]) != 2 || arp[4] != 6 || arp[5] != 4 {
+		return nil, nil, false
+	}
+	mac := make(net.HardwareAddr, 6)
+	copy(mac, arp[8:14])
+	return net.IP(bytes.Clone(arp[14:18])), mac, true
+}
+
+// ARPScan 向本地网段广播ARP请求并列出应答的主机及其MAC和厂商。
+// ARP不经过主机防火墙，适合可靠地发现同一二层网段内的在线主机；
+// 需要root或CAP_NET_RAW权限，目前只支持Linux。每收到一个应答发送 host-found 事件
+func (a *App) ARPScan(cidr string) ([]HostInfo, error) {
+	if a == nil || a.ctx == nil {
+		return nil, fmt.Errorf("app context is not initialized")
+	}
+	_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	iface, self, err := arpInterface(network)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := arpTargets(network, self, defaultMaxHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu    sync.Mutex
+		hosts []HostInfo
+	)
+	fmt.Printf("ARP扫描 %s，网卡 %s，共 %d 个地址\n", network, iface.Name, len(targets))
+	err = arpScan(context.Background(), iface, self, targets, func(ip net.IP, mac net.HardwareAddr) {
+		host := HostInfo{IP: ip.String(), MAC: mac.String(), Vendor: macVendor(mac), Interface: iface.Name}
+		mu.Lock()
+		hosts = append(hosts, host)
+		mu.Unlock()
+		a.emitEvent("host-found", host)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(hosts, func(i, j int) bool {
+		return bytes.Compare(net.ParseIP(hosts[i].IP).To4(), net.ParseIP(hosts[j].IP).To4()) < 0
+	})
+	fmt.Printf("ARP扫描完成，发现 %d 台主机\n", len(hosts))
+	return hosts, nil
+}
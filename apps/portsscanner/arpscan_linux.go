@@ -0,0 +1,103 @@
+//go:build linux
+
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// htons 将端口/协议号转换为网络字节序
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+// packetSocketAvailable 尝试创建链路层套接字以判断是否具备ARP扫描所需权限
+func packetSocketAvailable() error {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(syscall.ETH_P_ARP)))
+	if err != nil {
+		return fmt.Errorf("ARP scanning requires root or CAP_NET_RAW: %w", err)
+	}
+	syscall.Close(fd)
+	return nil
+}
+
+// arpScan 通过AF_PACKET套接字在指定网卡上发送ARP请求，每个目标的首个应答回调一次
+func arpScan(ctx context.Context, iface *net.Interface, self net.IP, targets []net.IP, onReply func(net.IP, net.HardwareAddr)) error {
+	proto := htons(syscall.ETH_P_ARP)
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(proto))
+	if err != nil {
+		return fmt.Errorf("ARP scanning requires root or CAP_NET_RAW: %w", err)
+	}
+	defer syscall.Close(fd)
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: proto, Ifindex: iface.Index}); err != nil {
+		return fmt.Errorf("failed to bind to interface %s: %w", iface.Name, err)
+	}
+	// 设置读取超时，保证发送结束后接收协程能及时退出
+	tv := syscall.NsecToTimeval(int64(200 * time.Millisecond))
+	syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+
+	pending := make(map[[4]byte]bool, len(targets))
+	for _, ip := range targets {
+		pending[[4]byte(ip.To4())] = true
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		buf := make([]byte, 1500)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if err != nil {
+				continue
+			}
+			ip, mac, ok := parseARPReply(buf[:n])
+			if !ok {
+				continue
+			}
+			// 接收协程独占pending，无需加锁
+			key := [4]byte(ip.To4())
+			if !pending[key] {
+				continue
+			}
+			delete(pending, key)
+			onReply(ip, mac)
+		}
+	}()
+
+	dst := &syscall.SockaddrLinklayer{
+		Protocol: proto,
+		Ifindex:  iface.Index,
+		Halen:    6,
+		Addr:     [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	var sendErr error
+	for _, ip := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := syscall.Sendto(fd, buildARPRequest(iface.HardwareAddr, self, ip), 0, dst); err != nil {
+			sendErr = fmt.Errorf("failed to send ARP request to %s: %w", ip, err)
+			break
+		}
+		time.Sleep(arpSendInterval)
+	}
+	if sendErr == nil {
+		select {
+		case <-ctx.Done():
+		case <-time.After(arpReplyWait):
+		}
+	}
+	close(done)
+	<-stopped
+	return sendErr
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package portsscanner
+
+import (
+	"context"
+	"net"
+)
+
+// packetSocketAvailable 非Linux平台不支持链路层套接字
+func packetSocketAvailable() error {
+	return errARPUnsupported
+}
+
+// arpScan 非Linux平台不支持通过原始套接字发送ARP请求
+func arpScan(ctx context.Context, iface *net.Interface, self net.IP, targets []net.IP, onReply func(net.IP, net.HardwareAddr)) error {
+	return errARPUnsupported
+}
@@ -0,0 +1,92 @@
+package portsscanner
+
+import (
+	"errors"
+	goruntime "runtime"
+	"syscall"
+)
+
+const (
+	// 每个扫描线程最多同时占用的文件描述符：端口探测连接和指纹识别连接
+	autoFDPerWorker = 2
+	// 为日志、存储文件和界面保留的文件描述符
+	autoFDReserve = 64
+	// 只使用打开文件数上限的这一比例，留出系统和解析器使用的余量
+	autoFDFraction = 0.8
+	// 每个CPU核心的扫描线程数，连接扫描大部分时间在等待网络，指纹匹配才消耗CPU
+	autoThreadsPerCPU = 128
+
+	minAutoConcurrency = 16
+	maxAutoConcurrency = 4096
+)
+
+// ConcurrencyDecision AutoConcurrency 选择并发数的依据，FDCap为0表示没有打开文件数限制
+type ConcurrencyDecision struct {
+	Concurrency int    `json:"concurrency"`
+	FDLimit     uint64 `json:"fd_limit"`
+	FDCap       int    `json:"fd_cap"`
+	CPUs        int    `json:"cpus"`
+	CPUCap      int    `json:"cpu_cap"`
+}
+
+// autoConcurrency 按打开文件数上限和CPU核心数确定初始并发数，取两者中较小的一个，
+// 并限制在 minAutoConcurrency..maxAutoConcurrency 之间。fdLimit为0表示不限制(Windows)
+func autoConcurrency(fdLimit uint64, cpus int) ConcurrencyDecision {
+	d := ConcurrencyDecision{FDLimit: fdLimit, CPUs: max(cpus, 1)}
+	d.CPUCap = d.CPUs * autoThreadsPerCPU
+	d.Concurrency = min(d.CPUCap, maxAutoConcurrency)
+	if fdLimit > 0 {
+		usable := int(float64(fdLimit)*autoFDFraction) - autoFDReserve
+		d.FDCap = max(usable/autoFDPerWorker, 1)
+		d.Concurrency = min(d.Concurrency, d.FDCap)
+	}
+	// 下限不能突破打开文件数的限制
+	if d.Concurrency < minAutoConcurrency && (d.FDCap == 0 || d.FDCap >= minAutoConcurrency) {
+		d.Concurrency = minAutoConcurrency
+	}
+	return d
+}
+
+// detectConcurrency 探测当前进程的资源限制并选择并发数
+func detectConcurrency() ConcurrencyDecision {
+	limit, err := openFileLimit()
+	if err != nil {
+		limit = 0
+	}
+	return autoConcurrency(limit, goruntime.NumCPU())
+}
+
+// isFDExhausted 判断拨号错误是否因为本机打开的文件数或套接字达到上限
+func isFDExhausted(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE)
+}
+
+// exhausted 本机资源耗尽时立即将并发减半，并把上限降为当前值，之后的恢复不会再超过该值。
+// 降低后已在进行的探测仍可能报告耗尽，一个统计窗口内只降低一次
+func (l *adaptiveLimiter) exhausted() {
+	l.mu.Lock()
+	if l.cooldown > 0 {
+		l.mu.Unlock()
+		return
+	}
+	l.cooldown = throttleWindow
+	previous := l.limit
+	if l.limit > 1 {
+		l.limit /= 2
+	}
+	l.max = l.limit
+	l.samples, l.timeouts = 0, 0
+	limit := l.limit
+	l.mu.Unlock()
+
+	if limit != previous && l.onChange != nil {
+		l.onChange(limit, previous, 0, "fd-exhausted")
+	}
+}
+
+// current 返回当前允许的并发数
+func (l *adaptiveLimiter) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
@@ -0,0 +1,151 @@
+package portsscanner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 自动导出支持的格式及文件扩展名
+var autoExportExtensions = map[string]string{
+	"json":     "json",
+	"csv":      "csv",
+	"text":     "txt",
+	"html":     "html",
+	"markdown": "md",
+}
+
+var errOutputDirWithoutFormats = errors.New("OutputDir requires AutoExportFormats")
+
+// exportDirSet 本次运行中自动导出写入过的OutputDir子目录，auto-export-complete 返回的路径可由OpenFile打开
+type exportDirSet struct {
+	mu   sync.Mutex
+	dirs []string
+}
+
+var autoExportDirs exportDirSet
+
+func (s *exportDirSet) add(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.dirs {
+		if d == dir {
+			return
+		}
+	}
+	s.dirs = append(s.dirs, dir)
+}
+
+func (s *exportDirSet) list() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.dirs...)
+}
+
+// validateAutoExport 校验自动导出格式，格式统一为小写并去重
+func validateAutoExport(config *ScanConfig) error {
+	var formats []string
+	for _, f := range config.AutoExportFormats {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if _, ok := autoExportExtensions[f]; !ok {
+			return fmt.Errorf("unsupported auto-export format %q (want json, csv, text, html or markdown)", f)
+		}
+		if !containsString(formats, f) {
+			formats = append(formats, f)
+		}
+	}
+	if len(formats) == 0 && config.OutputDir != "" {
+		return errOutputDirWithoutFormats
+	}
+	config.AutoExportFormats = formats
+	return nil
+}
+
+// autoExportDir 返回本次扫描的导出子目录(OutputDir或~/GlideWay/exports下以扫描ID和开始时间命名)，不存在时创建
+func autoExportDir(config ScanConfig, summary ScanSummary) (string, error) {
+	base := config.OutputDir
+	if base == "" {
+		var err error
+		if base, err = exportDir(); err != nil {
+			return "", err
+		}
+	}
+	name := sanitizeFileName(summary.ID) + "-" + summary.StartedAt.Format("20060102-150405")
+	dir := filepath.Join(base, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create output directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// encodeAutoExport 按格式编码扫描结果
+func encodeAutoExport(format string, summary ScanSummary, metadata ScanMetadata, results []PortInfo) ([]byte, error) {
+	switch format {
+	case "html":
+		return renderHTMLReport(summary, metadata, results)
+	case "markdown":
+		var sb strings.Builder
+		writeMarkdown(&sb, summary, results)
+		return []byte(sb.String()), nil
+	}
+	return encodeResults(format, summary, &metadata, results, nil)
+}
+
+// runAutoExport 扫描完成后将结果按AutoExportFormats写入导出子目录，发送 auto-export-complete 事件。
+// 单个格式写入失败不影响其他格式，错误记录在事件的errors中
+func (a *App) runAutoExport(config ScanConfig, record *scanRecord) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("Recovered from panic in auto-export: %v\n", r)
+			record.log.add(logError, "自动导出发生panic: %v", r)
+		}
+	}()
+	started := time.Now()
+	summary, _, results := record.snapshot()
+	metadata := record.scanMetadata()
+	paths := make(map[string]string)
+	failures := make(map[string]string)
+
+	dir, err := autoExportDir(config, summary)
+	if err != nil {
+		record.log.add(logError, "自动导出失败: %v", err)
+		for _, format := range config.AutoExportFormats {
+			failures[format] = err.Error()
+		}
+	} else {
+		if config.OutputDir != "" {
+			autoExportDirs.add(dir)
+		}
+		for _, format := range config.AutoExportFormats {
+			path := filepath.Join(dir, exportFileName(summary, autoExportExtensions[format]))
+			data, err := encodeAutoExport(format, summary, metadata, results)
+			if err == nil {
+				if err = writeFileAtomic(path, data); err != nil {
+					err = fmt.Errorf("failed to write %s: %w", path, err)
+				}
+			}
+			if err != nil {
+				record.log.add(logWarn, "自动导出 %s 失败: %v", format, err)
+				failures[format] = err.Error()
+				continue
+			}
+			paths[format] = path
+		}
+		record.log.add(logInfo, "已自动导出 %d 个文件到 %s，耗时 %v", len(paths), dir, time.Since(started).Round(time.Millisecond))
+	}
+
+	event := map[string]interface{}{
+		"scan_id":   summary.ID,
+		"directory": dir,
+		"paths":     paths,
+		"results":   len(results),
+	}
+	if len(failures) > 0 {
+		event["errors"] = failures
+	}
+	a.emitEvent("auto-export-complete", event)
+}
@@ -0,0 +1,131 @@
+package portsscanner
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 单次读写的最小分片，速率很低时也不会把读写拆得过碎
+const minBandwidthChunk = 512
+
+// bandwidthMeter 统计探测连接的收发字节数，设置MaxBandwidth时作为所有连接共享的令牌桶限制总吞吐。
+// 与自适应并发限制相互独立，两者可以同时生效。只统计应用层数据，不含TCP握手和报文头部
+type bandwidthMeter struct {
+	rate int64 // 字节/秒，0为不限速
+
+	mu   sync.Mutex
+	next time.Time // 已预约的发送额度用完的时间点
+
+	sent     int64
+	received int64
+}
+
+// newBandwidthMeter 创建计量器，rate<=0时只计数不限速
+func newBandwidthMeter(rate int) *bandwidthMeter {
+	if rate < 0 {
+		rate = 0
+	}
+	return &bandwidthMeter{rate: int64(rate)}
+}
+
+// chunk 单次读写允许的最大字节数，限制在约100毫秒的额度内使速率平滑
+func (m *bandwidthMeter) chunk(n int) int {
+	if m.rate == 0 {
+		return n
+	}
+	limit := int(max(m.rate/10, minBandwidthChunk))
+	return min(n, limit)
+}
+
+// wait 预约n字节的额度并等待到可以传输，最多允许积累一秒的突发额度。扫描被取消时返回false
+func (m *bandwidthMeter) wait(ctx context.Context, n int) bool {
+	if m.rate == 0 || n <= 0 {
+		return ctx.Err() == nil
+	}
+	m.mu.Lock()
+	now := time.Now()
+	if earliest := now.Add(-time.Second); m.next.Before(earliest) {
+		m.next = earliest
+	}
+	m.next = m.next.Add(time.Duration(int64(n) * int64(time.Second) / m.rate))
+	delay := m.next.Sub(now)
+	m.mu.Unlock()
+
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// totals 返回累计发送和接收的字节数
+func (m *bandwidthMeter) totals() (sent, received int64) {
+	return atomic.LoadInt64(&m.sent), atomic.LoadInt64(&m.received)
+}
+
+// wrap 为连接加上计量和限速，m为nil时原样返回
+func (m *bandwidthMeter) wrap(ctx context.Context, conn net.Conn) net.Conn {
+	if m == nil || conn == nil {
+		return conn
+	}
+	_, datagram := conn.(net.PacketConn)
+	return &meteredConn{Conn: conn, ctx: ctx, meter: m, datagram: datagram}
+}
+
+// meteredConn 写入前等待额度；读取时先限制单次读取大小，读到数据后再扣除额度。
+// 数据报连接(UDP)不能拆分读写，每个报文整体扣除额度
+type meteredConn struct {
+	net.Conn
+	ctx      context.Context
+	meter    *bandwidthMeter
+	datagram bool
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+	if !c.datagram {
+		b = b[:c.meter.chunk(len(b))]
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		atomic.AddInt64(&c.meter.received, int64(n))
+		if !c.meter.wait(c.ctx, n) && err == nil {
+			err = c.ctx.Err()
+		}
+	}
+	return n, err
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	// 空写入(如UDP的空数据包)同样要交给底层连接发送
+	if len(b) == 0 || c.datagram {
+		if !c.meter.wait(c.ctx, len(b)) {
+			return 0, c.ctx.Err()
+		}
+		n, err := c.Conn.Write(b)
+		atomic.AddInt64(&c.meter.sent, int64(n))
+		return n, err
+	}
+	written := 0
+	for written < len(b) {
+		n := c.meter.chunk(len(b) - written)
+		if !c.meter.wait(c.ctx, n) {
+			return written, c.ctx.Err()
+		}
+		n, err := c.Conn.Write(b[written : written+n])
+		written += n
+		atomic.AddInt64(&c.meter.sent, int64(n))
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
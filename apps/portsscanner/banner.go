@@ -0,0 +1,157 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 未识别服务记录到Info中的横幅最大字节数
+const unidentifiedBannerSize = 128
+
+const (
+	// 未设置BannerReadSize时读取横幅的字节数，与gonmap探针固定的读取大小一致
+	defaultBannerReadSize = 10240
+	// 单次读取的上限，防止恶意服务端持续发送数据导致无限读取
+	maxBannerReadSize = 1 << 20
+	// 收到部分横幅后等待后续数据的最长间隔
+	bannerIdleTimeout = 300 * time.Millisecond
+)
+
+// 生成探针建议时最多使用的横幅长度，nmap匹配规则以^锚定，无需完整横幅
+const suggestBannerSize = 64
+
+var versionTokenRegexp = regexp.MustCompile(`\d+(\.\d+)+\w*`)
+
+// bannerReadLimit 返回实际读取的响应字节数，size未设置时使用def
+func bannerReadLimit(size, def int) int {
+	if size <= 0 {
+		return def
+	}
+	if size > maxBannerReadSize {
+		return maxBannerReadSize
+	}
+	return size
+}
+
+// grabBanner 建立连接后不发送任何数据，读取服务端主动发送的横幅，
+// 持续读取直到达到size字节、连接关闭或超时，保证分多次发送的长横幅也能完整获取
+func grabBanner(ctx context.Context, config ScanConfig, host string, port int, size int) ([]byte, error) {
+	conn, err := config.dialProbe(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	banner := readBanner(conn, config.probeTimeout(port), size)
+	if len(banner) == 0 {
+		return nil, fmt.Errorf("no banner received from %s:%d", host, port)
+	}
+	return banner, nil
+}
+
+// readBanner 在已建立的连接上读取服务端主动发送的数据
+func readBanner(conn net.Conn, timeout time.Duration, size int) []byte {
+	deadline := time.Now().Add(timeout)
+	conn.SetReadDeadline(deadline)
+	buf := make([]byte, size)
+	total := 0
+	for total < size {
+		n, err := conn.Read(buf[total:])
+		total += n
+		if err != nil {
+			break
+		}
+		// 已收到数据后只再等待一个短暂的间隔，避免保持连接的服务拖满整个超时
+		if idle := time.Now().Add(bannerIdleTimeout); idle.Before(deadline) {
+			conn.SetReadDeadline(idle)
+		}
+	}
+	return buf[:total]
+}
+
+// escapeBanner 将横幅转换为可打印的转义文本(\r\n、\x00等)，可通过 unescapeBanner 还原
+func escapeBanner(banner []byte) string {
+	quoted := strconv.Quote(string(banner))
+	return quoted[1 : len(quoted)-1]
+}
+
+func unescapeBanner(s string) (string, error) {
+	return strconv.Unquote(`"` + s + `"`)
+}
+
+// SuggestProbe 根据未识别服务记录的横幅生成nmap-service-probes格式的匹配规则骨架，
+// 版本号形式的片段会被替换为捕获组，方便编写新的指纹
+func (a *App) SuggestProbe(portInfo PortInfo) string {
+	if !portInfo.Unidentified || portInfo.Info == "" {
+		return ""
+	}
+	banner, err := unescapeBanner(portInfo.Info)
+	if err != nil || banner == "" {
+		return ""
+	}
+
+	// 只取第一行，后续内容通常因会话而异
+	if i := strings.IndexByte(banner, '\n'); i >= 0 {
+		banner = banner[:i+1]
+	}
+	if len(banner) > suggestBannerSize {
+		banner = banner[:suggestBannerSize]
+	}
+
+	// 最后一个形如版本号的片段作为版本捕获组，前面的通常是协议版本(如SSH-2.0)
+	versionStart, versionEnd := -1, -1
+	if matches := versionTokenRegexp.FindAllStringIndex(banner, -1); len(matches) > 0 {
+		last := matches[len(matches)-1]
+		versionStart, versionEnd = last[0], last[1]
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(banner); {
+		if i == versionStart {
+			sb.WriteString(`([\w._-]+)`)
+			i = versionEnd
+			continue
+		}
+		c := banner[i]
+		switch {
+		case c == '\r':
+			sb.WriteString(`\r`)
+		case c == '\n':
+			sb.WriteString(`\n`)
+		case c == '\t':
+			sb.WriteString(`\t`)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&sb, `\x%02x`, c)
+		case c == '|':
+			sb.WriteString(`\|`)
+		case c >= '0' && c <= '9':
+			// 其余数字(会话ID、时间等)泛化为\d+
+			for i < len(banner) && i != versionStart && banner[i] >= '0' && banner[i] <= '9' {
+				i++
+			}
+			sb.WriteString(`\d+`)
+			continue
+		case unicode.IsLetter(rune(c)) || c == ' ':
+			sb.WriteByte(c)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+		i++
+	}
+
+	service := portInfo.Service
+	if service == "" {
+		service = "unknown"
+	}
+	rule := fmt.Sprintf("match %s m|^%s| p/Unknown/", service, sb.String())
+	if versionStart >= 0 {
+		rule += " v/$1/"
+	}
+	return rule
+}
@@ -0,0 +1,73 @@
+package portsscanner
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPassiveProbeMatchesPastGonmapReadSize(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// 标识位于gonmap固定读取的10240字节之后
+	banner := append(bytes.Repeat([]byte("x"), 12000), "HIDDEN-SERVICE/1.0\r\n"...)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write(banner)
+			conn.Close()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	probe, err := parseCustomProbe(CustomProbe{Name: "hidden", Protocol: "tcp", Ports: []int{port},
+		Service: "hidden", Match: `HIDDEN-SERVICE/\d`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	customProbesMu.Lock()
+	saved := customProbes
+	customProbes = []customProbe{probe}
+	customProbesMu.Unlock()
+	defer func() {
+		customProbesMu.Lock()
+		customProbes = saved
+		customProbesMu.Unlock()
+	}()
+
+	for _, tc := range []struct {
+		size int
+		want string
+	}{
+		{0, ""},
+		{32768, "hidden"},
+	} {
+		config := ScanConfig{Timeout: time.Second, BannerReadSize: tc.size}
+		s := &portScanner{config: config}
+		got, err := grabBanner(context.Background(), config, "127.0.0.1", port, bannerReadLimit(tc.size, defaultBannerReadSize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := PortInfo{Port: port, Unidentified: true}
+		s.matchBanner(scanTarget{IP: "127.0.0.1"}, port, got, &info)
+		if info.Service != tc.want || info.Unidentified != (tc.want == "") {
+			t.Errorf("BannerReadSize %d: read %d bytes, service %q unidentified %v, want %q",
+				tc.size, len(got), info.Service, info.Unidentified, tc.want)
+		}
+	}
+
+	if _, err := parseCustomProbe(CustomProbe{Name: "bare", Protocol: "tcp", Ports: []int{1}}); err == nil {
+		t.Error("passive probe without match was accepted")
+	}
+	if _, err := parseCustomProbe(CustomProbe{Name: "bare", Protocol: "udp", Ports: []int{1}, Match: "x"}); err == nil {
+		t.Error("UDP probe without payload was accepted")
+	}
+}
@@ -0,0 +1,107 @@
+package portsscanner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+)
+
+// 二进制结果文件头：4字节魔数 + 2字节格式版本(大端)，之后是gob编码的binaryResults。
+// gob按字段名匹配，新增字段时旧文件仍可读取，只有不兼容的结构变化才需要提升版本号。
+const (
+	binaryResultsMagic   = "GWRB"
+	binaryResultsVersion = 1
+)
+
+// binaryResults 二进制结果文件内容
+type binaryResults struct {
+	Scan     ScanSummary
+	Config   ScanConfig
+	Results  []PortInfo
+	Metadata ScanMetadata
+}
+
+// SaveResultsBinary 将指定扫描的配置和结果保存为紧凑的二进制文件，便于在会话之间快速加载
+func (a *App) SaveResultsBinary(scanID string, path string) error {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return err
+	}
+	summary, config, results := record.snapshot()
+	metadata := record.scanMetadata()
+	metadata.Config = ScanConfig{}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	w.WriteString(binaryResultsMagic)
+	binary.Write(w, binary.BigEndian, uint16(binaryResultsVersion))
+	if err := gob.NewEncoder(w).Encode(binaryResults{Scan: summary, Config: config, Results: results, Metadata: metadata}); err != nil {
+		return fmt.Errorf("failed to encode results: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// LoadResultsBinary 读取二进制结果文件并加入扫描历史，返回扫描概要，
+// 之后可通过 GetScanResults 获取结果
+func (a *App) LoadResultsBinary(path string) (ScanSummary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ScanSummary{}, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, len(binaryResultsMagic)+2)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:4]) != binaryResultsMagic {
+		return ScanSummary{}, fmt.Errorf("%s is not a GlideWay binary results file", path)
+	}
+	if version := binary.BigEndian.Uint16(header[4:]); version > binaryResultsVersion {
+		return ScanSummary{}, fmt.Errorf("%s uses results format version %d, this build supports up to %d", path, version, binaryResultsVersion)
+	}
+
+	var doc binaryResults
+	if err := gob.NewDecoder(r).Decode(&doc); err != nil {
+		return ScanSummary{}, fmt.Errorf("failed to decode results: %w", err)
+	}
+	if doc.Scan.ID == "" {
+		doc.Scan.ID = newScanID()
+	}
+	doc.Scan.OpenPorts = countOpen(doc.Results)
+	doc.Scan.ResultsTimestamp = doc.Scan.resultsTime()
+
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	if existing, ok := scanStore[doc.Scan.ID]; ok {
+		summary, _, _ := existing.snapshot()
+		if summary.Status == "running" {
+			return ScanSummary{}, fmt.Errorf("scan %q is still running", doc.Scan.ID)
+		}
+		if summary.Archived {
+			return ScanSummary{}, archivedError(doc.Scan.ID)
+		}
+	}
+	record := &scanRecord{
+		summary:  doc.Scan,
+		config:   doc.Config,
+		results:  doc.Results,
+		metadata: doc.Metadata,
+	}
+	scanStore[doc.Scan.ID] = record
+	latestResults = doc.Scan.ID
+	if err := saveScanRecord(record); err != nil {
+		fmt.Printf("保存扫描记录失败: %v\n", err)
+	}
+	a.warnIfStale(doc.Scan)
+	return doc.Scan, nil
+}
@@ -0,0 +1,530 @@
+package portsscanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 扫描活动的保存文件，位于 ~/GlideWay 下
+	campaignsFile = "campaigns.json"
+	// 每个活动保留的最近运行记录数
+	maxCampaignRuns = 100
+	// 等待其他扫描结束或扫描窗口打开的轮询间隔
+	campaignPollInterval = 5 * time.Second
+	// 活动名的最大长度，成员扫描ID还需附加运行时间和序号
+	maxCampaignNameLength = 64
+)
+
+// 活动运行的结束状态
+const (
+	campaignRunCompleted = "completed" // 全部成员扫描已完成
+	campaignRunPartial   = "partial"   // 部分成员扫描失败
+	campaignRunFailed    = "failed"    // 没有成员扫描完成
+	campaignRunCancelled = "cancelled" // 活动在运行中被删除
+)
+
+var errCampaignNotFound = errors.New("campaign not found")
+
+// Campaign 按同一cron计划定期运行的一组扫描。每次运行依次执行全部成员配置，
+// 成员扫描的ID为 "<活动名>-<运行开始时间>-<序号>"，项目名为空时使用活动名。
+// 成员扫描与其他扫描一样受保留策略清理，需要长期对比时应归档
+type Campaign struct {
+	Name      string        `json:"name"`
+	Schedule  string        `json:"schedule"` // cron表达式，见cronSchedule
+	Configs   []ScanConfig  `json:"configs"`
+	CreatedAt time.Time     `json:"created_at"`
+	NextRun   time.Time     `json:"next_run"`
+	Running   bool          `json:"running"`
+	Runs      []CampaignRun `json:"runs"` // 按时间从旧到新，最多保留maxCampaignRuns次
+}
+
+// CampaignRun 活动的一次运行
+type CampaignRun struct {
+	Number     int       `json:"number"` // 从1开始递增的运行序号
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Status     string    `json:"status"`
+	ScanIDs    []string  `json:"scan_ids"`         // 正常完成的成员扫描，只有这些扫描计入汇总和对比
+	Errors     []string  `json:"errors,omitempty"` // 未能完成的成员及原因
+}
+
+// CampaignResults 活动全部运行的汇总：Inventory合并所有成员扫描，记录每个端口的首次和最近发现时间
+type CampaignResults struct {
+	Campaign  Campaign  `json:"campaign"`
+	Inventory Inventory `json:"inventory"`
+}
+
+// CampaignChange 两次运行之间服务识别结果发生变化的端口
+type CampaignChange struct {
+	Before PortInfo `json:"before"`
+	After  PortInfo `json:"after"`
+}
+
+// CampaignDiff 活动两次运行之间的变化，每次运行的结果为其全部成员扫描的合并
+type CampaignDiff struct {
+	Campaign string           `json:"campaign"`
+	From     CampaignRun      `json:"from"`
+	To       CampaignRun      `json:"to"`
+	Opened   []PortInfo       `json:"opened"`            // To中新出现的开放端口
+	Closed   []PortInfo       `json:"closed"`            // From中开放、To中不再开放的端口
+	Changed  []CampaignChange `json:"changed"`           // 服务、产品或版本发生变化的端口
+	Missing  []string         `json:"missing,omitempty"` // 已被清理、无法比较的成员扫描
+}
+
+// campaign 运行中的活动及其调度协程
+type campaign struct {
+	Campaign
+	schedule cronSchedule
+	cancel   context.CancelFunc
+}
+
+var (
+	campaigns   = make(map[string]*campaign)
+	campaignsMu sync.Mutex
+
+	// campaignRunMu 保证同一时间只有一个活动在运行，不同活动的成员扫描依次执行
+	campaignRunMu sync.Mutex
+)
+
+// saveCampaignsLocked 将全部活动写入磁盘，调用方需持有campaignsMu
+func saveCampaignsLocked() error {
+	list := make([]Campaign, 0, len(campaigns))
+	for _, c := range campaigns {
+		list = append(list, c.Campaign)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	path, err := dataFilePath(campaignsFile)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write campaigns: %w", err)
+	}
+	return nil
+}
+
+// loadCampaigns 启动时读取保存的活动并恢复调度，上次退出时未完成的运行不会补跑
+func (a *App) loadCampaigns() error {
+	path, err := dataFilePath(campaignsFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read campaigns: %w", err)
+	}
+	var list []Campaign
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("failed to parse campaigns %s: %w", path, err)
+	}
+
+	campaignsMu.Lock()
+	defer campaignsMu.Unlock()
+	for _, c := range list {
+		schedule, err := parseCron(c.Schedule)
+		if err != nil {
+			fmt.Printf("扫描活动 %s 的计划无效，已跳过: %v\n", c.Name, err)
+			continue
+		}
+		c.Running = false
+		a.scheduleCampaignLocked(&campaign{Campaign: c, schedule: schedule})
+	}
+	return nil
+}
+
+// validateCampaignConfig 在创建活动时校验成员配置，避免到运行时才发现错误
+func validateCampaignConfig(config ScanConfig) error {
+	if config.ScanID != "" {
+		return errors.New("campaign scan configs must not set ScanID")
+	}
+	applyScanDefaults(&config)
+	if len(config.portList()) == 0 {
+		return fmt.Errorf("no ports to scan")
+	}
+	if _, err := validateRawConfig(config); err != nil {
+		return err
+	}
+	if config.StopOnMatch != "" {
+		if _, err := parseResultMatcher(config.StopOnMatch); err != nil {
+			return err
+		}
+	}
+	return validateScanIdentifier("correlation id", config.CorrelationID)
+}
+
+// scheduleCampaignLocked 注册活动并启动其调度协程，调用方需持有campaignsMu
+func (a *App) scheduleCampaignLocked(c *campaign) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.NextRun = c.schedule.next(time.Now())
+	campaigns[c.Name] = c
+	go a.campaignLoop(ctx, c)
+}
+
+// campaignLoop 按计划触发活动运行，上一次运行尚未结束时跳过本次触发
+func (a *App) campaignLoop(ctx context.Context, c *campaign) {
+	for {
+		campaignsMu.Lock()
+		next := c.NextRun
+		campaignsMu.Unlock()
+		if next.IsZero() {
+			fmt.Printf("扫描活动 %s 的计划没有下一次运行时间\n", c.Name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		campaignsMu.Lock()
+		c.NextRun = c.schedule.next(time.Now())
+		running := c.Running
+		c.Running = true
+		campaignsMu.Unlock()
+		if running {
+			fmt.Printf("扫描活动 %s 上一次运行尚未结束，跳过本次计划\n", c.Name)
+			continue
+		}
+		go a.runCampaign(ctx, c)
+	}
+}
+
+// waitForScanSlot 等待没有其他扫描进行且位于扫描窗口内，避免成员扫描被拒绝或排队
+func (a *App) waitForScanSlot(ctx context.Context) error {
+	for {
+		scanMutex.Lock()
+		busy := currentScan != nil || deferred != nil || resolveCancel != nil
+		window := a.scanWindow
+		scanMutex.Unlock()
+		if !busy && window.allows(time.Now()) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(campaignPollInterval):
+		}
+	}
+}
+
+// runCampaignMember 启动一个成员扫描并等待其结束
+func (a *App) runCampaignMember(ctx context.Context, config ScanConfig) error {
+	if err := a.waitForScanSlot(ctx); err != nil {
+		return err
+	}
+	if err := a.ScanWithConfig(config); err != nil {
+		return err
+	}
+	scanMutex.Lock()
+	scan := currentScan
+	scanMutex.Unlock()
+	if scan == nil || scan.record.summary.ID != config.ScanID {
+		return errors.New("scan did not start")
+	}
+	select {
+	case <-scan.done:
+	case <-ctx.Done():
+		a.StopScan()
+		<-scan.done
+		return ctx.Err()
+	}
+	summary, _, _ := scan.record.snapshot()
+	if summary.Status != "completed" {
+		return fmt.Errorf("scan %s", summary.Status)
+	}
+	return nil
+}
+
+// runCampaign 依次运行活动的全部成员扫描并记录本次运行
+func (a *App) runCampaign(ctx context.Context, c *campaign) {
+	campaignRunMu.Lock()
+	defer campaignRunMu.Unlock()
+
+	campaignsMu.Lock()
+	run := CampaignRun{Number: 1, StartedAt: time.Now(), ScanIDs: []string{}}
+	if n := len(c.Runs); n > 0 {
+		run.Number = c.Runs[n-1].Number + 1
+	}
+	configs := append([]ScanConfig(nil), c.Configs...)
+	campaignsMu.Unlock()
+
+	fmt.Printf("扫描活动 %s 第 %d 次运行开始，共 %d 个扫描\n", c.Name, run.Number, len(configs))
+	a.emitEvent("campaign-run-started", map[string]interface{}{
+		"campaign": c.Name,
+		"run":      run.Number,
+		"scans":    len(configs),
+	})
+
+	stamp := run.StartedAt.Format("20060102-150405")
+	for i, config := range configs {
+		config.ScanID = fmt.Sprintf("%s-%s-%d", c.Name, stamp, i+1)
+		if config.Project == "" {
+			config.Project = c.Name
+		}
+		err := a.runCampaignMember(ctx, config)
+		if errors.Is(err, context.Canceled) {
+			run.Status = campaignRunCancelled
+			break
+		}
+		if err != nil {
+			run.Errors = append(run.Errors, fmt.Sprintf("%s (%s): %v", config.ScanID, config.Target, err))
+			continue
+		}
+		run.ScanIDs = append(run.ScanIDs, config.ScanID)
+	}
+	run.FinishedAt = time.Now()
+	switch {
+	case run.Status != "":
+	case len(run.Errors) == 0:
+		run.Status = campaignRunCompleted
+	case len(run.ScanIDs) == 0:
+		run.Status = campaignRunFailed
+	default:
+		run.Status = campaignRunPartial
+	}
+
+	campaignsMu.Lock()
+	c.Running = false
+	c.Runs = append(c.Runs, run)
+	if len(c.Runs) > maxCampaignRuns {
+		c.Runs = c.Runs[len(c.Runs)-maxCampaignRuns:]
+	}
+	var saveErr error
+	if campaigns[c.Name] == c {
+		saveErr = saveCampaignsLocked()
+	}
+	campaignsMu.Unlock()
+	if saveErr != nil {
+		fmt.Printf("保存扫描活动失败: %v\n", saveErr)
+	}
+
+	fmt.Printf("扫描活动 %s 第 %d 次运行结束: %s，完成 %d 个扫描\n", c.Name, run.Number, run.Status, len(run.ScanIDs))
+	a.emitEvent("campaign-run-finished", map[string]interface{}{
+		"campaign": c.Name,
+		"run":      run,
+	})
+}
+
+// CreateCampaign 创建按cronSpec定期运行的扫描活动，configs为成员扫描的配置(不能指定ScanID)。
+// 名称只能包含字母、数字和 . _ - :，创建后保存到磁盘，应用重启后继续按计划运行
+func (a *App) CreateCampaign(name string, configs []ScanConfig, cronSpec string) (Campaign, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Campaign{}, errors.New("campaign name is required")
+	}
+	if len(name) > maxCampaignNameLength {
+		return Campaign{}, fmt.Errorf("campaign name is longer than %d characters", maxCampaignNameLength)
+	}
+	if err := validateScanIdentifier("campaign name", name); err != nil {
+		return Campaign{}, err
+	}
+	if len(configs) == 0 {
+		return Campaign{}, errors.New("campaign has no scan configs")
+	}
+	for i, config := range configs {
+		if err := validateCampaignConfig(config); err != nil {
+			return Campaign{}, fmt.Errorf("campaign scan %d: %w", i+1, err)
+		}
+	}
+	schedule, err := parseCron(cronSpec)
+	if err != nil {
+		return Campaign{}, err
+	}
+	if schedule.next(time.Now()).IsZero() {
+		return Campaign{}, fmt.Errorf("cron spec %q never fires", cronSpec)
+	}
+
+	campaignsMu.Lock()
+	defer campaignsMu.Unlock()
+	if _, exists := campaigns[name]; exists {
+		return Campaign{}, fmt.Errorf("campaign %q already exists", name)
+	}
+	c := &campaign{
+		Campaign: Campaign{
+			Name:      name,
+			Schedule:  strings.TrimSpace(cronSpec),
+			Configs:   append([]ScanConfig(nil), configs...),
+			CreatedAt: time.Now(),
+			Runs:      []CampaignRun{},
+		},
+		schedule: schedule,
+	}
+	a.scheduleCampaignLocked(c)
+	if err := saveCampaignsLocked(); err != nil {
+		c.cancel()
+		delete(campaigns, name)
+		return Campaign{}, err
+	}
+	fmt.Printf("已创建扫描活动 %s，%d 个扫描，下次运行 %s\n", name, len(configs), c.NextRun.Format("2006-01-02 15:04"))
+	return c.Campaign, nil
+}
+
+// DeleteCampaign 删除扫描活动并停止其调度，正在进行的运行会被停止；已完成的成员扫描保留在历史中
+func (a *App) DeleteCampaign(name string) error {
+	campaignsMu.Lock()
+	defer campaignsMu.Unlock()
+	c, ok := campaigns[name]
+	if !ok {
+		return errCampaignNotFound
+	}
+	c.cancel()
+	delete(campaigns, name)
+	return saveCampaignsLocked()
+}
+
+// ListCampaigns 返回全部扫描活动，按名称排序
+func (a *App) ListCampaigns() []Campaign {
+	campaignsMu.Lock()
+	defer campaignsMu.Unlock()
+	list := make([]Campaign, 0, len(campaigns))
+	for _, c := range campaigns {
+		list = append(list, c.Campaign)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// getCampaign 返回活动的副本
+func getCampaign(name string) (Campaign, error) {
+	campaignsMu.Lock()
+	defer campaignsMu.Unlock()
+	c, ok := campaigns[name]
+	if !ok {
+		return Campaign{}, errCampaignNotFound
+	}
+	campaign := c.Campaign
+	campaign.Runs = append([]CampaignRun(nil), c.Runs...)
+	return campaign, nil
+}
+
+// GetCampaignResults 合并活动全部运行的成员扫描，得到活动的资产清单
+func (a *App) GetCampaignResults(name string) (CampaignResults, error) {
+	c, err := getCampaign(name)
+	if err != nil {
+		return CampaignResults{}, err
+	}
+	var scanIDs []string
+	for _, run := range c.Runs {
+		scanIDs = append(scanIDs, run.ScanIDs...)
+	}
+	inventory := Inventory{GeneratedAt: time.Now(), Scans: []string{}, Entries: []InventoryEntry{}}
+	if len(scanIDs) > 0 {
+		inventory = a.BuildInventory(scanIDs)
+	}
+	return CampaignResults{Campaign: c, Inventory: inventory}, nil
+}
+
+// campaignRunResults 合并一次运行中全部成员扫描的开放端口，返回未找到的扫描ID
+func campaignRunResults(run CampaignRun) (map[string]PortInfo, []string) {
+	ports := make(map[string]PortInfo)
+	var missing []string
+	for _, id := range run.ScanIDs {
+		record, err := getScanRecord(id)
+		if err != nil {
+			missing = append(missing, id)
+			continue
+		}
+		_, _, results := record.snapshot()
+		for _, r := range results {
+			if r.State != "" {
+				continue
+			}
+			ports[net.JoinHostPort(r.Host, strconv.Itoa(r.Port))+"/"+r.Protocol] = r
+		}
+	}
+	return ports, missing
+}
+
+// sortCampaignPorts 按主机、端口和协议排序
+func sortCampaignPorts(list []PortInfo) {
+	sort.Slice(list, func(i, j int) bool {
+		if c := compareHosts(list[i].Host, list[j].Host); c != 0 {
+			return c < 0
+		}
+		if list[i].Port != list[j].Port {
+			return list[i].Port < list[j].Port
+		}
+		return list[i].Protocol < list[j].Protocol
+	})
+}
+
+// DiffCampaign 比较活动的两次运行，fromRun和toRun为运行序号，均为0时比较最近两次运行
+func (a *App) DiffCampaign(name string, fromRun, toRun int) (CampaignDiff, error) {
+	c, err := getCampaign(name)
+	if err != nil {
+		return CampaignDiff{}, err
+	}
+	if fromRun == 0 && toRun == 0 {
+		if len(c.Runs) < 2 {
+			return CampaignDiff{}, fmt.Errorf("campaign %q has fewer than two runs", name)
+		}
+		fromRun, toRun = c.Runs[len(c.Runs)-2].Number, c.Runs[len(c.Runs)-1].Number
+	}
+	findRun := func(number int) (CampaignRun, error) {
+		for _, run := range c.Runs {
+			if run.Number == number {
+				return run, nil
+			}
+		}
+		return CampaignRun{}, fmt.Errorf("campaign %q has no run %d", name, number)
+	}
+	diff := CampaignDiff{Campaign: name, Opened: []PortInfo{}, Closed: []PortInfo{}, Changed: []CampaignChange{}}
+	if diff.From, err = findRun(fromRun); err != nil {
+		return CampaignDiff{}, err
+	}
+	if diff.To, err = findRun(toRun); err != nil {
+		return CampaignDiff{}, err
+	}
+
+	before, missingFrom := campaignRunResults(diff.From)
+	after, missingTo := campaignRunResults(diff.To)
+	diff.Missing = append(missingFrom, missingTo...)
+	for key, r := range after {
+		old, ok := before[key]
+		switch {
+		case !ok:
+			diff.Opened = append(diff.Opened, r)
+		case old.Service != r.Service || old.ProductName != r.ProductName || old.Version != r.Version:
+			diff.Changed = append(diff.Changed, CampaignChange{Before: old, After: r})
+		}
+	}
+	for key, r := range before {
+		if _, ok := after[key]; !ok {
+			diff.Closed = append(diff.Closed, r)
+		}
+	}
+	sortCampaignPorts(diff.Opened)
+	sortCampaignPorts(diff.Closed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		x, y := diff.Changed[i].After, diff.Changed[j].After
+		if c := compareHosts(x.Host, y.Host); c != 0 {
+			return c < 0
+		}
+		if x.Port != y.Port {
+			return x.Port < y.Port
+		}
+		return x.Protocol < y.Protocol
+	})
+	return diff, nil
+}
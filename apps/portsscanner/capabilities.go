@@ -0,0 +1,114 @@
+package portsscanner
+
+import (
+	"net"
+	goruntime "runtime"
+	"sync"
+	"time"
+)
+
+// Capabilities 当前运行环境支持的扫描功能，前端据此隐藏或禁用无法使用的选项
+type Capabilities struct {
+	OS             string    `json:"os"`
+	ScanTypes      []string  `json:"scan_types"`                 // 可用的扫描类型
+	RawSockets     bool      `json:"raw_sockets"`                // 可发送原始TCP数据包(syn/fin/null/xmas/idle)
+	RawSocketError string    `json:"raw_socket_error,omitempty"` // 原始套接字不可用的原因
+	ARPScan        bool      `json:"arp_scan"`                   // 可进行ARP扫描(需要链路层套接字和直连的IPv4网段)
+	ARPScanError   string    `json:"arp_scan_error,omitempty"`
+	IPv6           bool      `json:"ipv6"`            // 存在到公网IPv6地址的路由
+	OpenFileLimit  uint64    `json:"open_file_limit"` // 打开文件数上限，0表示不限制或无法获取
+	CheckedAt      time.Time `json:"checked_at"`
+
+	FastOpen      bool   `json:"fast_open"` // 可探测TCP Fast Open(Linux且内核启用了TFO客户端)
+	FastOpenError string `json:"fast_open_error,omitempty"`
+}
+
+var (
+	capabilities   *Capabilities
+	capabilitiesMu sync.Mutex
+)
+
+// ipv6ProbeAddr 用于判断IPv6路由的公网地址，UDP connect只查询路由不发送数据包
+const ipv6ProbeAddr = "[2001:4860:4860::8888]:53"
+
+// detectCapabilities 探测运行环境
+func detectCapabilities() Capabilities {
+	caps := Capabilities{
+		OS:        goruntime.GOOS,
+		ScanTypes: []string{ScanTypeConnect, ScanTypeUDP, ScanTypeBoth},
+		CheckedAt: time.Now(),
+	}
+
+	if err := rawSocketAvailable(); err != nil {
+		caps.RawSocketError = err.Error()
+	} else {
+		caps.RawSockets = true
+		caps.ScanTypes = append(caps.ScanTypes, ScanTypeSYN, ScanTypeFIN, ScanTypeNull, ScanTypeXmas, ScanTypeIdle)
+	}
+
+	if err := packetSocketAvailable(); err != nil {
+		caps.ARPScanError = err.Error()
+	} else if !hasIPv4LAN() {
+		caps.ARPScanError = "no network interface with an IPv4 address is attached to a local network"
+	} else {
+		caps.ARPScan = true
+	}
+
+	if conn, err := net.Dial("udp6", ipv6ProbeAddr); err == nil {
+		conn.Close()
+		caps.IPv6 = true
+	}
+
+	if limit, err := openFileLimit(); err == nil {
+		caps.OpenFileLimit = limit
+	}
+
+	if err := fastOpenAvailable(); err != nil {
+		caps.FastOpenError = err.Error()
+	} else {
+		caps.FastOpen = true
+	}
+	return caps
+}
+
+// hasIPv4LAN 是否存在已启用、带MAC地址和IPv4地址的非回环网卡
+func hasIPv4LAN() bool {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return false
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) != 6 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetCapabilities 返回运行环境支持的扫描功能，首次调用时探测并缓存结果
+func (a *App) GetCapabilities() Capabilities {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	if capabilities == nil {
+		caps := detectCapabilities()
+		capabilities = &caps
+	}
+	return *capabilities
+}
+
+// RefreshCapabilities 重新探测运行环境，例如以管理员权限重启或切换网络之后
+func (a *App) RefreshCapabilities() Capabilities {
+	capabilitiesMu.Lock()
+	capabilities = nil
+	capabilitiesMu.Unlock()
+	return a.GetCapabilities()
+}
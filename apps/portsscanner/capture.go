@@ -0,0 +1,29 @@
+package portsscanner
+
+import "encoding/hex"
+
+const (
+	defaultCaptureSize = 256
+	// 单个端口最多保留的响应字节数，避免结果体积失控
+	maxCaptureSize = 4096
+)
+
+// captureLimit 返回实际使用的抓取字节数
+func captureLimit(size int) int {
+	if size <= 0 {
+		return defaultCaptureSize
+	}
+	if size > maxCaptureSize {
+		return maxCaptureSize
+	}
+	return size
+}
+
+// dumpResponse 截取服务响应的前N个字节，生成hex/ascii对照格式便于人工核对
+func dumpResponse(raw string, size int) string {
+	data := []byte(raw)
+	if limit := captureLimit(size); len(data) > limit {
+		data = data[:limit]
+	}
+	return hex.Dump(data)
+}
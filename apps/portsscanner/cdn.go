@@ -0,0 +1,232 @@
+package portsscanner
+
+import (
+	"crypto/x509"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CDNProvider CDN识别规则：Ranges为边缘节点的地址段，Servers为Server响应头包含的关键字，
+// Headers为该CDN特有的响应头名称，Certificates为边缘证书主题、SAN或签发者包含的关键字，均不区分大小写
+type CDNProvider struct {
+	Name         string   `json:"name"`
+	Ranges       []string `json:"ranges,omitempty"`
+	Servers      []string `json:"servers,omitempty"`
+	Headers      []string `json:"headers,omitempty"`
+	Certificates []string `json:"certificates,omitempty"`
+}
+
+// cdnRangeFile CDN规则文件结构
+type cdnRangeFile struct {
+	CDNs []CDNProvider `json:"cdns"`
+}
+
+// cdnProvider 解析后的CDN规则
+type cdnProvider struct {
+	CDNProvider
+	prefixes []netip.Prefix
+}
+
+// CDN识别依据
+const (
+	cdnByRange       = "ip-range"
+	cdnByServer      = "server-header"
+	cdnByHeader      = "header"
+	cdnByCertificate = "certificate"
+)
+
+// 内置的Cloudflare、Fastly、Akamai地址段和CloudFront等响应特征，可通过LoadCDNRanges替换
+//
+//go:embed cdn_ranges.json
+var builtinCDNRanges []byte
+
+var (
+	cdnProviders   []cdnProvider
+	cdnProvidersMu sync.RWMutex
+)
+
+func init() {
+	providers, err := parseCDNRanges(builtinCDNRanges)
+	if err != nil {
+		panic(fmt.Sprintf("invalid builtin CDN ranges: %v", err))
+	}
+	cdnProviders = providers
+}
+
+// parseCDNRanges 解析并校验CDN规则文件，任一规则格式错误时返回错误
+func parseCDNRanges(data []byte) ([]cdnProvider, error) {
+	var file cdnRangeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	providers := make([]cdnProvider, 0, len(file.CDNs))
+	for _, c := range file.CDNs {
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			return nil, fmt.Errorf("CDN name is empty")
+		}
+		if len(c.Ranges)+len(c.Servers)+len(c.Headers)+len(c.Certificates) == 0 {
+			return nil, fmt.Errorf("CDN %q has no ranges or heuristics", c.Name)
+		}
+		for _, existing := range providers {
+			if strings.EqualFold(existing.Name, c.Name) {
+				return nil, fmt.Errorf("duplicate CDN name %q", c.Name)
+			}
+		}
+		provider := cdnProvider{CDNProvider: c}
+		for _, r := range c.Ranges {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(r))
+			if err != nil {
+				return nil, fmt.Errorf("CDN %q: invalid range %q: %w", c.Name, r, err)
+			}
+			provider.prefixes = append(provider.prefixes, prefix.Masked())
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// loadedCDNProviders 返回当前使用的CDN规则
+func loadedCDNProviders() []cdnProvider {
+	cdnProvidersMu.RLock()
+	defer cdnProvidersMu.RUnlock()
+	return cdnProviders
+}
+
+// lookupCDN 返回地址所属的CDN，不在任何CDN地址段内时返回空
+func lookupCDN(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap().WithZone("")
+	for _, c := range loadedCDNProviders() {
+		for _, prefix := range c.prefixes {
+			if prefix.Contains(addr) {
+				return c.Name
+			}
+		}
+	}
+	return ""
+}
+
+// containsFold 判断s是否包含keywords中任一关键字(不区分大小写)
+func containsFold(s string, keywords []string) bool {
+	s = strings.ToLower(s)
+	for _, k := range keywords {
+		if k != "" && strings.Contains(s, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerNames 从状态行和响应头中提取响应头名称(小写)
+func headerNames(head string) map[string]bool {
+	names := make(map[string]bool)
+	for _, line := range strings.Split(head, "\n") {
+		if name, _, ok := strings.Cut(line, ":"); ok && !strings.ContainsAny(name, " \t") {
+			names[strings.ToLower(name)] = true
+		}
+	}
+	return names
+}
+
+// certificateNames 返回证书主题、SAN和签发者中用于匹配的名称
+func certificateNames(cert *x509.Certificate) string {
+	names := append([]string{cert.Subject.CommonName, cert.Issuer.CommonName}, cert.DNSNames...)
+	names = append(names, cert.Subject.Organization...)
+	names = append(names, cert.Issuer.Organization...)
+	return strings.Join(names, " ")
+}
+
+// cdnFromHTTP 按Server头、特有响应头和证书判断HTTP(S)服务是否由CDN提供，返回CDN名称和识别依据
+func cdnFromHTTP(result *httpProbeResult) (string, string) {
+	var headers map[string]bool
+	var certNames string
+	for _, c := range loadedCDNProviders() {
+		if result.Server != "" && containsFold(result.Server, c.Servers) {
+			return c.Name, cdnByServer
+		}
+		if len(c.Headers) > 0 && result.Head != "" {
+			if headers == nil {
+				headers = headerNames(result.Head)
+			}
+			for _, h := range c.Headers {
+				if headers[strings.ToLower(h)] {
+					return c.Name, cdnByHeader
+				}
+			}
+		}
+		if len(c.Certificates) > 0 && result.Certificate != nil {
+			if certNames == "" {
+				certNames = certificateNames(result.Certificate)
+			}
+			if containsFold(certNames, c.Certificates) {
+				return c.Name, cdnByCertificate
+			}
+		}
+	}
+	return "", ""
+}
+
+// labelCDN 按HTTP探测结果标记CDN，已按地址段标记时不做修改
+func labelCDN(portInfo *PortInfo, result *httpProbeResult) {
+	if portInfo.BehindCDN {
+		return
+	}
+	if name, by := cdnFromHTTP(result); name != "" {
+		portInfo.BehindCDN = true
+		portInfo.CDN = name
+		portInfo.cdnEvidence = by
+	}
+}
+
+// reportCDN 为结果补充地址段匹配的CDN标记，每台主机第一次确认位于CDN之后时发送 cdn-detected 事件
+func (s *portScanner) reportCDN(info *PortInfo) {
+	if !info.BehindCDN {
+		if name := lookupCDN(info.Host); name != "" {
+			info.BehindCDN = true
+			info.CDN = name
+			info.cdnEvidence = cdnByRange
+		}
+	}
+	if !info.BehindCDN {
+		return
+	}
+	if _, seen := s.cdnHosts.LoadOrStore(info.Host, true); seen {
+		return
+	}
+	s.log(logWarn, "%s 位于 %s 之后(%s)，扫描结果反映的是CDN边缘节点而不是源站", info.Host, info.CDN, info.cdnEvidence)
+	s.emit("cdn-detected", map[string]interface{}{
+		"host":     info.Host,
+		"aliases":  info.Aliases,
+		"cdn":      info.CDN,
+		"evidence": info.cdnEvidence,
+		"port":     info.Port,
+	})
+}
+
+// LoadCDNRanges 从JSON文件({"cdns": [...]}，格式见CDNProvider)加载CDN规则，替换内置或之前加载的全部规则。
+// 任一规则格式错误时不做任何修改并返回错误，成功时返回加载的CDN数量
+func (a *App) LoadCDNRanges(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CDN ranges %s: %w", path, err)
+	}
+	providers, err := parseCDNRanges(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse CDN ranges %s: %w", path, err)
+	}
+
+	cdnProvidersMu.Lock()
+	cdnProviders = providers
+	cdnProvidersMu.Unlock()
+	fmt.Printf("已加载 %d 个CDN规则\n", len(providers))
+	return len(providers), nil
+}
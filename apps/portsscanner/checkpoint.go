@@ -0,0 +1,266 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 扫描进行中定期保存检查点的间隔，进程意外退出后可从最近的检查点续扫
+const checkpointSaveInterval = 30 * time.Second
+
+// HostCheckpoint 检查点中单台主机的完成情况
+type HostCheckpoint struct {
+	Host      string `json:"host"`
+	Total     int    `json:"total"`                // 该主机需要扫描的端口数
+	Completed bool   `json:"completed"`            // 所有端口均已探测完成
+	DonePorts string `json:"done_ports,omitempty"` // 未完成主机已探测的端口，如 "1-1024,3306"
+}
+
+// scanCheckpoint 记录每台主机已完成探测的端口，中断后续扫时
+// 已完成的主机整体跳过，未完成的主机只扫描剩余端口
+type scanCheckpoint struct {
+	mu    sync.Mutex
+	hosts map[string]*hostProgress
+}
+
+// hostProgress 单台主机的端口完成位图
+type hostProgress struct {
+	total int
+	count int
+	done  []uint64
+}
+
+func newScanCheckpoint() *scanCheckpoint {
+	return &scanCheckpoint{hosts: make(map[string]*hostProgress)}
+}
+
+// restoreCheckpoint 从持久化的检查点恢复
+func restoreCheckpoint(list []HostCheckpoint) *scanCheckpoint {
+	c := newScanCheckpoint()
+	for _, hc := range list {
+		c.register(hc.Host, hc.Total)
+		hp := c.hosts[hc.Host]
+		if hc.Completed {
+			hp.count = hp.total
+			continue
+		}
+		for _, p := range parsePortRanges(hc.DonePorts) {
+			hp.mark(p)
+		}
+	}
+	return c
+}
+
+// register 登记主机需要扫描的端口数，已登记的主机保留原有进度
+func (c *scanCheckpoint) register(host string, total int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.hosts[host]; !ok {
+		c.hosts[host] = &hostProgress{total: total}
+	}
+}
+
+// markDone 端口探测完整结束后调用，被取消的探测不计入
+func (c *scanCheckpoint) markDone(host string, port int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hp, ok := c.hosts[host]; ok {
+		hp.mark(port)
+	}
+}
+
+func (hp *hostProgress) mark(port int) {
+	if port < 0 || port > 65535 || hp.completed() {
+		return
+	}
+	if hp.done == nil {
+		hp.done = make([]uint64, 65536/64)
+	}
+	word, bit := port/64, uint64(1)<<(port%64)
+	if hp.done[word]&bit == 0 {
+		hp.done[word] |= bit
+		hp.count++
+	}
+	if hp.completed() {
+		hp.done = nil
+	}
+}
+
+func (hp *hostProgress) completed() bool {
+	return hp.total > 0 && hp.count >= hp.total
+}
+
+func (hp *hostProgress) isDone(port int) bool {
+	if hp.completed() {
+		return true
+	}
+	return hp.done != nil && hp.done[port/64]&(uint64(1)<<(port%64)) != 0
+}
+
+// total 返回主机需要扫描的端口总数，续扫时同样为原始的完整端口数
+func (c *scanCheckpoint) total(host string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hp, ok := c.hosts[host]; ok {
+		return hp.total
+	}
+	return 0
+}
+
+// remaining 返回主机尚未探测的端口，未记录过的主机返回全部端口
+func (c *scanCheckpoint) remaining(host string, ports []int) []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hp, ok := c.hosts[host]
+	if !ok {
+		return ports
+	}
+	left := make([]int, 0, len(ports))
+	for _, p := range ports {
+		if !hp.isDone(p) {
+			left = append(left, p)
+		}
+	}
+	return left
+}
+
+// snapshot 导出检查点用于持久化，主机按地址排序
+func (c *scanCheckpoint) snapshot() []HostCheckpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]HostCheckpoint, 0, len(c.hosts))
+	for host, hp := range c.hosts {
+		hc := HostCheckpoint{Host: host, Total: hp.total, Completed: hp.completed()}
+		if !hc.Completed && hp.done != nil {
+			var done []int
+			for p := 0; p <= 65535; p++ {
+				if hp.isDone(p) {
+					done = append(done, p)
+				}
+			}
+			hc.DonePorts = formatPortRanges(done)
+		}
+		list = append(list, hc)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Host < list[j].Host })
+	return list
+}
+
+// formatPortRanges 将升序端口列表压缩为 "1-1024,3306" 形式
+func formatPortRanges(ports []int) string {
+	var sb strings.Builder
+	for i := 0; i < len(ports); {
+		j := i
+		for j+1 < len(ports) && ports[j+1] == ports[j]+1 {
+			j++
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.Itoa(ports[i]))
+		if j > i {
+			sb.WriteString("-" + strconv.Itoa(ports[j]))
+		}
+		i = j + 1
+	}
+	return sb.String()
+}
+
+// parsePortRanges 解析 formatPortRanges 生成的端口范围，忽略无法解析的部分
+func parsePortRanges(s string) []int {
+	var ports []int
+	for _, part := range strings.Split(s, ",") {
+		if part == "" {
+			continue
+		}
+		lo, hi, found := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		end := start
+		if found {
+			if end, err = strconv.Atoi(hi); err != nil {
+				continue
+			}
+		}
+		for p := start; p <= end && p <= 65535; p++ {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+// resumeState 续扫时沿用的原扫描进度和结果
+type resumeState struct {
+	from       string
+	checkpoint *scanCheckpoint
+	results    []PortInfo
+}
+
+// planResume 根据检查点生成续扫计划：已完成的主机不再扫描，
+// 未完成的主机只保留剩余端口，从未开始的主机扫描全部端口
+func planResume(config ScanConfig, hosts []scanTarget, ports []int) ([]scanTarget, map[string][]int, int) {
+	pending := make([]scanTarget, 0, len(hosts))
+	hostPorts := make(map[string][]int)
+	skipped := 0
+	for _, h := range hosts {
+		left := config.resume.checkpoint.remaining(h.IP, config.portsFor(h.IP, ports))
+		if len(left) == 0 {
+			skipped++
+			continue
+		}
+		pending = append(pending, h)
+		hostPorts[h.IP] = left
+	}
+	return pending, hostPorts, skipped
+}
+
+// resumeFrom 续扫记录沿用原扫描已发现的结果
+func (r *scanRecord) resumeFrom(state *resumeState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.ResumedFrom = state.from
+	r.results = append([]PortInfo(nil), state.results...)
+	r.summary.OpenPorts = countOpen(r.results)
+	r.config.resume = nil
+}
+
+// ResumeScan 从中断扫描的检查点继续：跳过已完成的主机，只扫描未完成主机的剩余端口
+// 和尚未开始的主机。续扫创建新的扫描记录并沿用原扫描已发现的结果
+func (a *App) ResumeScan(scanID string) error {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return err
+	}
+	summary, config, results := record.snapshot()
+	switch summary.Status {
+	case "completed":
+		return fmt.Errorf("scan %q has already completed", scanID)
+	case "imported":
+		return fmt.Errorf("scan %q was imported and cannot be resumed", scanID)
+	case "running":
+		scanMutex.Lock()
+		running := currentScan != nil && currentScan.record == record
+		scanMutex.Unlock()
+		if running {
+			return fmt.Errorf("scan %q is still running", scanID)
+		}
+	}
+
+	// 续扫生成新的扫描ID，通过ResumedFrom关联原扫描，关联ID保持不变
+	config.ScanID = ""
+	config.hosts = nil
+	config.hostPorts = nil
+	config.endpointNames = nil
+	config.resume = &resumeState{
+		from:       summary.ID,
+		checkpoint: restoreCheckpoint(record.checkpointSnapshot()),
+		results:    results,
+	}
+	return a.ScanWithConfig(config)
+}
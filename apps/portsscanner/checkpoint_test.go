@@ -0,0 +1,81 @@
+package portsscanner
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestResumeSkipsCompletedHosts(t *testing.T) {
+	hosts := []SimulatedHost{
+		{IP: "198.18.3.1", Ports: []SimulatedPort{{Port: 3, Service: "ssh"}}},
+		{IP: "198.18.3.2", Ports: []SimulatedPort{{Port: 7, Service: "http"}}},
+		{IP: "198.18.3.3"},
+	}
+	ports := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	config := simConfig(t, hosts, "198.18.3.1-198.18.3.3", ports...)
+	config.MaxThreads = 1
+	applyScanDefaults(&config)
+	targets, _, _, err := resolveTargets(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.hosts = targets
+	config.checkpoint = newScanCheckpoint()
+	for _, h := range targets {
+		config.checkpoint.register(h.IP, len(ports))
+	}
+
+	// 第二台主机扫到端口5时中断，端口5的探测被取消，不计入检查点
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first := &simScan{}
+	err = ScanPortsCombined(ctx, config, func(info PortInfo) {
+		if info.Protocol == "progress" && info.Host == "198.18.3.2" && info.Port == 5 {
+			cancel()
+		}
+		first.callback(info)
+	})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	// 与ResumeScan相同，检查点经过持久化格式还原
+	resume := config
+	resume.hosts = nil
+	resume.resume = &resumeState{
+		from:       "interrupted",
+		checkpoint: restoreCheckpoint(config.checkpoint.snapshot()),
+		results:    first.results,
+	}
+	resume.checkpoint = resume.resume.checkpoint
+	pending, hostPorts, skipped := planResume(resume, targets, ports)
+	if skipped != 1 || len(pending) != 2 {
+		t.Fatalf("planResume kept %d hosts and skipped %d, want 2 and 1", len(pending), skipped)
+	}
+	resume.hosts = pending
+	resume.hostPorts = hostPorts
+
+	second, err := runSimScan(t, context.Background(), resume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanned := make(map[string][]int)
+	for _, info := range second.progress {
+		scanned[info.Host] = append(scanned[info.Host], info.Port)
+	}
+	for host := range scanned {
+		sort.Ints(scanned[host])
+	}
+	want := map[string][]int{
+		"198.18.3.2": {5, 6, 7, 8},
+		"198.18.3.3": ports,
+	}
+	if !reflect.DeepEqual(scanned, want) {
+		t.Fatalf("resumed scan probed %v, want %v", scanned, want)
+	}
+	if len(second.results) != 1 || second.results[0].Host != "198.18.3.2" || second.results[0].Port != 7 {
+		t.Fatalf("resumed results = %+v, want only 198.18.3.2:7", second.results)
+	}
+}
@@ -0,0 +1,51 @@
+package portsscanner
+
+import "time"
+
+// 两次 scan-complete-alert 之间的最小间隔，计划任务或批量扫描连续结束时提示音不会叠在一起
+const completionAlertGap = 2 * time.Second
+
+// completionDispositions 各结束状态对应的提示类别，前端据此选择提示音
+var completionDispositions = map[string]string{
+	"completed": "success",
+	"error":     "failure",
+	"cancelled": "cancelled",
+}
+
+// alertScanComplete 扫描进入结束状态后发送一次 scan-complete-alert 事件，携带扫描概要，
+// 同一扫描只发送一次。距上一次提示不足completionAlertGap时延迟发送而不是丢弃
+func (a *App) alertScanComplete(config ScanConfig, record *scanRecord, scanErr error) {
+	if config.SilentCompletion {
+		return
+	}
+	record.mu.Lock()
+	alerted := record.alerted
+	record.alerted = true
+	record.mu.Unlock()
+	if alerted {
+		return
+	}
+
+	summary, _, _ := record.snapshot()
+	data := map[string]interface{}{
+		"scan_id":     summary.ID,
+		"status":      summary.Status,
+		"disposition": completionDispositions[summary.Status],
+		"summary":     summary,
+	}
+	if scanErr != nil && summary.Status == "error" {
+		data["error"] = scanErr.Error()
+	}
+
+	a.alertMu.Lock()
+	wait := completionAlertGap - time.Since(a.lastAlert)
+	if wait <= 0 {
+		a.lastAlert = time.Now()
+		a.alertMu.Unlock()
+		a.emitEvent("scan-complete-alert", data)
+		return
+	}
+	a.lastAlert = a.lastAlert.Add(completionAlertGap)
+	a.alertMu.Unlock()
+	time.AfterFunc(wait, func() { a.emitEvent("scan-complete-alert", data) })
+}
@@ -0,0 +1,276 @@
+package portsscanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CompliancePolicy 合规策略文件：按主机组列出允许开放的端口。
+// 主机按Groups的顺序匹配，第一个匹配的组生效，不匹配任何组的主机不做判断；
+// 需要默认拒绝时在末尾加一个匹配 0.0.0.0/0 且不允许任何端口的组
+type CompliancePolicy struct {
+	Tags   map[string][]string `json:"tags,omitempty"` // 标签 -> 地址、CIDR或主机名
+	Groups []ComplianceGroup   `json:"groups"`
+}
+
+// ComplianceGroup 主机组，CIDRs和Tags任意一项匹配即属于该组
+type ComplianceGroup struct {
+	Name         string   `json:"name"`
+	CIDRs        []string `json:"cidrs,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	AllowedPorts []string `json:"allowed_ports"` // 如 "22"、"8000-8100"、"53/udp"，不带协议时TCP和UDP都允许
+}
+
+// ComplianceViolation 违反策略的开放端口
+type ComplianceViolation struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service"`
+	Group    string `json:"group"`
+}
+
+// HostCompliance 单台主机的判断结果，Group为空表示不匹配任何组，未做判断
+type HostCompliance struct {
+	Host       string                `json:"host"`
+	Group      string                `json:"group,omitempty"`
+	Passed     bool                  `json:"passed"`
+	OpenPorts  []int                 `json:"open_ports"`
+	Violations []ComplianceViolation `json:"violations"`
+}
+
+// ComplianceReport 扫描结果对照策略的审计结论
+type ComplianceReport struct {
+	ScanID     string                `json:"scan_id"`
+	Policy     string                `json:"policy"`
+	CheckedAt  time.Time             `json:"checked_at"`
+	Passed     bool                  `json:"passed"`
+	Hosts      []HostCompliance      `json:"hosts"`
+	Violations []ComplianceViolation `json:"violations"`
+	Unmatched  []string              `json:"unmatched,omitempty"` // 不属于任何组的主机
+}
+
+// allowedPort 解析后的允许端口范围，protocol为空时匹配任意协议
+type allowedPort struct {
+	lo, hi   int
+	protocol string
+}
+
+// complianceGroup 解析后的主机组
+type complianceGroup struct {
+	name     string
+	prefixes []netip.Prefix
+	names    map[string]bool // 标签展开得到的主机名，小写
+	allowed  []allowedPort
+}
+
+// parseAllowedPort 解析 "22"、"8000-8100" 或 "53/udp"
+func parseAllowedPort(s string) (allowedPort, error) {
+	spec, protocol, _ := strings.Cut(strings.TrimSpace(s), "/")
+	protocol = strings.ToLower(protocol)
+	if protocol != "" && protocol != "tcp" && protocol != "udp" {
+		return allowedPort{}, fmt.Errorf("invalid allowed port %q: unknown protocol %q", s, protocol)
+	}
+	lo, hi, isRange := strings.Cut(spec, "-")
+	start, err := strconv.Atoi(lo)
+	if err != nil {
+		return allowedPort{}, fmt.Errorf("invalid allowed port %q", s)
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(hi); err != nil {
+			return allowedPort{}, fmt.Errorf("invalid allowed port %q", s)
+		}
+	}
+	if start < 1 || end > 65535 || start > end {
+		return allowedPort{}, fmt.Errorf("invalid allowed port %q: out of range", s)
+	}
+	return allowedPort{lo: start, hi: end, protocol: protocol}, nil
+}
+
+// loadCompliancePolicy 读取并解析策略文件，标签中的地址和CIDR并入组的地址范围，其余视为主机名
+func loadCompliancePolicy(path string) ([]complianceGroup, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy %s: %w", path, err)
+	}
+	var policy CompliancePolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", path, err)
+	}
+	if len(policy.Groups) == 0 {
+		return nil, fmt.Errorf("policy %s defines no host groups", path)
+	}
+
+	groups := make([]complianceGroup, 0, len(policy.Groups))
+	for i, g := range policy.Groups {
+		group := complianceGroup{name: g.Name, names: make(map[string]bool)}
+		if group.name == "" {
+			group.name = "group-" + strconv.Itoa(i+1)
+		}
+		if group.prefixes, err = parseAllowedRanges(g.CIDRs); err != nil {
+			return nil, fmt.Errorf("group %q: %w", group.name, err)
+		}
+		for _, tag := range g.Tags {
+			members, ok := policy.Tags[tag]
+			if !ok {
+				return nil, fmt.Errorf("group %q references undefined tag %q", group.name, tag)
+			}
+			for _, m := range members {
+				if prefixes, err := parseAllowedRanges([]string{m}); err == nil {
+					group.prefixes = append(group.prefixes, prefixes...)
+				} else {
+					group.names[strings.ToLower(strings.TrimSpace(m))] = true
+				}
+			}
+		}
+		for _, spec := range g.AllowedPorts {
+			allowed, err := parseAllowedPort(spec)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: %w", group.name, err)
+			}
+			group.allowed = append(group.allowed, allowed)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// matches 判断主机是否属于该组，域名目标按扫描时的别名匹配标签中的主机名
+func (g complianceGroup) matches(host string, aliases []string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		for _, prefix := range g.prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+	}
+	for _, name := range aliases {
+		if g.names[strings.ToLower(name)] {
+			return true
+		}
+	}
+	return g.names[strings.ToLower(host)]
+}
+
+// permits 判断端口是否在组的允许列表中
+func (g complianceGroup) permits(port int, protocol string) bool {
+	for _, a := range g.allowed {
+		if port >= a.lo && port <= a.hi && (a.protocol == "" || strings.EqualFold(a.protocol, protocol)) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateCompliance 对照策略逐台主机判断，open|filtered 等未确认开放的端口不计入
+func evaluateCompliance(groups []complianceGroup, results []PortInfo) ([]HostCompliance, []string) {
+	byHost := make(map[string][]PortInfo)
+	var order []string
+	for _, r := range results {
+		if r.State != "" {
+			continue
+		}
+		if _, ok := byHost[r.Host]; !ok {
+			order = append(order, r.Host)
+		}
+		byHost[r.Host] = append(byHost[r.Host], r)
+	}
+	sort.Slice(order, func(i, j int) bool { return compareHosts(order[i], order[j]) < 0 })
+
+	hosts := make([]HostCompliance, 0, len(order))
+	var unmatched []string
+	for _, host := range order {
+		ports := byHost[host]
+		sort.Slice(ports, func(i, j int) bool { return ports[i].Port < ports[j].Port })
+		hc := HostCompliance{Host: host, Passed: true, OpenPorts: []int{}, Violations: []ComplianceViolation{}}
+		for _, r := range ports {
+			hc.OpenPorts = append(hc.OpenPorts, r.Port)
+		}
+
+		var group *complianceGroup
+		for i := range groups {
+			if groups[i].matches(host, ports[0].Aliases) {
+				group = &groups[i]
+				break
+			}
+		}
+		if group == nil {
+			unmatched = append(unmatched, host)
+			hosts = append(hosts, hc)
+			continue
+		}
+
+		hc.Group = group.name
+		for _, r := range ports {
+			if group.permits(r.Port, r.Protocol) {
+				continue
+			}
+			hc.Violations = append(hc.Violations, ComplianceViolation{
+				Host:     host,
+				Port:     r.Port,
+				Protocol: r.Protocol,
+				Service:  r.Service,
+				Group:    group.name,
+			})
+		}
+		hc.Passed = len(hc.Violations) == 0
+		hosts = append(hosts, hc)
+	}
+	return hosts, unmatched
+}
+
+// setComplianceReport 保存最近一次合规检查的结果，导出发现时作为违规标记
+func (r *scanRecord) setComplianceReport(report ComplianceReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.compliance = &report
+}
+
+// complianceReport 返回最近一次合规检查的结果，未检查过时返回nil
+func (r *scanRecord) complianceReport() *ComplianceReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.compliance
+}
+
+// CheckCompliance 将扫描结果与策略文件对照，列出每台主机是否通过以及违反策略的开放端口，
+// 每个违规端口发送一次 compliance-violation 事件
+func (a *App) CheckCompliance(scanID, policyPath string) (ComplianceReport, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return ComplianceReport{}, err
+	}
+	groups, err := loadCompliancePolicy(policyPath)
+	if err != nil {
+		return ComplianceReport{}, err
+	}
+	_, _, results := record.snapshot()
+
+	report := ComplianceReport{
+		ScanID:     scanID,
+		Policy:     policyPath,
+		CheckedAt:  time.Now(),
+		Violations: []ComplianceViolation{},
+	}
+	report.Hosts, report.Unmatched = evaluateCompliance(groups, results)
+	for _, hc := range report.Hosts {
+		report.Violations = append(report.Violations, hc.Violations...)
+	}
+	report.Passed = len(report.Violations) == 0
+	record.setComplianceReport(report)
+
+	for _, v := range report.Violations {
+		a.emitEvent("compliance-violation", v)
+	}
+	fmt.Printf("合规检查 %s: %d 台主机，%d 个违规端口，%d 台主机不属于任何组\n",
+		scanID, len(report.Hosts), len(report.Violations), len(report.Unmatched))
+	return report, nil
+}
@@ -0,0 +1,59 @@
+package portsscanner
+
+import (
+	"math"
+
+	"github.com/lcvvvv/gonmap"
+)
+
+// 识别可信度的各项依据及权重。Confidence = 满足的权重之和 / 适用的权重之和 × 100：
+//
+//	fingerprint 50  服务响应匹配了指纹(TCP为gonmap探针，UDP为对应服务的探测载荷)
+//	version     10  指纹同时提取出了产品名或版本号
+//	port        15  服务与该端口在nmap-services中的常用服务一致
+//	tls         10  完成了TLS握手；仅对已握手或服务名本身要求TLS(如https、imaps)的端口适用
+//	http        15  HTTP探测返回了Server头或页面标题；仅对HTTP类服务适用
+//
+// 没有识别出服务时为0。只靠端口猜测的识别最多只有port一项；横幅、端口、TLS和HTTP响应全部一致时为100
+const (
+	confidenceFingerprint = 50
+	confidenceVersion     = 10
+	confidencePort        = 15
+	confidenceTLS         = 10
+	confidenceHTTP        = 15
+)
+
+// tlsServices 服务名本身表示TLS封装的常见服务
+var tlsServices = map[string]bool{
+	"https": true, "imaps": true, "pop3s": true, "smtps": true, "submissions": true,
+	"ldaps": true, "ftps": true, "ircs": true, "nntps": true, "dot": true,
+}
+
+// scoreConfidence 根据已收集的识别依据计算可信度，满足的依据名记录在ConfidenceSignals中
+func scoreConfidence(portInfo *PortInfo) {
+	portInfo.Confidence = 0
+	portInfo.ConfidenceSignals = nil
+	if portInfo.Service == "" || portInfo.State != "" {
+		return
+	}
+
+	earned, applicable := 0, 0
+	signal := func(name string, weight int, ok bool) {
+		applicable += weight
+		if ok {
+			earned += weight
+			portInfo.ConfidenceSignals = append(portInfo.ConfidenceSignals, name)
+		}
+	}
+	signal("fingerprint", confidenceFingerprint, !portInfo.Unidentified)
+	signal("version", confidenceVersion, portInfo.ProductName != "" || portInfo.Version != "")
+	signal("port", confidencePort, canonicalServiceName(gonmap.GuessProtocol(portInfo.Port)) == portInfo.Service)
+	handshaked := portInfo.TLS || portInfo.MutualTLS || !portInfo.TLSExpiry.IsZero()
+	if handshaked || tlsServices[portInfo.Service] {
+		signal("tls", confidenceTLS, handshaked)
+	}
+	if isHTTPService(portInfo.Service) {
+		signal("http", confidenceHTTP, portInfo.HTTPServer != "" || portInfo.HTTPTitle != "")
+	}
+	portInfo.Confidence = int(math.Round(float64(earned) * 100 / float64(applicable)))
+}
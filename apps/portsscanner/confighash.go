@@ -0,0 +1,81 @@
+package portsscanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// configHashVersion 规范化规则变化时递增，不同版本的哈希不可比较
+const configHashVersion = "v1"
+
+// normalizeConfigForHash 返回用于计算哈希的配置副本：去掉扫描ID、关联ID、项目名等标识，
+// 以及结果推送、保存期限等不影响探测的字段；目标列表和端口列表排序去重，写法不同但等价的配置得到相同哈希
+func normalizeConfigForHash(config ScanConfig) ScanConfig {
+	c := config
+	c.ScanID = ""
+	c.CorrelationID = ""
+	c.Project = ""
+	c.PortCount = 0
+	c.StreamToFile = ""
+	c.SyslogTarget = ""
+	c.FindingWebhook = ""
+	c.PostScanCommand = ""
+	c.PostScanTimeout = 0
+	c.AutoExportFormats = nil
+	c.OutputDir = ""
+	c.CompletedRetention = 0
+	c.UnthrottledEvents = false
+	c.SilentCompletion = false
+	c.ProbeOrderSeed = 0
+
+	var targets []string
+	for _, t := range splitTargetList(c.Target) {
+		if ip, ok := canonicalIP(t); ok {
+			t = ip
+		} else {
+			t = strings.ToLower(strings.TrimSuffix(t, "."))
+		}
+		if !containsString(targets, t) {
+			targets = append(targets, t)
+		}
+	}
+	sort.Strings(targets)
+	c.Target = strings.Join(targets, ",")
+
+	if len(c.Ports) > 0 {
+		ports := append([]int(nil), c.Ports...)
+		sort.Ints(ports)
+		c.Ports = ports[:0]
+		for i, p := range ports {
+			if i == 0 || p != ports[i-1] {
+				c.Ports = append(c.Ports, p)
+			}
+		}
+	}
+	return c
+}
+
+// configHash 计算填充默认值后实际配置的哈希，形如 "sha256:..."。
+// 未导出的运行时状态不参与计算，map按键排序编码，结果与字段赋值顺序无关
+func configHash(config ScanConfig) string {
+	data, err := json.Marshal(normalizeConfigForHash(config))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(append([]byte(configHashVersion+"\n"), data...))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// GetConfigHash 返回扫描开始时记录的配置哈希，哈希相同的两次扫描使用了相同的参数。
+// 扫描不存在、或是导入的没有配置的扫描时返回空字符串
+func (a *App) GetConfigHash(scanID string) string {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return ""
+	}
+	summary, _, _ := record.snapshot()
+	return summary.ConfigHash
+}
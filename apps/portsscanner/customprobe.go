@@ -0,0 +1,285 @@
+package portsscanner
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CustomProbe 自定义探测：向指定端口发送Payload，TCP端口在gonmap没有匹配时使用，
+// UDP端口在内置载荷之后发送。Encoding 为 "hex" 时Payload为十六进制(可含空白)，
+// 默认为文本，支持 \x00、\r、\n、\t、\0、\\ 和 \" 转义。
+// Match 为可选的正则表达式，设置时TCP响应需要匹配才识别为Service，否则收到任何响应即识别。
+// Payload为空的TCP探测是被动匹配规则：不发送数据，Match(必填)在抓取的完整横幅上匹配，温和识别时同样使用。
+// 响应和横幅按BannerReadSize读取，匹配总是作用于读取到的全部内容
+type CustomProbe struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"` // tcp(默认) 或 udp
+	Ports    []int  `json:"ports"`
+	Service  string `json:"service"`
+	Payload  string `json:"payload"`
+	Encoding string `json:"encoding,omitempty"`
+	Match    string `json:"match,omitempty"`
+}
+
+// customProbeFile 自定义探测文件结构
+type customProbeFile struct {
+	Probes []CustomProbe `json:"probes"`
+}
+
+// customProbe 校验后的自定义探测
+type customProbe struct {
+	CustomProbe
+	data  []byte
+	match *regexp.Regexp
+}
+
+var (
+	customProbes   []customProbe
+	customProbesMu sync.RWMutex
+)
+
+// decodeEscapedPayload 解码文本载荷中的转义序列，格式错误时报告出错的位置
+func decodeEscapedPayload(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+		if i+1 >= len(s) {
+			return nil, fmt.Errorf("trailing backslash at offset %d", i)
+		}
+		i++
+		switch s[i] {
+		case 'r':
+			out = append(out, '\r')
+		case 'n':
+			out = append(out, '\n')
+		case 't':
+			out = append(out, '\t')
+		case '0':
+			out = append(out, 0)
+		case '\\', '"':
+			out = append(out, s[i])
+		case 'x':
+			if i+2 >= len(s) {
+				return nil, fmt.Errorf("incomplete \\x escape at offset %d", i-1)
+			}
+			b, err := hex.DecodeString(s[i+1 : i+3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid \\x escape %q at offset %d", s[i-1:i+3], i-1)
+			}
+			out = append(out, b[0])
+			i += 2
+		default:
+			return nil, fmt.Errorf("unknown escape \\%c at offset %d", s[i], i-1)
+		}
+	}
+	return out, nil
+}
+
+// decodeProbePayload 按编码方式将载荷转换为原始字节
+func decodeProbePayload(payload, encoding string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "", "text", "escape":
+		return decodeEscapedPayload(payload)
+	case "hex":
+		digits := strings.Join(strings.Fields(payload), "")
+		digits = strings.TrimPrefix(strings.TrimPrefix(digits, "0x"), "0X")
+		data, err := hex.DecodeString(digits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex payload: %w", err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown payload encoding %q", encoding)
+}
+
+// parseCustomProbe 校验单个自定义探测，在加载时报告所有格式问题
+func parseCustomProbe(p CustomProbe) (customProbe, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return customProbe{}, fmt.Errorf("probe name is empty")
+	}
+	p.Protocol = strings.ToLower(strings.TrimSpace(p.Protocol))
+	if p.Protocol == "" {
+		p.Protocol = "tcp"
+	}
+	if p.Protocol != "tcp" && p.Protocol != "udp" {
+		return customProbe{}, fmt.Errorf("probe %q: unknown protocol %q", p.Name, p.Protocol)
+	}
+	if len(p.Ports) == 0 {
+		return customProbe{}, fmt.Errorf("probe %q: no ports", p.Name)
+	}
+	for _, port := range p.Ports {
+		if port < 1 || port > 65535 {
+			return customProbe{}, fmt.Errorf("probe %q: invalid port %d", p.Name, port)
+		}
+	}
+	if p.Service == "" {
+		return customProbe{}, fmt.Errorf("probe %q: service is empty", p.Name)
+	}
+
+	probe := customProbe{CustomProbe: p}
+	var err error
+	if probe.data, err = decodeProbePayload(p.Payload, p.Encoding); err != nil {
+		return customProbe{}, fmt.Errorf("probe %q: %w", p.Name, err)
+	}
+	if p.Match != "" {
+		if probe.match, err = regexp.Compile(p.Match); err != nil {
+			return customProbe{}, fmt.Errorf("probe %q: invalid match: %w", p.Name, err)
+		}
+	}
+	if len(probe.data) == 0 {
+		if p.Protocol == "udp" {
+			return customProbe{}, fmt.Errorf("probe %q: payload is empty", p.Name)
+		}
+		if probe.match == nil {
+			return customProbe{}, fmt.Errorf("probe %q: passive probes (empty payload) need a match", p.Name)
+		}
+	}
+	return probe, nil
+}
+
+// passive 被动匹配规则只在横幅上匹配，不发送载荷
+func (p customProbe) passive() bool {
+	return len(p.data) == 0
+}
+
+// customProbesFor 返回适用于该协议和端口的自定义探测
+func customProbesFor(protocol string, port int) []customProbe {
+	customProbesMu.RLock()
+	defer customProbesMu.RUnlock()
+	var list []customProbe
+	for _, p := range customProbes {
+		if p.Protocol != protocol {
+			continue
+		}
+		for _, pp := range p.Ports {
+			if pp == port {
+				list = append(list, p)
+				break
+			}
+		}
+	}
+	return list
+}
+
+// fingerprintCustom 对gonmap未匹配的TCP端口依次发送自定义探测，识别成功时返回true
+func (s *portScanner) fingerprintCustom(ctx context.Context, h scanTarget, p int, portInfo *PortInfo) bool {
+	for _, probe := range s.config.orderedCustomProbes(h.IP, p) {
+		if probe.passive() {
+			continue
+		}
+		if s.runCustomProbe(ctx, h, p, probe, portInfo) {
+			return true
+		}
+	}
+	return false
+}
+
+// runCustomProbe 发送单个自定义探测，响应符合时写入识别结果并返回true
+func (s *portScanner) runCustomProbe(ctx context.Context, h scanTarget, p int, probe customProbe, portInfo *PortInfo) bool {
+	size := bannerReadLimit(s.config.BannerReadSize, defaultBannerReadSize)
+	response, err := sendProbePayload(ctx, s.config, h.IP, p, probe.data, size)
+	if err != nil || len(response) == 0 {
+		return false
+	}
+	if probe.match != nil && !probe.match.Match(response) {
+		return false
+	}
+	portInfo.Service = probe.Service
+	portInfo.ProbeName = probe.Name
+	portInfo.Unidentified = false
+	if s.config.CaptureResponse {
+		portInfo.RawResponse = dumpResponse(string(response), s.config.CaptureSize)
+	}
+	s.captureBanner(portInfo, response)
+	if len(response) > unidentifiedBannerSize {
+		response = response[:unidentifiedBannerSize]
+	}
+	portInfo.Info = escapeBanner(response)
+	s.log(logDebug, "%s:%d 自定义探测 %s 识别为 %s", h.IP, p, probe.Name, probe.Service)
+	return true
+}
+
+// matchBanner 用被动匹配规则匹配抓取到的完整横幅，匹配时写入Service并返回true
+func (s *portScanner) matchBanner(h scanTarget, p int, banner []byte, portInfo *PortInfo) bool {
+	for _, probe := range customProbesFor("tcp", p) {
+		if !probe.passive() || !probe.match.Match(banner) {
+			continue
+		}
+		portInfo.Service = probe.Service
+		portInfo.ProbeName = probe.Name
+		portInfo.Unidentified = false
+		s.log(logDebug, "%s:%d 横幅(%d字节)匹配规则 %s，识别为 %s", h.IP, p, len(banner), probe.Name, probe.Service)
+		return true
+	}
+	return false
+}
+
+// customProbeByName 按名称(不区分大小写)查找已加载的TCP自定义探测
+func customProbeByName(name string) (customProbe, bool) {
+	customProbesMu.RLock()
+	defer customProbesMu.RUnlock()
+	for _, p := range customProbes {
+		if p.Protocol == "tcp" && strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return customProbe{}, false
+}
+
+// sendProbePayload 建立连接发送载荷后读取响应
+func sendProbePayload(ctx context.Context, config ScanConfig, host string, port int, payload []byte, size int) ([]byte, error) {
+	conn, err := config.dialProbe(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write(payload); err != nil {
+		return nil, err
+	}
+	return readBanner(conn, config.probeTimeout(port), size), nil
+}
+
+// LoadCustomProbes 从JSON文件({"probes": [...]}，格式见CustomProbe)加载自定义探测，替换之前加载的全部自定义探测。
+// 任一探测格式错误时不做任何修改并返回错误，成功时返回加载的数量
+func (a *App) LoadCustomProbes(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read probes %s: %w", path, err)
+	}
+	var file customProbeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return 0, fmt.Errorf("failed to parse probes %s: %w", path, err)
+	}
+
+	probes := make([]customProbe, 0, len(file.Probes))
+	for _, p := range file.Probes {
+		probe, err := parseCustomProbe(p)
+		if err != nil {
+			return 0, err
+		}
+		for _, existing := range probes {
+			if strings.EqualFold(existing.Name, probe.Name) {
+				return 0, fmt.Errorf("duplicate probe name %q", probe.Name)
+			}
+		}
+		probes = append(probes, probe)
+	}
+
+	customProbesMu.Lock()
+	customProbes = probes
+	customProbesMu.Unlock()
+	fmt.Printf("已加载 %d 个自定义探测\n", len(probes))
+	return len(probes), nil
+}
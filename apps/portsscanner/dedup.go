@@ -0,0 +1,64 @@
+package portsscanner
+
+// resultKey 标识一个端口的一次探测结果
+type resultKey struct {
+	host     string
+	port     int
+	protocol string
+}
+
+// progressKey 进度去重的主机和协议，both 扫描的TCP和UDP进度分别计算
+type progressKey struct {
+	host string
+	udp  bool
+}
+
+// resultDedup 保证每个端口只计入一次进度、只发送一次 port-found。
+// 复核(TreatResetAsAmbiguous)、续扫或两遍扫描等路径上同一端口可能被重复报告，
+// 只在结果分发协程中使用，不需要加锁
+type resultDedup struct {
+	progressed map[progressKey][]uint64 // 每台主机已计入进度的端口位图，内存只随主机数增长
+	found      map[resultKey]PortInfo
+}
+
+func newResultDedup() *resultDedup {
+	return &resultDedup{
+		progressed: make(map[progressKey][]uint64),
+		found:      make(map[resultKey]PortInfo),
+	}
+}
+
+// firstProgress 端口第一次报告进度时返回true，UDP的进度标记以Service区分
+func (d *resultDedup) firstProgress(info PortInfo) bool {
+	if info.Port < 0 || info.Port > 65535 {
+		return true
+	}
+	key := progressKey{info.Host, info.Service == ScanTypeUDP}
+	done := d.progressed[key]
+	if done == nil {
+		done = make([]uint64, 65536/64)
+		d.progressed[key] = done
+	}
+	word, bit := info.Port/64, uint64(1)<<(info.Port%64)
+	if done[word]&bit != 0 {
+		return false
+	}
+	done[word] |= bit
+	return true
+}
+
+// classify 判断开放端口是否为首次报告；重复报告带有之前没有的服务识别结果时返回updated，
+// 由调用方更新已保存的结果，其余重复报告直接丢弃
+func (d *resultDedup) classify(info PortInfo) (first, updated bool) {
+	key := resultKey{info.Host, info.Port, info.Protocol}
+	prev, ok := d.found[key]
+	if !ok {
+		d.found[key] = info
+		return true, false
+	}
+	if prev.Service == "" && info.Service != "" {
+		d.found[key] = info
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,64 @@
+package portsscanner
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRetryThenSuccessReportedOnce(t *testing.T) {
+	hosts := []SimulatedHost{{IP: "198.18.1.1", Ports: []SimulatedPort{
+		{Port: 8080, Service: "http", Flap: "fo"},
+	}}}
+	config := simConfig(t, hosts, "198.18.1.1", 8080, 8081)
+	config.ProbeAttempts = 3
+
+	scan, err := runSimScan(t, context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scan.results) != 1 || scan.results[0].Port != 8080 || scan.results[0].State != "" {
+		t.Fatalf("results = %+v, want a single open 8080", scan.results)
+	}
+	if len(scan.progress) != 2 {
+		t.Errorf("progress reports = %d, want one per port", len(scan.progress))
+	}
+	if !scan.results[0].Flapping {
+		t.Errorf("port open on the second attempt should be marked flapping")
+	}
+
+	// 把回调按结果分发的方式去重，重复的报告既不计入进度也不再发送 port-found
+	dedup := newResultDedup()
+	scanned, found := 0, 0
+	reports := append(append([]PortInfo{}, scan.progress...), scan.progress...)
+	reports = append(reports, scan.results...)
+	reports = append(reports, scan.results...)
+	for _, info := range reports {
+		if info.Protocol == "progress" {
+			if dedup.firstProgress(info) {
+				scanned++
+			}
+			continue
+		}
+		if first, _ := dedup.classify(info); first {
+			found++
+		}
+	}
+	if scanned != 2 || found != 1 {
+		t.Fatalf("scanned = %d, found = %d, want 2 and 1", scanned, found)
+	}
+}
+
+func TestProgressDedupSeparatesProtocols(t *testing.T) {
+	dedup := newResultDedup()
+	tcp := PortInfo{Host: "198.18.1.1", Port: 53, Protocol: "progress"}
+	udp := PortInfo{Host: "198.18.1.1", Port: 53, Protocol: "progress", Service: ScanTypeUDP}
+	other := PortInfo{Host: "198.18.1.2", Port: 53, Protocol: "progress"}
+	for _, info := range []PortInfo{tcp, udp, other} {
+		if !dedup.firstProgress(info) {
+			t.Errorf("first progress for %+v was dropped", info)
+		}
+		if dedup.firstProgress(info) {
+			t.Errorf("repeated progress for %+v was counted", info)
+		}
+	}
+}
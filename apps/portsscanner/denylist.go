@@ -0,0 +1,227 @@
+package portsscanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 拒绝列表的文件名，保存在 ~/GlideWay 下
+const denylistFile = "denylist.json"
+
+// storedDenylist 拒绝列表在磁盘上的存储结构
+type storedDenylist struct {
+	Entries []string `json:"entries"`
+}
+
+// hostDenylist 永久排除的主机，每次扫描都会应用。条目可以是IP、CIDR或主机名
+type hostDenylist struct {
+	mu      sync.Mutex
+	loaded  bool
+	entries []string
+}
+
+var denylist hostDenylist
+
+// denylistPath 返回拒绝列表文件路径，目录不存在时自动创建
+func denylistPath() (string, error) {
+	return dataFilePath(denylistFile)
+}
+
+// normalizeDenylistEntry 规范化条目：地址和CIDR转为标准写法，主机名转为小写
+func normalizeDenylistEntry(entry string) (string, error) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return "", errors.New("denylist entry is empty")
+	}
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return "", fmt.Errorf("invalid denylist entry %q: %w", entry, err)
+		}
+		return prefix.Masked().String(), nil
+	}
+	if ip, ok := canonicalIP(entry); ok {
+		return ip, nil
+	}
+	name := strings.ToLower(strings.TrimSuffix(entry, "."))
+	if strings.ContainsAny(name, " \t:,") {
+		return "", fmt.Errorf("invalid denylist entry %q", entry)
+	}
+	return name, nil
+}
+
+// load 首次使用时从磁盘读取，文件不存在时为空列表。调用方需持有mu
+func (d *hostDenylist) load() error {
+	if d.loaded {
+		return nil
+	}
+	path, err := denylistPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		d.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read denylist: %w", err)
+	}
+	var stored storedDenylist
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to parse denylist %s: %w", path, err)
+	}
+	d.entries = stored.Entries
+	d.loaded = true
+	return nil
+}
+
+// save 写回磁盘。调用方需持有mu
+func (d *hostDenylist) save() error {
+	path, err := denylistPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(storedDenylist{Entries: d.entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write denylist: %w", err)
+	}
+	return nil
+}
+
+// snapshot 返回当前条目的副本
+func (d *hostDenylist) snapshot() ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return append([]string{}, d.entries...), nil
+}
+
+// denylistMatch 返回命中主机的条目，IP按地址和CIDR匹配，主机名按扫描时的原始输入匹配
+func denylistMatch(entries []string, h scanTarget) (string, bool) {
+	addr, addrErr := netip.ParseAddr(h.IP)
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err == nil && addrErr == nil && prefix.Contains(addr.Unmap()) {
+				return entry, true
+			}
+			continue
+		}
+		if entry == h.IP {
+			return entry, true
+		}
+		for _, name := range h.Names {
+			if strings.EqualFold(strings.TrimSuffix(name, "."), entry) {
+				return entry, true
+			}
+		}
+	}
+	return "", false
+}
+
+// applyDenylist 从目标中去掉拒绝列表中的主机，每台发送一次 host-skipped-denylist 事件。
+// 列表无法读取时拒绝扫描，避免安全列表失效时误扫
+func (a *App) applyDenylist(config ScanConfig, hosts []scanTarget) ([]scanTarget, error) {
+	entries, err := denylist.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return hosts, nil
+	}
+	kept := hosts[:0:0]
+	for _, h := range hosts {
+		entry, denied := denylistMatch(entries, h)
+		if !denied {
+			kept = append(kept, h)
+			continue
+		}
+		a.emitEvent("host-skipped-denylist", map[string]interface{}{
+			"target": config.Target,
+			"host":   h.IP,
+			"names":  h.Names,
+			"entry":  entry,
+		})
+	}
+	if skipped := len(hosts) - len(kept); skipped > 0 {
+		fmt.Printf("拒绝列表: 跳过 %d 台主机\n", skipped)
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("all %d target host(s) are on the denylist", len(hosts))
+	}
+	return kept, nil
+}
+
+// AddToDenylist 将IP、CIDR或主机名加入永久拒绝列表，之后的每次扫描都会跳过匹配的主机。已存在时不重复添加
+func (a *App) AddToDenylist(host string) error {
+	entry, err := normalizeDenylistEntry(host)
+	if err != nil {
+		return err
+	}
+	denylist.mu.Lock()
+	defer denylist.mu.Unlock()
+	if err := denylist.load(); err != nil {
+		return err
+	}
+	if containsString(denylist.entries, entry) {
+		return nil
+	}
+	denylist.entries = append(denylist.entries, entry)
+	sort.Strings(denylist.entries)
+	if err := denylist.save(); err != nil {
+		denylist.entries = removeString(denylist.entries, entry)
+		return err
+	}
+	return nil
+}
+
+// RemoveFromDenylist 从永久拒绝列表中删除条目，需与添加时的写法一致(地址和CIDR按规范化后的形式比较)
+func (a *App) RemoveFromDenylist(host string) error {
+	entry, err := normalizeDenylistEntry(host)
+	if err != nil {
+		return err
+	}
+	denylist.mu.Lock()
+	defer denylist.mu.Unlock()
+	if err := denylist.load(); err != nil {
+		return err
+	}
+	if !containsString(denylist.entries, entry) {
+		return fmt.Errorf("%s is not on the denylist", entry)
+	}
+	previous := denylist.entries
+	denylist.entries = removeString(denylist.entries, entry)
+	if err := denylist.save(); err != nil {
+		denylist.entries = previous
+		return err
+	}
+	return nil
+}
+
+// GetDenylist 返回永久拒绝列表的全部条目
+func (a *App) GetDenylist() ([]string, error) {
+	return denylist.snapshot()
+}
+
+// removeString 返回去掉s后的新切片
+func removeString(list []string, s string) []string {
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
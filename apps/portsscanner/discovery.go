@@ -0,0 +1,131 @@
+package portsscanner
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 存活探测使用的端口，任一端口连接成功或被拒绝即说明主机在线
+var discoveryPorts = []int{80, 443, 22, 445, 3389}
+
+// discoverHosts 通过TCP连接探测存活主机，无需原始套接字权限，返回顺序与输入一致。
+// 每探测完一台主机调用一次progress，可为nil
+func discoverHosts(ctx context.Context, hosts []scanTarget, timeout time.Duration, threads int, dial contextDialer, sources *sourcePool, progress func()) []scanTarget {
+	alive := make([]bool, len(hosts))
+	semaphore := make(chan struct{}, threads)
+	var wg sync.WaitGroup
+
+	for i, h := range hosts {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, ip string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			alive[i] = hostAlive(ctx, ip, timeout, dial, sources)
+			if progress != nil {
+				progress()
+			}
+		}(i, h.IP)
+	}
+	wg.Wait()
+
+	live := make([]scanTarget, 0, len(hosts))
+	for i, h := range hosts {
+		if alive[i] {
+			live = append(live, h)
+		}
+	}
+	return live
+}
+
+// hostAlive 并发连接探测端口，收到SYN/ACK或RST都说明主机在线
+func hostAlive(ctx context.Context, ip string, timeout time.Duration, dial contextDialer, sources *sourcePool) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan bool, len(discoveryPorts))
+	for _, port := range discoveryPorts {
+		go func(port int) {
+			conn, err := dialTCP(ctx, dial, sources, net.JoinHostPort(ip, strconv.Itoa(port)), timeout)
+			if err == nil {
+				conn.Close()
+			}
+			results <- err == nil || isConnRefused(err)
+		}(port)
+	}
+	for range discoveryPorts {
+		if <-results {
+			return true
+		}
+	}
+	return false
+}
+
+// HostSummary 扫描结束时按主机汇总的开放端口和服务
+type HostSummary struct {
+	Host      string   `json:"host"`
+	Aliases   []string `json:"aliases,omitempty"`
+	OpenPorts []int    `json:"open_ports"`
+	Services  []string `json:"services"` // 形如 "22/ssh"，未识别的服务只有端口号
+
+	LikelyHoneypot bool `json:"likely_honeypot"` // 开放端口比例异常或多个端口返回相同横幅
+
+	Geo *GeoInfo `json:"geo,omitempty"` // 外部地址的ASN和国家，需先通过SetGeoDB加载数据库
+}
+
+// summarizeHosts 按主机汇总扫描的开放端口，open|filtered 等无法确认开放的结果不计入，没有开放端口的主机也会列出
+func summarizeHosts(hosts []scanTarget, results []PortInfo) []HostSummary {
+	byHost := make(map[string][]PortInfo)
+	for _, r := range results {
+		if r.State != "" {
+			continue
+		}
+		byHost[r.Host] = append(byHost[r.Host], r)
+	}
+
+	summaries := make([]HostSummary, 0, len(hosts))
+	for _, h := range hosts {
+		found := byHost[h.IP]
+		sort.Slice(found, func(i, j int) bool { return found[i].Port < found[j].Port })
+
+		summary := HostSummary{Host: h.IP, Aliases: h.Names, OpenPorts: []int{}, Services: []string{}}
+		if geo, ok := lookupGeo(h.IP); ok {
+			summary.Geo = &geo
+		}
+		for _, r := range found {
+			summary.OpenPorts = append(summary.OpenPorts, r.Port)
+			service := strconv.Itoa(r.Port)
+			if r.Service != "" {
+				service += "/" + strings.ToLower(r.Service)
+			}
+			summary.Services = append(summary.Services, service)
+			if r.LikelyHoneypot {
+				summary.LikelyHoneypot = true
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// QuickScan 快速模式：探测网段内的存活主机，对存活主机扫描最常见的100个端口并识别服务，
+// 结束时为每台主机发送一条 host-summary 事件
+func (a *App) QuickScan(cidr string, maxThreads int) error {
+	return a.ScanWithConfig(ScanConfig{
+		Target:          cidr,
+		TopPorts:        100,
+		Discover:        true,
+		EmitHostSummary: true,
+		MaxThreads:      maxThreads,
+	})
+}
@@ -0,0 +1,134 @@
+package portsscanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// DNS over HTTPS 单次查询的超时时间，与自定义DNS服务器的拨号超时一致
+	dohTimeout = 5 * time.Second
+	// RFC 8484 规定的DNS消息媒体类型
+	dohContentType = "application/dns-message"
+	// 未指定路径时使用的常见DoH路径
+	dohDefaultPath = "/dns-query"
+)
+
+var dohClient = &http.Client{Timeout: dohTimeout}
+
+// parseDoHEndpoint 校验DoH地址，只接受https，未指定路径时补全为 /dns-query
+func parseDoHEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DoH endpoint %q: %w", endpoint, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid DoH endpoint %q: must be an https URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = dohDefaultPath
+	}
+	return u, nil
+}
+
+// newDoHResolver 创建通过DNS over HTTPS查询的解析器，正向和反向解析都经由该地址。
+// Go解析器对非PacketConn连接使用TCP分帧(2字节长度前缀)，dohConn在写入完整请求后发起HTTPS查询
+func newDoHResolver(endpoint *url.URL) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, endpoint: endpoint.String(), addr: dohAddr(endpoint.Host)}, nil
+		},
+	}
+}
+
+// dohAddr DoH服务器地址，仅用于满足net.Conn接口
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
+
+// dohConn 将Go解析器的一次DNS交换转换为一次HTTPS POST请求
+type dohConn struct {
+	ctx      context.Context
+	endpoint string
+	addr     dohAddr
+	deadline time.Time
+	req      bytes.Buffer
+	resp     bytes.Buffer
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.req.Write(b)
+	buf := c.req.Bytes()
+	if len(buf) < 2 {
+		return len(b), nil
+	}
+	size := int(binary.BigEndian.Uint16(buf))
+	if len(buf) < 2+size {
+		return len(b), nil
+	}
+	answer, err := c.exchange(buf[2 : 2+size])
+	c.req.Reset()
+	if err != nil {
+		return 0, err
+	}
+	var prefix [2]byte
+	binary.BigEndian.PutUint16(prefix[:], uint16(len(answer)))
+	c.resp.Write(prefix[:])
+	c.resp.Write(answer)
+	return len(b), nil
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.resp.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.resp.Read(b)
+}
+
+// exchange 按RFC 8484以POST方式发送DNS查询并返回应答报文
+func (c *dohConn) exchange(msg []byte) ([]byte, error) {
+	ctx := c.ctx
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohContentType)
+	req.Header.Set("Accept", dohContentType)
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH endpoint returned %s", resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != dohContentType {
+		return nil, fmt.Errorf("DoH endpoint returned unexpected content type %q", ct)
+	}
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr("local") }
+func (c *dohConn) RemoteAddr() net.Addr               { return c.addr }
+func (c *dohConn) SetDeadline(t time.Time) error      { c.deadline = t; return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { c.deadline = t; return nil }
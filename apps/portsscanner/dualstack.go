@@ -0,0 +1,149 @@
+package portsscanner
+
+import (
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+)
+
+// DualStackDiff 同一主机名的IPv4和IPv6地址开放端口的对比，端口写作 "443/tcp"
+type DualStackDiff struct {
+	Hostname string   `json:"hostname"`
+	IPv4     string   `json:"ipv4"`
+	IPv6     string   `json:"ipv6"`
+	IPv4Only []string `json:"ipv4_only"` // 只在IPv4地址上开放
+	IPv6Only []string `json:"ipv6_only"` // 只在IPv6地址上开放，常见于IPv6未配置防火墙
+	Common   []string `json:"common"`
+}
+
+// dualStackAddresses 返回解析结果中的首个IPv4地址和首个IPv6地址，只有一个地址族时只返回一个
+func dualStackAddresses(addrs []net.IPAddr) []string {
+	var v4, v6 string
+	for _, a := range addrs {
+		ip, ok := canonicalIP(a.String())
+		if !ok {
+			continue
+		}
+		if a.IP.To4() != nil {
+			if v4 == "" {
+				v4 = ip
+			}
+		} else if v6 == "" {
+			v6 = ip
+		}
+	}
+	var ips []string
+	for _, ip := range []string{v4, v6} {
+		if ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// dualStackPort 对比使用的端口键
+type dualStackPort struct {
+	port     int
+	protocol string
+}
+
+func (p dualStackPort) String() string {
+	return strconv.Itoa(p.port) + "/" + p.protocol
+}
+
+// openPortSet 返回主机已确认开放的端口
+func openPortSet(results []PortInfo, host string) map[dualStackPort]bool {
+	set := make(map[dualStackPort]bool)
+	for _, r := range results {
+		if r.Host == host && r.State == "" {
+			set[dualStackPort{r.Port, r.Protocol}] = true
+		}
+	}
+	return set
+}
+
+// sortedPorts 按端口号和协议排序后格式化
+func sortedPorts(ports []dualStackPort) []string {
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].port != ports[j].port {
+			return ports[i].port < ports[j].port
+		}
+		return ports[i].protocol < ports[j].protocol
+	})
+	list := make([]string, len(ports))
+	for i, p := range ports {
+		list[i] = p.String()
+	}
+	return list
+}
+
+// dualStackDiffs 找出同一主机名同时对应IPv4和IPv6目标的主机，对比两者的开放端口，按主机名排序
+func dualStackDiffs(hosts []scanTarget, results []PortInfo) []DualStackDiff {
+	type pair struct{ v4, v6 string }
+	pairs := make(map[string]*pair)
+	for _, h := range hosts {
+		addr, err := netip.ParseAddr(h.IP)
+		if err != nil {
+			continue
+		}
+		for _, name := range h.Names {
+			if _, isIP := canonicalIP(name); isIP {
+				continue
+			}
+			p := pairs[name]
+			if p == nil {
+				p = &pair{}
+				pairs[name] = p
+			}
+			if addr.Is4() {
+				p.v4 = h.IP
+			} else {
+				p.v6 = h.IP
+			}
+		}
+	}
+
+	var diffs []DualStackDiff
+	for name, p := range pairs {
+		if p.v4 == "" || p.v6 == "" {
+			continue
+		}
+		v4, v6 := openPortSet(results, p.v4), openPortSet(results, p.v6)
+		var only4, only6, common []dualStackPort
+		for port := range v4 {
+			if v6[port] {
+				common = append(common, port)
+			} else {
+				only4 = append(only4, port)
+			}
+		}
+		for port := range v6 {
+			if !v4[port] {
+				only6 = append(only6, port)
+			}
+		}
+		diffs = append(diffs, DualStackDiff{
+			Hostname: name,
+			IPv4:     p.v4,
+			IPv6:     p.v6,
+			IPv4Only: sortedPorts(only4),
+			IPv6Only: sortedPorts(only6),
+			Common:   sortedPorts(common),
+		})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Hostname < diffs[j].Hostname })
+	return diffs
+}
+
+// emitDualStackDiffs 扫描完成后为每个双栈主机名发送 dualstack-diff 事件
+func (a *App) emitDualStackDiffs(record *scanRecord, hosts []scanTarget) {
+	_, _, results := record.snapshot()
+	for _, diff := range dualStackDiffs(hosts, results) {
+		if len(diff.IPv4Only) > 0 || len(diff.IPv6Only) > 0 {
+			record.log.add(logWarn, "%s 的IPv4(%s)和IPv6(%s)开放端口不一致: 仅IPv4 %v，仅IPv6 %v",
+				diff.Hostname, diff.IPv4, diff.IPv6, diff.IPv4Only, diff.IPv6Only)
+		}
+		a.emitEvent("dualstack-diff", diff)
+	}
+}
@@ -0,0 +1,33 @@
+package portsscanner
+
+import (
+	"net"
+	"os"
+)
+
+// egressIP 根据到目标的路由确定本机出口地址(UDP connect不会发送数据包)
+func egressIP(target string) (string, error) {
+	conn, err := net.Dial("udp", net.JoinHostPort(target, "80"))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// collectEgress 返回扫描机主机名和访问各目标时用到的出口地址(去重)
+func collectEgress(hosts []scanTarget) (string, []string) {
+	hostname, _ := os.Hostname()
+
+	seen := make(map[string]bool)
+	var ips []string
+	for _, h := range hosts {
+		ip, err := egressIP(h.IP)
+		if err != nil || seen[ip] {
+			continue
+		}
+		seen[ip] = true
+		ips = append(ips, ip)
+	}
+	return hostname, ips
+}
@@ -0,0 +1,156 @@
+package portsscanner
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// scanEndpoint 用户输入的单个 host:port 扫描目标
+type scanEndpoint struct {
+	Input string
+	Host  string
+	Port  int
+}
+
+// parseEndpoints 解析 host:port 列表，IPv6地址需写成 [::1]:22 形式
+func parseEndpoints(inputs []string) ([]scanEndpoint, error) {
+	endpoints := make([]scanEndpoint, 0, len(inputs))
+	for _, input := range inputs {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		host, portStr, err := net.SplitHostPort(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %w", input, err)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port in endpoint %q", input)
+		}
+		if _, _, isSpan, _ := parseTargetSpan(host); isSpan {
+			return nil, fmt.Errorf("endpoint %q must name a single host, not a range", input)
+		}
+		endpoints = append(endpoints, scanEndpoint{Input: input, Host: host, Port: port})
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("endpoint list is empty")
+	}
+	return endpoints, nil
+}
+
+// endpointTargets 返回端点列表中去重的主机和排序后的端口，作为扫描配置的目标和端口列表
+func endpointTargets(endpoints []scanEndpoint) ([]string, []int) {
+	var hosts []string
+	seenPorts := make(map[int]bool)
+	var ports []int
+	for _, ep := range endpoints {
+		if !containsString(hosts, ep.Host) {
+			hosts = append(hosts, ep.Host)
+		}
+		if !seenPorts[ep.Port] {
+			seenPorts[ep.Port] = true
+			ports = append(ports, ep.Port)
+		}
+	}
+	sort.Ints(ports)
+	return hosts, ports
+}
+
+// ScanEndpoints 直接扫描 host:port 列表，每个端点独立探测和指纹识别，
+// 进度按端点数量计算，port-found 事件的 endpoint 字段为对应的原始输入
+func (a *App) ScanEndpoints(endpoints []string, maxThreads int) error {
+	parsed, err := parseEndpoints(endpoints)
+	if err != nil {
+		return err
+	}
+	hosts, ports := endpointTargets(parsed)
+	return a.ScanWithConfig(ScanConfig{
+		Target:     strings.Join(hosts, ","),
+		Ports:      ports,
+		MaxThreads: maxThreads,
+		endpoints:  parsed,
+	})
+}
+
+// ScanDiscovered 以之前快速扫描发现的开放TCP端口为端点列表进行深入扫描(指纹、TLS和HTTP探测按config设置)，
+// 只探测这些 host:port，不再扫描其他端口。config中的目标和端口范围被忽略，也不再进行存活探测和两段式扫描
+func (a *App) ScanDiscovered(fastScanID string, config ScanConfig) error {
+	record, err := getScanRecord(fastScanID)
+	if err != nil {
+		return err
+	}
+	if config.ScanType == ScanTypeUDP || config.ScanType == ScanTypeBoth {
+		return fmt.Errorf("ScanDiscovered only rescans TCP ports, scan type %q is not supported", config.ScanType)
+	}
+	_, _, results := record.snapshot()
+	var inputs []string
+	for _, r := range results {
+		if r.Protocol != "tcp" || r.State != "" {
+			continue
+		}
+		if input := net.JoinHostPort(r.Host, strconv.Itoa(r.Port)); !containsString(inputs, input) {
+			inputs = append(inputs, input)
+		}
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("scan %q has no open TCP ports to rescan", fastScanID)
+	}
+	parsed, err := parseEndpoints(inputs)
+	if err != nil {
+		return err
+	}
+	hosts, ports := endpointTargets(parsed)
+	config.Target = strings.Join(hosts, ",")
+	config.Ports = ports
+	config.TopPorts = 0
+	config.StartPort, config.EndPort = 0, 0
+	config.Discover = false
+	config.TwoPass = false
+	config.endpoints = parsed
+	fmt.Printf("基于扫描 %s 发现的 %d 个开放端口进行深入扫描\n", fastScanID, len(parsed))
+	return a.ScanWithConfig(config)
+}
+
+// planEndpoints 将端点对应到去重后的主机，返回每台主机需要扫描的端口
+// 以及 ip:port 到原始输入的映射，同一主机端口的多个写法只扫描一次
+func planEndpoints(hosts []scanTarget, endpoints []scanEndpoint) (map[string][]int, map[string]string) {
+	hostPorts := make(map[string][]int)
+	names := make(map[string]string)
+	for _, ep := range endpoints {
+		for _, h := range hosts {
+			if !containsString(h.Names, ep.Host) {
+				continue
+			}
+			key := net.JoinHostPort(h.IP, strconv.Itoa(ep.Port))
+			if _, exists := names[key]; !exists {
+				names[key] = ep.Input
+				hostPorts[h.IP] = append(hostPorts[h.IP], ep.Port)
+			}
+			break
+		}
+	}
+	for _, ports := range hostPorts {
+		sort.Ints(ports)
+	}
+	return hostPorts, names
+}
+
+// portsFor 返回指定主机需要扫描的端口，端点扫描时每台主机的端口各不相同
+func (c ScanConfig) portsFor(ip string, ports []int) []int {
+	if c.hostPorts == nil {
+		return ports
+	}
+	return c.hostPorts[ip]
+}
+
+// endpointName 返回端点扫描中 ip:port 对应的原始输入
+func (c ScanConfig) endpointName(ip string, port int) string {
+	if c.endpointNames == nil {
+		return ""
+	}
+	return c.endpointNames[net.JoinHostPort(ip, strconv.Itoa(port))]
+}
@@ -0,0 +1,126 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// 校准时最多采样的端口数
+const estimateSampleSize = 5
+
+// ScanEstimate 扫描耗时预估结果及其依据
+type ScanEstimate struct {
+	Duration      time.Duration `json:"duration"`
+	Hosts         int           `json:"hosts"`
+	PortsPerHost  int           `json:"ports_per_host"`
+	TotalProbes   int           `json:"total_probes"` // 含ProbeAttempts和UDPAttempts的重复探测
+	Threads       int           `json:"threads"`
+	Timeout       time.Duration `json:"timeout"`
+	SampleSize    int           `json:"sample_size"`
+	SampleLatency time.Duration `json:"sample_latency"` // 校准得到的平均单次探测耗时
+	Assumptions   []string      `json:"assumptions"`
+}
+
+// EstimateScanDuration 根据端口数、主机数、探测次数、连接线程数和超时时间预估扫描耗时，
+// 并对首个目标做少量端口的采样扫描以校准实际延迟。采样与扫描使用相同的拨号方式(模拟网络、代理和源地址)
+func (a *App) EstimateScanDuration(config ScanConfig) (ScanEstimate, error) {
+	return estimateScanDuration(context.Background(), config)
+}
+
+func estimateScanDuration(ctx context.Context, config ScanConfig) (ScanEstimate, error) {
+	ports, _, err := prepareScanConfig(&config)
+	if err != nil {
+		return ScanEstimate{}, err
+	}
+	defer config.proxies.close()
+
+	hosts, _, _, err := resolveTargets(ctx, config)
+	if err != nil {
+		return ScanEstimate{}, err
+	}
+
+	threads := config.connectThreads()
+	estimate := ScanEstimate{
+		Hosts:        len(hosts),
+		PortsPerHost: len(ports),
+		Threads:      threads,
+		Timeout:      config.Timeout,
+	}
+
+	// 未开放的TCP端口会探测满ProbeAttempts次；UDP端口按无响应计算，每次发送都等待超时
+	tcpAttempts := max(config.ProbeAttempts, 1)
+	udpAttempts := config.UDPAttempts
+	if udpAttempts <= 0 {
+		udpAttempts = defaultUDPAttempts
+	}
+	tasks := estimate.Hosts * estimate.PortsPerHost
+	tcpProbes, udpProbes := tasks*tcpAttempts, 0
+	switch config.ScanType {
+	case ScanTypeUDP:
+		tcpProbes, udpProbes = 0, tasks*udpAttempts
+	case ScanTypeBoth:
+		udpProbes = tasks * udpAttempts
+	}
+	estimate.TotalProbes = tcpProbes + udpProbes
+
+	estimate.SampleLatency, estimate.SampleSize = sampleLatency(ctx, config, hosts[0].IP, ports)
+	perProbe := estimate.SampleLatency
+	if estimate.SampleSize == 0 {
+		perProbe = config.Timeout
+		estimate.Assumptions = append(estimate.Assumptions, "采样失败，按每个端口耗尽超时时间计算(最坏情况)")
+	} else {
+		estimate.Assumptions = append(estimate.Assumptions,
+			fmt.Sprintf("采样 %d 个端口，平均探测耗时 %v", estimate.SampleSize, estimate.SampleLatency.Round(time.Millisecond)))
+	}
+
+	// 同一端口的多次探测在一个线程中依次进行，按端口任务的平均耗时计算轮数
+	work := time.Duration(tcpProbes)*perProbe + time.Duration(udpProbes)*config.Timeout
+	tasks *= int(config.unitsPerPort())
+	rounds := (tasks + threads - 1) / threads
+	estimate.Duration = time.Duration(rounds) * (work / time.Duration(tasks))
+
+	estimate.Assumptions = append(estimate.Assumptions,
+		fmt.Sprintf("%d 个探测任务(共 %d 次探测)按 %d 个连接线程并发，共约 %d 轮", tasks, estimate.TotalProbes, threads, rounds))
+	if tcpProbes > 0 && tcpAttempts > 1 {
+		estimate.Assumptions = append(estimate.Assumptions,
+			fmt.Sprintf("TCP端口按未开放计算，每个端口探测 %d 次(ProbeAttempts)", tcpAttempts))
+	}
+	if udpProbes > 0 {
+		estimate.Assumptions = append(estimate.Assumptions,
+			fmt.Sprintf("UDP端口按无响应计算，每个端口发送 %d 次(UDPAttempts)，每次等待超时时间", udpAttempts))
+	}
+	estimate.Assumptions = append(estimate.Assumptions,
+		"未计入开放端口指纹识别的额外耗时，开放端口较多时实际耗时会更长")
+	return estimate, nil
+}
+
+// sampleLatency 按扫描的拨号方式对少量端口做连接测试，返回平均耗时和成功采样数
+func sampleLatency(ctx context.Context, config ScanConfig, host string, ports []int) (time.Duration, int) {
+	var total time.Duration
+	count := 0
+	for _, port := range ports {
+		if count >= estimateSampleSize {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return 0, 0
+		default:
+		}
+
+		start := time.Now()
+		conn, err := dialTCP(ctx, config.connectDialer(), config.sources, net.JoinHostPort(host, strconv.Itoa(port)), config.connectTimeout())
+		if err == nil {
+			conn.Close()
+		}
+		total += time.Since(start)
+		count++
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return total / time.Duration(count), count
+}
@@ -0,0 +1,216 @@
+package portsscanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// 录制文件写缓冲的刷新间隔，进程崩溃时最多丢失这段时间内的事件
+	eventRecordFlushInterval = time.Second
+	// 回放时相邻事件的最长等待，录制中的长时间空闲不必原样等待
+	eventReplayMaxGap = 2 * time.Second
+)
+
+// recordedEvent 录制文件中的一行
+type recordedEvent struct {
+	Time    time.Time       `json:"time"`
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload"`
+	Context *EventContext   `json:"context,omitempty"` // 扫描进行中发送的事件附带的标识
+}
+
+// eventRecorder 将发送给前端的事件追加写入JSONL文件。
+// 事件在发送线程中编码后写入内存缓冲，由后台协程定期刷新到磁盘，不在扫描路径上等待IO
+type eventRecorder struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	w     *bufio.Writer
+	count int64
+	err   error // 第一次写入失败的错误，之后的事件不再写入
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newEventRecorder(path string) (*eventRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event recording %s: %w", path, err)
+	}
+	r := &eventRecorder{
+		path: path,
+		file: f,
+		w:    bufio.NewWriterSize(f, 64*1024),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go r.flushLoop()
+	return r, nil
+}
+
+func (r *eventRecorder) flushLoop() {
+	defer close(r.done)
+	ticker := time.NewTicker(eventRecordFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.mu.Lock()
+			if r.err == nil {
+				r.err = r.w.Flush()
+			}
+			r.mu.Unlock()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// record 写入一条事件，无法编码的负载记录为null
+func (r *eventRecorder) record(name string, data interface{}, ctx *EventContext) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload = []byte("null")
+	}
+	line, err := json.Marshal(recordedEvent{Time: time.Now(), Name: name, Payload: payload, Context: ctx})
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if _, r.err = r.w.Write(line); r.err == nil {
+		r.count++
+	}
+}
+
+// close 停止刷新协程，写出剩余缓冲并关闭文件
+func (r *eventRecorder) close() (int64, error) {
+	close(r.stop)
+	<-r.done
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	if err := r.file.Close(); r.err == nil {
+		r.err = err
+	}
+	return r.count, r.err
+}
+
+// StartEventRecording 开始将发送给前端的每个事件(名称、负载和时间)写入JSONL文件，
+// 用于复现问题或配合ReplayEvents开发界面。只记录按当前详细程度实际发送的事件
+func (a *App) StartEventRecording(path string) error {
+	if path == "" {
+		return fmt.Errorf("recording path is required")
+	}
+	if a.recorder.Load() != nil {
+		return fmt.Errorf("event recording is already in progress")
+	}
+	r, err := newEventRecorder(path)
+	if err != nil {
+		return err
+	}
+	if !a.recorder.CompareAndSwap(nil, r) {
+		r.close()
+		return fmt.Errorf("event recording is already in progress")
+	}
+	fmt.Printf("开始录制事件到 %s\n", path)
+	return nil
+}
+
+// StopEventRecording 停止录制并关闭文件
+func (a *App) StopEventRecording() error {
+	r := a.recorder.Swap(nil)
+	if r == nil {
+		return fmt.Errorf("no event recording in progress")
+	}
+	count, err := r.close()
+	if err != nil {
+		return fmt.Errorf("failed to write event recording %s: %w", r.path, err)
+	}
+	fmt.Printf("事件录制结束，%d 个事件已写入 %s\n", count, r.path)
+	return nil
+}
+
+// loadRecordedEvents 读取录制文件，跳过空行
+func loadRecordedEvents(path string) ([]recordedEvent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var events []recordedEvent
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var ev recordedEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, i+1, err)
+		}
+		if ev.Name == "" {
+			return nil, fmt.Errorf("%s line %d: missing event name", path, i+1)
+		}
+		events = append(events, ev)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%s contains no events", path)
+	}
+	return events, nil
+}
+
+// ReplayEvents 按录制时的先后和间隔(单次最多等待2秒)在后台重新发送录制的事件，
+// 不经过详细程度过滤也不会被正在进行的录制再次记录，全部发送后发送 replay-complete
+func (a *App) ReplayEvents(path string) error {
+	if a == nil || a.ctx == nil {
+		return fmt.Errorf("app context is not initialized")
+	}
+	events, err := loadRecordedEvents(path)
+	if err != nil {
+		return err
+	}
+	if !atomic.CompareAndSwapInt32(&a.replaying, 0, 1) {
+		return fmt.Errorf("an event replay is already in progress")
+	}
+	fmt.Printf("开始回放 %s 中的 %d 个事件\n", path, len(events))
+
+	go func() {
+		defer atomic.StoreInt32(&a.replaying, 0)
+		for i, ev := range events {
+			if i > 0 {
+				gap := ev.Time.Sub(events[i-1].Time)
+				if gap > eventReplayMaxGap {
+					gap = eventReplayMaxGap
+				}
+				if gap > 0 {
+					time.Sleep(gap)
+				}
+			}
+			if ev.Context != nil {
+				runtime.EventsEmit(a.ctx, ev.Name, ev.Payload, *ev.Context)
+			} else {
+				runtime.EventsEmit(a.ctx, ev.Name, ev.Payload)
+			}
+		}
+		runtime.EventsEmit(a.ctx, "replay-complete", map[string]interface{}{
+			"path":   path,
+			"events": len(events),
+		})
+	}()
+	return nil
+}
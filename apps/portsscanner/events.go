@@ -0,0 +1,113 @@
+package portsscanner
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// 进度事件的最短发送间隔，即每秒最多约10次
+	progressEmitInterval = 100 * time.Millisecond
+	// 单个间隔内超过该数量的port-found改为批量发送ports-found-batch
+	portFoundBurstLimit = 20
+)
+
+// eventThrottle 合并高频扫描事件，避免大规模扫描时淹没前端的事件处理
+type eventThrottle struct {
+	mu   sync.Mutex
+	emit EventFunc
+
+	pendingProgress interface{}
+	lastProgress    time.Time
+
+	windowFound int        // 当前间隔内已直接发送的port-found数量
+	batch       []PortInfo // 等待批量发送的结果
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// newEventThrottle 创建事件节流器，disabled为true时所有事件直接发送
+func newEventThrottle(emit EventFunc, disabled bool) *eventThrottle {
+	t := &eventThrottle{emit: emit}
+	if disabled {
+		return t
+	}
+	t.stop = make(chan struct{})
+	go t.loop()
+	return t
+}
+
+func (t *eventThrottle) loop() {
+	ticker := time.NewTicker(progressEmitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.flush()
+		}
+	}
+}
+
+// progress 发送进度事件，间隔内的多次更新只保留最新一次
+func (t *eventThrottle) progress(data interface{}) {
+	if t.stop == nil {
+		t.emit("scan-progress", data)
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if now := time.Now(); now.Sub(t.lastProgress) >= progressEmitInterval {
+		t.lastProgress = now
+		t.pendingProgress = nil
+		t.emit("scan-progress", data)
+		return
+	}
+	t.pendingProgress = data
+}
+
+// portFound 结果较少时逐条发送port-found，短时间内大量出现时合并为ports-found-batch
+func (t *eventThrottle) portFound(info PortInfo) {
+	if t.stop == nil {
+		t.emit("port-found", info)
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.batch) == 0 && t.windowFound < portFoundBurstLimit {
+		t.windowFound++
+		t.emit("port-found", info)
+		return
+	}
+	t.batch = append(t.batch, info)
+}
+
+// flush 发送积压的批量结果和最新进度
+func (t *eventThrottle) flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.windowFound = 0
+	if len(t.batch) > 0 {
+		t.emit("ports-found-batch", t.batch)
+		t.batch = nil
+	}
+	if t.pendingProgress != nil {
+		t.lastProgress = time.Now()
+		t.emit("scan-progress", t.pendingProgress)
+		t.pendingProgress = nil
+	}
+}
+
+// close 停止定时发送并清空积压事件，需在发送扫描结束事件之前调用以保证顺序
+func (t *eventThrottle) close() {
+	if t.stop == nil {
+		return
+	}
+	t.closeOnce.Do(func() {
+		close(t.stop)
+		t.flush()
+	})
+}
@@ -0,0 +1,189 @@
+package portsscanner
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// 单项证据(横幅、HTTP响应头)默认保留的字节数
+	defaultEvidenceSize = 4096
+	// EvidenceSize 的上限
+	maxEvidenceSize = 64 << 10
+	// 单次扫描保存的证据总字节数上限，超出后不再保存新的证据
+	maxScanEvidenceBytes = 16 << 20
+)
+
+// Evidence 证明服务识别结果的原始数据：服务端发送的横幅、TLS证书和HTTP响应头，
+// 只记录服务端返回的内容，不包含任何凭据
+type Evidence struct {
+	Host             string    `json:"host"`
+	Port             int       `json:"port"`
+	Protocol         string    `json:"protocol"`
+	Service          string    `json:"service"`
+	ProbeName        string    `json:"probe_name,omitempty"`
+	Banner           []byte    `json:"banner,omitempty"` // JSON中为base64
+	CertificatePEM   string    `json:"certificate_pem,omitempty"`
+	HTTPResponseHead string    `json:"http_response_head,omitempty"` // 状态行和响应头
+	Truncated        bool      `json:"truncated,omitempty"`          // 横幅或响应头超过EvidenceSize被截断
+	CapturedAt       time.Time `json:"captured_at"`
+}
+
+// size 证据占用的字节数，用于限制单次扫描的总量
+func (e *Evidence) size() int {
+	return len(e.Banner) + len(e.CertificatePEM) + len(e.HTTPResponseHead)
+}
+
+// evidenceLimit 返回单项证据实际保留的字节数
+func evidenceLimit(size int) int {
+	if size <= 0 {
+		return defaultEvidenceSize
+	}
+	if size > maxEvidenceSize {
+		return maxEvidenceSize
+	}
+	return size
+}
+
+// evidenceFor 返回端口上正在收集的证据，未启用CaptureEvidence时返回nil
+func (s *portScanner) evidenceFor(portInfo *PortInfo) *Evidence {
+	if !s.config.CaptureEvidence {
+		return nil
+	}
+	if portInfo.evidence == nil {
+		portInfo.evidence = &Evidence{CapturedAt: time.Now()}
+	}
+	return portInfo.evidence
+}
+
+// captureBanner 保存服务端发送的原始横幅
+func (s *portScanner) captureBanner(portInfo *PortInfo, banner []byte) {
+	e := s.evidenceFor(portInfo)
+	if e == nil || len(banner) == 0 {
+		return
+	}
+	if limit := evidenceLimit(s.config.EvidenceSize); len(banner) > limit {
+		banner = banner[:limit]
+		e.Truncated = true
+	}
+	e.Banner = append([]byte(nil), banner...)
+}
+
+// captureHTTP 保存HTTP探测得到的响应头和服务端证书
+func (s *portScanner) captureHTTP(portInfo *PortInfo, result *httpProbeResult) {
+	e := s.evidenceFor(portInfo)
+	if e == nil {
+		return
+	}
+	if head := result.Head; head != "" {
+		if limit := evidenceLimit(s.config.EvidenceSize); len(head) > limit {
+			head = head[:limit]
+			e.Truncated = true
+		}
+		e.HTTPResponseHead = head
+	}
+	s.captureCertificate(portInfo, result.Certificate)
+}
+
+// captureCertificate 以PEM格式保存服务端证书，cert为nil时不做修改
+func (s *portScanner) captureCertificate(portInfo *PortInfo, cert *x509.Certificate) {
+	e := s.evidenceFor(portInfo)
+	if e == nil || cert == nil {
+		return
+	}
+	e.CertificatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+// responseHead 还原HTTP响应的状态行和响应头
+func responseHead(resp *http.Response) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s\r\n", resp.Proto, resp.Status)
+	resp.Header.Write(&buf)
+	return buf.String()
+}
+
+// addEvidence 保存结果附带的证据，超过单次扫描的总量上限时丢弃。调用方需持有mu
+func (r *scanRecord) addEvidence(info PortInfo) {
+	e := info.evidence
+	if e == nil {
+		return
+	}
+	key := resultKey{info.Host, info.Port, info.Protocol}
+	if r.evidence == nil {
+		r.evidence = make(map[resultKey]*Evidence)
+	}
+	if old, ok := r.evidence[key]; ok {
+		r.evidenceBytes -= old.size()
+	}
+	if r.evidenceBytes+e.size() > maxScanEvidenceBytes {
+		if !r.evidenceFull {
+			r.evidenceFull = true
+			r.log.add(logWarn, "证据总量超过 %d 字节，之后的端口不再保存证据", maxScanEvidenceBytes)
+		}
+		delete(r.evidence, key)
+		return
+	}
+	stored := *e
+	stored.Host, stored.Port, stored.Protocol = info.Host, info.Port, info.Protocol
+	stored.Service, stored.ProbeName = info.Service, info.ProbeName
+	r.evidence[key] = &stored
+	r.evidenceBytes += stored.size()
+}
+
+// evidenceSnapshot 按主机和端口排序导出全部证据，用于持久化
+func (r *scanRecord) evidenceSnapshot() []Evidence {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.evidence) == 0 {
+		return nil
+	}
+	list := make([]Evidence, 0, len(r.evidence))
+	for _, e := range r.evidence {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if c := compareHosts(list[i].Host, list[j].Host); c != 0 {
+			return c < 0
+		}
+		if list[i].Port != list[j].Port {
+			return list[i].Port < list[j].Port
+		}
+		return list[i].Protocol < list[j].Protocol
+	})
+	return list
+}
+
+// restoreEvidence 加载存储的证据
+func (r *scanRecord) restoreEvidence(list []Evidence) {
+	r.evidence = make(map[resultKey]*Evidence, len(list))
+	for i := range list {
+		e := list[i]
+		r.evidence[resultKey{e.Host, e.Port, e.Protocol}] = &e
+		r.evidenceBytes += e.size()
+	}
+}
+
+// GetEvidence 返回扫描中某个开放端口保存的原始证据，同一端口TCP和UDP都有时返回TCP的。
+// 需要在扫描时启用CaptureEvidence
+func (a *App) GetEvidence(scanID, host string, port int) (Evidence, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return Evidence{}, err
+	}
+	if ip, ok := canonicalIP(host); ok {
+		host = ip
+	}
+	record.mu.Lock()
+	defer record.mu.Unlock()
+	for _, protocol := range []string{"tcp", "udp"} {
+		if e, ok := record.evidence[resultKey{host, port, protocol}]; ok {
+			return *e, nil
+		}
+	}
+	return Evidence{}, fmt.Errorf("no evidence recorded for %s:%d in scan %q", host, port, scanID)
+}
@@ -0,0 +1,130 @@
+package portsscanner
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// 默认最多展开的主机数量(相当于一个/16网段)，防止误把/28写成/8时一次展开上千万台主机
+const defaultMaxHosts = 65536
+
+// expandTargets 将目标中的CIDR网段(192.168.1.0/24)和IP范围
+// (192.168.1.1-50 或 192.168.1.1-192.168.1.50)展开为单个IP，
+// 展开前先计算总主机数，超过maxHosts时直接拒绝，不会分配任何内存
+func expandTargets(inputs []string, maxHosts int) ([]string, error) {
+	type span struct {
+		input string
+		first netip.Addr
+		count uint64
+	}
+
+	spans := make([]span, 0, len(inputs))
+	var total uint64
+	for _, input := range inputs {
+		first, count, ok, err := parseTargetSpan(input)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			first, count = netip.Addr{}, 1
+		}
+		spans = append(spans, span{input: input, first: first, count: count})
+		if total += count; total < count || total > uint64(maxHosts) {
+			return nil, fmt.Errorf("target list expands to %s hosts, which exceeds the MaxHosts cap of %d; raise MaxHosts to scan this many hosts",
+				formatHostCount(inputs), maxHosts)
+		}
+	}
+
+	targets := make([]string, 0, total)
+	for _, s := range spans {
+		if !s.first.IsValid() {
+			targets = append(targets, s.input)
+			continue
+		}
+		addr := s.first
+		for i := uint64(0); i < s.count; i++ {
+			targets = append(targets, addr.String())
+			addr = addr.Next()
+		}
+	}
+	return targets, nil
+}
+
+// parseTargetSpan 识别CIDR或IP范围，返回起始地址和主机数量；
+// 普通IP或主机名返回ok=false，交给后续解析处理
+func parseTargetSpan(input string) (netip.Addr, uint64, bool, error) {
+	if strings.Contains(input, "/") {
+		prefix, err := netip.ParsePrefix(input)
+		if err != nil {
+			return netip.Addr{}, 0, false, fmt.Errorf("invalid CIDR %q: %w", input, err)
+		}
+		prefix = prefix.Masked()
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits >= 64 {
+			return prefix.Addr(), ^uint64(0), true, nil
+		}
+		return prefix.Addr().Unmap(), uint64(1) << hostBits, true, nil
+	}
+
+	startStr, endStr, found := strings.Cut(input, "-")
+	if !found || strings.Contains(input, "%") {
+		// 带区域标识的IPv6地址(网卡名可能包含连字符，如 fe80::1%br-lan)不作为范围解析
+		return netip.Addr{}, 0, false, nil
+	}
+	start, err := netip.ParseAddr(startStr)
+	if err != nil {
+		// 带连字符的主机名(如 my-host.example.com)
+		return netip.Addr{}, 0, false, nil
+	}
+	start = start.Unmap()
+
+	var end netip.Addr
+	if octet, err := strconv.Atoi(endStr); err == nil && start.Is4() {
+		if octet < 0 || octet > 255 {
+			return netip.Addr{}, 0, false, fmt.Errorf("invalid IP range %q: last octet out of range", input)
+		}
+		b := start.As4()
+		b[3] = byte(octet)
+		end = netip.AddrFrom4(b)
+	} else if end, err = netip.ParseAddr(endStr); err != nil {
+		return netip.Addr{}, 0, false, fmt.Errorf("invalid IP range %q: %w", input, err)
+	}
+	end = end.Unmap()
+
+	if start.BitLen() != end.BitLen() {
+		return netip.Addr{}, 0, false, fmt.Errorf("invalid IP range %q: mixed address families", input)
+	}
+	if end.Less(start) {
+		return netip.Addr{}, 0, false, fmt.Errorf("invalid IP range %q: end address is before start", input)
+	}
+	return start, addrDistance(start, end) + 1, true, nil
+}
+
+// addrDistance 计算同族两个地址之间的距离，超过uint64范围时饱和
+func addrDistance(start, end netip.Addr) uint64 {
+	s, e := start.As16(), end.As16()
+	diff := new(big.Int).Sub(new(big.Int).SetBytes(e[:]), new(big.Int).SetBytes(s[:]))
+	if !diff.IsUint64() || diff.Uint64() == ^uint64(0) {
+		return ^uint64(0) - 1
+	}
+	return diff.Uint64()
+}
+
+// formatHostCount 计算目标列表的展开总数用于错误提示，超出uint64时给出近似描述
+func formatHostCount(inputs []string) string {
+	var total uint64
+	for _, input := range inputs {
+		_, count, ok, _ := parseTargetSpan(input)
+		if !ok {
+			count = 1
+		}
+		if count == ^uint64(0) || total+count < total {
+			return "more than " + strconv.FormatUint(^uint64(0)-1, 10)
+		}
+		total += count
+	}
+	return strconv.FormatUint(total, 10)
+}
@@ -0,0 +1,276 @@
+package portsscanner
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportDir 返回导出文件所在目录(~/GlideWay/exports)，不存在时自动创建
+func exportDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	dir := filepath.Join(home, "GlideWay", "exports")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+	return dir, nil
+}
+
+// ExportResults 将指定扫描的结果导出为 csv、json 或 text(纯文本摘要) 文件，返回文件路径。
+// fields 为PortInfo的JSON字段名(见ListExportFields)，只导出这些字段，为空时导出全部字段；text格式不支持fields
+func (a *App) ExportResults(scanID string, format string, fields []string) (string, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return "", err
+	}
+	fields, err = parseExportFields(fields)
+	if err != nil {
+		return "", err
+	}
+	summary, _, results := record.snapshot()
+	metadata := record.scanMetadata()
+
+	format = strings.ToLower(format)
+	dir, err := exportDir()
+	if err != nil {
+		return "", err
+	}
+	ext := format
+	if format == "text" {
+		ext = "txt"
+	}
+	path := filepath.Join(dir, exportFileName(summary, ext))
+	if err := writeResults(path, format, summary, &metadata, results, fields); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// exportDocument JSON导出文件结构，附带扫描元数据
+type exportDocument struct {
+	Scan     ScanSummary   `json:"scan"`
+	Metadata *ScanMetadata `json:"metadata,omitempty"` // 运行环境和实际配置，导入时可以没有
+	Results  []PortInfo    `json:"results"`
+	HostRisk []HostRisk    `json:"host_risk,omitempty"` // 导出时按当前风险模型计算，导入时忽略
+}
+
+// exportFileName 生成导出文件名，有项目名称时作为前缀
+func exportFileName(summary ScanSummary, ext string) string {
+	name := fmt.Sprintf("scan-%s.%s", summary.ID, ext)
+	if project := sanitizeFileName(summary.Project); project != "" {
+		name = project + "-" + name
+	}
+	return name
+}
+
+// sanitizeFileName 替换文件名中不安全的字符
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r == ':' || r == '*' || r == '?' || r == '"' || r == '<' || r == '>' || r == '|':
+			return '_'
+		case r < 0x20:
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
+
+// fieldsDocument 指定了导出字段时的JSON导出文件结构，每条结果只包含选择的字段
+type fieldsDocument struct {
+	Scan     ScanSummary                  `json:"scan"`
+	Metadata *ScanMetadata                `json:"metadata,omitempty"`
+	Results  []map[string]json.RawMessage `json:"results"`
+}
+
+// encodeResults 按格式编码扫描结果，JSON导出附带扫描元数据。fields为nil时导出全部字段
+func encodeResults(format string, summary ScanSummary, metadata *ScanMetadata, results []PortInfo, fields []string) ([]byte, error) {
+	switch format {
+	case "json":
+		if fields == nil {
+			return json.MarshalIndent(exportDocument{Scan: summary, Metadata: metadata, Results: results, HostRisk: hostRisks(results, time.Now())}, "", "  ")
+		}
+		rows, err := selectResultFields(results, fields)
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(fieldsDocument{Scan: summary, Metadata: metadata, Results: rows}, "", "  ")
+	case "csv":
+		var sb strings.Builder
+		write := func() error { return writeCSV(&sb, summary, results) }
+		if fields != nil {
+			write = func() error { return writeFieldsCSV(&sb, results, fields) }
+		}
+		if err := write(); err != nil {
+			return nil, err
+		}
+		return []byte(sb.String()), nil
+	case "text":
+		if fields != nil {
+			return nil, fmt.Errorf("export fields are not supported for text format")
+		}
+		var sb strings.Builder
+		if err := writeText(&sb, summary, results); err != nil {
+			return nil, err
+		}
+		return []byte(sb.String()), nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// writeResults 按格式写出扫描结果
+func writeResults(path string, format string, summary ScanSummary, metadata *ScanMetadata, results []PortInfo, fields []string) error {
+	data, err := encodeResults(format, summary, metadata, results, fields)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// 停止扫描后等待扫描协程收尾的最长时间
+const stopExportTimeout = 30 * time.Second
+
+// StopScanAndExport 停止当前扫描，等待扫描完全结束后将已发现的结果写入path(csv、json或text)。
+// 结果在扫描记录最终保存之后读取，写入通过临时文件重命名完成，不会留下不完整的文件。fields 同 ExportResults
+func (a *App) StopScanAndExport(format, path string, fields []string) error {
+	if a == nil || a.ctx == nil {
+		return fmt.Errorf("app context is not initialized")
+	}
+	format = strings.ToLower(format)
+	if format != "json" && format != "csv" && format != "text" {
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+	fields, err := parseExportFields(fields)
+	if err != nil {
+		return err
+	}
+
+	scanMutex.Lock()
+	scan := currentScan
+	scanMutex.Unlock()
+	if scan == nil {
+		return fmt.Errorf("no scan is running")
+	}
+	if err := a.StopScan(); err != nil {
+		return err
+	}
+	select {
+	case <-scan.done:
+	case <-time.After(stopExportTimeout):
+		return fmt.Errorf("scan did not stop within %v", stopExportTimeout)
+	}
+
+	summary, _, results := scan.record.snapshot()
+	metadata := scan.record.scanMetadata()
+	data, err := encodeResults(format, summary, &metadata, results, fields)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("扫描已停止，%d 个结果已导出到 %s\n", len(results), path)
+	a.emitEvent("scan-exported", map[string]interface{}{
+		"scan_id": summary.ID,
+		"path":    path,
+		"format":  format,
+		"results": len(results),
+		"status":  summary.Status,
+	})
+	return nil
+}
+
+var csvHeader = []string{"host", "port", "protocol", "service", "product_name", "version", "info", "tls", "http_title", "http_server", "cpe", "project", "scanner_host", "egress_ips", "scan_id", "correlation_id", "config_hash"}
+
+func writeCSV(sb *strings.Builder, summary ScanSummary, results []PortInfo) error {
+	egress := strings.Join(summary.EgressIPs, " ")
+	w := csv.NewWriter(sb)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		row := []string{
+			r.Host, strconv.Itoa(r.Port), r.Protocol, r.Service, r.ProductName, r.Version,
+			r.Info, strconv.FormatBool(r.TLS), r.HTTPTitle, r.HTTPServer, r.CPE,
+			summary.Project, summary.ScannerHost, egress, summary.ID, summary.CorrelationID, summary.ConfigHash,
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// OpenFile 使用系统默认程序打开导出文件，只允许打开导出目录和本次运行中自动导出写入过的OutputDir子目录内的文件
+func (a *App) OpenFile(path string) error {
+	dir, err := exportDir()
+	if err != nil {
+		return err
+	}
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	abs, err = filepath.EvalSymlinks(abs)
+	if err != nil {
+		return fmt.Errorf("file %s does not exist", path)
+	}
+
+	if !withinDir(dir, abs) && !withinAutoExportDir(abs) {
+		return fmt.Errorf("file %s is outside the export directory %s", path, dir)
+	}
+	info, err := os.Stat(abs)
+	if err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+
+	var cmd *exec.Cmd
+	switch goruntime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", abs)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", abs)
+	default:
+		cmd = exec.Command("xdg-open", abs)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", abs, err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// withinDir 判断已解析符号链接的路径是否位于dir内
+func withinDir(dir, abs string) bool {
+	rel, err := filepath.Rel(dir, abs)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// withinAutoExportDir 判断路径是否位于自动导出写入过的OutputDir子目录内
+func withinAutoExportDir(abs string) bool {
+	for _, dir := range autoExportDirs.list() {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil && withinDir(resolved, abs) {
+			return true
+		}
+	}
+	return false
+}
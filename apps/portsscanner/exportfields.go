@@ -0,0 +1,107 @@
+package portsscanner
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// portInfoFields 按结构体顺序列出PortInfo可导出的字段，名称与JSON字段名相同
+func portInfoFields() []string {
+	t := reflect.TypeOf(PortInfo{})
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// parseExportFields 校验导出字段名(不区分大小写)并去重，为空时返回nil表示导出全部字段
+func parseExportFields(fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	known := portInfoFields()
+	var selected []string
+	for _, f := range fields {
+		name := strings.ToLower(strings.TrimSpace(f))
+		if name == "" {
+			continue
+		}
+		if !containsString(known, name) {
+			return nil, fmt.Errorf("unknown export field %q, valid fields: %s", f, strings.Join(known, ", "))
+		}
+		if !containsString(selected, name) {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
+
+// selectResultFields 只保留结果中指定的字段，值与完整JSON导出中的相同
+func selectResultFields(results []PortInfo, fields []string) ([]map[string]json.RawMessage, error) {
+	rows := make([]map[string]json.RawMessage, 0, len(results))
+	for _, r := range results {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+		row := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				row[f] = v
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// csvCell 将JSON值转换为CSV单元格：字符串去掉引号，null和省略的字段为空，数组和对象保留JSON
+func csvCell(v json.RawMessage) string {
+	if len(v) == 0 || string(v) == "null" {
+		return ""
+	}
+	var s string
+	if v[0] == '"' && json.Unmarshal(v, &s) == nil {
+		return s
+	}
+	return string(v)
+}
+
+// writeFieldsCSV 只写出指定字段的CSV，列顺序与fields一致
+func writeFieldsCSV(sb *strings.Builder, results []PortInfo, fields []string) error {
+	rows, err := selectResultFields(results, fields)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(sb)
+	if err := w.Write(fields); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		cells := make([]string, len(fields))
+		for i, f := range fields {
+			cells[i] = csvCell(row[f])
+		}
+		if err := w.Write(cells); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// ListExportFields 返回导出时可选择的字段名
+func (a *App) ListExportFields() []string {
+	return portInfoFields()
+}
@@ -0,0 +1,35 @@
+package portsscanner
+
+import (
+	"context"
+	"net"
+	"strconv"
+)
+
+// PortInfo.FastOpen 的取值
+const (
+	FastOpenAccepted    = "accepted"    // 服务端确认了随SYN发送的数据
+	FastOpenRejected    = "rejected"    // 重连时服务端仍只确认SYN，数据在握手完成后才被接收
+	FastOpenUnsupported = "unsupported" // 本机平台或内核不支持TFO客户端，未进行探测
+)
+
+// fastOpenAttempts 第一次连接向服务端请求Cookie，第二次携带Cookie在SYN中发送数据
+const fastOpenAttempts = 2
+
+// fastOpenPayload TFO探测随SYN发送的数据，空行对大多数文本协议没有副作用
+var fastOpenPayload = []byte("\r\n")
+
+// probeFastOpen 启用FastOpen时检测开放端口是否接受TCP Fast Open，结果记录在PortInfo.FastOpen。
+// 端口状态探测不使用TFO：有Cookie时connect不等待握手即返回，无法据此判断端口是否开放
+func (s *portScanner) probeFastOpen(ctx context.Context, h scanTarget, p int, portInfo *PortInfo) {
+	if !s.config.FastOpen || s.config.dialer != nil || s.config.proxies != nil {
+		return
+	}
+	address := net.JoinHostPort(h.IP, strconv.Itoa(p))
+	result, err := detectFastOpen(ctx, s.config, address)
+	if err != nil {
+		s.log(logDebug, "%s TFO探测失败: %v", address, err)
+		return
+	}
+	portInfo.FastOpen = result
+}
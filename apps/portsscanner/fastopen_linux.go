@@ -0,0 +1,126 @@
+//go:build linux
+
+package portsscanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	tcpiOptSynData = 0x20 // TCPI_OPT_SYN_DATA：随SYN发送的数据已被服务端确认
+	tcpSynSent     = 2    // TCP_SYN_SENT，服务端尚未应答SYN
+
+	// 等待握手完成时查询TCP_INFO的间隔
+	fastOpenPollInterval = 5 * time.Millisecond
+)
+
+// errFastOpenUnsupported 内核不支持TCP_FASTOPEN_CONNECT(Linux 4.11之前)
+var errFastOpenUnsupported = errors.New("TCP_FASTOPEN_CONNECT is not supported by the kernel")
+
+// fastOpenAvailable 检查内核是否启用了TFO客户端(net.ipv4.tcp_fastopen 的第0位)
+func fastOpenAvailable() error {
+	data, err := os.ReadFile("/proc/sys/net/ipv4/tcp_fastopen")
+	if err != nil {
+		return fmt.Errorf("failed to read net.ipv4.tcp_fastopen: %w", err)
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("invalid net.ipv4.tcp_fastopen value %q", strings.TrimSpace(string(data)))
+	}
+	if v&1 == 0 {
+		return errors.New("TCP Fast Open client is disabled (net.ipv4.tcp_fastopen)")
+	}
+	return nil
+}
+
+// detectFastOpen 依次尝试TFO连接，根据TCP_INFO判断随SYN发送的数据是否被确认。
+// 内核已缓存该服务端的Cookie时第一次连接即可确认
+func detectFastOpen(ctx context.Context, config ScanConfig, address string) (string, error) {
+	if fastOpenAvailable() != nil {
+		return FastOpenUnsupported, nil
+	}
+	for i := 0; i < fastOpenAttempts; i++ {
+		acked, err := fastOpenConnect(ctx, config, address)
+		if errors.Is(err, errFastOpenUnsupported) {
+			return FastOpenUnsupported, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if acked {
+			return FastOpenAccepted, nil
+		}
+	}
+	return FastOpenRejected, nil
+}
+
+// fastOpenConnect 以TCP_FASTOPEN_CONNECT建立连接并发送数据，握手完成后返回数据是否随SYN被确认
+func fastOpenConnect(ctx context.Context, config ScanConfig, address string) (bool, error) {
+	var sockErr error
+	control := func(network, address string, c syscall.RawConn) error {
+		if err := c.Control(func(fd uintptr) {
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_CONNECT, 1)
+		}); err != nil {
+			return err
+		}
+		if sockErr != nil {
+			return errFastOpenUnsupported
+		}
+		return nil
+	}
+	conn, err := config.sources.dial(ctx, config.Timeout, "tcp", address, control)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	applyClosePolicy(conn, config.AbruptClose)
+
+	// 有Cookie时connect立即返回，SYN随第一次写入一起发送
+	if _, err := conn.Write(fastOpenPayload); err != nil {
+		return false, err
+	}
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok {
+		return false, errFastOpenUnsupported
+	}
+	raw, err := tcp.SyscallConn()
+	if err != nil {
+		return false, err
+	}
+
+	deadline := time.Now().Add(config.Timeout)
+	for {
+		var info *unix.TCPInfo
+		var infoErr error
+		if err := raw.Control(func(fd uintptr) {
+			info, infoErr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+		}); err != nil {
+			return false, err
+		}
+		if infoErr != nil {
+			return false, infoErr
+		}
+		// 服务端应答后可能已立即关闭连接，只要离开SYN_SENT即可判断
+		if info.State != tcpSynSent {
+			return info.Options&tcpiOptSynData != 0, nil
+		}
+		if time.Now().After(deadline) {
+			return false, errors.New("TCP handshake did not complete")
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(fastOpenPollInterval):
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package portsscanner
+
+import (
+	"context"
+	"errors"
+)
+
+// fastOpenAvailable Windows和macOS只能通过ConnectEx/connectx使用TFO，net包不支持
+func fastOpenAvailable() error {
+	return errors.New("TCP Fast Open probing is only supported on Linux")
+}
+
+// detectFastOpen 不支持的平台直接报告unsupported，不发起连接
+func detectFastOpen(ctx context.Context, config ScanConfig, address string) (string, error) {
+	return FastOpenUnsupported, nil
+}
@@ -0,0 +1,335 @@
+package portsscanner
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FindingsSchemaVersion 发现导出格式的版本，字段含义变化或删除字段时递增，只新增属性时不变
+const FindingsSchemaVersion = "1"
+
+const (
+	// 导出文件遵循的OSCAL版本
+	findingsOSCALVersion = "1.1.2"
+	// GlideWay自定义属性的命名空间，OSCAL要求非标准属性带ns
+	findingsNamespace = "https://github.com/only9464/GlideWay/ns/findings"
+)
+
+// 发现类型，作为finding的target-id，GRC工具可以据此映射到自己的控制项
+const (
+	FindingTLSExpired          = "tls-certificate-expired"
+	FindingTLSExpiring         = "tls-certificate-expiring"
+	FindingLikelyHoneypot      = "likely-honeypot"
+	FindingUnidentifiedService = "unidentified-service"
+	FindingComplianceViolation = "compliance-violation"
+)
+
+// 导出文件结构：OSCAL assessment-results 的子集。
+//
+//   - 每台主机是 local-definitions 中的一个 inventory-item，props 记录 ipv4-address/ipv6-address 和 fqdn(扫描时的别名)
+//   - 每个开放端口是一个 observation，subjects 指向所属主机，
+//     端口、协议、服务、产品、版本、CPE 等记录在 GlideWay命名空间的 props 中，只包含非空值
+//   - 端口上的每个标记(证书过期、疑似蜜罐、未识别服务、违反最近一次合规检查)是一个 finding，
+//     target.target-id 为上面的 Finding* 常量，related-observations 指向对应端口
+//
+// 所有UUID由扫描ID和主机/端口确定性生成，同一扫描重复导出的结果相同
+type findingsDocument struct {
+	AssessmentResults oscalAssessmentResults `json:"assessment-results"`
+}
+
+type oscalAssessmentResults struct {
+	UUID     string        `json:"uuid"`
+	Metadata oscalMetadata `json:"metadata"`
+	ImportAP oscalImportAP `json:"import-ap"`
+	Results  []oscalResult `json:"results"`
+}
+
+type oscalMetadata struct {
+	Title        string      `json:"title"`
+	LastModified time.Time   `json:"last-modified"`
+	Version      string      `json:"version"`
+	OSCALVersion string      `json:"oscal-version"`
+	Props        []oscalProp `json:"props,omitempty"`
+}
+
+// oscalImportAP OSCAL要求引用评估计划，GlideWay没有单独的计划文档，指向文档内的空锚点
+type oscalImportAP struct {
+	Href string `json:"href"`
+}
+
+type oscalResult struct {
+	UUID             string                `json:"uuid"`
+	Title            string                `json:"title"`
+	Description      string                `json:"description"`
+	Start            time.Time             `json:"start"`
+	End              *time.Time            `json:"end,omitempty"`
+	Props            []oscalProp           `json:"props,omitempty"`
+	LocalDefinitions oscalLocalDefinitions `json:"local-definitions"`
+	ReviewedControls oscalReviewedControls `json:"reviewed-controls"`
+	Observations     []oscalObservation    `json:"observations,omitempty"`
+	Findings         []oscalFinding        `json:"findings,omitempty"`
+}
+
+type oscalLocalDefinitions struct {
+	InventoryItems []oscalInventoryItem `json:"inventory-items,omitempty"`
+}
+
+// oscalReviewedControls 端口扫描不针对特定控制项，按OSCAL要求声明为全部
+type oscalReviewedControls struct {
+	ControlSelections []oscalControlSelection `json:"control-selections"`
+}
+
+type oscalControlSelection struct {
+	IncludeAll struct{} `json:"include-all"`
+}
+
+type oscalInventoryItem struct {
+	UUID        string      `json:"uuid"`
+	Description string      `json:"description"`
+	Props       []oscalProp `json:"props"`
+}
+
+type oscalObservation struct {
+	UUID        string         `json:"uuid"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Methods     []string       `json:"methods"`
+	Types       []string       `json:"types"`
+	Props       []oscalProp    `json:"props"`
+	Subjects    []oscalSubject `json:"subjects"`
+	Collected   time.Time      `json:"collected"`
+}
+
+type oscalSubject struct {
+	SubjectUUID string `json:"subject-uuid"`
+	Type        string `json:"type"`
+}
+
+type oscalFinding struct {
+	UUID                string                    `json:"uuid"`
+	Title               string                    `json:"title"`
+	Description         string                    `json:"description"`
+	Target              oscalFindingTarget        `json:"target"`
+	RelatedObservations []oscalRelatedObservation `json:"related-observations"`
+}
+
+type oscalFindingTarget struct {
+	Type     string            `json:"type"`
+	TargetID string            `json:"target-id"`
+	Status   oscalTargetStatus `json:"status"`
+}
+
+type oscalTargetStatus struct {
+	State string `json:"state"`
+}
+
+type oscalRelatedObservation struct {
+	ObservationUUID string `json:"observation-uuid"`
+}
+
+type oscalProp struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	NS    string `json:"ns,omitempty"`
+}
+
+// portFlag 端口上的一个标记
+type portFlag struct {
+	kind        string
+	title       string
+	description string
+}
+
+// findingUUID 由字段生成确定性的UUID(RFC 4122 第5版格式)
+func findingUUID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// glidewayProps 生成GlideWay命名空间的属性，跳过空值
+func glidewayProps(pairs ...string) []oscalProp {
+	props := make([]oscalProp, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			props = append(props, oscalProp{Name: pairs[i], Value: pairs[i+1], NS: findingsNamespace})
+		}
+	}
+	return props
+}
+
+// portFlags 根据结果和合规检查违规列出端口上的标记
+func portFlags(r PortInfo, violations map[resultKey]ComplianceViolation, now time.Time) []portFlag {
+	var flags []portFlag
+	if !r.TLSExpiry.IsZero() {
+		days := int(r.TLSExpiry.Sub(now).Hours() / 24)
+		switch {
+		case r.TLSExpiry.Before(now):
+			flags = append(flags, portFlag{FindingTLSExpired, "TLS certificate expired",
+				"the certificate presented on this port expired on " + r.TLSExpiry.UTC().Format(time.RFC3339)})
+		case days < reportTLSWarnDays:
+			flags = append(flags, portFlag{FindingTLSExpiring, "TLS certificate expiring soon",
+				fmt.Sprintf("the certificate presented on this port expires in %d days", days)})
+		}
+	}
+	if r.LikelyHoneypot {
+		flags = append(flags, portFlag{FindingLikelyHoneypot, "Host is a likely honeypot",
+			"the host answered on an implausible number of ports or with identical banners; its results may not reflect real services"})
+	}
+	if r.Unidentified {
+		flags = append(flags, portFlag{FindingUnidentifiedService, "Unidentified service",
+			"the port is open but no service fingerprint matched"})
+	}
+	if v, ok := violations[resultKey{r.Host, r.Port, r.Protocol}]; ok {
+		flags = append(flags, portFlag{FindingComplianceViolation, "Port not allowed by policy",
+			fmt.Sprintf("port %d/%s is open but not allowed for host group %q", v.Port, v.Protocol, v.Group)})
+	}
+	return flags
+}
+
+// buildFindings 将扫描结果映射为导出文档，主机和端口按地址和端口号排序
+func buildFindings(summary ScanSummary, results []PortInfo, compliance *ComplianceReport, now time.Time) findingsDocument {
+	violations := make(map[resultKey]ComplianceViolation)
+	if compliance != nil {
+		for _, v := range compliance.Violations {
+			violations[resultKey{v.Host, v.Port, v.Protocol}] = v
+		}
+	}
+
+	sorted := append([]PortInfo(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if c := compareHosts(sorted[i].Host, sorted[j].Host); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Port < sorted[j].Port
+	})
+
+	result := oscalResult{
+		UUID:        findingUUID(summary.ID, "result"),
+		Title:       "GlideWay port scan " + summary.ID,
+		Description: fmt.Sprintf("Open ports discovered on %s (status %s)", summary.Target, summary.Status),
+		Start:       summary.StartedAt,
+		Props: glidewayProps(
+			"scan-id", summary.ID,
+			"correlation-id", summary.CorrelationID,
+			"target", summary.Target,
+			"project", summary.Project,
+			"status", summary.Status,
+			"config-hash", summary.ConfigHash,
+		),
+		ReviewedControls: oscalReviewedControls{ControlSelections: []oscalControlSelection{{}}},
+	}
+	if !summary.FinishedAt.IsZero() {
+		end := summary.FinishedAt
+		result.End = &end
+	}
+
+	hosts := make(map[string]string)
+	for _, r := range sorted {
+		hostUUID, ok := hosts[r.Host]
+		if !ok {
+			hostUUID = findingUUID(summary.ID, "host", r.Host)
+			hosts[r.Host] = hostUUID
+			addrProp := "ipv4-address"
+			if strings.Contains(r.Host, ":") {
+				addrProp = "ipv6-address"
+			}
+			item := oscalInventoryItem{UUID: hostUUID, Description: r.Host, Props: []oscalProp{{Name: addrProp, Value: r.Host}}}
+			for _, alias := range r.Aliases {
+				item.Props = append(item.Props, oscalProp{Name: "fqdn", Value: alias})
+			}
+			result.LocalDefinitions.InventoryItems = append(result.LocalDefinitions.InventoryItems, item)
+		}
+
+		var tlsExpiry string
+		if !r.TLSExpiry.IsZero() {
+			tlsExpiry = r.TLSExpiry.UTC().Format(time.RFC3339)
+		}
+		port := strconv.Itoa(r.Port)
+		obsUUID := findingUUID(summary.ID, "port", r.Host, port, r.Protocol)
+		collected := summary.FinishedAt
+		if collected.IsZero() {
+			collected = now
+		}
+		result.Observations = append(result.Observations, oscalObservation{
+			UUID:        obsUUID,
+			Title:       fmt.Sprintf("Open port %s:%d/%s", r.Host, r.Port, r.Protocol),
+			Description: strings.TrimSpace(fmt.Sprintf("%s %s %s", r.Service, r.ProductName, r.Version)),
+			Methods:     []string{"TEST"},
+			Types:       []string{"discovery"},
+			Props: glidewayProps(
+				"port", port,
+				"protocol", r.Protocol,
+				"service", r.Service,
+				"product", r.ProductName,
+				"version", r.Version,
+				"cpe", r.CPE,
+				"state", r.State,
+				"tls", strconv.FormatBool(r.TLS),
+				"tls-expiry", tlsExpiry,
+				"http-title", r.HTTPTitle,
+				"http-server", r.HTTPServer,
+			),
+			Subjects:  []oscalSubject{{SubjectUUID: hostUUID, Type: "inventory-item"}},
+			Collected: collected,
+		})
+
+		for _, flag := range portFlags(r, violations, now) {
+			result.Findings = append(result.Findings, oscalFinding{
+				UUID:        findingUUID(summary.ID, "finding", flag.kind, r.Host, port, r.Protocol),
+				Title:       fmt.Sprintf("%s: %s:%d/%s", flag.title, r.Host, r.Port, r.Protocol),
+				Description: flag.description,
+				Target: oscalFindingTarget{
+					Type:     "objective-id",
+					TargetID: flag.kind,
+					Status:   oscalTargetStatus{State: "not-satisfied"},
+				},
+				RelatedObservations: []oscalRelatedObservation{{ObservationUUID: obsUUID}},
+			})
+		}
+	}
+
+	return findingsDocument{AssessmentResults: oscalAssessmentResults{
+		UUID: findingUUID(summary.ID, "assessment-results"),
+		Metadata: oscalMetadata{
+			Title:        "GlideWay scan findings " + summary.ID,
+			LastModified: now,
+			Version:      Version,
+			OSCALVersion: findingsOSCALVersion,
+			Props:        glidewayProps("findings-schema-version", FindingsSchemaVersion),
+		},
+		ImportAP: oscalImportAP{Href: "#"},
+		Results:  []oscalResult{result},
+	}}
+}
+
+// ExportFindings 将扫描结果以OSCAL assessment-results格式写入path，供GRC工具导入。
+// 每个开放端口是一个observation，证书过期、疑似蜜罐、未识别服务和最近一次CheckCompliance的违规是finding
+func (a *App) ExportFindings(scanID, path string) error {
+	if strings.TrimSpace(path) == "" {
+		return fmt.Errorf("findings path is required")
+	}
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return err
+	}
+	summary, _, results := record.snapshot()
+	doc := buildFindings(summary, results, record.complianceReport(), time.Now())
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode findings: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("扫描 %s 的发现已导出到 %s: %d 个端口，%d 个发现\n",
+		scanID, path, len(doc.AssessmentResults.Results[0].Observations), len(doc.AssessmentResults.Results[0].Findings))
+	return nil
+}
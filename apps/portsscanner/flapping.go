@@ -0,0 +1,117 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StatePolicy 可选值，决定多次探测结果不一致时端口的最终状态
+const (
+	StatePolicyPreferOpen = "prefer-open" // 默认，任一次探测开放即为开放，此后不再探测
+	StatePolicyPreferLast = "prefer-last" // 以最后一次探测为准
+	StatePolicyMajority   = "majority"    // 取出现次数最多的状态，票数相同时开放优先，否则取其中最后出现的
+)
+
+// ProbeAttempts 的上限
+const maxProbeAttempts = 10
+
+// validateStatePolicy 校验ProbeAttempts和StatePolicy
+func validateStatePolicy(config ScanConfig) error {
+	if config.ProbeAttempts < 0 || config.ProbeAttempts > maxProbeAttempts {
+		return fmt.Errorf("probe attempts %d must be between 1 and %d", config.ProbeAttempts, maxProbeAttempts)
+	}
+	switch config.StatePolicy {
+	case "", StatePolicyPreferOpen, StatePolicyPreferLast, StatePolicyMajority:
+		return nil
+	}
+	return fmt.Errorf("unknown state policy %q", config.StatePolicy)
+}
+
+// reconcileStates 按策略从多次探测的状态中选出最终状态，开放与未开放的结果同时出现时flapping为true
+func reconcileStates(policy string, states []string) (state string, flapping bool) {
+	if len(states) == 0 {
+		return portFiltered, false
+	}
+	open := 0
+	for _, s := range states {
+		if s == portOpen {
+			open++
+		}
+	}
+	flapping = open > 0 && open < len(states)
+
+	switch policy {
+	case StatePolicyPreferLast:
+		return states[len(states)-1], flapping
+	case StatePolicyMajority:
+		counts := make(map[string]int, len(states))
+		best := 0
+		for _, s := range states {
+			counts[s]++
+			best = max(best, counts[s])
+		}
+		if counts[portOpen] == best {
+			return portOpen, flapping
+		}
+		for i := len(states) - 1; i >= 0; i-- {
+			if counts[states[i]] == best {
+				return states[i], flapping
+			}
+		}
+	}
+	if open > 0 {
+		return portOpen, flapping
+	}
+	return states[len(states)-1], flapping
+}
+
+// probeAttempts 按ProbeAttempts多次探测端口并按StatePolicy合并结果。
+// 返回的ICMP报文和耗时来自最后一次得到最终状态的探测；状态不一致时发送port-flapping事件
+func (s *portScanner) probeAttempts(ctx context.Context, h scanTarget, p int) (string, *icmpReply, time.Duration, bool) {
+	attempts := max(s.config.ProbeAttempts, 1)
+	states := make([]string, 0, attempts)
+	icmps := make([]*icmpReply, 0, attempts)
+	latencies := make([]time.Duration, 0, attempts)
+	for i := 0; i < attempts; i++ {
+		if i > 0 && ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+		state, icmp := s.probePort(ctx, h, p)
+		states = append(states, state)
+		icmps = append(icmps, icmp)
+		latencies = append(latencies, time.Since(start))
+		if state == portOpen && (s.config.StatePolicy == "" || s.config.StatePolicy == StatePolicyPreferOpen) {
+			break
+		}
+	}
+	if len(states) == 1 {
+		return states[0], icmps[0], latencies[0], false
+	}
+
+	state, flapping := reconcileStates(s.config.StatePolicy, states)
+	var icmp *icmpReply
+	var latency time.Duration
+	for i := len(states) - 1; i >= 0; i-- {
+		if states[i] == state {
+			icmp, latency = icmps[i], latencies[i]
+			break
+		}
+	}
+	if flapping {
+		policy := s.config.StatePolicy
+		if policy == "" {
+			policy = StatePolicyPreferOpen
+		}
+		s.log(logWarn, "%s:%d 多次探测结果不一致 %v，按 %s 判定为 %s", h.IP, p, states, policy, state)
+		s.emit("port-flapping", map[string]interface{}{
+			"host":   h.IP,
+			"port":   p,
+			"states": states,
+			"state":  state,
+			"policy": policy,
+		})
+	}
+	return state, icmp, latency, flapping
+}
@@ -0,0 +1,62 @@
+package portsscanner
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestStatePolicyWithFlappingPort(t *testing.T) {
+	tests := []struct {
+		policy     string
+		flap       string
+		attempts   int
+		wantOpen   bool
+		wantStates []string // port-flapping事件中的各次探测结果，nil为结果一致不发送事件
+	}{
+		{StatePolicyPreferOpen, "coc", 3, true, []string{portClosed, portOpen}},
+		{StatePolicyPreferOpen, "o", 3, true, nil},
+		{StatePolicyPreferOpen, "c", 3, false, nil},
+		{StatePolicyPreferLast, "coc", 3, false, []string{portClosed, portOpen, portClosed}},
+		{StatePolicyPreferLast, "cco", 3, true, []string{portClosed, portClosed, portOpen}},
+		{StatePolicyMajority, "coc", 3, false, []string{portClosed, portOpen, portClosed}},
+		{StatePolicyMajority, "oco", 3, true, []string{portOpen, portClosed, portOpen}},
+		{StatePolicyMajority, "co", 2, true, []string{portClosed, portOpen}}, // 票数相同时开放优先
+	}
+	for _, tt := range tests {
+		hosts := []SimulatedHost{{IP: "198.18.4.1", Ports: []SimulatedPort{{Port: 443, Flap: tt.flap}}}}
+		config := simConfig(t, hosts, "198.18.4.1", 443)
+		config.ProbeAttempts = tt.attempts
+		config.StatePolicy = tt.policy
+		var mu sync.Mutex
+		var states []string
+		config.emit = func(name string, data interface{}) {
+			if name == "port-flapping" {
+				mu.Lock()
+				states = data.(map[string]interface{})["states"].([]string)
+				mu.Unlock()
+			}
+		}
+
+		scan, err := runSimScan(t, context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var open []PortInfo
+		for _, r := range scan.results {
+			if r.State == "" {
+				open = append(open, r)
+			}
+		}
+		if got := len(open) == 1; got != tt.wantOpen || len(open) > 1 {
+			t.Errorf("%s %q: open results = %+v, want open %v", tt.policy, tt.flap, open, tt.wantOpen)
+		}
+		if tt.wantOpen && len(open) == 1 && open[0].Flapping != (tt.wantStates != nil) {
+			t.Errorf("%s %q: Flapping = %v, want %v", tt.policy, tt.flap, open[0].Flapping, tt.wantStates != nil)
+		}
+		if !reflect.DeepEqual(states, tt.wantStates) {
+			t.Errorf("%s %q: flapping states = %v, want %v", tt.policy, tt.flap, states, tt.wantStates)
+		}
+	}
+}
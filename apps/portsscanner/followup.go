@@ -0,0 +1,304 @@
+package portsscanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// errFollowUpIdle 空闲扫描不会从本机直接连接目标，条件探测会暴露扫描源地址
+var errFollowUpIdle = errors.New("FollowUpProbes is not supported with idle scans")
+
+// FollowUpRule 条件探测规则：发现WhenPorts中的端口或WhenServices中的服务开放后，对同一主机的Ports
+// 补充连接探测和指纹识别。Probe为自定义探测名称(见LoadCustomProbes)时优先用该探测识别；
+// Ports为空时对触发端口本身发送Probe，用于确认特定服务
+type FollowUpRule struct {
+	Name         string   `json:"name"`
+	WhenPorts    []int    `json:"when_ports,omitempty"`
+	WhenServices []string `json:"when_services,omitempty"` // 按规范化后的名称比较
+	Ports        []int    `json:"ports,omitempty"`
+	Probe        string   `json:"probe,omitempty"`
+}
+
+// FollowUpRules 条件探测规则文件结构，ReplaceDefaults 为false时规则追加在默认规则之后
+type FollowUpRules struct {
+	ReplaceDefaults bool           `json:"replace_defaults"`
+	Rules           []FollowUpRule `json:"rules"`
+}
+
+// defaultFollowUpRules 内置规则：通常成对出现的服务端口
+var defaultFollowUpRules = []FollowUpRule{
+	{Name: "smb-netbios", WhenPorts: []int{445}, Ports: []int{139}},
+	{Name: "netbios-smb", WhenPorts: []int{139}, Ports: []int{445}},
+	{Name: "mysql-x-protocol", WhenPorts: []int{3306}, WhenServices: []string{"mysql"}, Ports: []int{33060}},
+	{Name: "web-tls", WhenPorts: []int{80}, Ports: []int{443, 8443}},
+	{Name: "rdp-winrm", WhenPorts: []int{3389}, Ports: []int{5985, 5986}},
+	{Name: "docker-tls", WhenPorts: []int{2375}, Ports: []int{2376}},
+	{Name: "kubernetes-node", WhenPorts: []int{6443}, Ports: []int{10250, 2379}},
+}
+
+// followUpRule 校验后的规则
+type followUpRule struct {
+	FollowUpRule
+	services []string
+}
+
+var (
+	followUpRules   []followUpRule
+	followUpRulesMu sync.RWMutex
+)
+
+func init() {
+	rules, err := compileFollowUpRules(defaultFollowUpRules)
+	if err != nil {
+		panic(err)
+	}
+	followUpRules = rules
+}
+
+// compileFollowUpRules 校验规则，格式错误时报告规则名
+func compileFollowUpRules(rules []FollowUpRule) ([]followUpRule, error) {
+	compiled := make([]followUpRule, 0, len(rules))
+	for i, r := range rules {
+		if r.Name == "" {
+			r.Name = "rule-" + strconv.Itoa(i+1)
+		}
+		if len(r.WhenPorts) == 0 && len(r.WhenServices) == 0 {
+			return nil, fmt.Errorf("follow-up rule %q: no trigger ports or services", r.Name)
+		}
+		if len(r.Ports) == 0 && r.Probe == "" {
+			return nil, fmt.Errorf("follow-up rule %q: needs ports or a probe", r.Name)
+		}
+		for _, p := range append(append([]int(nil), r.WhenPorts...), r.Ports...) {
+			if p < 1 || p > 65535 {
+				return nil, fmt.Errorf("follow-up rule %q: invalid port %d", r.Name, p)
+			}
+		}
+		rule := followUpRule{FollowUpRule: r}
+		for _, s := range r.WhenServices {
+			rule.services = append(rule.services, canonicalServiceName(s))
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+// triggeredBy 判断开放端口是否触发该规则
+func (r followUpRule) triggeredBy(info PortInfo) bool {
+	return containsInt(r.WhenPorts, info.Port) ||
+		(info.Service != "" && containsString(r.services, canonicalServiceName(info.Service)))
+}
+
+// followUp 一次待执行的补充探测
+type followUp struct {
+	target   scanTarget
+	port     int
+	rule     followUpRule
+	trigger  PortInfo
+	existing *PortInfo // 端口已有开放结果时为该结果，只运行Probe
+}
+
+// note 结果中记录的触发来源
+func (f followUp) note() string {
+	return fmt.Sprintf("%s: %d/%s", f.rule.Name, f.trigger.Port, f.trigger.Protocol)
+}
+
+// planFollowUps 按规则列出需要补充探测的端口：主扫描已探测过的端口只在已开放且规则带有Probe时再次识别，
+// 同一端口只由第一个触发的规则探测
+func planFollowUps(config ScanConfig, rules []followUpRule, results []PortInfo) []followUp {
+	type key struct {
+		host string
+		port int
+	}
+	open := make(map[key]int)
+	for i, r := range results {
+		if r.Protocol == "tcp" && r.State == "" {
+			open[key{r.Host, r.Port}] = i
+		}
+	}
+	ports := config.portList()
+	planned := make(map[key]bool)
+	var plan []followUp
+	for _, trigger := range fingerprintTargets(results) {
+		for _, rule := range rules {
+			if !rule.triggeredBy(trigger) {
+				continue
+			}
+			targets := rule.Ports
+			if len(targets) == 0 {
+				targets = []int{trigger.Port}
+			}
+			for _, port := range targets {
+				k := key{trigger.Host, port}
+				if planned[k] {
+					continue
+				}
+				f := followUp{
+					target:  scanTarget{IP: trigger.Host, Names: trigger.Aliases},
+					port:    port,
+					rule:    rule,
+					trigger: trigger,
+				}
+				if i, ok := open[k]; ok {
+					if rule.Probe == "" {
+						continue
+					}
+					existing := results[i]
+					f.existing = &existing
+				} else if containsInt(config.portsFor(trigger.Host, ports), port) {
+					continue
+				}
+				planned[k] = true
+				plan = append(plan, f)
+			}
+		}
+	}
+	return plan
+}
+
+// probeFollowUp 执行一次补充探测：新端口先连接确认开放再识别，已开放的端口只运行规则的Probe。
+// 新发现的端口加入结果并调用found，已有结果被Probe重新识别时发送 port-updated
+func (a *App) probeFollowUp(ctx context.Context, s *portScanner, record *scanRecord, f followUp, found func(PortInfo)) bool {
+	probe, hasProbe := customProbe{}, false
+	if f.rule.Probe != "" && s.gentleReason(f.target, f.port) != "" {
+		// 温和识别的主机不发送自定义探测载荷
+		record.log.add(logInfo, "%s:%d 已启用温和识别，条件探测 %s 不发送自定义探测", f.target.IP, f.port, f.rule.Name)
+	} else if f.rule.Probe != "" {
+		if probe, hasProbe = customProbeByName(f.rule.Probe); !hasProbe {
+			record.log.add(logWarn, "条件探测规则 %s 使用的自定义探测 %s 未加载", f.rule.Name, f.rule.Probe)
+		}
+	}
+
+	if f.existing != nil {
+		info := *f.existing
+		if !hasProbe || !s.runCustomProbe(ctx, f.target, f.port, probe, &info) {
+			return false
+		}
+		info.TriggeredBy = f.note()
+		canonicalizeService(&info)
+		scoreConfidence(&info)
+		if record.updateResult(info) {
+			a.emitEvent("port-updated", info)
+		}
+		return true
+	}
+
+	start := time.Now()
+	address := net.JoinHostPort(f.target.IP, strconv.Itoa(f.port))
+	conn, err := dialTCP(ctx, s.config.connectDialer(), s.config.sources, address, s.config.connectTimeout())
+	if err != nil {
+		return false
+	}
+	applyClosePolicy(conn, s.config.AbruptClose)
+	conn.Close()
+
+	info := PortInfo{
+		Host:        f.target.IP,
+		Aliases:     f.target.Names,
+		Port:        f.port,
+		Endpoint:    s.config.endpointName(f.target.IP, f.port),
+		Protocol:    "tcp",
+		Latency:     time.Since(start),
+		TriggeredBy: f.note(),
+	}
+	if hasProbe && s.runCustomProbe(ctx, f.target, f.port, probe, &info) {
+		canonicalizeService(&info)
+		scoreConfidence(&info)
+	} else {
+		s.identify(ctx, f.target, f.port, &info)
+	}
+	record.addResult(info)
+	found(info)
+	return true
+}
+
+// runFollowUps 主扫描结束后按条件探测规则补充探测，并发数与端口探测相同。扫描被取消时返回ctx的错误
+func (a *App) runFollowUps(ctx context.Context, config ScanConfig, record *scanRecord, found func(PortInfo)) error {
+	followUpRulesMu.RLock()
+	rules := followUpRules
+	followUpRulesMu.RUnlock()
+	_, _, results := record.snapshot()
+	plan := planFollowUps(config, rules, results)
+	if len(plan) == 0 {
+		return nil
+	}
+	record.log.add(logInfo, "条件探测: 补充探测 %d 个相关端口", len(plan))
+
+	s := &portScanner{config: config, callback: func(PortInfo) {}}
+	s.seedGentle(results)
+	var wg sync.WaitGroup
+	hits := 0
+	var mu sync.Mutex
+	sem := make(chan struct{}, max(config.connectThreads(), 1))
+	for _, f := range plan {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(f followUp) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if a.probeFollowUp(ctx, s, record, f, found) {
+				record.log.add(logInfo, "%s:%d 由条件探测 %s 发现", f.target.IP, f.port, f.note())
+				mu.Lock()
+				hits++
+				mu.Unlock()
+			}
+		}(f)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	record.log.add(logInfo, "条件探测完成: %d/%d 个端口有结果", hits, len(plan))
+	return nil
+}
+
+// LoadFollowUpRules 从JSON文件(格式见FollowUpRules)加载条件探测规则，返回生效的规则数。
+// 任一规则格式错误时保持原有规则不变
+func (a *App) LoadFollowUpRules(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read follow-up rules %s: %w", path, err)
+	}
+	var file FollowUpRules
+	if err := json.Unmarshal(data, &file); err != nil {
+		return 0, fmt.Errorf("failed to parse follow-up rules %s: %w", path, err)
+	}
+	rules := file.Rules
+	if !file.ReplaceDefaults {
+		rules = append(append([]FollowUpRule(nil), defaultFollowUpRules...), file.Rules...)
+	}
+	compiled, err := compileFollowUpRules(rules)
+	if err != nil {
+		return 0, err
+	}
+
+	followUpRulesMu.Lock()
+	followUpRules = compiled
+	followUpRulesMu.Unlock()
+	fmt.Printf("已加载 %d 条条件探测规则\n", len(compiled))
+	return len(compiled), nil
+}
+
+// GetFollowUpRules 返回当前生效的条件探测规则
+func (a *App) GetFollowUpRules() []FollowUpRule {
+	followUpRulesMu.RLock()
+	defer followUpRulesMu.RUnlock()
+	rules := make([]FollowUpRule, len(followUpRules))
+	for i, r := range followUpRules {
+		rules[i] = r.FollowUpRule
+	}
+	return rules
+}
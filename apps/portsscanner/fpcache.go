@@ -0,0 +1,123 @@
+package portsscanner
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFingerprintCacheSize = 4096
+	defaultFingerprintCacheTTL  = 30 * time.Minute
+)
+
+// fingerprintCache 按 host:port 缓存最近的指纹识别结果，容量满时淘汰最久未使用的条目
+type fingerprintCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	items map[string]*list.Element
+	order *list.List
+}
+
+type fingerprintEntry struct {
+	key    string
+	info   PortInfo
+	stored time.Time
+}
+
+// 指纹缓存跨扫描任务共享
+var fpCache = newFingerprintCache(defaultFingerprintCacheSize, defaultFingerprintCacheTTL)
+
+func newFingerprintCache(size int, ttl time.Duration) *fingerprintCache {
+	return &fingerprintCache{
+		size:  size,
+		ttl:   ttl,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+func fingerprintKey(host string, port int) string {
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+// Get 返回未过期的缓存结果
+func (c *fingerprintCache) Get(key string) (PortInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return PortInfo{}, false
+	}
+	entry := elem.Value.(*fingerprintEntry)
+	if time.Since(entry.stored) > c.ttl {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		return PortInfo{}, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.info, true
+}
+
+// Put 写入或刷新缓存条目
+func (c *fingerprintCache) Put(key string, info PortInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*fingerprintEntry)
+		entry.info = info
+		entry.stored = time.Now()
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&fingerprintEntry{key: key, info: info, stored: time.Now()})
+	c.evict()
+}
+
+// Configure 调整缓存容量和有效期，容量缩小时立即淘汰多余条目
+func (c *fingerprintCache) Configure(size int, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size = size
+	c.ttl = ttl
+	c.evict()
+}
+
+// Clear 清空缓存
+func (c *fingerprintCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+func (c *fingerprintCache) evict() {
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*fingerprintEntry).key)
+	}
+}
+
+// ConfigureFingerprintCache 设置指纹缓存的容量和有效期
+func (a *App) ConfigureFingerprintCache(size int, ttl time.Duration) error {
+	if size <= 0 {
+		return fmt.Errorf("fingerprint cache size must be positive")
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("fingerprint cache ttl must be positive")
+	}
+	fpCache.Configure(size, ttl)
+	return nil
+}
+
+// ClearFingerprintCache 清空指纹缓存，下次扫描将重新进行完整指纹识别
+func (a *App) ClearFingerprintCache() {
+	fpCache.Clear()
+}
@@ -0,0 +1,80 @@
+package portsscanner
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// IP分片
+//
+// 开启 Fragment 后，原始套接字扫描的探测包被拆分为多个IP分片(与nmap -f相同，默认每片8字节)，
+// 20字节的TCP头分散在3个分片中，只检查单个分片的简单包过滤器无法匹配端口和标志位。
+// 现代防火墙和大多数操作系统协议栈会先重组分片再过滤，此时扫描结果与不分片时相同；
+// 部分网络会直接丢弃分片，端口可能全部显示为filtered或open|filtered。
+const (
+	defaultFragmentSize = 8
+	ipFlagMF            = 0x2000 // More Fragments
+)
+
+// fragmentSizeFor 返回实际使用的分片载荷大小：8的倍数，且分片(含20字节IP头)不超过网卡MTU
+func fragmentSizeFor(size, mtu int) int {
+	if size <= 0 {
+		size = defaultFragmentSize
+	}
+	if mtu > 20 && size > mtu-20 {
+		size = mtu - 20
+	}
+	size -= size % 8
+	if size < 8 {
+		size = 8
+	}
+	return size
+}
+
+// fragmentPacket 将带IP头的数据包按size字节的载荷拆分为IP分片，载荷不超过size时原样返回
+func fragmentPacket(pkt []byte, size int) [][]byte {
+	ihl := int(pkt[0]&0x0f) * 4
+	payload := pkt[ihl:]
+	if len(payload) <= size {
+		return [][]byte{pkt}
+	}
+
+	var frags [][]byte
+	for off := 0; off < len(payload); off += size {
+		end := min(off+size, len(payload))
+		frag := make([]byte, ihl+end-off)
+		copy(frag, pkt[:ihl])
+		copy(frag[ihl:], payload[off:end])
+
+		binary.BigEndian.PutUint16(frag[2:], uint16(len(frag)))
+		flags := uint16(off / 8)
+		if end < len(payload) {
+			flags |= ipFlagMF
+		}
+		binary.BigEndian.PutUint16(frag[6:], flags)
+		frag[10], frag[11] = 0, 0
+		binary.BigEndian.PutUint16(frag[10:], checksum(frag[:ihl]))
+		frags = append(frags, frag)
+	}
+	return frags
+}
+
+// interfaceMTU 返回配置了该源地址的网卡MTU，找不到时返回0
+func interfaceMTU(src net.IP) int {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return 0
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(src) {
+				return iface.MTU
+			}
+		}
+	}
+	return 0
+}
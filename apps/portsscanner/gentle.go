@@ -0,0 +1,174 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// 工控协议的常用端口，主机开放这些端口时自动对其启用温和识别
+var icsPorts = map[int]string{
+	102:   "S7comm",
+	502:   "Modbus",
+	789:   "Red Lion Crimson",
+	1911:  "Niagara Fox",
+	1962:  "PCWorx",
+	2404:  "IEC 60870-5-104",
+	4840:  "OPC UA",
+	5007:  "MELSEC",
+	9600:  "OMRON FINS",
+	18245: "GE SRTP",
+	20000: "DNP3",
+	44818: "EtherNet/IP",
+	47808: "BACnet",
+}
+
+// embeddedDeviceTypes 指纹识别出的设备类型属于这些类别时视为工控/嵌入式设备
+var embeddedDeviceTypes = []string{"specialized", "power-device", "plc", "scada", "industrial"}
+
+// embeddedKeywords 产品名、系统或横幅中出现这些关键字时视为工控/嵌入式设备
+var embeddedKeywords = []string{
+	"simatic", "siemens s7", "modicon", "schneider electric", "allen-bradley", "rockwell",
+	"moxa", "wago", "beckhoff", "omron", "codesys", "bacnet", "modbus", "scada", "vxworks", "lantronix",
+}
+
+// gentlePolicy GentleHosts 配置的温和识别范围：地址和CIDR按前缀匹配，其余按主机名匹配
+type gentlePolicy struct {
+	prefixes []netip.Prefix
+	names    map[string]bool
+}
+
+// newGentlePolicy 解析GentleHosts，列表为空时返回nil
+func newGentlePolicy(hosts []string) (*gentlePolicy, error) {
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+	p := &gentlePolicy{names: make(map[string]bool)}
+	for _, h := range hosts {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if prefixes, err := parseAllowedRanges([]string{h}); err == nil {
+			p.prefixes = append(p.prefixes, prefixes...)
+			continue
+		}
+		if strings.Contains(h, "/") {
+			return nil, fmt.Errorf("invalid gentle host %q", h)
+		}
+		p.names[strings.ToLower(h)] = true
+	}
+	return p, nil
+}
+
+// matches 判断主机是否在温和识别范围内，域名目标按指向该IP的原始输入匹配
+func (p *gentlePolicy) matches(h scanTarget) bool {
+	if p == nil {
+		return false
+	}
+	if addr, err := netip.ParseAddr(h.IP); err == nil {
+		addr = addr.Unmap()
+		for _, prefix := range p.prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+	}
+	for _, name := range h.Names {
+		if p.names[strings.ToLower(name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// gentleReason 返回对该端口启用温和识别的原因，不需要时返回空。
+// 未设置SkipGentleDetection时，工控协议端口会使整台主机之后的识别都改为温和识别
+func (s *portScanner) gentleReason(h scanTarget, p int) string {
+	switch {
+	case s.config.GentleFingerprint:
+		return s.markGentle(h, "configured (GentleFingerprint)")
+	case s.config.gentle.matches(h):
+		return s.markGentle(h, "configured (GentleHosts)")
+	}
+	if reason, ok := s.gentleHosts.Load(h.IP); ok {
+		return reason.(string)
+	}
+	if name, ok := icsPorts[p]; ok && !s.config.SkipGentleDetection {
+		return s.markGentle(h, "industrial protocol port "+strconv.Itoa(p)+"/tcp ("+name+")")
+	}
+	return ""
+}
+
+// markGentle 记录主机已启用温和识别，每台主机第一次启用时写入日志并发送 gentle-mode-applied 事件。
+// 返回该主机的启用原因(并发识别时以先记录的为准)
+func (s *portScanner) markGentle(h scanTarget, reason string) string {
+	if prev, seen := s.gentleHosts.LoadOrStore(h.IP, reason); seen {
+		return prev.(string)
+	}
+	s.log(logWarn, "%s 启用温和识别(%s)：只读取服务端主动发送的横幅，不发送探测载荷", h.IP, reason)
+	s.emit("gentle-mode-applied", map[string]interface{}{
+		"host":    h.IP,
+		"aliases": h.Names,
+		"reason":  reason,
+	})
+	return reason
+}
+
+// detectEmbedded 识别结果显示为工控/嵌入式设备时，对该主机之后的端口自动启用温和识别
+func (s *portScanner) detectEmbedded(h scanTarget, portInfo *PortInfo) {
+	if s.config.SkipGentleDetection {
+		return
+	}
+	deviceType := strings.ToLower(portInfo.DeviceType)
+	for _, t := range embeddedDeviceTypes {
+		if strings.Contains(deviceType, t) {
+			s.markGentle(h, "device type "+portInfo.DeviceType+" on port "+strconv.Itoa(portInfo.Port))
+			return
+		}
+	}
+	text := strings.ToLower(portInfo.ProductName + " " + portInfo.OperatingSystem + " " + portInfo.Info)
+	for _, k := range embeddedKeywords {
+		if strings.Contains(text, k) {
+			s.markGentle(h, "embedded device signature "+strconv.Quote(k)+" on port "+strconv.Itoa(portInfo.Port))
+			return
+		}
+	}
+}
+
+// seedGentle 补充探测和重新识别时沿用之前结果中已启用温和识别的主机
+func (s *portScanner) seedGentle(results []PortInfo) {
+	for _, r := range results {
+		if r.Gentle && r.GentleReason != "" {
+			s.gentleHosts.LoadOrStore(r.Host, r.GentleReason)
+		}
+	}
+}
+
+// fingerprintGentle 温和识别：只建立连接读取服务端主动发送的横幅，不发送任何探测载荷，
+// 也不进行TLS、STARTTLS和HTTP等协议探测
+func (s *portScanner) fingerprintGentle(ctx context.Context, h scanTarget, p int, portInfo *PortInfo) {
+	if sim, ok := s.config.dialer.(*SimulatedNetwork); ok {
+		sim.identify(ctx, h.IP, p, portInfo)
+		return
+	}
+	portInfo.Unidentified = true
+	size := bannerReadLimit(s.config.BannerReadSize, defaultBannerReadSize)
+	banner, err := grabBanner(ctx, s.config, h.IP, p, size)
+	if err != nil {
+		s.log(logDebug, "%s:%d 温和识别未收到横幅: %v", h.IP, p, err)
+		return
+	}
+	if s.config.CaptureResponse {
+		portInfo.RawResponse = dumpResponse(string(banner), s.config.CaptureSize)
+	}
+	s.captureBanner(portInfo, banner)
+	s.matchBanner(h, p, banner, portInfo)
+	if len(banner) > unidentifiedBannerSize {
+		banner = banner[:unidentifiedBannerSize]
+	}
+	portInfo.Info = escapeBanner(banner)
+	s.log(logDebug, "%s:%d 温和识别横幅: %q", h.IP, p, portInfo.Info)
+}
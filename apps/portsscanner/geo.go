@@ -0,0 +1,105 @@
+package portsscanner
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// GeoInfo 外部地址的ASN和国家信息，来自SetGeoDB加载的本地MaxMind数据库
+type GeoInfo struct {
+	ASN          uint   `json:"asn,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	Country      string `json:"country,omitempty"`
+	CountryCode  string `json:"country_code,omitempty"`
+}
+
+// 已加载的数据库，ASN库和国家/城市库可以分别加载，未加载时不进行富化
+var (
+	geoMutex     sync.RWMutex
+	geoASNDB     *mmdbReader
+	geoCountryDB *mmdbReader
+)
+
+// SetGeoDB 加载本地MaxMind格式数据库(GeoLite2-ASN、GeoLite2-Country/City 或兼容格式)，
+// 按数据库类型分别保存，先后加载ASN库和国家库即可同时获得两类信息。path为空时卸载全部数据库。
+// 查询完全离线，不会访问任何外部服务
+func (a *App) SetGeoDB(path string) error {
+	if path == "" {
+		geoMutex.Lock()
+		geoASNDB, geoCountryDB = nil, nil
+		geoMutex.Unlock()
+		return nil
+	}
+	db, err := openMMDB(path)
+	if err != nil {
+		return err
+	}
+	dbType := strings.ToLower(db.dbType)
+	geoMutex.Lock()
+	defer geoMutex.Unlock()
+	switch {
+	case strings.Contains(dbType, "asn") || strings.Contains(dbType, "isp"):
+		geoASNDB = db
+	case strings.Contains(dbType, "country") || strings.Contains(dbType, "city"):
+		geoCountryDB = db
+	default:
+		return fmt.Errorf("unsupported MaxMind database type %q", db.dbType)
+	}
+	fmt.Printf("已加载地理位置数据库 %s (%s)\n", path, db.dbType)
+	return nil
+}
+
+// isExternalAddress 公网地址才进行富化，私有、回环、链路本地等地址不在数据库中
+func isExternalAddress(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// lookupGeo 查询地址的ASN和国家，没有加载数据库、不是公网地址或查不到时返回false
+func lookupGeo(ip string) (GeoInfo, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !isExternalAddress(addr.Unmap()) {
+		return GeoInfo{}, false
+	}
+	geoMutex.RLock()
+	asnDB, countryDB := geoASNDB, geoCountryDB
+	geoMutex.RUnlock()
+
+	var info GeoInfo
+	target := addr.Unmap().AsSlice()
+	if asnDB != nil {
+		if rec, err := asnDB.lookup(target); err == nil && rec != nil {
+			info.ASN = uint(mmdbUint(rec["autonomous_system_number"]))
+			info.Organization, _ = rec["autonomous_system_organization"].(string)
+			if info.Organization == "" {
+				info.Organization, _ = rec["organization"].(string)
+			}
+		}
+	}
+	if countryDB != nil {
+		if rec, err := countryDB.lookup(target); err == nil && rec != nil {
+			info.CountryCode, _ = mmdbPath(rec, "country", "iso_code").(string)
+			info.Country, _ = mmdbPath(rec, "country", "names", "en").(string)
+		}
+	}
+	return info, info != GeoInfo{}
+}
+
+// emitHostInfo 扫描开始时为有地理信息的外部主机发送 host-info 事件
+func (a *App) emitHostInfo(hosts []scanTarget) {
+	for _, h := range hosts {
+		geo, ok := lookupGeo(h.IP)
+		if !ok {
+			continue
+		}
+		a.emitEvent("host-info", map[string]interface{}{
+			"host":         h.IP,
+			"aliases":      h.Names,
+			"asn":          geo.ASN,
+			"organization": geo.Organization,
+			"country":      geo.Country,
+			"country_code": geo.CountryCode,
+		})
+	}
+}
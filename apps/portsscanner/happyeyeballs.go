@@ -0,0 +1,194 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultHappyEyeballsDelay RFC 8305建议的连接尝试间隔
+const defaultHappyEyeballsDelay = 250 * time.Millisecond
+
+// AddressSelection HappyEyeballs为主机名选择扫描地址的结果
+type AddressSelection struct {
+	Hostname   string        `json:"hostname"`
+	Address    string        `json:"address"`
+	Candidates []string      `json:"candidates"` // 按尝试顺序排列的全部解析地址
+	Port       int           `json:"port"`       // 连接尝试使用的端口
+	Elapsed    time.Duration `json:"elapsed"`    // 选出地址用时
+	Fallback   bool          `json:"fallback"`   // 全部地址都没有响应，按默认规则选择
+}
+
+// interleaveFamilies 按RFC 8305交替排列两个地址族的地址，首个地址所属的地址族在前
+func interleaveFamilies(ips []string) []string {
+	var first, second []string
+	firstIs4 := false
+	for i, ip := range ips {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			continue
+		}
+		if i == 0 {
+			firstIs4 = addr.Is4()
+		}
+		if addr.Is4() == firstIs4 {
+			first = append(first, ip)
+		} else {
+			second = append(second, ip)
+		}
+	}
+	ordered := make([]string, 0, len(first)+len(second))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ordered = append(ordered, first[i])
+		}
+		if i < len(second) {
+			ordered = append(ordered, second[i])
+		}
+	}
+	return ordered
+}
+
+// preferIPv4 与resolveHost相同的默认选择：第一个IPv4地址，没有时为第一个地址
+func preferIPv4(ips []string) string {
+	for _, ip := range ips {
+		if addr, err := netip.ParseAddr(ip); err == nil && addr.Is4() {
+			return ip
+		}
+	}
+	return ips[0]
+}
+
+// raceConnect 依次向各地址的port发起连接，每隔delay或上一个尝试失败时开始下一个，
+// 返回最先连接成功或被拒绝的地址，全部无响应时返回空字符串
+func raceConnect(ctx context.Context, config ScanConfig, ips []string, port int) string {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type attempt struct {
+		ip string
+		ok bool
+	}
+	results := make(chan attempt, len(ips))
+	next, pending := 0, 0
+	launch := func() {
+		ip := ips[next]
+		next++
+		pending++
+		go func() {
+			conn, err := dialTCP(ctx, config.connectDialer(), config.sources, net.JoinHostPort(ip, strconv.Itoa(port)), config.connectTimeout())
+			if err == nil {
+				conn.Close()
+			}
+			results <- attempt{ip, err == nil || isConnRefused(err)}
+		}()
+	}
+
+	launch()
+	for pending > 0 {
+		var fallback <-chan time.Time
+		var timer *time.Timer
+		if next < len(ips) {
+			timer = time.NewTimer(config.HappyEyeballsDelay)
+			fallback = timer.C
+		}
+		select {
+		case r := <-results:
+			pending--
+			if r.ok {
+				return r.ip
+			}
+			if next < len(ips) {
+				launch()
+			}
+		case <-fallback:
+			launch()
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return ""
+		}
+	}
+	return ""
+}
+
+// selectAddress 只有一个地址时直接使用，否则通过raceConnect选出响应最快的地址
+func selectAddress(ctx context.Context, config ScanConfig, host string, ips []string, port int) AddressSelection {
+	sel := AddressSelection{Hostname: host, Candidates: interleaveFamilies(ips), Port: port}
+	if len(sel.Candidates) == 1 {
+		sel.Address = sel.Candidates[0]
+		return sel
+	}
+	start := time.Now()
+	sel.Address = raceConnect(ctx, config, sel.Candidates, port)
+	sel.Elapsed = time.Since(start)
+	if sel.Address == "" {
+		sel.Address = preferIPv4(sel.Candidates)
+		sel.Fallback = true
+	}
+	return sel
+}
+
+// happyEyeballsLookup 使用dialCtx并发为每个已解析的主机名选择地址，解析失败的主机名不在resolved中。
+// 连接尝试的端口为扫描的第一个端口，端口关闭时的RST同样说明地址可达
+func happyEyeballsLookup(dialCtx context.Context, config ScanConfig, resolved map[string][]net.IPAddr) (map[string]AddressSelection, error) {
+	candidates := make(map[string][]string, len(resolved))
+	for host, addrs := range resolved {
+		var ips []string
+		for _, a := range addrs {
+			if ip, ok := canonicalIP(a.String()); ok && !containsString(ips, ip) {
+				ips = append(ips, ip)
+			}
+		}
+		candidates[host] = ips
+	}
+
+	port := 0
+	if ports := config.portList(); len(ports) > 0 {
+		port = ports[0]
+	}
+	selections := make(map[string]AddressSelection, len(candidates))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, config.connectThreads())
+	for host, ips := range candidates {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(host string, ips []string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			sel := selectAddress(dialCtx, config, host, ips, port)
+			mu.Lock()
+			selections[host] = sel
+			mu.Unlock()
+		}(host, ips)
+	}
+	wg.Wait()
+	if dialCtx.Err() != nil {
+		return nil, context.Canceled
+	}
+	return selections, nil
+}
+
+// reportAddressSelections 为HappyEyeballs选出的每个地址发送 address-selected 事件
+func (a *App) reportAddressSelections(hosts []scanTarget) {
+	for _, h := range hosts {
+		for _, sel := range h.Selections {
+			if sel.Fallback {
+				fmt.Printf("%s 的 %d 个地址在端口 %d 上都没有响应，使用 %s\n", sel.Hostname, len(sel.Candidates), sel.Port, sel.Address)
+			} else {
+				fmt.Printf("%s 选用地址 %s (共 %d 个地址，用时 %v)\n", sel.Hostname, sel.Address, len(sel.Candidates), sel.Elapsed)
+			}
+			a.emitEvent("address-selected", sel)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// 开放端口占已扫描端口的比例超过该值时怀疑为蜜罐
+	defaultHoneypotOpenRatio = 0.8
+	// 相同横幅出现在至少这么多个不同端口上时怀疑为蜜罐
+	defaultHoneypotBannerPorts = 5
+	// 扫描端口数少于该值时不按开放比例判断，避免只扫几个常用端口时误报
+	honeypotMinPorts = 20
+)
+
+// honeypotVerdict 单台主机的蜜罐判断结果
+type honeypotVerdict struct {
+	Host         string  `json:"host"`
+	Reason       string  `json:"reason"`
+	OpenPorts    int     `json:"open_ports"`
+	ScannedPorts int     `json:"scanned_ports"`
+	OpenRatio    float64 `json:"open_ratio"`
+	BannerPorts  []int   `json:"banner_ports,omitempty"` // 返回相同横幅的端口
+}
+
+// honeypotThresholds 返回配置的判断阈值，未设置时使用默认值
+func (c ScanConfig) honeypotThresholds() (float64, int) {
+	ratio, bannerPorts := c.HoneypotOpenRatio, c.HoneypotBannerPorts
+	if ratio <= 0 || ratio > 1 {
+		ratio = defaultHoneypotOpenRatio
+	}
+	if bannerPorts <= 1 {
+		bannerPorts = defaultHoneypotBannerPorts
+	}
+	return ratio, bannerPorts
+}
+
+// portBanner 用于比较的横幅：优先使用抓取的原始响应，否则使用指纹信息或未识别服务的横幅
+func portBanner(r PortInfo) string {
+	if r.RawResponse != "" {
+		return r.RawResponse
+	}
+	return r.Info
+}
+
+// detectHoneypot 根据开放端口比例和跨端口的相同横幅判断主机是否可能为蜜罐/tarpit。
+// scanned 为该主机扫描的端口总数，open|filtered 等无法确认开放的结果不计入
+func detectHoneypot(host string, scanned int, results []PortInfo, openRatio float64, bannerPorts int) (honeypotVerdict, bool) {
+	verdict := honeypotVerdict{Host: host, ScannedPorts: scanned}
+	byBanner := make(map[string][]int)
+	for _, r := range results {
+		if r.Host != host || r.State != "" {
+			continue
+		}
+		verdict.OpenPorts++
+		if banner := portBanner(r); banner != "" {
+			byBanner[banner] = append(byBanner[banner], r.Port)
+		}
+	}
+	if scanned > 0 {
+		verdict.OpenRatio = float64(verdict.OpenPorts) / float64(scanned)
+	}
+
+	if scanned >= honeypotMinPorts && verdict.OpenRatio > openRatio {
+		verdict.Reason = fmt.Sprintf("%d of %d scanned ports are open", verdict.OpenPorts, scanned)
+		return verdict, true
+	}
+	for _, ports := range byBanner {
+		if len(ports) >= bannerPorts && len(ports) > len(verdict.BannerPorts) {
+			verdict.BannerPorts = ports
+		}
+	}
+	if len(verdict.BannerPorts) > 0 {
+		sort.Ints(verdict.BannerPorts)
+		verdict.Reason = fmt.Sprintf("identical banner on %d different ports", len(verdict.BannerPorts))
+		return verdict, true
+	}
+	return verdict, false
+}
+
+// flagHoneypot 将主机的所有结果标记为疑似蜜罐
+func (r *scanRecord) flagHoneypot(host string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.results {
+		if r.results[i].Host == host {
+			r.results[i].LikelyHoneypot = true
+		}
+	}
+}
+
+// checkHoneypots 扫描完成后逐台主机检查，疑似蜜罐的主机标记结果并发送 honeypot-suspected 事件
+func (a *App) checkHoneypots(config ScanConfig, record *scanRecord, hosts []scanTarget) {
+	openRatio, bannerPorts := config.honeypotThresholds()
+	_, _, results := record.snapshot()
+	for _, h := range hosts {
+		scanned := config.checkpoint.total(h.IP) * int(config.unitsPerPort())
+		verdict, ok := detectHoneypot(h.IP, scanned, results, openRatio, bannerPorts)
+		if !ok {
+			continue
+		}
+		record.flagHoneypot(h.IP)
+		record.log.add(logWarn, "主机 %s 疑似蜜罐: %s", h.IP, verdict.Reason)
+		a.emitEvent("honeypot-suspected", verdict)
+	}
+}
@@ -0,0 +1,169 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// hostRegistry 记录多目标扫描中每台主机的取消函数和派发进度
+type hostRegistry struct {
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	total      int // 该主机需要扫描的端口数
+	dispatched int // 已派发的端口数
+	completed  int // 已完成探测的端口数
+	cancelled  bool
+
+	results    []PortInfo // 注册了主机钩子或启用EmitHostEvents时保存的该主机结果
+	incomplete bool       // 有端口的探测被取消，结果不完整
+	hooked     bool       // 已调用过主机钩子
+	announced  bool       // 已发送 host-scan-started 事件
+}
+
+func newHostRegistry() *hostRegistry {
+	return &hostRegistry{hosts: make(map[string]*hostState)}
+}
+
+// start 为主机创建可单独取消的上下文，重复调用返回同一上下文
+func (r *hostRegistry) start(parent context.Context, host string, total int) context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st, ok := r.hosts[host]
+	if !ok {
+		st = &hostState{total: total}
+		r.hosts[host] = st
+	}
+	if st.ctx == nil {
+		st.ctx, st.cancel = context.WithCancel(parent)
+		if st.cancelled {
+			st.cancel()
+		}
+	}
+	return st.ctx
+}
+
+// dispatch 派发一个端口前调用，主机已被取消时返回false
+func (r *hostRegistry) dispatch(host string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st, ok := r.hosts[host]
+	if !ok {
+		return true
+	}
+	if st.cancelled {
+		return false
+	}
+	st.dispatched++
+	return true
+}
+
+// register 预先登记主机，使尚未开始扫描的主机也可以被取消
+func (r *hostRegistry) register(host string, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.hosts[host]; !ok {
+		r.hosts[host] = &hostState{total: total}
+	}
+}
+
+// cancel 取消主机的后续扫描和进行中的连接，返回未派发的端口数
+func (r *hostRegistry) cancel(host string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st, ok := r.hosts[host]
+	if !ok {
+		return 0, fmt.Errorf("host %s is not part of the current scan", host)
+	}
+	if st.cancelled {
+		return 0, fmt.Errorf("host %s has already been cancelled", host)
+	}
+	st.cancelled = true
+	if st.cancel != nil {
+		st.cancel()
+	}
+	return st.total - st.dispatched, nil
+}
+
+// complete 端口探测结束后调用。主机的全部端口都完整扫描后返回其结果和true，每台主机只返回一次，
+// 主机被取消或counted为false(端口探测被取消)时不视为完成
+func (r *hostRegistry) complete(host string, counted bool) ([]PortInfo, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st, ok := r.hosts[host]
+	if !ok {
+		return nil, false
+	}
+	st.completed++
+	if !counted {
+		st.incomplete = true
+	}
+	return st.finish()
+}
+
+// completeEmpty 没有需要扫描的端口的主机直接视为完成
+func (r *hostRegistry) completeEmpty(host string) ([]PortInfo, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st, ok := r.hosts[host]
+	if !ok || st.total != 0 {
+		return nil, false
+	}
+	return st.finish()
+}
+
+func (st *hostState) finish() ([]PortInfo, bool) {
+	if st.hooked || st.cancelled || st.incomplete || st.completed < st.total {
+		return nil, false
+	}
+	st.hooked = true
+	results := st.results
+	st.results = nil
+	return results, true
+}
+
+// recordResult 保存主机的结果，主机完成时整体交给主机钩子
+func (r *hostRegistry) recordResult(info PortInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if st, ok := r.hosts[info.Host]; ok {
+		st.results = append(st.results, info)
+	}
+}
+
+// announce 主机第一次开始扫描时返回true，两段式扫描的第二遍不再重复
+func (r *hostRegistry) announce(host string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st, ok := r.hosts[host]
+	if !ok || st.announced {
+		return false
+	}
+	st.announced = true
+	return true
+}
+
+// expire 主机超过单主机时间上限时取消其剩余扫描，返回未派发的端口数；
+// 主机已扫描完成或已被取消时返回false
+func (r *hostRegistry) expire(host string) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st, ok := r.hosts[host]
+	if !ok || st.cancelled || st.completed >= st.total {
+		return 0, false
+	}
+	st.cancelled = true
+	if st.cancel != nil {
+		st.cancel()
+	}
+	return st.total - st.dispatched, true
+}
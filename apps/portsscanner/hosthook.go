@@ -0,0 +1,56 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// HostHook 主机的所有端口扫描完成后调用一次，ports为该主机的全部结果，没有开放端口时为空。
+// 钩子在扫描线程中同步执行，耗时的处理应自行转到其他协程
+type HostHook func(host string, ports []PortInfo) error
+
+var (
+	hostHooksMu sync.RWMutex
+	hostHooks   []HostHook
+)
+
+// RegisterHostHook 注册主机完成钩子，供集成方按整台主机评估(如打分)。
+// 被取消或超时放弃的主机端口不完整，不会调用钩子
+func RegisterHostHook(hook HostHook) {
+	if hook == nil {
+		return
+	}
+	hostHooksMu.Lock()
+	hostHooks = append(hostHooks, hook)
+	hostHooksMu.Unlock()
+}
+
+// registeredHostHooks 返回已注册的钩子，没有钩子时扫描不保存每台主机的结果
+func registeredHostHooks() []HostHook {
+	hostHooksMu.RLock()
+	defer hostHooksMu.RUnlock()
+	return hostHooks
+}
+
+// runHostHooks 按端口排序后依次调用钩子，钩子出错或panic只记录日志
+func (s *portScanner) runHostHooks(host string, ports []PortInfo) {
+	hooks := registeredHostHooks()
+	if len(hooks) == 0 {
+		return
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Port < ports[j].Port })
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Printf("Recovered from panic in host hook: %v\n", r)
+					s.log(logError, "主机 %s 的完成钩子发生panic: %v", host, r)
+				}
+			}()
+			if err := hook(host, append([]PortInfo{}, ports...)); err != nil {
+				s.log(logWarn, "主机 %s 的完成钩子返回错误: %v", host, err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,152 @@
+package portsscanner
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TLS证书在该天数内到期时在报告中高亮
+const reportTLSWarnDays = 30
+
+//go:embed report.html.tmpl
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"fmtTime": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Local().Format("2006-01-02 15:04:05")
+	},
+	"join": strings.Join,
+}).Parse(reportTemplateText))
+
+// reportData HTML报告模板使用的数据
+type reportData struct {
+	Summary   ScanSummary
+	Metadata  ScanMetadata
+	Generated time.Time
+	Hosts     []reportHost
+	Services  []reportService
+	TLS       []reportCert
+	Risks     []HostRisk
+	OpenPorts int
+}
+
+type reportHost struct {
+	Host    string
+	Aliases []string
+	Geo     *GeoInfo
+	Ports   []PortInfo
+}
+
+// reportService 服务分布图中的一项，Percent为相对最多服务的宽度百分比
+type reportService struct {
+	Name    string
+	Count   int
+	Percent int
+}
+
+// reportCert 带证书的端口，Class为 expired、expiring 或 ok
+type reportCert struct {
+	Host     string
+	Port     int
+	Service  string
+	Expiry   time.Time
+	DaysLeft int
+	Class    string
+}
+
+// buildReportData 按主机分组结果并统计服务分布和证书到期情况
+func buildReportData(summary ScanSummary, metadata ScanMetadata, results []PortInfo, now time.Time) reportData {
+	data := reportData{Summary: summary, Metadata: metadata, Generated: now, OpenPorts: len(results)}
+
+	byHost := make(map[string]*reportHost)
+	serviceCounts := make(map[string]int)
+	for _, r := range results {
+		h, ok := byHost[r.Host]
+		if !ok {
+			h = &reportHost{Host: r.Host, Aliases: r.Aliases}
+			if geo, found := lookupGeo(r.Host); found {
+				h.Geo = &geo
+			}
+			byHost[r.Host] = h
+		}
+		h.Ports = append(h.Ports, r)
+
+		service := strings.ToLower(r.Service)
+		if service == "" {
+			service = "unknown"
+		}
+		serviceCounts[service]++
+
+		if !r.TLSExpiry.IsZero() {
+			days := int(r.TLSExpiry.Sub(now).Hours() / 24)
+			class := "ok"
+			switch {
+			case !r.TLSExpiry.After(now):
+				class = "expired"
+			case days < reportTLSWarnDays:
+				class = "expiring"
+			}
+			data.TLS = append(data.TLS, reportCert{Host: r.Host, Port: r.Port, Service: r.Service, Expiry: r.TLSExpiry, DaysLeft: days, Class: class})
+		}
+	}
+
+	for _, h := range byHost {
+		sort.Slice(h.Ports, func(i, j int) bool { return h.Ports[i].Port < h.Ports[j].Port })
+		data.Hosts = append(data.Hosts, *h)
+	}
+	sort.Slice(data.Hosts, func(i, j int) bool { return compareHosts(data.Hosts[i].Host, data.Hosts[j].Host) < 0 })
+
+	maxCount := 0
+	for name, count := range serviceCounts {
+		data.Services = append(data.Services, reportService{Name: name, Count: count})
+		maxCount = max(maxCount, count)
+	}
+	sort.Slice(data.Services, func(i, j int) bool {
+		if data.Services[i].Count != data.Services[j].Count {
+			return data.Services[i].Count > data.Services[j].Count
+		}
+		return data.Services[i].Name < data.Services[j].Name
+	})
+	for i := range data.Services {
+		data.Services[i].Percent = data.Services[i].Count * 100 / maxCount
+	}
+
+	sort.Slice(data.TLS, func(i, j int) bool { return data.TLS[i].Expiry.Before(data.TLS[j].Expiry) })
+	data.Risks = hostRisks(results, now)
+	return data
+}
+
+// renderHTMLReport 生成单个自包含的HTML文件，样式和脚本全部内联，离线即可打开
+func renderHTMLReport(summary ScanSummary, metadata ScanMetadata, results []PortInfo) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, buildReportData(summary, metadata, results, time.Now())); err != nil {
+		return nil, fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ExportHTMLReport 将扫描结果导出为可直接通过邮件分享的单文件HTML报告，
+// 包含扫描元数据、主机风险评分、按主机分组的端口表、服务分布和TLS证书到期情况
+func (a *App) ExportHTMLReport(scanID, path string) error {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return err
+	}
+	summary, _, results := record.snapshot()
+	data, err := renderHTMLReport(summary, record.scanMetadata(), results)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package portsscanner
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认使用常见浏览器UA，避免暴露Go默认的 "Go-http-client"
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36"
+
+// 未设置BannerReadSize时HTTP探测读取的响应体大小
+const httpProbeBodyLimit = 64 * 1024
+
+var titleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// httpProbeResult HTTP(S)标题/Server探测结果
+type httpProbeResult struct {
+	Title     string
+	Server    string
+	MutualTLS bool      // 服务端要求客户端证书，使用配置的证书完成了握手
+	TLSExpiry time.Time // 服务端证书的到期时间，非HTTPS时为零值
+
+	Status      int
+	Head        string            // 状态行和响应头
+	Certificate *x509.Certificate // 服务端证书，非HTTPS时为nil
+
+	ALPN   string // TLS握手协商出的应用层协议，非HTTPS或服务端不支持ALPN时为空
+	AltSvc string // Alt-Svc响应头
+}
+
+// isHTTPService 判断指纹识别出的服务是否需要进行HTTP标题探测
+func isHTTPService(service string) bool {
+	switch strings.ToLower(service) {
+	case "http", "https", "http-proxy", "ssl/http":
+		return true
+	}
+	return false
+}
+
+// probeHTTP 使用配置的User-Agent和请求头获取页面标题和Server头，
+// HTTP与HTTPS探测共用同一套请求头。HTTPS先进行普通握手，
+// 被服务端拒绝且配置了客户端证书时再以mTLS重试
+func probeHTTP(ctx context.Context, config ScanConfig, host string, port int, useTLS bool) (*httpProbeResult, error) {
+	return probeHTTPName(ctx, config, host, port, useTLS, "")
+}
+
+// probeHTTPName 以serverName作为SNI和Host头探测，serverName为空时与probeHTTP相同
+func probeHTTPName(ctx context.Context, config ScanConfig, host string, port int, useTLS bool, serverName string) (*httpProbeResult, error) {
+	result, err := doProbeHTTP(ctx, config, host, port, useTLS, nil, serverName)
+	if err != nil && useTLS && config.clientCert != nil && isTLSRejection(err) {
+		if result, err = doProbeHTTP(ctx, config, host, port, true, config.clientCert, serverName); err == nil {
+			result.MutualTLS = true
+		}
+	}
+	return result, err
+}
+
+func doProbeHTTP(ctx context.Context, config ScanConfig, host string, port int, useTLS bool, cert *tls.Certificate, serverName string) (*httpProbeResult, error) {
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	// 通过url.URL生成地址，IPv6区域标识中的%会被转义为%25
+	target := &url.URL{Scheme: scheme, Host: net.JoinHostPort(host, strconv.Itoa(port)), Path: "/"}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	applyProbeHeaders(req, config)
+	tlsConfig := probeTLSConfig(cert)
+	if serverName != "" {
+		req.Host = serverName
+		tlsConfig.ServerName = serverName
+	}
+
+	client := &http.Client{
+		Timeout: config.probeTimeout(port),
+		Transport: &http.Transport{
+			DialContext:       config.dialProbe,
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+			// 握手时通过ALPN提供 h2 和 http/1.1，服务端选择h2时以HTTP/2请求
+			ForceAttemptHTTP2: true,
+		},
+		// 不跟随跳转，只记录目标端口本身的响应
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	limit := bannerReadLimit(config.BannerReadSize, httpProbeBodyLimit)
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, int64(limit)))
+
+	result := &httpProbeResult{
+		Server: resp.Header.Get("Server"),
+		Status: resp.StatusCode,
+		Head:   responseHead(resp),
+		AltSvc: resp.Header.Get("Alt-Svc"),
+	}
+	if resp.TLS != nil {
+		result.ALPN = resp.TLS.NegotiatedProtocol
+		if len(resp.TLS.PeerCertificates) > 0 {
+			result.Certificate = resp.TLS.PeerCertificates[0]
+			result.TLSExpiry = result.Certificate.NotAfter
+		}
+	}
+	if m := titleRegexp.FindSubmatch(body); m != nil {
+		result.Title = strings.TrimSpace(html.UnescapeString(string(m[1])))
+	}
+	return result, nil
+}
+
+// applyProbeHeaders 设置自定义User-Agent和附加请求头
+func applyProbeHeaders(req *http.Request, config ScanConfig) {
+	userAgent := config.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for key, value := range config.Headers {
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+}
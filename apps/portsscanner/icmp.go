@@ -0,0 +1,173 @@
+package portsscanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ICMPHandling 可选值
+const (
+	ICMPIgnore    = "ignore"    // 默认，不监听ICMP，无响应按超时处理
+	ICMPInterpret = "interpret" // 按收到的ICMP目标不可达判断端口状态
+	ICMPReport    = "report"    // 同interpret，并将被ICMP判定为过滤的端口作为结果报告
+)
+
+// ICMP目标不可达和超时报文的类型，以及目标不可达的常见代码
+const (
+	icmpDestUnreachable = 3
+	icmpTimeExceeded    = 11
+
+	icmpNetUnreachable   = 0
+	icmpHostUnreachable  = 1
+	icmpProtoUnreachable = 2
+	icmpPortUnreachable  = 3
+	icmpNetProhibited    = 9
+	icmpHostProhibited   = 10
+	icmpAdminProhibited  = 13
+)
+
+// ICMP报文中引用的原始探测包的协议号
+const (
+	ipProtoTCP = 6
+	ipProtoUDP = 17
+)
+
+// icmpGrace 连接的UDP套接字报错后等待监听器收到对应ICMP报文的时间
+const icmpGrace = 50 * time.Millisecond
+
+var errICMPScanType = errors.New("ICMP handling requires a raw socket scan type (syn, fin, null or xmas) or UDP scanning")
+
+// icmpReply 与某次探测对应的ICMP目标不可达或超时报文
+type icmpReply struct {
+	Type uint8
+	Code uint8
+	From string // 发出该报文的地址：目标本身、沿途防火墙或TTL耗尽处的路由器
+}
+
+// String 形如 "3/13 admin-prohibited from 192.0.2.1"，写入结果和probe-result事件
+func (r icmpReply) String() string {
+	name := "unreachable"
+	switch r.Type {
+	case icmpTimeExceeded:
+		name = "ttl-exceeded"
+		if r.Code == 1 {
+			name = "reassembly-exceeded"
+		}
+	case icmpDestUnreachable:
+		switch r.Code {
+		case icmpNetUnreachable:
+			name = "net-unreachable"
+		case icmpHostUnreachable:
+			name = "host-unreachable"
+		case icmpProtoUnreachable:
+			name = "proto-unreachable"
+		case icmpPortUnreachable:
+			name = "port-unreachable"
+		case icmpNetProhibited:
+			name = "net-prohibited"
+		case icmpHostProhibited:
+			name = "host-prohibited"
+		case icmpAdminProhibited:
+			name = "admin-prohibited"
+		}
+	}
+	if r.From == "" {
+		return fmt.Sprintf("%d/%d %s", r.Type, r.Code, name)
+	}
+	return fmt.Sprintf("%d/%d %s from %s", r.Type, r.Code, name, r.From)
+}
+
+// icmpPortState 按ICMP报文判断端口状态：UDP的端口不可达为关闭，其余(主机不可达、管理禁止、TTL超时等)为过滤
+func icmpPortState(r icmpReply, protocol string) string {
+	if protocol == "udp" && r.Type == icmpDestUnreachable && r.Code == icmpPortUnreachable {
+		return portClosed
+	}
+	return portFiltered
+}
+
+// validateICMPHandling 校验ICMPHandling，只有原始套接字扫描和UDP扫描能关联ICMP报文
+func validateICMPHandling(config ScanConfig) error {
+	switch config.ICMPHandling {
+	case "", ICMPIgnore:
+		return nil
+	case ICMPInterpret, ICMPReport:
+	default:
+		return fmt.Errorf("unknown ICMP handling %q", config.ICMPHandling)
+	}
+	switch config.ScanType {
+	case ScanTypeSYN, ScanTypeFIN, ScanTypeNull, ScanTypeXmas, ScanTypeUDP, ScanTypeBoth:
+		return nil
+	}
+	return errICMPScanType
+}
+
+// handlesICMP 本次扫描是否按ICMP目标不可达报文判断状态
+func (c ScanConfig) handlesICMP() bool {
+	return c.ICMPHandling == ICMPInterpret || c.ICMPHandling == ICMPReport
+}
+
+// icmpTypes 本次扫描需要监听的ICMP报文类型，为空时不创建监听器
+func (c ScanConfig) icmpTypes() []uint8 {
+	var types []uint8
+	if c.handlesICMP() {
+		types = append(types, icmpDestUnreachable)
+	}
+	if c.ProbeTTL > 0 {
+		types = append(types, icmpTimeExceeded)
+	}
+	return types
+}
+
+// waitICMP 在grace时间内等待对应的ICMP报文，grace不大于0时只检查已收到的报文，ch为nil时立即返回
+func waitICMP(ch <-chan icmpReply, grace time.Duration) (icmpReply, bool) {
+	if ch == nil {
+		return icmpReply{}, false
+	}
+	if grace <= 0 {
+		select {
+		case r := <-ch:
+			return r, true
+		default:
+			return icmpReply{}, false
+		}
+	}
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r, true
+	case <-timer.C:
+		return icmpReply{}, false
+	}
+}
+
+// reportICMPFiltered ICMPHandling为report时，将被ICMP判定为过滤的端口作为结果报告，附带ICMP类型和代码
+func (s *portScanner) reportICMPFiltered(ctx context.Context, h scanTarget, p int, protocol string, r icmpReply) {
+	s.log(logInfo, "%s:%d/%s filtered (ICMP %s)", h.IP, p, protocol, r)
+	if ctx.Err() != nil {
+		return
+	}
+	s.callback(PortInfo{
+		Host:     h.IP,
+		Aliases:  h.Names,
+		Port:     p,
+		Endpoint: s.config.endpointName(h.IP, p),
+		Protocol: protocol,
+		State:    portFiltered,
+		ICMP:     r.String(),
+	})
+}
+
+// reportTTLExceeded 设置ProbeTTL时报告每次探测在途中TTL耗尽的位置，hop为回复超时报文的路由器
+func (s *portScanner) reportTTLExceeded(h scanTarget, p int, r icmpReply) {
+	s.log(logDebug, "%s:%d 探测包TTL %d 在 %s 耗尽", h.IP, p, s.config.ProbeTTL, r.From)
+	s.emit("ttl-exceeded", map[string]interface{}{
+		"host": h.IP,
+		"port": p,
+		"ttl":  s.config.ProbeTTL,
+		"hop":  r.From,
+		"icmp": r.String(),
+	})
+}
@@ -0,0 +1,128 @@
+//go:build linux
+
+package portsscanner
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// icmpKey 用ICMP报文中引用的原始探测包定位等待者
+type icmpKey struct {
+	ip      [4]byte
+	proto   uint8
+	srcPort uint16
+	dstPort uint16
+}
+
+// icmpListener 通过原始ICMP套接字接收目标不可达和超时报文，按其中引用的原始包头关联到探测
+type icmpListener struct {
+	fd    int
+	types map[uint8]bool // 关联的ICMP报文类型
+
+	mu      sync.Mutex
+	waiters map[icmpKey]chan icmpReply
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newICMPListener(types ...uint8) (*icmpListener, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_ICMP)
+	if err != nil {
+		return nil, fmt.Errorf("ICMP handling requires root or CAP_NET_RAW: %w", err)
+	}
+	// 设置读取超时，保证关闭时接收协程能及时退出
+	tv := syscall.NsecToTimeval(int64(200 * time.Millisecond))
+	syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+
+	l := &icmpListener{
+		fd:      fd,
+		types:   make(map[uint8]bool, len(types)),
+		waiters: make(map[icmpKey]chan icmpReply),
+		done:    make(chan struct{}),
+	}
+	for _, t := range types {
+		l.types[t] = true
+	}
+	go l.receiveLoop()
+	return l, nil
+}
+
+// Close 关闭套接字并停止接收协程，l为nil时不做任何事
+func (l *icmpListener) Close() {
+	if l == nil {
+		return
+	}
+	l.closeOnce.Do(func() {
+		close(l.done)
+		syscall.Close(l.fd)
+	})
+}
+
+// watch 在发送探测前登记，返回收到对应ICMP报文的通道和取消登记的函数。l为nil时通道为nil
+func (l *icmpListener) watch(dst net.IP, proto uint8, srcPort, dstPort int) (<-chan icmpReply, func()) {
+	dst4 := dst.To4()
+	if l == nil || dst4 == nil {
+		return nil, func() {}
+	}
+	key := icmpKey{proto: proto, srcPort: uint16(srcPort), dstPort: uint16(dstPort)}
+	copy(key.ip[:], dst4)
+	ch := make(chan icmpReply, 1)
+	l.mu.Lock()
+	l.waiters[key] = ch
+	l.mu.Unlock()
+	return ch, func() {
+		l.mu.Lock()
+		delete(l.waiters, key)
+		l.mu.Unlock()
+	}
+}
+
+func (l *icmpListener) receiveLoop() {
+	buf := make([]byte, 65535)
+	for {
+		select {
+		case <-l.done:
+			return
+		default:
+		}
+
+		n, _, err := syscall.Recvfrom(l.fd, buf, 0)
+		if err != nil || n < 20 {
+			continue
+		}
+		ihl := int(buf[0]&0x0f) * 4
+		// ICMP头8字节之后是原始探测包的IP头和至少8字节的传输层头
+		if n < ihl+8+20 || !l.types[buf[ihl]] {
+			continue
+		}
+		reply := icmpReply{Type: buf[ihl], Code: buf[ihl+1], From: net.IP(buf[12:16]).String()}
+		inner := buf[ihl+8 : n]
+		innerIHL := int(inner[0]&0x0f) * 4
+		if len(inner) < innerIHL+4 {
+			continue
+		}
+		key := icmpKey{
+			proto:   inner[9],
+			srcPort: binary.BigEndian.Uint16(inner[innerIHL:]),
+			dstPort: binary.BigEndian.Uint16(inner[innerIHL+2:]),
+		}
+		copy(key.ip[:], inner[16:20])
+
+		l.mu.Lock()
+		ch, ok := l.waiters[key]
+		l.mu.Unlock()
+		if !ok {
+			continue
+		}
+		select {
+		case ch <- reply:
+		default:
+		}
+	}
+}
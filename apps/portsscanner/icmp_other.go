@@ -0,0 +1,18 @@
+//go:build !linux
+
+package portsscanner
+
+import "net"
+
+// icmpListener 非Linux平台不支持监听ICMP
+type icmpListener struct{}
+
+func newICMPListener(types ...uint8) (*icmpListener, error) {
+	return nil, errRawUnsupported
+}
+
+func (l *icmpListener) Close() {}
+
+func (l *icmpListener) watch(dst net.IP, proto uint8, srcPort, dstPort int) (<-chan icmpReply, func()) {
+	return nil, func() {}
+}
@@ -0,0 +1,169 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// 空闲扫描的默认僵尸主机端口，开放或关闭均可，只要能回复RST
+const defaultZombiePort = 80
+
+const (
+	zombieSampleCount = 4 // 检查僵尸主机IP ID序列时的采样次数
+	zombieMaxStep     = 5 // 相邻两次采样允许的最大IP ID增量，超过说明僵尸主机不够空闲
+	idleProbeRetries  = 3 // IP ID增量异常时的重试次数
+	idleMinDelay      = 200 * time.Millisecond
+)
+
+// 僵尸主机IP ID序列检查结论
+const (
+	zombieIncremental = "incremental"
+	zombieConstant    = "constant"
+	zombieIrregular   = "irregular"
+)
+
+// zombieStatus 僵尸主机IP ID采样结果
+type zombieStatus struct {
+	IPIDs    []uint16
+	RTT      time.Duration
+	Sequence string
+}
+
+// idleScanner 空闲(僵尸)扫描：以僵尸主机的地址向目标发送伪造的SYN，
+// 通过僵尸主机IP ID的增量推断目标端口状态，目标不会看到扫描机的真实地址。
+// 增量为2表示目标回复了SYN/ACK(端口开放)，增量为1表示端口关闭或被过滤。
+type idleScanner struct {
+	mu      sync.Mutex // IP ID推断要求同一时间只有一个探测在进行
+	raw     *rawScanner
+	zombie  net.IP
+	port    int
+	timeout time.Duration
+	delay   time.Duration // 发送伪造SYN后等待目标与僵尸主机完成交互的时间
+}
+
+// newIdleScanner 解析僵尸主机并采样其IP ID序列
+func newIdleScanner(ctx context.Context, raw *rawScanner, config ScanConfig) (*idleScanner, zombieStatus, error) {
+	ip, ok := canonicalIP(config.ZombieHost)
+	if !ok {
+		resolved, err := resolveHost(ctx, config.resolver(), config.ZombieHost)
+		if err != nil {
+			return nil, zombieStatus{}, err
+		}
+		ip = resolved
+	}
+	zombie := net.ParseIP(ip).To4()
+	if zombie == nil {
+		return nil, zombieStatus{}, fmt.Errorf("zombie host %s is not an IPv4 address", config.ZombieHost)
+	}
+
+	port := config.ZombiePort
+	if port <= 0 {
+		port = defaultZombiePort
+	}
+
+	s := &idleScanner{
+		raw:     raw,
+		zombie:  zombie,
+		port:    port,
+		timeout: config.Timeout,
+	}
+	status, err := s.sample(ctx)
+	if err != nil {
+		return nil, status, err
+	}
+	s.delay = 3 * status.RTT
+	if s.delay < idleMinDelay {
+		s.delay = idleMinDelay
+	}
+	return s, status, nil
+}
+
+// zombieIPID 向僵尸主机发送SYN/ACK，从其回复的RST中读取当前IP ID
+func (s *idleScanner) zombieIPID(ctx context.Context) (uint16, time.Duration, error) {
+	start := time.Now()
+	reply, ok, err := s.raw.probe(ctx, s.zombie, s.port, tcpSYN|tcpACK, s.timeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !ok {
+		return 0, 0, fmt.Errorf("zombie %s did not answer on port %d", s.zombie, s.port)
+	}
+	return reply.IPID, time.Since(start), nil
+}
+
+// sample 连续采样僵尸主机IP ID，判断其是否全局递增
+func (s *idleScanner) sample(ctx context.Context) (zombieStatus, error) {
+	var status zombieStatus
+	var total time.Duration
+	for i := 0; i < zombieSampleCount; i++ {
+		ipid, rtt, err := s.zombieIPID(ctx)
+		if err != nil {
+			return status, err
+		}
+		status.IPIDs = append(status.IPIDs, ipid)
+		total += rtt
+		time.Sleep(50 * time.Millisecond)
+	}
+	status.RTT = total / zombieSampleCount
+	status.Sequence = classifyIPIDs(status.IPIDs)
+	return status, nil
+}
+
+// classifyIPIDs 根据相邻采样的增量判断IP ID序列类型：
+// 全部不变(为0或按连接随机)的主机无法用于空闲扫描，增量过大说明存在其他流量或IP ID随机
+func classifyIPIDs(ids []uint16) string {
+	constant := true
+	for i := 1; i < len(ids); i++ {
+		step := ids[i] - ids[i-1]
+		if step != 0 {
+			constant = false
+		}
+		if step > zombieMaxStep {
+			return zombieIrregular
+		}
+	}
+	if constant {
+		return zombieConstant
+	}
+	return zombieIncremental
+}
+
+// probe 通过僵尸主机探测目标端口，返回端口状态和最后一次观察到的IP ID增量
+func (s *idleScanner) probe(ctx context.Context, target net.IP, port int) (string, uint16, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var step uint16
+	for attempt := 0; attempt < idleProbeRetries; attempt++ {
+		before, _, err := s.zombieIPID(ctx)
+		if err != nil {
+			return portFiltered, 0, err
+		}
+		if err := s.raw.spoof(s.zombie, target, port, tcpSYN); err != nil {
+			return portFiltered, 0, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return portFiltered, 0, ctx.Err()
+		case <-time.After(s.delay):
+		}
+
+		after, _, err := s.zombieIPID(ctx)
+		if err != nil {
+			return portFiltered, 0, err
+		}
+
+		// 减去本次探测僵尸主机自身产生的1个增量
+		switch step = after - before; step {
+		case 1:
+			return portClosed, step, nil
+		case 2:
+			return portOpen, step, nil
+		}
+	}
+	return portFiltered, step, nil
+}
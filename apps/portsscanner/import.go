@@ -0,0 +1,320 @@
+package portsscanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importedHost 从外部结果文件中读取的主机及其开放端口
+type importedHost struct {
+	Host  string
+	Ports []int
+}
+
+// nmapRun Nmap XML输出(-oX)中导入需要的部分
+type nmapRun struct {
+	XMLName xml.Name `xml:"nmaprun"`
+	Start   int64    `xml:"start,attr"` // 扫描开始的Unix时间
+	Hosts   []struct {
+		Status struct {
+			State string `xml:"state,attr"`
+		} `xml:"status"`
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Ports []struct {
+			Protocol string `xml:"protocol,attr"`
+			PortID   int    `xml:"portid,attr"`
+			State    struct {
+				State string `xml:"state,attr"`
+			} `xml:"state"`
+			Service struct {
+				Name       string   `xml:"name,attr"`
+				Product    string   `xml:"product,attr"`
+				Version    string   `xml:"version,attr"`
+				ExtraInfo  string   `xml:"extrainfo,attr"`
+				Hostname   string   `xml:"hostname,attr"`
+				OSType     string   `xml:"ostype,attr"`
+				DeviceType string   `xml:"devicetype,attr"`
+				Tunnel     string   `xml:"tunnel,attr"`
+				CPE        []string `xml:"cpe"`
+			} `xml:"service"`
+		} `xml:"ports>port"`
+	} `xml:"host"`
+}
+
+// ImportTargets 从Nmap XML或GlideWay JSON导出文件中提取主机列表，可直接作为扫描目标
+func (a *App) ImportTargets(path string) ([]string, error) {
+	hosts, err := importHosts(path)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		targets = append(targets, h.Host)
+	}
+	return targets, nil
+}
+
+// ImportEndpoints 从导入文件中提取开放端口的 host:port 列表，用于 ScanEndpoints 复测
+func (a *App) ImportEndpoints(path string) ([]string, error) {
+	hosts, err := importHosts(path)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []string
+	for _, h := range hosts {
+		for _, p := range h.Ports {
+			endpoints = append(endpoints, net.JoinHostPort(h.Host, strconv.Itoa(p)))
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("%s contains no open ports", path)
+	}
+	return endpoints, nil
+}
+
+// importHosts 根据扩展名或文件内容识别格式并解析
+func importHosts(path string) ([]importedHost, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var hosts []importedHost
+	switch detectImportFormat(path, data) {
+	case "xml":
+		hosts, err = parseNmapXML(data)
+	case "json":
+		hosts, err = parseGlideWayJSON(data)
+	default:
+		return nil, fmt.Errorf("%s is neither an Nmap XML file nor a GlideWay JSON export", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("%s contains no hosts", path)
+	}
+	return hosts, nil
+}
+
+func detectImportFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		return "xml"
+	case ".json":
+		return "json"
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return ""
+	}
+	switch trimmed[0] {
+	case '<':
+		return "xml"
+	case '{':
+		return "json"
+	}
+	return ""
+}
+
+// parseNmapXML 读取状态为up的主机IP地址及其开放的TCP端口，忽略MAC地址
+func parseNmapXML(data []byte) ([]importedHost, error) {
+	var run nmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("invalid Nmap XML: %w", err)
+	}
+
+	var hosts []importedHost
+	for _, h := range run.Hosts {
+		if h.Status.State != "" && h.Status.State != "up" {
+			continue
+		}
+		addr := ""
+		for _, a := range h.Addresses {
+			if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+				addr = a.Addr
+				break
+			}
+		}
+		if addr == "" {
+			continue
+		}
+		imported := importedHost{Host: addr}
+		for _, p := range h.Ports {
+			if p.Protocol == "tcp" && p.State.State == "open" {
+				imported.Ports = append(imported.Ports, p.PortID)
+			}
+		}
+		hosts = append(hosts, imported)
+	}
+	return hosts, nil
+}
+
+// parseGlideWayJSON 读取 ExportResults 导出的JSON文件，按首次出现的顺序合并主机
+func parseGlideWayJSON(data []byte) ([]importedHost, error) {
+	var doc exportDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid GlideWay export: %w", err)
+	}
+	if doc.Results == nil {
+		return nil, fmt.Errorf("invalid GlideWay export: missing results")
+	}
+
+	var hosts []importedHost
+	index := make(map[string]int)
+	for _, r := range doc.Results {
+		if r.Host == "" {
+			continue
+		}
+		i, ok := index[r.Host]
+		if !ok {
+			i = len(hosts)
+			index[r.Host] = i
+			hosts = append(hosts, importedHost{Host: r.Host})
+		}
+		if r.Port > 0 && r.State != portOpenFiltered {
+			hosts[i].Ports = append(hosts[i].Ports, r.Port)
+		}
+	}
+	return hosts, nil
+}
+
+// importFindings 读取导入文件中的开放端口及其服务信息，结果均标记为导入，
+// 同时返回文件记录的原始扫描时间，文件中没有时为零值
+func importFindings(path string) ([]PortInfo, time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var (
+		results []PortInfo
+		scanned time.Time
+	)
+	switch detectImportFormat(path, data) {
+	case "xml":
+		results, scanned, err = parseNmapXMLFindings(data)
+	case "json":
+		var doc exportDocument
+		if err = json.Unmarshal(data, &doc); err == nil && doc.Results == nil {
+			err = fmt.Errorf("invalid GlideWay export: missing results")
+		}
+		results, scanned = doc.Results, doc.Scan.resultsTime()
+	default:
+		return nil, time.Time{}, fmt.Errorf("%s is neither an Nmap XML file nor a GlideWay JSON export", path)
+	}
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(results) == 0 {
+		return nil, time.Time{}, fmt.Errorf("%s contains no open ports", path)
+	}
+	for i := range results {
+		results[i].Imported = true
+		canonicalizeService(&results[i])
+	}
+	return results, scanned, nil
+}
+
+// parseNmapXMLFindings 将Nmap XML中开放的TCP端口转换为扫描结果，并返回Nmap记录的扫描开始时间
+func parseNmapXMLFindings(data []byte) ([]PortInfo, time.Time, error) {
+	var run nmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid Nmap XML: %w", err)
+	}
+	var scanned time.Time
+	if run.Start > 0 {
+		scanned = time.Unix(run.Start, 0)
+	}
+
+	var results []PortInfo
+	for _, h := range run.Hosts {
+		addr := ""
+		for _, a := range h.Addresses {
+			if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+				addr = a.Addr
+				break
+			}
+		}
+		if addr == "" {
+			continue
+		}
+		for _, p := range h.Ports {
+			if p.Protocol != "tcp" || p.State.State != "open" {
+				continue
+			}
+			svc := p.Service
+			info := PortInfo{
+				Host:            addr,
+				Port:            p.PortID,
+				Protocol:        "tcp",
+				Service:         svc.Name,
+				ProductName:     svc.Product,
+				Version:         svc.Version,
+				Info:            svc.ExtraInfo,
+				Hostname:        svc.Hostname,
+				OperatingSystem: svc.OSType,
+				DeviceType:      svc.DeviceType,
+				TLS:             svc.Tunnel == "ssl",
+			}
+			if len(svc.CPE) > 0 {
+				info.CPE = svc.CPE[0]
+			}
+			results = append(results, info)
+		}
+	}
+	return results, scanned, nil
+}
+
+// LoadResultsIntoSession 将Nmap XML或GlideWay JSON中的结果作为一次导入扫描加入历史，
+// 并像实时扫描一样发送 port-found 和 scan-complete 事件，结果带有 imported 标记
+func (a *App) LoadResultsIntoSession(path string) error {
+	if a == nil || a.ctx == nil {
+		return fmt.Errorf("app context is not initialized")
+	}
+	results, scanned, err := importFindings(path)
+	if err != nil {
+		return err
+	}
+
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+	if currentScan != nil {
+		return fmt.Errorf("cannot load results while a scan is running")
+	}
+
+	record := createScanRecord(ScanConfig{Target: filepath.Base(path)})
+	if !scanned.IsZero() {
+		record.setResultsTime(scanned)
+	}
+	events := newEventThrottle(a.emitEvent, false)
+	for _, r := range results {
+		record.addResult(r)
+		events.portFound(r)
+	}
+	events.close()
+	record.log.add(logInfo, "从 %s 导入 %d 个开放端口", path, len(results))
+	record.finish("imported")
+	summary, _, _ := record.snapshot()
+	a.warnIfStale(summary)
+	fmt.Printf("从 %s 导入 %d 个开放端口\n", path, len(results))
+
+	a.emitEvent("scan-complete", map[string]interface{}{
+		"scan_id":  record.summary.ID,
+		"imported": true,
+		"source":   path,
+		"results":  len(results),
+	})
+	return nil
+}
@@ -0,0 +1,81 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// interceptionProbes 正常情况下不可能建立TCP连接的地址：
+// RFC 5737 文档保留地址段中的主机，以及一个极少开放的高位端口。
+// 任意一个能连通都说明出站流量被强制门户或透明代理截获
+var interceptionProbes = []string{
+	"192.0.2.1:80",
+	"198.51.100.1:443",
+	"203.0.113.1:47811",
+}
+
+// 拦截检测的单次连接超时上限
+const interceptionProbeTimeout = 2 * time.Second
+
+// detectInterception 连接已知不存在的主机，返回意外连通的地址
+func detectInterception(ctx context.Context, timeout time.Duration) []string {
+	if timeout <= 0 || timeout > interceptionProbeTimeout {
+		timeout = interceptionProbeTimeout
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		responded []string
+	)
+	for _, addr := range interceptionProbes {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			dialer := net.Dialer{Timeout: timeout}
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return
+			}
+			conn.Close()
+			mu.Lock()
+			responded = append(responded, addr)
+			mu.Unlock()
+		}(addr)
+	}
+	wg.Wait()
+	return responded
+}
+
+// needsInterceptionCheck 目标全部为本机回环地址时流量不会经过网关，无需检测
+func needsInterceptionCheck(config ScanConfig, hosts []scanTarget) bool {
+	if config.SkipInterceptionCheck || config.dialer != nil {
+		return false
+	}
+	for _, h := range hosts {
+		if !isLoopbackTarget(h.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkInterception 扫描前的网络拦截检测，发现异常时发送警告但不阻止扫描
+func (a *App) checkInterception(ctx context.Context, config ScanConfig, hosts []scanTarget) {
+	if !needsInterceptionCheck(config, hosts) {
+		return
+	}
+	responded := detectInterception(ctx, config.Timeout)
+	if len(responded) == 0 {
+		return
+	}
+	fmt.Printf("检测到网络拦截，以下不存在的地址可以连通: %v\n", responded)
+	a.emitEvent("network-interception-detected", map[string]interface{}{
+		"target":    config.Target,
+		"responded": responded,
+		"message":   "connections to non-existent hosts succeeded; a captive portal or transparent proxy may make every port look open",
+	})
+}
@@ -0,0 +1,113 @@
+package portsscanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// InventoryEntry 合并多次扫描后单个 host:port 的最新观察结果
+type InventoryEntry struct {
+	Result    PortInfo  `json:"result"`     // 最近一次扫描到的结果
+	ScanID    string    `json:"scan_id"`    // 最近一次结果所属的扫描
+	FirstSeen time.Time `json:"first_seen"` // 首次发现的扫描开始时间
+	LastSeen  time.Time `json:"last_seen"`  // 最近发现的扫描开始时间
+	SeenCount int       `json:"seen_count"` // 出现该端口的扫描次数
+}
+
+// Inventory 多次扫描合并后的资产清单
+type Inventory struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Scans       []string         `json:"scans"`
+	Missing     []string         `json:"missing,omitempty"` // 未找到的扫描ID
+	Hosts       int              `json:"hosts"`
+	Entries     []InventoryEntry `json:"entries"`
+}
+
+// BuildInventory 合并多次扫描的结果，每个 host:port 保留最近一次观察并记录首次/最近发现时间，
+// scanIDs 为空时合并全部历史扫描
+func (a *App) BuildInventory(scanIDs []string) Inventory {
+	if len(scanIDs) == 0 {
+		for _, summary := range a.ListScans() {
+			scanIDs = append(scanIDs, summary.ID)
+		}
+	}
+
+	inventory := Inventory{
+		GeneratedAt: time.Now(),
+		Scans:       []string{},
+		Entries:     []InventoryEntry{},
+	}
+	entries := make(map[string]*InventoryEntry)
+	hosts := make(map[string]bool)
+
+	for _, id := range scanIDs {
+		record, err := getScanRecord(id)
+		if err != nil {
+			inventory.Missing = append(inventory.Missing, id)
+			continue
+		}
+		summary, _, results := record.snapshot()
+		inventory.Scans = append(inventory.Scans, id)
+		seen := summary.StartedAt
+
+		for _, r := range results {
+			key := net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+			hosts[r.Host] = true
+			entry, ok := entries[key]
+			if !ok {
+				entries[key] = &InventoryEntry{Result: r, ScanID: id, FirstSeen: seen, LastSeen: seen, SeenCount: 1}
+				continue
+			}
+			entry.SeenCount++
+			if seen.Before(entry.FirstSeen) {
+				entry.FirstSeen = seen
+			}
+			if !seen.Before(entry.LastSeen) {
+				entry.LastSeen = seen
+				entry.Result = r
+				entry.ScanID = id
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		inventory.Entries = append(inventory.Entries, *entry)
+	}
+	sort.Slice(inventory.Entries, func(i, j int) bool {
+		a, b := inventory.Entries[i].Result, inventory.Entries[j].Result
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		return a.Port < b.Port
+	})
+	inventory.Hosts = len(hosts)
+	return inventory
+}
+
+// ExportInventory 将合并后的资产清单导出为JSON文件，返回文件路径
+func (a *App) ExportInventory(scanIDs []string) (string, error) {
+	inventory := a.BuildInventory(scanIDs)
+	if len(inventory.Scans) == 0 {
+		return "", fmt.Errorf("no scans to build an inventory from")
+	}
+
+	data, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	dir, err := exportDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "inventory-"+inventory.GeneratedAt.Format("20060102-150405")+".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
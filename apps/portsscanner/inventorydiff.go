@@ -0,0 +1,241 @@
+package portsscanner
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InventoryRecord 资产清单CSV中的一行。清单文件第一行为表头(不区分大小写，列顺序任意)：
+//
+//	host,port,protocol,service,owner
+//
+// host 必填，为IP地址或主机名(与扫描目标中的写法匹配)；port 为空表示只登记主机、不限定其开放的端口；
+// protocol 默认tcp；service 为空表示不检查服务名；owner 等其他列原样带入报告。以#开头的行为注释
+type InventoryRecord struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Service  string `json:"service,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+	Line     int    `json:"line"` // 在清单文件中的行号
+}
+
+// ServiceMismatch 清单登记的端口已开放，但识别出的服务与清单不同
+type ServiceMismatch struct {
+	Expected InventoryRecord `json:"expected"`
+	Actual   PortInfo        `json:"actual"`
+}
+
+// InventoryDiff 扫描结果与外部资产清单的对账结果。扫描只能看到有开放端口的主机，
+// 清单中的主机没有任何开放端口时同样列为MissingHosts
+type InventoryDiff struct {
+	ScanID     string    `json:"scan_id"`
+	Inventory  string    `json:"inventory"`
+	ComparedAt time.Time `json:"compared_at"`
+	Matched    int       `json:"matched"` // 清单登记且扫描中开放的端口数
+
+	MissingHosts       []InventoryRecord `json:"missing_hosts"`       // 清单中有、扫描中没有开放端口的主机(可能已下线)
+	MissingServices    []InventoryRecord `json:"missing_services"`    // 主机在线但清单登记的端口未开放
+	UnexpectedHosts    []string          `json:"unexpected_hosts"`    // 扫描发现、清单中没有的主机
+	UnexpectedServices []PortInfo        `json:"unexpected_services"` // 清单未登记的开放端口(包括未登记主机上的端口)
+	ServiceMismatches  []ServiceMismatch `json:"service_mismatches"`
+}
+
+// loadInventoryCSV 读取资产清单，IP地址转为规范写法，主机名转为小写
+func loadInventoryCSV(path string) ([]InventoryRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory %s: %w", path, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("inventory %s is empty", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse inventory %s: %w", path, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["host"]; !ok {
+		return nil, fmt.Errorf("inventory %s has no host column", path)
+	}
+
+	var records []InventoryRecord
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse inventory %s: %w", path, err)
+		}
+		line, _ := r.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		rec := InventoryRecord{
+			Host:     field("host"),
+			Protocol: strings.ToLower(field("protocol")),
+			Service:  field("service"),
+			Owner:    field("owner"),
+			Line:     line,
+		}
+		if rec.Host == "" {
+			continue
+		}
+		if ip, ok := canonicalIP(rec.Host); ok {
+			rec.Host = ip
+		} else {
+			rec.Host = strings.ToLower(strings.TrimSuffix(rec.Host, "."))
+		}
+		if port := field("port"); port != "" {
+			if rec.Port, err = strconv.Atoi(port); err != nil || rec.Port < 1 || rec.Port > 65535 {
+				return nil, fmt.Errorf("inventory %s line %d: invalid port %q", path, line, port)
+			}
+		}
+		switch rec.Protocol {
+		case "":
+			if rec.Port > 0 {
+				rec.Protocol = "tcp"
+			}
+		case "tcp", "udp":
+		default:
+			return nil, fmt.Errorf("inventory %s line %d: unknown protocol %q", path, line, rec.Protocol)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// inventoryHostKeys 返回结果可以匹配的清单主机写法：IP地址和扫描时输入的主机名
+func inventoryHostKeys(r PortInfo) []string {
+	keys := []string{r.Host}
+	for _, alias := range r.Aliases {
+		keys = append(keys, strings.ToLower(strings.TrimSuffix(alias, ".")))
+	}
+	return keys
+}
+
+// compareInventory 对照清单和扫描结果，open|filtered 等未确认开放的端口不计入
+func compareInventory(records []InventoryRecord, results []PortInfo) InventoryDiff {
+	diff := InventoryDiff{
+		MissingHosts:       []InventoryRecord{},
+		MissingServices:    []InventoryRecord{},
+		UnexpectedHosts:    []string{},
+		UnexpectedServices: []PortInfo{},
+		ServiceMismatches:  []ServiceMismatch{},
+	}
+
+	type portKey struct {
+		host     string
+		port     int
+		protocol string
+	}
+	listedHosts := make(map[string]bool) // 清单中的主机
+	listsPorts := make(map[string]bool)  // 清单为该主机登记了端口
+	listed := make(map[portKey]InventoryRecord)
+	for _, rec := range records {
+		listedHosts[rec.Host] = true
+		if rec.Port > 0 {
+			listsPorts[rec.Host] = true
+			listed[portKey{rec.Host, rec.Port, rec.Protocol}] = rec
+		}
+	}
+
+	seenHosts := make(map[string]bool) // 扫描中有开放端口的主机(按清单写法)
+	matched := make(map[portKey]bool)
+	unexpectedHosts := make(map[string]bool)
+	for _, r := range results {
+		if r.State != "" {
+			continue
+		}
+		keys := inventoryHostKeys(r)
+		host := ""
+		for _, k := range keys {
+			seenHosts[k] = true
+			if host == "" && listedHosts[k] {
+				host = k
+			}
+		}
+		if host == "" {
+			unexpectedHosts[r.Host] = true
+			diff.UnexpectedServices = append(diff.UnexpectedServices, r)
+			continue
+		}
+
+		rec, ok := listed[portKey{host, r.Port, strings.ToLower(r.Protocol)}]
+		switch {
+		case ok:
+			matched[portKey{host, r.Port, rec.Protocol}] = true
+			diff.Matched++
+			if rec.Service != "" && canonicalServiceName(rec.Service) != canonicalServiceName(r.Service) {
+				diff.ServiceMismatches = append(diff.ServiceMismatches, ServiceMismatch{Expected: rec, Actual: r})
+			}
+		case listsPorts[host]:
+			diff.UnexpectedServices = append(diff.UnexpectedServices, r)
+		}
+	}
+
+	missingHost := make(map[string]bool)
+	for _, rec := range records {
+		switch {
+		case !seenHosts[rec.Host]:
+			if !missingHost[rec.Host] {
+				missingHost[rec.Host] = true
+				diff.MissingHosts = append(diff.MissingHosts, rec)
+			}
+		case rec.Port > 0 && !matched[portKey{rec.Host, rec.Port, rec.Protocol}]:
+			diff.MissingServices = append(diff.MissingServices, rec)
+		}
+	}
+	for host := range unexpectedHosts {
+		diff.UnexpectedHosts = append(diff.UnexpectedHosts, host)
+	}
+	sort.Slice(diff.UnexpectedHosts, func(i, j int) bool {
+		return compareHosts(diff.UnexpectedHosts[i], diff.UnexpectedHosts[j]) < 0
+	})
+	return diff
+}
+
+// CompareToInventory 将扫描结果与外部资产清单(CSV，格式见InventoryRecord)对账，
+// 找出清单中已不存在的资产和清单之外的主机与服务
+func (a *App) CompareToInventory(scanID, inventoryPath string) (InventoryDiff, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return InventoryDiff{}, err
+	}
+	records, err := loadInventoryCSV(inventoryPath)
+	if err != nil {
+		return InventoryDiff{}, err
+	}
+	_, _, results := record.snapshot()
+
+	diff := compareInventory(records, results)
+	diff.ScanID = scanID
+	diff.Inventory = inventoryPath
+	diff.ComparedAt = time.Now()
+	fmt.Printf("资产清单对账 %s: 匹配 %d 个端口，缺失 %d 台主机 %d 个端口，清单外 %d 台主机 %d 个端口，服务不符 %d 个\n",
+		scanID, diff.Matched, len(diff.MissingHosts), len(diff.MissingServices),
+		len(diff.UnexpectedHosts), len(diff.UnexpectedServices), len(diff.ServiceMismatches))
+	return diff, nil
+}
@@ -0,0 +1,61 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// markdownCell 转义表格单元格：竖线转义为 \|，换行合并为空格，空值写作 -
+func markdownCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// writeMarkdown 写出GitHub风格的Markdown表格：一行摘要，随后每个端口一行，按主机和端口排序
+func writeMarkdown(sb *strings.Builder, summary ScanSummary, results []PortInfo) {
+	sorted := append([]PortInfo(nil), results...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := compareHosts(sorted[i].Host, sorted[j].Host); c != 0 {
+			return c < 0
+		}
+		if sorted[i].Port != sorted[j].Port {
+			return sorted[i].Port < sorted[j].Port
+		}
+		return sorted[i].Protocol < sorted[j].Protocol
+	})
+	hosts := make(map[string]bool)
+	for _, r := range sorted {
+		hosts[r.Host] = true
+	}
+
+	fmt.Fprintf(sb, "**%d ports on %d hosts** · scan `%s` of %s · %s\n\n", len(sorted), len(hosts),
+		summary.ID, markdownCell(summary.Target), summary.resultsTime().Format("2006-01-02 15:04 MST"))
+	sb.WriteString("| Host | Port | Service | Version |\n")
+	sb.WriteString("|---|---|---|---|\n")
+	for _, r := range sorted {
+		port := fmt.Sprintf("%d/%s", r.Port, r.Protocol)
+		if r.State != "" {
+			port += " " + r.State
+		}
+		fmt.Fprintf(sb, "| %s | %s | %s | %s |\n", markdownCell(textHostName(r.Host, r.Aliases)), markdownCell(port),
+			markdownCell(r.Service), markdownCell(r.ProductName+" "+r.Version))
+	}
+	writeRiskMarkdown(sb, hostRisks(results, time.Now()))
+}
+
+// ExportMarkdown 返回扫描结果的Markdown表格(host、port、service、version)和主机风险评分表，供前端直接复制到剪贴板
+func (a *App) ExportMarkdown(scanID string) (string, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return "", err
+	}
+	summary, _, results := record.snapshot()
+	var sb strings.Builder
+	writeMarkdown(&sb, summary, results)
+	return sb.String(), nil
+}
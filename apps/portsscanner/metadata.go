@@ -0,0 +1,76 @@
+package portsscanner
+
+import (
+	"os"
+	"os/user"
+	goruntime "runtime"
+	"time"
+)
+
+// Version GlideWay版本号，发布构建时通过 -ldflags "-X GlideWay/apps/portsscanner.Version=v1.2.3" 设置
+var Version = "dev"
+
+// ScanMetadata 扫描的运行环境和来源信息，用于审计和复现：由谁、在哪台机器、以什么权限和配置得到了这些结果
+type ScanMetadata struct {
+	ScanID     string     `json:"scan_id"`
+	Version    string     `json:"version"`
+	OS         string     `json:"os"`
+	Arch       string     `json:"arch"`
+	GoVersion  string     `json:"go_version"`
+	User       string     `json:"user"`
+	Hostname   string     `json:"hostname"`
+	Elevated   bool       `json:"elevated"`       // 以root运行(Windows上无法判断，总是false)
+	RawSockets bool       `json:"raw_sockets"`    // 扫描类型使用了原始套接字
+	DSCP       int        `json:"dscp,omitempty"` // 探测流量设置的DSCP标记
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt time.Time  `json:"finished_at"`
+	Config     ScanConfig `json:"config"` // 填充默认值后实际使用的配置
+}
+
+// currentUser 返回运行扫描的用户名，无法获取时使用环境变量
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// captureMetadata 在扫描开始时记录运行环境，时间和配置在查询时由扫描记录补全
+func captureMetadata(config ScanConfig) ScanMetadata {
+	hostname, _ := os.Hostname()
+	return ScanMetadata{
+		Version:    Version,
+		OS:         goruntime.GOOS,
+		Arch:       goruntime.GOARCH,
+		GoVersion:  goruntime.Version(),
+		User:       currentUser(),
+		Hostname:   hostname,
+		Elevated:   os.Geteuid() == 0,
+		RawSockets: isRawScanType(config.ScanType),
+		DSCP:       config.DSCP,
+	}
+}
+
+// scanMetadata 返回完整的扫描元数据
+func (r *scanRecord) scanMetadata() ScanMetadata {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	meta := r.metadata
+	meta.ScanID = r.summary.ID
+	meta.StartedAt = r.summary.StartedAt
+	meta.FinishedAt = r.summary.FinishedAt
+	meta.Config = r.config
+	return meta
+}
+
+// GetScanMetadata 返回扫描的运行环境、时间和实际配置，扫描不存在时返回零值
+func (a *App) GetScanMetadata(scanID string) ScanMetadata {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return ScanMetadata{}
+	}
+	return record.scanMetadata()
+}
@@ -0,0 +1,336 @@
+package portsscanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// MaxMind DB(.mmdb)格式的只读解析，只实现查询ASN和国家所需的部分：
+// 文件末尾的元数据、二叉搜索树和数据区解码。格式说明见 https://maxmind.github.io/MaxMind-DB/
+
+// 元数据起始标记，位于文件最后128KB内
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+const mmdbMetadataMaxSize = 128 * 1024
+
+// 数据区类型
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+var errMMDBCorrupt = errors.New("corrupt MaxMind database")
+
+// mmdbReader 已加载到内存的数据库
+type mmdbReader struct {
+	buf        []byte
+	dbType     string
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	treeSize   uint
+	ipv4Start  uint // IPv6数据库中IPv4地址(::/96)对应的起始节点
+}
+
+// openMMDB 读取并校验数据库文件
+func openMMDB(path string) (*mmdbReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tail := buf
+	if len(tail) > mmdbMetadataMaxSize {
+		tail = tail[len(tail)-mmdbMetadataMaxSize:]
+	}
+	idx := bytes.LastIndex(tail, mmdbMetadataMarker)
+	if idx < 0 {
+		return nil, fmt.Errorf("%s is not a MaxMind database", path)
+	}
+	start := len(buf) - len(tail) + idx + len(mmdbMetadataMarker)
+
+	meta, _, err := (&mmdbDecoder{data: buf[start:]}).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("read MaxMind metadata: %w", err)
+	}
+	m, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, errMMDBCorrupt
+	}
+	r := &mmdbReader{buf: buf}
+	r.dbType, _ = m["database_type"].(string)
+	r.nodeCount = uint(mmdbUint(m["node_count"]))
+	r.recordSize = uint(mmdbUint(m["record_size"]))
+	r.ipVersion = uint(mmdbUint(m["ip_version"]))
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported MaxMind record size %d", r.recordSize)
+	}
+	r.treeSize = r.nodeCount * r.recordSize / 4
+	if r.treeSize+16 > uint(start) {
+		return nil, errMMDBCorrupt
+	}
+	if r.ipVersion == 6 {
+		// 沿96个0位走到IPv4子树
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// record 读取节点的左(bit=0)或右(bit=1)记录
+func (r *mmdbReader) record(node uint, bit int) uint {
+	switch r.recordSize {
+	case 24:
+		off := node*6 + uint(bit)*3
+		b := r.buf[off : off+3]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.buf[node*7 : node*7+7]
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		off := node*8 + uint(bit)*4
+		return uint(binary.BigEndian.Uint32(r.buf[off : off+4]))
+	}
+}
+
+// lookup 查询地址对应的记录，数据库中没有该地址时返回nil
+func (r *mmdbReader) lookup(ip net.IP) (map[string]interface{}, error) {
+	addr := ip.To4()
+	node := uint(0)
+	if addr != nil && r.ipVersion == 6 {
+		node = r.ipv4Start
+	} else if addr == nil {
+		if r.ipVersion != 6 {
+			return nil, nil
+		}
+		addr = ip.To16()
+	}
+	if addr == nil {
+		return nil, nil
+	}
+	for i := 0; i < len(addr)*8 && node < r.nodeCount; i++ {
+		bit := int(addr[i/8]>>(7-uint(i%8))) & 1
+		node = r.record(node, bit)
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, errMMDBCorrupt
+	}
+	offset := node - r.nodeCount - 16
+	dataStart := r.treeSize + 16
+	if dataStart+offset >= uint(len(r.buf)) {
+		return nil, errMMDBCorrupt
+	}
+	d := &mmdbDecoder{data: r.buf[dataStart:]}
+	value, _, err := d.decode(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := value.(map[string]interface{})
+	return m, nil
+}
+
+// mmdbDecoder 解码数据区，指针相对于data起始位置
+type mmdbDecoder struct {
+	data []byte
+}
+
+// 嵌套层数上限，防止损坏的文件导致无限递归
+const mmdbMaxDepth = 32
+
+func (d *mmdbDecoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDepth || offset >= uint(len(d.data)) {
+		return nil, 0, errMMDBCorrupt
+	}
+	ctrl := d.data[offset]
+	offset++
+	typ := int(ctrl >> 5)
+	if typ == mmdbPointer {
+		ptr, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(ptr, depth+1)
+		return value, next, err
+	}
+	if typ == mmdbExtended {
+		if offset >= uint(len(d.data)) {
+			return nil, 0, errMMDBCorrupt
+		}
+		typ = 7 + int(d.data[offset])
+		offset++
+	}
+	size, offset, err := d.size(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch typ {
+	case mmdbMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errMMDBCorrupt
+			}
+			value, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+			offset = next
+		}
+		return m, offset, nil
+	case mmdbArray:
+		list := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			list = append(list, value)
+			offset = next
+		}
+		return list, offset, nil
+	case mmdbBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.data)) {
+		return nil, 0, errMMDBCorrupt
+	}
+	b := d.data[offset : offset+size]
+	next := offset + size
+	switch typ {
+	case mmdbString:
+		return string(b), next, nil
+	case mmdbBytes:
+		return append([]byte(nil), b...), next, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errMMDBCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errMMDBCorrupt
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64, mmdbUint128:
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, next, nil
+	case mmdbInt32:
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int64(int32(v)), next, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported MaxMind data type %d", typ)
+}
+
+// size 解析控制字节中的长度，29-31表示后续1-3个字节的扩展长度
+func (d *mmdbDecoder) size(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1f)
+	if size < 29 {
+		return size, offset, nil
+	}
+	n := size - 28
+	if offset+n > uint(len(d.data)) {
+		return 0, 0, errMMDBCorrupt
+	}
+	var v uint
+	for _, c := range d.data[offset : offset+n] {
+		v = v<<8 | uint(c)
+	}
+	switch size {
+	case 29:
+		v += 29
+	case 30:
+		v += 285
+	default:
+		v += 65821
+	}
+	return v, offset + n, nil
+}
+
+// pointer 解析指针，返回指向的位置和指针之后的位置
+func (d *mmdbDecoder) pointer(ctrl byte, offset uint) (uint, uint, error) {
+	n := uint(ctrl>>3&0x3) + 1
+	if offset+n > uint(len(d.data)) {
+		return 0, 0, errMMDBCorrupt
+	}
+	b := d.data[offset : offset+n]
+	vvv := uint(ctrl & 0x7)
+	var ptr uint
+	switch n {
+	case 1:
+		ptr = vvv<<8 | uint(b[0])
+	case 2:
+		ptr = (vvv<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 3:
+		ptr = (vvv<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	default:
+		ptr = uint(binary.BigEndian.Uint32(b))
+	}
+	return ptr, offset + n, nil
+}
+
+// mmdbUint 将解码出的整数转换为uint64
+func mmdbUint(v interface{}) uint64 {
+	switch n := v.(type) {
+	case uint64:
+		return n
+	case int64:
+		if n > 0 {
+			return uint64(n)
+		}
+	}
+	return 0
+}
+
+// mmdbPath 按路径取嵌套map中的值，如 country.names.en
+func mmdbPath(m map[string]interface{}, keys ...string) interface{} {
+	var v interface{} = m
+	for _, k := range keys {
+		mm, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = mm[k]
+	}
+	return v
+}
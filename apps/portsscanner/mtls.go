@@ -0,0 +1,109 @@
+package portsscanner
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadClientCertificate 加载mTLS探测使用的客户端证书和私钥(PEM)，未配置时返回nil
+func loadClientCertificate(config ScanConfig) (*tls.Certificate, error) {
+	if config.TLSClientCert == "" && config.TLSClientKey == "" {
+		return nil, nil
+	}
+	if config.TLSClientCert == "" || config.TLSClientKey == "" {
+		return nil, fmt.Errorf("TLSClientCert and TLSClientKey must be set together")
+	}
+	cert, err := tls.LoadX509KeyPair(config.TLSClientCert, config.TLSClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS client certificate: %w", err)
+	}
+	return &cert, nil
+}
+
+// probeTLSConfig 探测使用的TLS配置，不校验服务端证书；cert非空时在服务端请求证书时提供
+func probeTLSConfig(cert *tls.Certificate) *tls.Config {
+	config := &tls.Config{InsecureSkipVerify: true}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	return config
+}
+
+// isTLSRejection 判断错误是否为服务端发送的TLS告警(如 certificate required)。
+// TLS 1.3下客户端握手先完成，服务端的拒绝要到首次读写时才出现
+func isTLSRejection(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "remote error: tls:")
+}
+
+// grabTLSBanner 完成TLS握手后读取服务端主动发送的数据，handshaked表示握手是否完成。
+// 读取失败时(包括TLS 1.3下延迟到达的拒绝告警)保留错误供调用方判断
+func grabTLSBanner(ctx context.Context, config ScanConfig, host string, port int, size int, cert *tls.Certificate) (banner []byte, handshaked bool, err error) {
+	raw, err := config.dialProbe(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, false, err
+	}
+	conn := tls.Client(raw, probeTLSConfig(cert))
+	defer conn.Close()
+	timeout := config.probeTimeout(port)
+	handshakeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := conn.HandshakeContext(handshakeCtx); err != nil {
+		return nil, false, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	first := make([]byte, 1)
+	n, err := conn.Read(first)
+	if n == 0 {
+		return nil, true, err
+	}
+	return append(first[:n], readBanner(conn, bannerIdleTimeout, size-n)...), true, nil
+}
+
+// fingerprintMTLS 对未识别的服务尝试以TLS重新探测：先进行普通握手，
+// 服务端要求客户端证书时使用配置的证书完成mTLS握手后再识别HTTP或记录横幅
+func (s *portScanner) fingerprintMTLS(ctx context.Context, h scanTarget, p int, portInfo *PortInfo) {
+	config := s.config
+	if result, err := probeHTTP(ctx, config, h.IP, p, true); err == nil {
+		portInfo.Service = "https"
+		portInfo.TLS = true
+		portInfo.MutualTLS = result.MutualTLS
+		portInfo.Unidentified = false
+		portInfo.HTTPTitle = result.Title
+		portInfo.HTTPServer = result.Server
+		s.captureHTTP(portInfo, result)
+		applyALPN(portInfo, result)
+		s.log(logDebug, "%s:%d TLS重新探测识别为HTTPS (mTLS %v)", h.IP, p, result.MutualTLS)
+		return
+	}
+
+	size := bannerReadLimit(config.BannerReadSize, defaultBannerReadSize)
+	banner, handshaked, err := grabTLSBanner(ctx, config, h.IP, p, size, nil)
+	mutual := false
+	if isTLSRejection(err) {
+		banner, handshaked, err = grabTLSBanner(ctx, config, h.IP, p, size, config.clientCert)
+		mutual = true
+	}
+	// 握手完成但服务端静默直到超时，同样说明端口使用TLS；其他读取错误视为握手被拒绝
+	var netErr net.Error
+	if !handshaked || (err != nil && !(errors.As(err, &netErr) && netErr.Timeout())) {
+		s.log(logDebug, "%s:%d TLS重新探测失败: %v", h.IP, p, err)
+		return
+	}
+	portInfo.Service = "ssl"
+	portInfo.TLS = true
+	portInfo.MutualTLS = mutual
+	if len(banner) > unidentifiedBannerSize {
+		banner = banner[:unidentifiedBannerSize]
+	}
+	if len(banner) > 0 {
+		portInfo.Info = escapeBanner(banner)
+	}
+	s.log(logDebug, "%s:%d TLS重新探测成功 (mTLS %v)，横幅: %q", h.IP, p, mutual, portInfo.Info)
+}
@@ -0,0 +1,90 @@
+package portsscanner
+
+import "strings"
+
+// ScanAggregate 多目标扫描结束时的 scan-aggregate 事件，Hosts按主机分组列出全部结果，没有开放端口的主机也会列出
+type ScanAggregate struct {
+	ScanID     string             `json:"scan_id"`
+	Status     string             `json:"status"` // completed 或 cancelled，取消时结果不完整
+	HostCount  int                `json:"host_count"`
+	HostsOpen  int                `json:"hosts_with_open_ports"`
+	OpenPorts  int                `json:"open_ports"`
+	Unresolved []UnresolvedTarget `json:"unresolved,omitempty"`
+	Hosts      []HostSummary      `json:"hosts"`
+}
+
+// ScanTargets 在一次扫描中扫描多个目标。targets可混合CIDR(192.168.1.0/24)、IP范围(10.0.0.1-10.0.0.50)、
+// 逗号分隔的IP和主机名，主机名先解析，指向同一IP的目标合并为一台主机；ports为端口规格(见ScanPortsSpec)，
+// 为空时扫描最常见的100个端口。所有主机共享maxThreads个并发，按主机发送 host-scan-started 和
+// host-scan-complete 事件，port-found 的Host字段标明所属主机，结束时发送 scan-aggregate 汇总。
+// StopScan、PauseScan和ContinueScan作用于整个任务
+func (a *App) ScanTargets(targets, ports string, maxThreads int) error {
+	if strings.TrimSpace(ports) == "" {
+		ports = topPortsAlias
+	}
+	list, err := parsePortSpec(ports)
+	if err != nil {
+		return err
+	}
+	return a.ScanWithConfig(ScanConfig{
+		Target:         targets,
+		Ports:          list,
+		MaxThreads:     maxThreads,
+		EmitHostEvents: true,
+	})
+}
+
+// hostStarted 启用EmitHostEvents时在主机第一次开始派发端口时发送 host-scan-started 事件
+func (s *portScanner) hostStarted(registry *hostRegistry, h scanTarget) {
+	if !s.config.EmitHostEvents || !registry.announce(h.IP) {
+		return
+	}
+	s.emit("host-scan-started", map[string]interface{}{
+		"host":    h.IP,
+		"aliases": h.Names,
+		"ports":   len(s.config.portsFor(h.IP, s.config.portList())),
+	})
+}
+
+// hostFinished 主机的全部端口扫描完成后调用主机钩子，启用EmitHostEvents时发送 host-scan-complete 事件。
+// 被取消或超时放弃的主机不会调用(见hostRegistry.complete)，由 host-timeout 等事件报告
+func (s *portScanner) hostFinished(h scanTarget, results []PortInfo) {
+	s.runHostHooks(h.IP, results)
+	if !s.config.EmitHostEvents {
+		return
+	}
+	open := []int{}
+	for _, r := range results {
+		if r.State == "" {
+			open = append(open, r.Port)
+		}
+	}
+	s.emit("host-scan-complete", map[string]interface{}{
+		"host":       h.IP,
+		"aliases":    h.Names,
+		"open_ports": open,
+		"results":    len(results),
+	})
+}
+
+// emitAggregate 启用EmitHostEvents时在扫描结束后发送按主机分组的 scan-aggregate 事件
+func (a *App) emitAggregate(config ScanConfig, record *scanRecord, hosts []scanTarget, status string) {
+	if !config.EmitHostEvents {
+		return
+	}
+	summary, _, results := record.snapshot()
+	agg := ScanAggregate{
+		ScanID:     summary.ID,
+		Status:     status,
+		HostCount:  len(hosts),
+		Unresolved: summary.Unresolved,
+		Hosts:      summarizeHosts(hosts, results),
+	}
+	for _, h := range agg.Hosts {
+		if len(h.OpenPorts) > 0 {
+			agg.HostsOpen++
+			agg.OpenPorts += len(h.OpenPorts)
+		}
+	}
+	a.emitEvent("scan-aggregate", agg)
+}
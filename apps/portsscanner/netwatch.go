@@ -0,0 +1,211 @@
+package portsscanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 检查默认路由和网卡地址是否变化的间隔
+const networkCheckInterval = 3 * time.Second
+
+// pauseGate 暂停时阻止派发新的探测，已在进行的探测继续完成
+type pauseGate struct {
+	mu      sync.Mutex
+	resumed chan struct{} // 暂停时非nil，恢复时关闭
+}
+
+// pause 进入暂停状态，已暂停时返回false
+func (g *pauseGate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		return false
+	}
+	g.resumed = make(chan struct{})
+	return true
+}
+
+// resume 解除暂停，未暂停时返回false
+func (g *pauseGate) resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		return false
+	}
+	close(g.resumed)
+	g.resumed = nil
+	return true
+}
+
+// paused 返回是否处于暂停状态，g为nil时始终为false
+func (g *pauseGate) paused() bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed != nil
+}
+
+// wait 暂停期间阻塞直到恢复，ctx取消时返回false
+func (g *pauseGate) wait(ctx context.Context) bool {
+	if g == nil {
+		return ctx.Err() == nil
+	}
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+	if resumed == nil {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-resumed:
+		return true
+	}
+}
+
+// networkState 本机网络环境的快照：已启用网卡的IPv4地址和到目标的出口地址。
+// 不比较IPv6地址，临时地址定期轮换会造成误报
+type networkState struct {
+	Interfaces []string `json:"interfaces"`       // "网卡名 地址"
+	Egress     string   `json:"egress,omitempty"` // 没有到目标的路由时为空
+}
+
+// captureNetworkState 采集当前网络环境，target为用于确定出口地址的目标
+func captureNetworkState(target string) networkState {
+	var state networkState
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+				continue
+			}
+			addrs, err := iface.Addrs()
+			if err != nil {
+				continue
+			}
+			for _, addr := range addrs {
+				if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+					state.Interfaces = append(state.Interfaces, iface.Name+" "+ipNet.IP.String())
+				}
+			}
+		}
+		sort.Strings(state.Interfaces)
+	}
+	state.Egress, _ = egressIP(target)
+	return state
+}
+
+// equal 比较两次快照的网卡地址和出口地址是否相同
+func (n networkState) equal(o networkState) bool {
+	return n.Egress == o.Egress && strings.Join(n.Interfaces, ",") == strings.Join(o.Interfaces, ",")
+}
+
+// watchNetwork 扫描期间定期检查网络环境，默认路由或网卡地址变化时暂停扫描并发送 network-changed 事件，
+// 由用户调用ContinueScan继续或StopScan中止。恢复后以当时的网络环境作为新的基准
+func (a *App) watchNetwork(ctx context.Context, config ScanConfig, record *scanRecord) {
+	if len(config.hosts) == 0 || config.pause == nil {
+		return
+	}
+	target := config.hosts[0].IP
+	baseline := captureNetworkState(target)
+	wasPaused := false
+
+	ticker := time.NewTicker(networkCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if config.pause.paused() {
+			wasPaused = true
+			continue
+		}
+		current := captureNetworkState(target)
+		if wasPaused {
+			baseline, wasPaused = current, false
+			continue
+		}
+		if current.equal(baseline) || !config.pause.pause() {
+			continue
+		}
+		wasPaused = true
+		fmt.Printf("网络环境变化，扫描已暂停: 出口地址 %s -> %s\n", baseline.Egress, current.Egress)
+		record.log.add(logWarn, "网络环境变化，扫描已暂停: 出口地址 %q -> %q，网卡 %v -> %v",
+			baseline.Egress, current.Egress, baseline.Interfaces, current.Interfaces)
+		a.emitEvent("network-changed", map[string]interface{}{
+			"scan_id":  record.summary.ID,
+			"previous": baseline,
+			"current":  current,
+		})
+		a.emitEvent("scan-status", "paused")
+	}
+}
+
+// PauseScan 暂停当前扫描：不再派发新的探测，进行中的探测继续完成，多目标扫描的所有主机一起暂停。
+// 使用ContinueScan继续
+func (a *App) PauseScan() error {
+	scanMutex.Lock()
+	scan := currentScan
+	scanMutex.Unlock()
+	if scan == nil || scan.pause == nil {
+		return errors.New("no running scan")
+	}
+	if !scan.pause.pause() {
+		return errors.New("scan is already paused")
+	}
+	scan.record.log.add(logInfo, "扫描已暂停")
+	a.emitEvent("scan-status", "paused")
+	return nil
+}
+
+// ContinueScan 继续通过PauseScan或因网络变化暂停的扫描。validateEgress为true时先重新确定到各目标的出口地址，
+// 与扫描开始时记录的不一致则返回错误并保持暂停，此时可以StopScan中止或以false强制继续
+func (a *App) ContinueScan(validateEgress bool) error {
+	scanMutex.Lock()
+	scan := currentScan
+	scanMutex.Unlock()
+	if scan == nil || !scan.pause.paused() {
+		return errors.New("no paused scan")
+	}
+
+	summary, config, _ := scan.record.snapshot()
+	if validateEgress {
+		_, current := collectEgress(config.hosts)
+		if !sameStrings(summary.EgressIPs, current) {
+			return fmt.Errorf("egress addresses changed from %v to %v", summary.EgressIPs, current)
+		}
+	}
+	if !scan.pause.resume() {
+		return errors.New("no paused scan")
+	}
+	scan.record.log.add(logInfo, "扫描已继续")
+	a.emitEvent("scan-status", "running")
+	return nil
+}
+
+// sameStrings 判断两个列表包含的元素是否相同，不考虑顺序
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package portsscanner
+
+import (
+	"regexp"
+	"strings"
+)
+
+// productMapping 产品名称到规范名称及CPE(vendor:product)的映射
+type productMapping struct {
+	match   *regexp.Regexp
+	product string
+	vendor  string
+	cpeName string
+}
+
+// 只收录能够确定厂商和产品的常见服务，未命中时CPE留空
+var productMappings = []productMapping{
+	{regexp.MustCompile(`(?i)^nginx`), "nginx", "nginx", "nginx"},
+	{regexp.MustCompile(`(?i)^apache( httpd)?$`), "Apache httpd", "apache", "http_server"},
+	{regexp.MustCompile(`(?i)^apache tomcat`), "Apache Tomcat", "apache", "tomcat"},
+	{regexp.MustCompile(`(?i)^(microsoft[- ])?iis`), "Microsoft IIS httpd", "microsoft", "internet_information_services"},
+	{regexp.MustCompile(`(?i)^lighttpd`), "lighttpd", "lighttpd", "lighttpd"},
+	{regexp.MustCompile(`(?i)^jetty`), "Jetty", "eclipse", "jetty"},
+	{regexp.MustCompile(`(?i)^caddy`), "Caddy", "caddyserver", "caddy"},
+	{regexp.MustCompile(`(?i)^openssh`), "OpenSSH", "openbsd", "openssh"},
+	{regexp.MustCompile(`(?i)^dropbear`), "Dropbear sshd", "dropbear_ssh_project", "dropbear_ssh"},
+	{regexp.MustCompile(`(?i)^vsftpd`), "vsftpd", "beasts", "vsftpd"},
+	{regexp.MustCompile(`(?i)^proftpd`), "ProFTPD", "proftpd", "proftpd"},
+	{regexp.MustCompile(`(?i)^pure-ftpd`), "Pure-FTPd", "pureftpd", "pure-ftpd"},
+	{regexp.MustCompile(`(?i)^filezilla`), "FileZilla ftpd", "filezilla-project", "filezilla_server"},
+	{regexp.MustCompile(`(?i)^mysql`), "MySQL", "oracle", "mysql"},
+	{regexp.MustCompile(`(?i)^mariadb`), "MariaDB", "mariadb", "mariadb"},
+	{regexp.MustCompile(`(?i)^postgresql`), "PostgreSQL", "postgresql", "postgresql"},
+	{regexp.MustCompile(`(?i)^microsoft sql server`), "Microsoft SQL Server", "microsoft", "sql_server"},
+	{regexp.MustCompile(`(?i)^redis`), "Redis", "redis", "redis"},
+	{regexp.MustCompile(`(?i)^mongodb`), "MongoDB", "mongodb", "mongodb"},
+	{regexp.MustCompile(`(?i)^memcached`), "Memcached", "memcached", "memcached"},
+	{regexp.MustCompile(`(?i)^elasticsearch`), "Elasticsearch", "elastic", "elasticsearch"},
+	{regexp.MustCompile(`(?i)^rabbitmq`), "RabbitMQ", "vmware", "rabbitmq"},
+	{regexp.MustCompile(`(?i)^postfix`), "Postfix smtpd", "postfix", "postfix"},
+	{regexp.MustCompile(`(?i)^exim`), "Exim smtpd", "exim", "exim"},
+	{regexp.MustCompile(`(?i)^dovecot`), "Dovecot", "dovecot", "dovecot"},
+	{regexp.MustCompile(`(?i)^samba`), "Samba smbd", "samba", "samba"},
+}
+
+// 匹配 "nginx/1.18.0"、"Apache/2.4.41 (Ubuntu)" 这类 产品/版本 写法
+var productVersionRegexp = regexp.MustCompile(`^([A-Za-z][\w .-]*?)/v?(\d[\w.-]*)`)
+
+// normalizeProduct 将横幅中的产品信息拆分为规范的产品名和版本，并在能确定映射时生成CPE
+func normalizeProduct(portInfo *PortInfo) {
+	product := strings.TrimSpace(portInfo.ProductName)
+	version := strings.TrimSpace(portInfo.Version)
+
+	// 指纹未识别出产品时，尝试使用HTTP Server头
+	if product == "" && portInfo.HTTPServer != "" {
+		product = strings.TrimSpace(portInfo.HTTPServer)
+	}
+	if product == "" {
+		return
+	}
+
+	if m := productVersionRegexp.FindStringSubmatch(product); m != nil {
+		product = strings.TrimSpace(m[1])
+		if version == "" {
+			version = m[2]
+		}
+	}
+
+	for _, mapping := range productMappings {
+		if !mapping.match.MatchString(product) {
+			continue
+		}
+		portInfo.ProductName = mapping.product
+		portInfo.Version = version
+		portInfo.CPE = "cpe:/a:" + mapping.vendor + ":" + mapping.cpeName
+		if version != "" {
+			portInfo.CPE += ":" + strings.ToLower(version)
+		}
+		return
+	}
+
+	portInfo.ProductName = product
+	portInfo.Version = version
+}
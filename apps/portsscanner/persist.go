@@ -0,0 +1,208 @@
+package portsscanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// 当前扫描记录的存储格式版本，修改存储结构时需要同时追加迁移
+const storeSchemaVersion = 1
+
+// 记录存储格式版本的文件名
+const storeSchemaFile = "schema.json"
+
+// storedScan 单次扫描的存储结构，以JSON保存在存储后端中
+type storedScan struct {
+	SchemaVersion int         `json:"schema_version"`
+	Scan          ScanSummary `json:"scan"`
+	Config        ScanConfig  `json:"config"`
+	Results       []PortInfo  `json:"results"`
+
+	Checkpoint []HostCheckpoint `json:"checkpoint,omitempty"` // 每台主机的完成进度，用于续扫
+	Metadata   *ScanMetadata    `json:"metadata,omitempty"`   // 扫描的运行环境，旧记录没有该字段
+	Evidence   []Evidence       `json:"evidence,omitempty"`   // CaptureEvidence 收集的原始证据
+	Timeline   *storedTimeline  `json:"timeline,omitempty"`   // 扫描过程中的事件时间线
+}
+
+// storeMigration 将存储记录从上一版本升级到version，直接操作解码后的JSON对象，
+// 不依赖当时的结构体定义；新增的可选字段无需迁移，加载时保持零值即可
+type storeMigration struct {
+	version     int
+	description string
+	apply       func(doc map[string]interface{}) error
+}
+
+// storeMigrations 按版本号递增排列
+var storeMigrations = []storeMigration{
+	{1, "为未带版本号的记录补齐 results 字段", func(doc map[string]interface{}) error {
+		if doc["results"] == nil {
+			doc["results"] = []interface{}{}
+		}
+		return nil
+	}},
+}
+
+// storeDir 返回扫描记录的存储目录(~/GlideWay/scans)，不存在时自动创建
+func storeDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	dir := filepath.Join(home, "GlideWay", "scans")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create scan store directory: %w", err)
+	}
+	return dir, nil
+}
+
+// dataFilePath 返回 ~/GlideWay 下的设置文件路径，目录不存在时自动创建
+func dataFilePath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	dir := filepath.Join(home, "GlideWay")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// upgradeStoredScan 对单条记录依次执行尚未应用的迁移，返回记录原来的版本
+func upgradeStoredScan(doc map[string]interface{}) (int, error) {
+	from := 0
+	if v, ok := doc["schema_version"].(float64); ok {
+		from = int(v)
+	}
+	if from > storeSchemaVersion {
+		return from, fmt.Errorf("scan record uses schema version %d, this build supports up to %d", from, storeSchemaVersion)
+	}
+	for _, m := range storeMigrations {
+		if m.version <= from {
+			continue
+		}
+		if err := m.apply(doc); err != nil {
+			return from, fmt.Errorf("migration to schema version %d failed: %w", m.version, err)
+		}
+	}
+	doc["schema_version"] = storeSchemaVersion
+	return from, nil
+}
+
+// decodeStoredScan 解码存储记录，旧版本的记录在内存中透明升级，返回是否发生了升级
+func decodeStoredScan(data []byte) (*storedScan, bool, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false, err
+	}
+	from, err := upgradeStoredScan(doc)
+	if err != nil {
+		return nil, false, err
+	}
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false, err
+	}
+	var scan storedScan
+	if err := json.Unmarshal(upgraded, &scan); err != nil {
+		return nil, false, err
+	}
+	return &scan, from != storeSchemaVersion, nil
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免写入中断导致记录损坏
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// recordFromStored 由存储记录恢复扫描记录，进程退出时仍在运行的扫描视为中断，可通过ResumeScan续扫
+func recordFromStored(scan *storedScan) *scanRecord {
+	if scan.Scan.Status == "running" {
+		scan.Scan.Status = "interrupted"
+	}
+	scan.Scan.ResultsTimestamp = scan.Scan.resultsTime()
+	record := &scanRecord{summary: scan.Scan, config: scan.Config, results: scan.Results}
+	if scan.Metadata != nil {
+		record.metadata = *scan.Metadata
+	}
+	if scan.Checkpoint != nil {
+		record.checkpoint = restoreCheckpoint(scan.Checkpoint)
+	}
+	record.restoreEvidence(scan.Evidence)
+	record.timeline.restore(scan.Timeline)
+	return record
+}
+
+// loadStoredRecord 从存储后端读取并解码单条扫描记录
+func loadStoredRecord(store ResultStore, id string) (*scanRecord, error) {
+	stored, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	scan, _, err := decodeStoredScan(stored.Data)
+	if err != nil {
+		return nil, err
+	}
+	if scan.Scan.ID == "" {
+		return nil, fmt.Errorf("scan record %q has no id", id)
+	}
+	return recordFromStored(scan), nil
+}
+
+// loadStoredScans 将存储后端中的历史扫描加载到内存
+func loadStoredScans() error {
+	store, err := activeStore()
+	if err != nil {
+		return err
+	}
+	summaries, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	for _, summary := range summaries {
+		if _, exists := scanStore[summary.ID]; exists {
+			continue
+		}
+		record, err := loadStoredRecord(store, summary.ID)
+		if err != nil {
+			fmt.Printf("跳过无法加载的扫描记录 %s: %v\n", summary.ID, err)
+			continue
+		}
+		scanStore[summary.ID] = record
+	}
+	return nil
+}
+
+// saveScanRecord 将扫描记录以当前格式写入存储后端
+func saveScanRecord(record *scanRecord) error {
+	store, err := activeStore()
+	if err != nil {
+		return err
+	}
+	summary, config, results := record.snapshot()
+	metadata := record.scanMetadata()
+	metadata.Config = ScanConfig{} // 配置已单独保存
+	data, err := json.Marshal(storedScan{
+		SchemaVersion: storeSchemaVersion,
+		Scan:          summary,
+		Config:        config,
+		Results:       results,
+		Checkpoint:    record.checkpointSnapshot(),
+		Metadata:      &metadata,
+		Evidence:      record.evidenceSnapshot(),
+		Timeline:      record.timeline.stored(),
+	})
+	if err != nil {
+		return err
+	}
+	return store.Save(StoredRecord{Summary: summary, Data: data})
+}
@@ -0,0 +1,243 @@
+package portsscanner
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 各阶段在整体进度中的权重，只统计本次扫描启用的阶段并按比例折算
+var phaseWeights = map[string]float64{
+	StageDiscovery:   10,
+	StageConnect:     60,
+	StageFingerprint: 30,
+}
+
+// PhaseProgress 单个阶段的进度。fingerprint阶段的Total随着发现开放端口增长
+type PhaseProgress struct {
+	Phase    string  `json:"phase"`
+	Done     int64   `json:"done"`
+	Total    int64   `json:"total"`
+	Percent  float64 `json:"percent"`
+	Complete bool    `json:"complete"`
+}
+
+// ScanPhaseProgress 多阶段扫描的整体进度，随 scan-phase-progress 事件发送
+type ScanPhaseProgress struct {
+	ScanID  string          `json:"scan_id,omitempty"`
+	Phase   string          `json:"phase"`   // 当前阶段，结束后为 completed、cancelled 或 error
+	Percent float64         `json:"percent"` // 按阶段权重加权的整体进度
+	Phases  []PhaseProgress `json:"phases"`
+}
+
+// phaseTracker 统计流水线各阶段的进度。连接完成的端口数由扫描线程计数，
+// 开放端口在连接阶段计入fingerprint的总数，识别结束后计入完成数。方法允许nil接收者
+type phaseTracker struct {
+	stages []string
+
+	discoveryTotal  atomic.Int64
+	discoveryDone   atomic.Int64
+	discoveryClosed atomic.Bool
+
+	connectDone atomic.Int64
+	fpTotal     atomic.Int64
+	fpDone      atomic.Int64
+
+	mu          sync.Mutex
+	scanID      string
+	connectSize func() int64 // 连接阶段的端口总数，扫描开始前为nil
+	finished    string       // 扫描结束时的状态
+}
+
+// newPhaseTracker 按本次扫描实际执行的阶段创建进度统计
+func newPhaseTracker(config ScanConfig) *phaseTracker {
+	t := &phaseTracker{}
+	if config.runsDiscovery() {
+		t.stages = append(t.stages, StageDiscovery)
+	}
+	t.stages = append(t.stages, StageConnect)
+	if config.runsFingerprint() {
+		t.stages = append(t.stages, StageFingerprint)
+	}
+	return t
+}
+
+func (t *phaseTracker) startDiscovery(hosts int) {
+	if t != nil {
+		t.discoveryTotal.Store(int64(hosts))
+	}
+}
+
+func (t *phaseTracker) hostDiscovered() {
+	if t != nil {
+		t.discoveryDone.Add(1)
+	}
+}
+
+func (t *phaseTracker) discoveryFinished() {
+	if t != nil {
+		t.discoveryClosed.Store(true)
+	}
+}
+
+// attach 在连接阶段开始时记录扫描ID和端口总数的来源
+func (t *phaseTracker) attach(scanID string, connectSize func() int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.scanID = scanID
+	t.connectSize = connectSize
+}
+
+// connected 端口探测结束，开放端口先计入指纹识别总数再计入连接完成数，
+// 保证连接阶段完成时指纹识别的总数已经确定
+func (t *phaseTracker) connected(open bool) {
+	if t == nil {
+		return
+	}
+	if open {
+		t.fpTotal.Add(1)
+	}
+	t.connectDone.Add(1)
+}
+
+func (t *phaseTracker) fingerprinted() {
+	if t != nil {
+		t.fpDone.Add(1)
+	}
+}
+
+// finish 记录扫描结束时的状态，之后的快照中所有阶段视为结束
+func (t *phaseTracker) finish(status string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished = status
+}
+
+// snapshot 计算当前阶段和加权整体进度。fingerprint阶段在整体进度中按连接阶段的完成比例折算，
+// 避免连接阶段尚未结束时因为已发现的端口全部识别完而显示满格
+func (t *phaseTracker) snapshot() ScanPhaseProgress {
+	t.mu.Lock()
+	scanID, connectSize, finished := t.scanID, t.connectSize, t.finished
+	t.mu.Unlock()
+
+	progress := ScanPhaseProgress{ScanID: scanID, Phases: make([]PhaseProgress, 0, len(t.stages))}
+	var connectFraction, weighted, weights float64
+	for _, stage := range t.stages {
+		p := PhaseProgress{Phase: stage}
+		switch stage {
+		case StageDiscovery:
+			p.Done, p.Total = t.discoveryDone.Load(), t.discoveryTotal.Load()
+			p.Complete = t.discoveryClosed.Load()
+		case StageConnect:
+			p.Done = t.connectDone.Load()
+			if connectSize != nil {
+				p.Total = connectSize()
+				p.Complete = p.Done >= p.Total
+			}
+		case StageFingerprint:
+			p.Done, p.Total = t.fpDone.Load(), t.fpTotal.Load()
+			p.Complete = connectFraction >= 1 && p.Done >= p.Total
+		}
+		p.Complete = p.Complete || finished != ""
+
+		fraction := 0.0
+		switch {
+		case p.Complete:
+			fraction = 1
+		case p.Total > 0:
+			fraction = min(float64(p.Done)/float64(p.Total), 1)
+		}
+		p.Percent = fraction * 100
+		if stage == StageConnect {
+			connectFraction = fraction
+		}
+		if stage == StageFingerprint && !p.Complete {
+			if p.Total == 0 {
+				fraction = 0
+			}
+			fraction *= connectFraction
+		}
+		weighted += fraction * phaseWeights[stage]
+		weights += phaseWeights[stage]
+
+		if progress.Phase == "" && !p.Complete {
+			progress.Phase = stage
+		}
+		progress.Phases = append(progress.Phases, p)
+	}
+	if weights > 0 {
+		progress.Percent = weighted * 100 / weights
+	}
+	switch {
+	case finished != "":
+		progress.Phase = finished
+	case progress.Phase == "":
+		progress.Phase = t.stages[len(t.stages)-1]
+	}
+	return progress
+}
+
+// sameProgress 进度没有变化时不重复发送
+func sameProgress(a, b ScanPhaseProgress) bool {
+	if a.Phase != b.Phase || a.ScanID != b.ScanID || len(a.Phases) != len(b.Phases) {
+		return false
+	}
+	for i := range a.Phases {
+		if a.Phases[i] != b.Phases[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// run 定期发送 scan-phase-progress 事件，当前阶段变化时先发送 scan-phase-changed。
+// 返回的stop函数发送最后一次进度后返回，可重复调用
+func (t *phaseTracker) run(emit EventFunc) (stop func()) {
+	if t == nil || emit == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	var last ScanPhaseProgress
+	send := func() {
+		current := t.snapshot()
+		if sameProgress(current, last) {
+			return
+		}
+		if current.Phase != last.Phase {
+			emit("scan-phase-changed", map[string]interface{}{
+				"scan_id": current.ScanID,
+				"from":    last.Phase,
+				"to":      current.Phase,
+			})
+		}
+		emit("scan-phase-progress", current)
+		last = current
+	}
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(progressEmitInterval)
+		defer ticker.Stop()
+		send()
+		for {
+			select {
+			case <-done:
+				send()
+				return
+			case <-ticker.C:
+				send()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
@@ -0,0 +1,53 @@
+package portsscanner
+
+import "context"
+
+// 连接线程与指纹线程池之间的队列长度，写满时连接线程等待
+const fingerprintQueueSize = 1024
+
+// fingerprintJob 等待指纹识别的开放端口
+type fingerprintJob struct {
+	ctx  context.Context
+	host scanTarget
+	port int
+	info *PortInfo
+}
+
+// startFingerprintPool 启动固定数量的指纹识别线程
+func (s *portScanner) startFingerprintPool(registry *hostRegistry, threads int) {
+	s.fpQueue = make(chan fingerprintJob, fingerprintQueueSize)
+	for i := 0; i < threads; i++ {
+		s.fpWG.Add(1)
+		go func() {
+			defer s.fpWG.Done()
+			for job := range s.fpQueue {
+				s.runFingerprintJob(registry, job)
+			}
+		}()
+	}
+}
+
+func (s *portScanner) runFingerprintJob(registry *hostRegistry, job fingerprintJob) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			s.portPanicked(job.host, job.port, StageFingerprint, r, job.info)
+		}
+		s.config.phases.fingerprinted()
+		s.portDone(registry, job.ctx, job.host, job.port, r != nil)
+	}()
+	// 排队期间被取消的端口同样已确认开放，由identify跳过识别直接报告
+	s.config.pause.wait(job.ctx)
+	s.identify(job.ctx, job.host, job.port, job.info)
+}
+
+// closeFingerprintPool 所有连接线程结束后调用，等待队列中的指纹识别完成
+func (s *portScanner) closeFingerprintPool() {
+	if s.fpQueue == nil {
+		return
+	}
+	s.fpCloseOnce.Do(func() {
+		close(s.fpQueue)
+	})
+	s.fpWG.Wait()
+}
@@ -0,0 +1,279 @@
+package portsscanner
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 未指定端口时矩阵自动选取的列数，按开放主机数从多到少选取
+const defaultMatrixColumns = 20
+
+// 矩阵中端口未在本次扫描中探测(或导入的扫描没有端口列表)时的单元格
+const matrixNotScanned = "-"
+
+//go:embed portmatrix.html.tmpl
+var matrixTemplateText string
+
+var matrixTemplate = template.Must(template.New("matrix").Funcs(template.FuncMap{
+	"fmtTime": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Local().Format("2006-01-02 15:04:05")
+	},
+	"cellClass": func(state string) string {
+		switch state {
+		case portOpen:
+			return "open"
+		case portClosed:
+			return "closed"
+		case matrixNotScanned:
+			return "none"
+		}
+		return "filtered"
+	},
+}).Parse(matrixTemplateText))
+
+// matrixKey 矩阵中与主机无关的端口和协议
+type matrixKey struct {
+	port     int
+	protocol string
+}
+
+// matrixColumn 矩阵的一列，即一个端口和协议
+type matrixColumn struct {
+	Port     int
+	Protocol string
+	Open     int // 该端口开放的主机数
+}
+
+func (c matrixColumn) String() string {
+	return strconv.Itoa(c.Port) + "/" + c.Protocol
+}
+
+// matrixRow 矩阵的一行，Cells与列一一对应
+type matrixRow struct {
+	Host  string
+	Name  string
+	Cells []string
+	Open  int
+}
+
+// portMatrix 主机与端口的状态矩阵
+type portMatrix struct {
+	Summary   ScanSummary
+	Generated time.Time
+	Columns   []matrixColumn
+	Rows      []matrixRow
+}
+
+// scannedProtocols 扫描类型探测的协议
+func scannedProtocols(scanType string) []string {
+	switch scanType {
+	case ScanTypeUDP:
+		return []string{"udp"}
+	case ScanTypeBoth:
+		return []string{"tcp", "udp"}
+	}
+	return []string{"tcp"}
+}
+
+// matrixColumns 确定矩阵的列：指定了端口时按给定顺序，每个端口使用结果中出现的协议(没有结果时为扫描的协议)；
+// 未指定时选取开放主机最多的defaultMatrixColumns个端口，按端口号排列
+func matrixColumns(config ScanConfig, results []PortInfo, ports []int) []matrixColumn {
+	open := make(map[matrixKey]map[string]bool)
+	var seen []matrixKey
+	for _, r := range results {
+		key := matrixKey{r.Port, r.Protocol}
+		if open[key] == nil {
+			open[key] = make(map[string]bool)
+			seen = append(seen, key)
+		}
+		if r.State == "" {
+			open[key][r.Host] = true
+		}
+	}
+
+	var columns []matrixColumn
+	if len(ports) > 0 {
+		added := make(map[matrixKey]bool)
+		for _, p := range ports {
+			var protocols []string
+			for _, proto := range []string{"tcp", "udp"} {
+				if _, ok := open[matrixKey{p, proto}]; ok {
+					protocols = append(protocols, proto)
+				}
+			}
+			if len(protocols) == 0 {
+				protocols = scannedProtocols(config.ScanType)
+			}
+			for _, proto := range protocols {
+				key := matrixKey{p, proto}
+				if added[key] {
+					continue
+				}
+				added[key] = true
+				columns = append(columns, matrixColumn{Port: p, Protocol: proto, Open: len(open[key])})
+			}
+		}
+		return columns
+	}
+
+	for _, key := range seen {
+		if n := len(open[key]); n > 0 {
+			columns = append(columns, matrixColumn{Port: key.port, Protocol: key.protocol, Open: n})
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		if columns[i].Open != columns[j].Open {
+			return columns[i].Open > columns[j].Open
+		}
+		return columns[i].Port < columns[j].Port
+	})
+	if len(columns) > defaultMatrixColumns {
+		columns = columns[:defaultMatrixColumns]
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		if columns[i].Port != columns[j].Port {
+			return columns[i].Port < columns[j].Port
+		}
+		return columns[i].Protocol < columns[j].Protocol
+	})
+	return columns
+}
+
+// buildPortMatrix 生成主机与端口的状态矩阵。行是结果中出现的主机；单元格为 open、结果记录的状态
+// (如 filtered、open|filtered)、closed(端口已探测但未开放，扫描不区分关闭和无响应)，
+// 或 "-"(该端口未在本次扫描中探测)
+func buildPortMatrix(summary ScanSummary, config ScanConfig, results []PortInfo, ports []int, now time.Time) portMatrix {
+	columns := matrixColumns(config, results, ports)
+
+	// 只有保存了端口列表的扫描才能判断未开放的端口是否被探测过，导入的扫描没有端口列表
+	scanned := make(map[matrixKey]bool)
+	if len(config.Ports) > 0 {
+		for _, proto := range scannedProtocols(config.ScanType) {
+			for _, p := range config.Ports {
+				scanned[matrixKey{p, proto}] = true
+			}
+		}
+	}
+
+	states := make(map[string]map[matrixKey]string)
+	aliases := make(map[string][]string)
+	var hosts []string
+	for _, r := range results {
+		if states[r.Host] == nil {
+			states[r.Host] = make(map[matrixKey]string)
+			hosts = append(hosts, r.Host)
+		}
+		if len(r.Aliases) > 0 {
+			aliases[r.Host] = r.Aliases
+		}
+		key := matrixKey{r.Port, r.Protocol}
+		state := r.State
+		if state == "" {
+			state = portOpen
+		}
+		// 同一端口有多条结果(如端点扫描)时以开放为准
+		if prev, ok := states[r.Host][key]; !ok || prev != portOpen {
+			states[r.Host][key] = state
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return compareHosts(hosts[i], hosts[j]) < 0 })
+
+	m := portMatrix{Summary: summary, Generated: now, Columns: columns, Rows: make([]matrixRow, 0, len(hosts))}
+	for _, host := range hosts {
+		row := matrixRow{Host: host, Name: textHostName(host, aliases[host]), Cells: make([]string, len(columns))}
+		for i, c := range columns {
+			key := matrixKey{c.Port, c.Protocol}
+			state, ok := states[host][key]
+			switch {
+			case ok:
+			case scanned[key]:
+				state = portClosed
+			default:
+				state = matrixNotScanned
+			}
+			if state == portOpen {
+				row.Open++
+			}
+			row.Cells[i] = state
+		}
+		m.Rows = append(m.Rows, row)
+	}
+	return m
+}
+
+// writeMatrixCSV 写出矩阵的CSV，每行一台主机，最后一列为该主机在所选端口中开放的数量
+func writeMatrixCSV(sb *strings.Builder, m portMatrix) error {
+	w := csv.NewWriter(sb)
+	header := []string{"host"}
+	for _, c := range m.Columns {
+		header = append(header, c.String())
+	}
+	header = append(header, "open")
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, r := range m.Rows {
+		row := append([]string{r.Name}, r.Cells...)
+		row = append(row, strconv.Itoa(r.Open))
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// ExportPortMatrix 将扫描结果导出为主机×端口的状态矩阵(format为csv或html)，便于一眼看出哪些主机暴露了相同的端口。
+// ports指定作为列的端口，为空时自动选取开放主机最多的端口
+func (a *App) ExportPortMatrix(scanID string, ports []int, path, format string) error {
+	if path == "" {
+		return fmt.Errorf("export path is empty")
+	}
+	for _, p := range ports {
+		if p < 1 || p > 65535 {
+			return fmt.Errorf("invalid port %d", p)
+		}
+	}
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return err
+	}
+	summary, config, results := record.snapshot()
+	m := buildPortMatrix(summary, config, results, ports, time.Now())
+	if len(m.Columns) == 0 {
+		return fmt.Errorf("scan %q has no open ports to build a matrix from", scanID)
+	}
+
+	var data []byte
+	switch strings.ToLower(format) {
+	case "csv":
+		var sb strings.Builder
+		if err := writeMatrixCSV(&sb, m); err != nil {
+			return err
+		}
+		data = []byte(sb.String())
+	case "html":
+		var buf bytes.Buffer
+		if err := matrixTemplate.Execute(&buf, m); err != nil {
+			return fmt.Errorf("failed to render port matrix: %w", err)
+		}
+		data = buf.Bytes()
+	default:
+		return fmt.Errorf("unsupported matrix format %q (want csv or html)", format)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("%d 台主机 × %d 个端口的矩阵已导出到 %s\n", len(m.Rows), len(m.Columns), path)
+	return nil
+}
@@ -0,0 +1,161 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// 默认至少这么多个连续端口指纹相同时合并为一个范围
+	defaultCollapseThreshold = 5
+	// CollapseThreshold 的下限，两个端口不值得合并
+	minCollapseThreshold = 3
+)
+
+// PortRange 同一主机上指纹相同的一段连续端口，Ports列出范围内实际开放的端口
+type PortRange struct {
+	Host        string `json:"host"`
+	Protocol    string `json:"protocol"`
+	StartPort   int    `json:"start_port"`
+	EndPort     int    `json:"end_port"`
+	Count       int    `json:"count"`
+	Ports       []int  `json:"ports"`
+	Service     string `json:"service"`
+	ProductName string `json:"product_name,omitempty"`
+	Version     string `json:"version,omitempty"`
+	TLS         bool   `json:"tls,omitempty"`
+	HTTPTitle   string `json:"http_title,omitempty"`
+}
+
+// collapseSettings 返回生效的合并阈值和允许的最大端口间隔
+func (c ScanConfig) collapseSettings() (threshold, gap int) {
+	threshold = c.CollapseThreshold
+	if threshold <= 0 {
+		threshold = defaultCollapseThreshold
+	}
+	return max(threshold, minCollapseThreshold), max(c.CollapseMaxGap, 1)
+}
+
+// rangeFingerprint 判断相邻端口是否为同一服务的实例，只比较与端口无关的指纹字段
+func rangeFingerprint(r PortInfo) string {
+	return strings.Join([]string{
+		r.Protocol, r.Service, r.ProductName, r.Version, fmt.Sprint(r.TLS), r.HTTPTitle, r.HTTPServer,
+	}, "\x00")
+}
+
+// collapsePortRanges 按主机和协议查找指纹相同、相邻端口间隔不超过gap的连续端口，
+// 达到threshold个端口的段作为一个范围返回。未确认开放和没有识别出服务的端口不参与合并
+func collapsePortRanges(results []PortInfo, threshold, gap int) []PortRange {
+	type group struct{ host, protocol string }
+	byGroup := make(map[group][]PortInfo)
+	for _, r := range results {
+		if r.State != "" || r.Service == "" || r.Unidentified {
+			continue
+		}
+		g := group{r.Host, r.Protocol}
+		byGroup[g] = append(byGroup[g], r)
+	}
+
+	var ranges []PortRange
+	flush := func(run []PortInfo) {
+		if len(run) < threshold {
+			return
+		}
+		first := run[0]
+		pr := PortRange{
+			Host:        first.Host,
+			Protocol:    first.Protocol,
+			StartPort:   first.Port,
+			EndPort:     run[len(run)-1].Port,
+			Count:       len(run),
+			Service:     first.Service,
+			ProductName: first.ProductName,
+			Version:     first.Version,
+			TLS:         first.TLS,
+			HTTPTitle:   first.HTTPTitle,
+		}
+		for _, r := range run {
+			pr.Ports = append(pr.Ports, r.Port)
+		}
+		ranges = append(ranges, pr)
+	}
+	for _, ports := range byGroup {
+		sort.Slice(ports, func(i, j int) bool { return ports[i].Port < ports[j].Port })
+		var run []PortInfo
+		for _, r := range ports {
+			if len(run) > 0 {
+				last := run[len(run)-1]
+				if r.Port-last.Port > gap || rangeFingerprint(r) != rangeFingerprint(last) {
+					flush(run)
+					run = nil
+				}
+			}
+			run = append(run, r)
+		}
+		flush(run)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		if c := compareHosts(ranges[i].Host, ranges[j].Host); c != 0 {
+			return c < 0
+		}
+		if ranges[i].Protocol != ranges[j].Protocol {
+			return ranges[i].Protocol < ranges[j].Protocol
+		}
+		return ranges[i].StartPort < ranges[j].StartPort
+	})
+	return ranges
+}
+
+// emitPortRanges 扫描完成后合并端口范围，每个范围发送一次 port-range-found 事件
+func (a *App) emitPortRanges(config ScanConfig, record *scanRecord) {
+	threshold, gap := config.collapseSettings()
+	_, _, results := record.snapshot()
+	ranges := collapsePortRanges(results, threshold, gap)
+	for _, pr := range ranges {
+		a.emitEvent("port-range-found", pr)
+	}
+	if len(ranges) > 0 {
+		record.log.add(logInfo, "合并 %d 个指纹相同的连续端口范围", len(ranges))
+	}
+}
+
+// GetPortRanges 按扫描时的CollapseThreshold和CollapseMaxGap设置合并扫描结果中的连续端口范围，
+// 扫描时未启用CollapsePortRanges也可以调用
+func (a *App) GetPortRanges(scanID string) ([]PortRange, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return nil, err
+	}
+	_, config, results := record.snapshot()
+	threshold, gap := config.collapseSettings()
+	ranges := collapsePortRanges(results, threshold, gap)
+	if ranges == nil {
+		ranges = []PortRange{}
+	}
+	return ranges, nil
+}
+
+// ExpandPortRange 返回合并范围内每个端口的完整结果，用于在界面上展开范围
+func (a *App) ExpandPortRange(scanID, host, protocol string, startPort, endPort int) ([]PortInfo, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return nil, err
+	}
+	if startPort > endPort {
+		return nil, fmt.Errorf("invalid port range %d-%d", startPort, endPort)
+	}
+	if ip, ok := canonicalIP(host); ok {
+		host = ip
+	}
+	_, _, results := record.snapshot()
+	ports := []PortInfo{}
+	for _, r := range results {
+		if r.Host == host && strings.EqualFold(r.Protocol, protocol) && r.Port >= startPort && r.Port <= endPort {
+			ports = append(ports, r)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Port < ports[j].Port })
+	return ports, nil
+}
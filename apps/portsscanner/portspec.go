@@ -0,0 +1,81 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 端口规格中代表最常见端口列表(topTCPPorts)的名称
+const topPortsAlias = "top-ports"
+
+// parsePortSpec 解析nmap风格的端口规格，如 "22,80,443,8000-8100,top-ports"，
+// 返回去重并排序后的端口列表。空项、起点大于终点的范围和超出1-65535的端口都会报错
+func parsePortSpec(spec string) ([]int, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, fmt.Errorf("port specification is empty")
+	}
+	seen := make(map[int]bool)
+	for _, token := range strings.Split(spec, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			return nil, fmt.Errorf("invalid port specification %q: empty entry", spec)
+		}
+		if strings.EqualFold(token, topPortsAlias) {
+			for _, p := range topTCPPorts {
+				seen[p] = true
+			}
+			continue
+		}
+		lo, hi, isRange := strings.Cut(token, "-")
+		start, err := parseSpecPort(lo, token)
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if isRange {
+			if end, err = parseSpecPort(hi, token); err != nil {
+				return nil, err
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid port range %q: start is greater than end", token)
+			}
+		}
+		for p := start; p <= end; p++ {
+			seen[p] = true
+		}
+	}
+	ports := make([]int, 0, len(seen))
+	for p := range seen {
+		ports = append(ports, p)
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
+
+// parseSpecPort 解析端口规格中的单个端口号
+func parseSpecPort(s, token string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid port %q in %q", s, token)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port %d in %q is out of range 1-65535", port, token)
+	}
+	return port, nil
+}
+
+// ScanPortsSpec 按端口规格扫描，如 "22,80,443,8000-8100,3306"，"top-ports" 展开为最常见的100个端口。
+// 规格无效时在扫描开始前返回错误，进度按实际端口数计算
+func (a *App) ScanPortsSpec(IP string, portSpec string, maxThreads int) error {
+	ports, err := parsePortSpec(portSpec)
+	if err != nil {
+		return err
+	}
+	return a.ScanWithConfig(ScanConfig{
+		Target:     IP,
+		Ports:      ports,
+		MaxThreads: maxThreads,
+	})
+}
@@ -0,0 +1,176 @@
+package portsscanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// 未设置PostScanTimeout时扫描后命令的最长运行时间
+	defaultPostScanTimeout = 5 * time.Minute
+	// post-scan-output 事件中最多携带的输出字节数
+	maxPostScanOutput = 64 << 10
+)
+
+// splitCommandLine 按空白拆分命令行，支持单引号和双引号包含空格的参数
+func splitCommandLine(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command %q", s)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("post-scan command is empty")
+	}
+	return args, nil
+}
+
+// limitedBuffer 只保留前limit字节的输出
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// postScanResultFile 返回传给扫描后命令的结果文件：file后端直接使用扫描记录文件，其他后端没有对应文件，
+// 将存储的记录(与file后端格式相同)写入临时文件，命令结束后调用cleanup删除
+func postScanResultFile(scanID string) (path string, cleanup func(), err error) {
+	if path := storedScanPath(scanID); path != "" {
+		return path, func() {}, nil
+	}
+	store, err := activeStore()
+	if err != nil {
+		return "", nil, err
+	}
+	stored, err := store.Get(scanID)
+	if err != nil {
+		return "", nil, err
+	}
+	f, err := os.CreateTemp("", "glideway-"+sanitizeFileName(scanID)+"-*.json")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create result file: %w", err)
+	}
+	_, err = f.Write(stored.Data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", nil, fmt.Errorf("failed to write result file: %w", err)
+	}
+	return f.Name(), func() { os.Remove(f.Name()) }, nil
+}
+
+// runPostScanCommand 扫描完成后在后台执行PostScanCommand，扫描ID和结果文件路径依次追加为最后两个参数，
+// 输出通过 post-scan-output 事件发送。命令失败、超时或panic只记录日志，不影响GlideWay；
+// 无法提供结果文件时不执行命令，在 post-scan-output 事件中报告错误
+func (a *App) runPostScanCommand(config ScanConfig, record *scanRecord) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("Recovered from panic in post-scan command: %v\n", r)
+			record.log.add(logError, "扫描后命令发生panic: %v", r)
+		}
+	}()
+	args, err := splitCommandLine(config.PostScanCommand)
+	if err != nil {
+		record.log.add(logError, "扫描后命令无效: %v", err)
+		return
+	}
+	scanID := record.summary.ID
+	resultPath, cleanup, err := postScanResultFile(scanID)
+	if err != nil {
+		record.log.add(logError, "扫描后命令未执行，无法生成结果文件: %v", err)
+		a.emitEvent("post-scan-output", map[string]interface{}{
+			"scan_id": scanID,
+			"command": config.PostScanCommand,
+			"error":   fmt.Sprintf("no result file for scan %q: %v", scanID, err),
+		})
+		return
+	}
+	defer cleanup()
+	timeout := config.PostScanTimeout
+	if timeout <= 0 {
+		timeout = defaultPostScanTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], scanID, resultPath)...)
+	hideConsole(cmd)
+	output := &limitedBuffer{limit: maxPostScanOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// 子进程继承管道时，超时杀死命令后不再等待管道关闭
+	cmd.WaitDelay = time.Second
+
+	record.log.add(logInfo, "执行扫描后命令: %s", config.PostScanCommand)
+	started := time.Now()
+	err = cmd.Run()
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+
+	event := map[string]interface{}{
+		"scan_id":     scanID,
+		"command":     config.PostScanCommand,
+		"result_file": resultPath,
+		"output":      output.buf.String(),
+		"truncated":   output.truncated,
+		"exit_code":   cmd.ProcessState.ExitCode(),
+		"duration":    time.Since(started).Seconds(),
+		"timed_out":   timedOut,
+	}
+	switch {
+	case timedOut:
+		event["error"] = fmt.Sprintf("post-scan command timed out after %v", timeout)
+		record.log.add(logWarn, "扫描后命令超过 %v 未结束，已终止", timeout)
+	case err != nil:
+		event["error"] = err.Error()
+		record.log.add(logWarn, "扫描后命令执行失败: %v", err)
+	default:
+		record.log.add(logInfo, "扫描后命令执行完成，耗时 %v", time.Since(started).Round(time.Millisecond))
+	}
+	a.emitEvent("post-scan-output", event)
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package portsscanner
+
+import "os/exec"
+
+// hideConsole 仅Windows需要隐藏控制台窗口
+func hideConsole(cmd *exec.Cmd) {}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package portsscanner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// hideConsole 扫描后命令不弹出控制台窗口
+func hideConsole(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
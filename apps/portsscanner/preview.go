@@ -0,0 +1,163 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// 预览中列出的目标上限，超出部分只计入TargetCount
+const maxPreviewTargets = 1000
+
+// PreviewTarget 预览中的单个目标，Names为指向该IP的全部输入
+type PreviewTarget struct {
+	IP    string   `json:"ip"`
+	Names []string `json:"names"`
+	Ports int      `json:"ports"`
+}
+
+// ScanPreview PreviewScan的结果：配置无效时Valid为false，Error为ScanWithConfig会返回的错误。
+// Connections为端口探测的连接(或报文)数，不含重试、指纹识别和后续探测产生的连接
+type ScanPreview struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+
+	Mode   ScanModeSelection `json:"mode"`
+	Stages string            `json:"stages"`
+	Probes []string          `json:"probes"`
+
+	Targets      []PreviewTarget    `json:"targets"`
+	TargetCount  int                `json:"target_count"`
+	Truncated    bool               `json:"truncated,omitempty"` // Targets只列出前maxPreviewTargets台主机
+	Duplicates   int                `json:"duplicates"`
+	Unresolved   []UnresolvedTarget `json:"unresolved,omitempty"`   // 解析失败、扫描时会跳过的主机名
+	Denied       []string           `json:"denied,omitempty"`       // 拒绝列表中、扫描时会跳过的主机
+	Unauthorized []string           `json:"unauthorized,omitempty"` // 安全模式下未确认的越界主机，扫描会被拒绝
+
+	Ports       string `json:"ports"`
+	PortCount   int    `json:"port_count"`
+	Connections int64  `json:"connections"`
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// PreviewScan 按ScanWithConfig相同的默认值、校验、扫描类型选择、目标展开、拒绝列表和安全模式规则
+// 预览一次扫描，不建立连接也不发送探测报文。主机名解析仍会发出DNS查询；
+// HappyEyeballs的连接竞速和存活主机探测会被跳过，实际扫描的主机数可能更少
+func (a *App) PreviewScan(config ScanConfig) ScanPreview {
+	preview := ScanPreview{Targets: []PreviewTarget{}, Probes: []string{}}
+	fail := func(err error) ScanPreview {
+		preview.Error = err.Error()
+		return preview
+	}
+
+	scanMutex.Lock()
+	window := a.scanWindow
+	if config.pipeline == nil {
+		config.pipeline = a.pipeline
+	}
+	policy := a.safeMode
+	scanMutex.Unlock()
+
+	if now := time.Now(); !window.allows(now) {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("outside allowed scan window %s-%s, next opening %s",
+			window.Start, window.End, window.nextOpen(now).Format("2006-01-02 15:04")))
+	}
+
+	ports, _, err := prepareScanConfig(&config)
+	if err != nil {
+		return fail(err)
+	}
+	mode, err := selectScanType(config, rawSocketAvailable)
+	if err != nil {
+		return fail(err)
+	}
+	config.ScanType = mode.Selected
+	preview.Mode = mode
+	preview.Stages = config.stageNames()
+	preview.Ports = formatPortRanges(ports)
+	preview.PortCount = len(ports)
+
+	if config.HappyEyeballs {
+		config.HappyEyeballs = false
+		preview.Warnings = append(preview.Warnings, "happy eyeballs address selection is skipped in preview")
+	}
+	if config.runsDiscovery() {
+		preview.Warnings = append(preview.Warnings, "host discovery is skipped in preview, hosts that do not answer will not be scanned")
+	}
+
+	hosts, duplicates, unresolved, err := resolveTargets(context.Background(), config)
+	preview.Unresolved = unresolved
+	if err != nil {
+		return fail(err)
+	}
+	preview.Duplicates = duplicates
+
+	entries, err := denylist.snapshot()
+	if err != nil {
+		return fail(err)
+	}
+	kept := hosts[:0:0]
+	for _, h := range hosts {
+		if _, denied := denylistMatch(entries, h); denied {
+			preview.Denied = append(preview.Denied, h.IP)
+			continue
+		}
+		kept = append(kept, h)
+	}
+	if len(kept) == 0 {
+		return fail(fmt.Errorf("all %d target host(s) are on the denylist", len(hosts)))
+	}
+	hosts = kept
+	if !policy.disabled && !config.Acknowledged && config.dialer == nil {
+		preview.Unauthorized = policy.unauthorizedHosts(hosts)
+	}
+
+	units := int64(config.unitsPerPort())
+	for _, h := range hosts {
+		n := len(config.portsFor(h.IP, ports))
+		preview.Connections += int64(n) * units
+		if len(preview.Targets) < maxPreviewTargets {
+			preview.Targets = append(preview.Targets, PreviewTarget{IP: h.IP, Names: h.Names, Ports: n})
+		}
+	}
+	preview.TargetCount = len(hosts)
+	preview.Probes = previewProbes(config, hosts)
+	preview.Truncated = len(hosts) > len(preview.Targets)
+
+	if len(preview.Unauthorized) > 0 {
+		return fail(fmt.Errorf("%w: %d target(s) outside the allowed ranges; set Acknowledged to scan them",
+			ErrUnauthorizedTarget, len(preview.Unauthorized)))
+	}
+	preview.Valid = true
+	return preview
+}
+
+// previewProbes 列出端口探测之外会执行的探测，与扫描中各探测的启用条件一致
+func previewProbes(config ScanConfig, hosts []scanTarget) []string {
+	probes := []string{}
+	add := func(enabled bool, name string) {
+		if enabled {
+			probes = append(probes, name)
+		}
+	}
+	add(config.runsDiscovery(), "discovery")
+	add(needsInterceptionCheck(config, hosts), "interception-check")
+	add(config.VerifyOpen, "verify-open")
+	add(config.FastOpen && config.dialer == nil && config.proxies == nil, "tcp-fast-open")
+	if config.runsFingerprint() {
+		if config.GentleFingerprint {
+			probes = append(probes, "gentle-fingerprint")
+		} else {
+			probes = append(probes, "fingerprint", "http")
+			add(!config.SkipStartTLS, "starttls")
+			add(config.virtualHostsEnabled(), "virtual-hosts")
+			add(config.clientCert != nil, "tls-client-auth")
+			add(config.gentle != nil || !config.SkipGentleDetection, "gentle-detection")
+		}
+		add(config.FollowUpProbes, "follow-up")
+	}
+	add(config.DetectHoneypots, "honeypot-detection")
+	add(config.CaptureEvidence, "evidence")
+	return probes
+}
@@ -0,0 +1,57 @@
+package portsscanner
+
+// defaultPriorityPorts 优先探测的高价值端口，按重要程度排列
+var defaultPriorityPorts = []int{
+	22, 80, 443, 3389, 445, 21, 23, 25, 53, 135, 139, 8080, 8443,
+	3306, 1433, 1521, 5432, 6379, 27017, 9200, 5900, 110, 143, 993, 995,
+	111, 2049, 5985, 5986, 8000, 8888, 9000, 9090, 11211, 2375, 6443, 10250,
+}
+
+// prioritizePorts 将优先级列表中的端口提前，其余端口保持原有顺序。
+// 只改变派发顺序，端口总数不变，进度统计不受影响
+func prioritizePorts(ports []int, priority []int) []int {
+	if len(priority) == 0 {
+		priority = defaultPriorityPorts
+	}
+
+	present := make(map[int]bool, len(ports))
+	for _, p := range ports {
+		present[p] = true
+	}
+
+	ordered := make([]int, 0, len(ports))
+	picked := make(map[int]bool, len(priority))
+	for _, p := range priority {
+		if present[p] && !picked[p] {
+			picked[p] = true
+			ordered = append(ordered, p)
+		}
+	}
+	for _, p := range ports {
+		if !picked[p] {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered
+}
+
+// prioritySet 返回优先端口集合，priority为空时使用内置列表
+func prioritySet(priority []int) map[int]bool {
+	if len(priority) == 0 {
+		priority = defaultPriorityPorts
+	}
+	set := make(map[int]bool, len(priority))
+	for _, p := range priority {
+		set[p] = true
+	}
+	return set
+}
+
+// priorityPrefix 返回prioritizePorts排序后位于开头的优先端口数
+func priorityPrefix(ordered []int, set map[int]bool) int {
+	n := 0
+	for n < len(ordered) && set[ordered[n]] {
+		n++
+	}
+	return n
+}
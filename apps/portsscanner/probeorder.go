@@ -0,0 +1,46 @@
+package portsscanner
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+)
+
+// probeOrder 启用RandomizeProbeOrder时返回n个探测的随机发送顺序，未启用或不足两个探测时返回nil(保持原有顺序)。
+// 顺序只由ProbeOrderSeed、主机和端口决定，与并发调度无关，同一种子的扫描对每个端口重现相同的顺序
+func (c ScanConfig) probeOrder(host string, port, n int) []int {
+	if !c.RandomizeProbeOrder || n < 2 {
+		return nil
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s:%d", host, port)
+	return rand.New(rand.NewSource(c.ProbeOrderSeed ^ int64(h.Sum64()))).Perm(n)
+}
+
+// orderedCustomProbes 按probeOrder排列端口适用的自定义TCP探测
+func (c ScanConfig) orderedCustomProbes(host string, port int) []customProbe {
+	probes := customProbesFor("tcp", port)
+	order := c.probeOrder(host, port, len(probes))
+	if order == nil {
+		return probes
+	}
+	ordered := make([]customProbe, len(probes))
+	for i, j := range order {
+		ordered[i] = probes[j]
+	}
+	return ordered
+}
+
+// orderedPayloads 按probeOrder排列端口对应的UDP载荷(内置和自定义)，没有专用载荷时只有空数据包
+func (c ScanConfig) orderedPayloads(host string, port int) []udpPayload {
+	payloads := payloadsFor(port)
+	order := c.probeOrder(host, port, len(payloads))
+	if order == nil {
+		return payloads
+	}
+	ordered := make([]udpPayload, len(payloads))
+	for i, j := range order {
+		ordered[i] = payloads[j]
+	}
+	return ordered
+}
@@ -0,0 +1,103 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lcvvvv/gonmap"
+)
+
+// ProbeInfo 扫描时可用的一种服务探测，Ports为空表示不限端口(按识别出的服务触发)
+type ProbeInfo struct {
+	Name        string   `json:"name"`
+	Protocol    string   `json:"protocol"` // tcp 或 udp
+	Services    []string `json:"services,omitempty"`
+	Ports       []int    `json:"ports,omitempty"`
+	Description string   `json:"description"`
+}
+
+// probeRegistry 列出本程序内置的全部服务探测和已加载的自定义探测，UDP载荷和STARTTLS协商直接取自各自的表，
+// 新增探测时只需修改对应的表
+func probeRegistry() []ProbeInfo {
+	probes := []ProbeInfo{
+		{Name: "nmap-service-probes", Protocol: "tcp",
+			Description: fmt.Sprintf("gonmap service fingerprinting (%d probes, %d matches)", gonmap.ProbesCount, gonmap.MatchCount)},
+		{Name: "banner", Protocol: "tcp",
+			Description: "raw banner capture for ports without a fingerprint match"},
+		{Name: "http-title", Protocol: "tcp", Services: []string{"http", "https"},
+			Description: "HTTP(S) page title, Server header and certificate expiry"},
+		{Name: "vhost", Protocol: "tcp", Services: []string{"https"},
+			Description: "SNI virtual host discovery on HTTPS ports"},
+		{Name: "mtls", Protocol: "tcp",
+			Description: "TLS re-probe with the configured client certificate"},
+	}
+
+	services := make([]string, 0, len(startTLSDialogues))
+	for service := range startTLSDialogues {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	probes = append(probes, ProbeInfo{Name: "starttls", Protocol: "tcp", Services: services,
+		Description: "STARTTLS upgrade to capture certificates on plaintext services"})
+
+	for _, pl := range udpPayloads {
+		probes = append(probes, ProbeInfo{Name: pl.name, Protocol: "udp", Services: []string{pl.service}, Ports: pl.ports,
+			Description: "UDP " + pl.service + " request payload"})
+	}
+	probes = append(probes, ProbeInfo{Name: udpEmptyPayload.name, Protocol: "udp",
+		Description: "empty UDP datagram for ports without a dedicated payload"})
+
+	customProbesMu.RLock()
+	for _, p := range customProbes {
+		probes = append(probes, ProbeInfo{Name: p.Name, Protocol: p.Protocol, Services: []string{p.Service}, Ports: p.Ports,
+			Description: "custom " + p.Protocol + " probe loaded with LoadCustomProbes"})
+	}
+	customProbesMu.RUnlock()
+	return probes
+}
+
+// ListProbes 列出当前版本支持的服务探测，前端和脚本据此判断功能是否可用
+func (a *App) ListProbes() []ProbeInfo {
+	return probeRegistry()
+}
+
+// HasCapability 判断当前版本和运行环境是否支持name(不区分大小写)，name可以是：
+// 扫描类型(如 udp、syn)、运行环境功能(raw-sockets、arp-scan、ipv6、tcp-fast-open)、
+// 探测名称(如 http-title、SNMPv1public)或有专用探测的服务名(如 snmp、smtp)
+func (a *App) HasCapability(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return false
+	}
+
+	caps := a.GetCapabilities()
+	for _, scanType := range caps.ScanTypes {
+		if name == scanType {
+			return true
+		}
+	}
+	switch name {
+	case "raw-sockets":
+		return caps.RawSockets
+	case "arp-scan":
+		return caps.ARPScan
+	case "ipv6":
+		return caps.IPv6
+	case "tcp-fast-open":
+		return caps.FastOpen
+	}
+
+	service := canonicalServiceName(name)
+	for _, probe := range probeRegistry() {
+		if strings.EqualFold(probe.Name, name) {
+			return true
+		}
+		for _, s := range probe.Services {
+			if canonicalServiceName(s) == service {
+				return true
+			}
+		}
+	}
+	return false
+}
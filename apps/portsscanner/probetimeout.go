@@ -0,0 +1,41 @@
+package portsscanner
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/lcvvvv/gonmap"
+)
+
+// defaultProbeTimeouts 响应较慢的服务默认使用的识别超时。邮件服务常在发送欢迎横幅前做反向解析，
+// LDAP和数据库在握手阶段可能有明显延迟，用全局Timeout容易在收到响应前断开导致识别错误。
+// 只在大于全局Timeout时生效，不会缩短用户设置的超时
+var defaultProbeTimeouts = map[string]time.Duration{
+	"smtp":       8 * time.Second,
+	"submission": 8 * time.Second,
+	"imap":       6 * time.Second,
+	"pop3":       6 * time.Second,
+	"ldap":       6 * time.Second,
+	"mysql":      5 * time.Second,
+	"postgresql": 5 * time.Second,
+	"ms-sql-s":   5 * time.Second,
+	"oracle":     5 * time.Second,
+	"mongodb":    5 * time.Second,
+}
+
+// probeTimeout 返回端口指纹识别、横幅抓取和HTTP探测使用的超时。
+// 依次查找ProbeTimeouts中的端口号、按端口推测的服务名，最后是默认表；
+// 识别前还不知道实际服务，因此按nmap-services中该端口的常见服务判断
+func (c ScanConfig) probeTimeout(port int) time.Duration {
+	if t := c.ProbeTimeouts[strconv.Itoa(port)]; t > 0 {
+		return t
+	}
+	service := canonicalServiceName(gonmap.GuessProtocol(port))
+	if t := c.ProbeTimeouts[service]; t > 0 {
+		return t
+	}
+	if t := defaultProbeTimeouts[service]; t > c.Timeout {
+		return t
+	}
+	return c.Timeout
+}
@@ -0,0 +1,15 @@
+package portsscanner
+
+import "net/netip"
+
+// ProcessInfo 扫描本机时占用端口的进程
+type ProcessInfo struct {
+	PID  int    `json:"pid"`
+	Name string `json:"name"`
+}
+
+// isLoopbackTarget 判断目标是否为本机回环地址，只有扫描本机时才能关联进程
+func isLoopbackTarget(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	return err == nil && addr.IsLoopback()
+}
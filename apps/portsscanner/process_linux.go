@@ -0,0 +1,70 @@
+//go:build linux
+
+package portsscanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 监听状态在 /proc/net/tcp 中的编码
+const procTCPListen = "0A"
+
+// lookupListener 通过 /proc/net/tcp(6) 找到监听该端口的套接字inode，
+// 再遍历 /proc/<pid>/fd 找到持有该套接字的进程。其他用户的进程需要root权限才能查看
+func lookupListener(port int) (*ProcessInfo, error) {
+	inodes := make(map[string]bool)
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := os.ReadFile(table)
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(data), "\n")
+		for _, line := range lines[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 10 || fields[3] != procTCPListen {
+				continue
+			}
+			i := strings.LastIndexByte(fields[1], ':')
+			if i < 0 {
+				continue
+			}
+			if p, err := strconv.ParseUint(fields[1][i+1:], 16, 16); err == nil && int(p) == port {
+				inodes[fields[9]] = true
+			}
+		}
+	}
+	if len(inodes) == 0 {
+		return nil, fmt.Errorf("no listening socket found on port %d", port)
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+				comm, _ := os.ReadFile(filepath.Join("/proc", entry.Name(), "comm"))
+				return &ProcessInfo{PID: pid, Name: strings.TrimSpace(string(comm))}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("owner of port %d not found, root may be required", port)
+}
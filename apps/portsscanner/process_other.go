@@ -0,0 +1,38 @@
+//go:build !linux && !windows
+
+package portsscanner
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// lookupListener 使用 lsof 查找监听该端口的进程(macOS/BSD)
+func lookupListener(port int) (*ProcessInfo, error) {
+	out, err := exec.Command("lsof", "-nP", "-iTCP:"+strconv.Itoa(port), "-sTCP:LISTEN", "-Fpc").Output()
+	if err != nil {
+		return nil, fmt.Errorf("no listening socket found on port %d: %w", port, err)
+	}
+
+	info := &ProcessInfo{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case 'p':
+			if info.PID != 0 {
+				return info, nil
+			}
+			info.PID, _ = strconv.Atoi(line[1:])
+		case 'c':
+			info.Name = line[1:]
+		}
+	}
+	if info.PID == 0 {
+		return nil, fmt.Errorf("no listening socket found on port %d", port)
+	}
+	return info, nil
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package portsscanner
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// lookupListener 解析 netstat -ano 找到监听该端口的PID，再通过 tasklist 获取进程名
+func lookupListener(port int) (*ProcessInfo, error) {
+	out, err := hiddenCommand("netstat", "-ano", "-p", "TCP").Output()
+	if err != nil {
+		return nil, fmt.Errorf("netstat failed: %w", err)
+	}
+	out6, _ := hiddenCommand("netstat", "-ano", "-p", "TCPv6").Output()
+
+	pid := 0
+	for _, line := range strings.Split(string(out)+"\n"+string(out6), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 5 || fields[3] != "LISTENING" {
+			continue
+		}
+		i := strings.LastIndexByte(fields[1], ':')
+		if i < 0 || fields[1][i+1:] != strconv.Itoa(port) {
+			continue
+		}
+		if pid, err = strconv.Atoi(fields[4]); err == nil {
+			break
+		}
+	}
+	if pid == 0 {
+		return nil, fmt.Errorf("no listening socket found on port %d", port)
+	}
+
+	info := &ProcessInfo{PID: pid}
+	out, err = hiddenCommand("tasklist", "/FI", fmt.Sprintf("PID eq %d", pid), "/FO", "CSV", "/NH").Output()
+	if err == nil {
+		if record, err := csv.NewReader(strings.NewReader(string(out))).Read(); err == nil && len(record) > 0 {
+			info.Name = record[0]
+		}
+	}
+	return info, nil
+}
+
+// hiddenCommand 创建不弹出控制台窗口的命令
+func hiddenCommand(name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	return cmd
+}
@@ -0,0 +1,322 @@
+package portsscanner
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/proxy"
+)
+
+// errProxyAuth 代理服务器拒绝了提供的认证信息
+var errProxyAuth = errors.New("proxy authentication failed")
+
+// 代理测试连接的目标，只需要能返回HTTP状态行即可
+const proxyTestTarget = "www.baidu.com:80"
+
+// 代理测试的整体超时时间
+const proxyTestTimeout = 10 * time.Second
+
+// ProxyTestResult 代理连通性测试结果
+type ProxyTestResult struct {
+	Success   bool          `json:"success"`
+	Scheme    string        `json:"scheme"`
+	Target    string        `json:"target"`
+	Latency   time.Duration `json:"latency"` // 建立代理连接到收到目标响应的耗时
+	AuthError bool          `json:"auth_error"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// contextDialer 经代理建立TCP连接的拨号器
+type contextDialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// parseProxyURL 解析代理地址，支持 socks5://、socks5h://、http://、https:// 和 ssh://
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(proxyURL))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", proxyURL)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	switch u.Scheme {
+	case "socks5", "socks5h", "http", "https", "ssh":
+		return u, nil
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+}
+
+// newProxyDialer 根据代理地址创建拨号器。
+// SSH代理会立即建立会话，使用完毕后需要通过closeDialer关闭
+func newProxyDialer(u *url.URL, timeout time.Duration) (contextDialer, error) {
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if u.User != nil {
+			password, _ := u.User.Password()
+			auth = &proxy.Auth{User: u.User.Username(), Password: password}
+		}
+		d, err := proxy.SOCKS5("tcp", proxyHostPort(u, "1080"), auth, proxyForward{timeout: timeout})
+		if err != nil {
+			return nil, err
+		}
+		// socks5:// 在本地解析目标主机名，socks5h:// 交由代理服务器解析
+		return &socksDialer{dialer: d.(proxy.ContextDialer), resolveLocal: u.Scheme == "socks5"}, nil
+	case "http", "https":
+		return &httpConnectDialer{proxy: u, timeout: timeout}, nil
+	case "ssh":
+		return newSSHDialer(u, timeout)
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+}
+
+// closeDialer 释放拨号器持有的长连接(目前只有SSH)
+func closeDialer(d contextDialer) {
+	if c, ok := d.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// proxyHostPort 返回代理服务器地址，未指定端口时使用协议默认端口
+func proxyHostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// socksDialer 包装SOCKS5拨号器，将认证失败统一为errProxyAuth
+type socksDialer struct {
+	dialer       proxy.ContextDialer
+	resolveLocal bool
+}
+
+func (d *socksDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.resolveLocal {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := canonicalIP(host); !ok {
+			ip, err := resolveHost(ctx, net.DefaultResolver, host)
+			if err != nil {
+				return nil, err
+			}
+			addr = net.JoinHostPort(ip, port)
+		}
+	}
+	conn, err := d.dialer.DialContext(ctx, network, addr)
+	if err != nil && strings.Contains(err.Error(), "username/password authentication failed") {
+		return nil, fmt.Errorf("%w: %v", errProxyAuth, err)
+	}
+	return conn, err
+}
+
+// httpConnectDialer 通过HTTP CONNECT隧道建立连接，https:// 代理先与代理服务器握手TLS
+type httpConnectDialer struct {
+	proxy   *url.URL
+	timeout time.Duration
+}
+
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	defaultPort := "80"
+	if d.proxy.Scheme == "https" {
+		defaultPort = "443"
+	}
+	dialer := &net.Dialer{Timeout: d.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyHostPort(d.proxy, defaultPort))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errProxyUnreachable, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else if d.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(d.timeout))
+	}
+
+	if d.proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxy.Hostname(), InsecureSkipVerify: true})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: %v", errProxyUnreachable, err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.proxy.User != nil {
+		password, _ := d.proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		conn.Close()
+		return nil, fmt.Errorf("%w: %s", errProxyAuth, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT to %s: %s", addr, resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 保留CONNECT响应之后已被读入缓冲区的数据
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// sshDialer 通过SSH会话的direct-tcpip通道转发连接(等同 ssh -D)。
+// 认证方式：URL中的密码，或 ?key=私钥文件路径
+type sshDialer struct {
+	client *ssh.Client
+}
+
+func newSSHDialer(u *url.URL, timeout time.Duration) (*sshDialer, error) {
+	var methods []ssh.AuthMethod
+	if keyFile := u.Query().Get("key"); keyFile != "" {
+		pem, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ssh key %s: %w", keyFile, err)
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, fmt.Errorf("parse ssh key %s: %w", keyFile, err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			methods = append(methods, ssh.Password(password))
+		}
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("ssh proxy requires a password or ?key= private key file")
+	}
+
+	user := ""
+	if u.User != nil {
+		user = u.User.Username()
+	}
+	client, err := ssh.Dial("tcp", proxyHostPort(u, "22"), &ssh.ClientConfig{
+		User: user,
+		Auth: methods,
+		// 扫描器不维护known_hosts，跳板机指纹由用户自行确认
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         timeout,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			return nil, fmt.Errorf("%w: %v", errProxyAuth, err)
+		}
+		return nil, fmt.Errorf("%w: %v", errProxyUnreachable, err)
+	}
+	return &sshDialer{client: client}, nil
+}
+
+// DialContext 跳板机拒绝打开通道表示目标不可达，其他错误说明SSH会话已断开
+func (d *sshDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.client.DialContext(ctx, network, addr)
+	var rejected *ssh.OpenChannelError
+	if err != nil && ctx.Err() == nil && !errors.As(err, &rejected) {
+		return nil, fmt.Errorf("%w: %v", errProxyUnreachable, err)
+	}
+	return conn, err
+}
+
+func (d *sshDialer) Close() error {
+	return d.client.Close()
+}
+
+// TestProxy 经指定代理连接一个已知地址并发送HTTP请求，报告是否成功、耗时以及认证错误。
+// 只有代理地址本身无效时才返回error，连接失败体现在结果中
+func (a *App) TestProxy(proxyURL string) (ProxyTestResult, error) {
+	u, err := parseProxyURL(proxyURL)
+	if err != nil {
+		return ProxyTestResult{}, err
+	}
+	result := ProxyTestResult{Scheme: u.Scheme, Target: proxyTestTarget}
+
+	ctx, cancel := context.WithTimeout(context.Background(), proxyTestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := testProxyConnection(ctx, u); err != nil {
+		result.AuthError = errors.Is(err, errProxyAuth)
+		result.Error = err.Error()
+		fmt.Printf("代理 %s 测试失败: %v\n", u.Redacted(), err)
+		return result, nil
+	}
+	result.Latency = time.Since(start)
+	result.Success = true
+	fmt.Printf("代理 %s 测试成功，耗时 %v\n", u.Redacted(), result.Latency)
+	return result, nil
+}
+
+// testProxyConnection 经代理连接测试目标并确认收到HTTP状态行
+func testProxyConnection(ctx context.Context, u *url.URL) error {
+	dialer, err := newProxyDialer(u, proxyTestTimeout)
+	if err != nil {
+		return err
+	}
+	defer closeDialer(dialer)
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyTestTarget)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(proxyTestTarget)
+	if _, err := fmt.Fprintf(conn, "HEAD / HTTP/1.0\r\nHost: %s\r\n\r\n", host); err != nil {
+		return err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("no response from %s through proxy: %w", proxyTestTarget, err)
+	}
+	if !strings.HasPrefix(line, "HTTP/") {
+		return fmt.Errorf("unexpected response from %s through proxy: %q", proxyTestTarget, strings.TrimSpace(line))
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package portsscanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 代理不可用后暂停使用的时长，到期后重新加入轮询
+const proxyRetryAfter = 30 * time.Second
+
+// errProxyUnreachable 无法连接代理服务器本身，与代理报告目标端口关闭或不可达区分
+var errProxyUnreachable = errors.New("proxy unreachable")
+
+// errNoProxyAvailable 代理池中所有代理都处于暂停状态
+var errNoProxyAvailable = errors.New("all proxies are unavailable")
+
+// errProxyScanType 代理只能转发TCP连接
+var errProxyScanType = errors.New("proxies are only supported with connect scans")
+
+// isProxyFailure 判断拨号错误是否由代理本身造成，此时应换用其他代理而不是判定端口关闭
+func isProxyFailure(err error) bool {
+	return errors.Is(err, errProxyUnreachable) || errors.Is(err, errProxyAuth)
+}
+
+// pooledProxy 代理池中的一个代理
+type pooledProxy struct {
+	url   *url.URL
+	conns int64 // 经该代理建立的连接数
+
+	mu        sync.Mutex
+	dialer    contextDialer // SSH代理在首次使用时建立会话，不可用时关闭并在恢复后重建
+	downUntil time.Time
+}
+
+// proxyPool Proxies 的轮询拨号器，每个连接依次经由下一个可用的代理
+type proxyPool struct {
+	proxies []*pooledProxy
+	next    uint64
+	timeout time.Duration
+
+	// onDown 代理被暂停使用时调用
+	onDown func(proxy string, err error, retryAfter time.Duration)
+}
+
+// newProxyPool 解析代理地址并去重，地址无效时返回错误
+func newProxyPool(urls []string, timeout time.Duration) (*proxyPool, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	pool := &proxyPool{timeout: timeout}
+	seen := make(map[string]bool)
+	for _, raw := range urls {
+		u, err := parseProxyURL(raw)
+		if err != nil {
+			return nil, err
+		}
+		if seen[u.String()] {
+			continue
+		}
+		seen[u.String()] = true
+		pool.proxies = append(pool.proxies, &pooledProxy{url: u})
+	}
+	return pool, nil
+}
+
+// pick 从轮询位置开始返回下一个未暂停的代理，全部暂停时返回nil
+func (p *proxyPool) pick() *pooledProxy {
+	now := time.Now()
+	start := atomic.AddUint64(&p.next, 1) - 1
+	for i := 0; i < len(p.proxies); i++ {
+		px := p.proxies[(start+uint64(i))%uint64(len(p.proxies))]
+		px.mu.Lock()
+		available := !now.Before(px.downUntil)
+		px.mu.Unlock()
+		if available {
+			return px
+		}
+	}
+	return nil
+}
+
+// dialerFor 返回代理的拨号器，尚未建立时创建
+func (p *proxyPool) dialerFor(px *pooledProxy) (contextDialer, error) {
+	px.mu.Lock()
+	defer px.mu.Unlock()
+	if px.dialer != nil {
+		return px.dialer, nil
+	}
+	d, err := newProxyDialer(px.url, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	px.dialer = d
+	return d, nil
+}
+
+// markDown 暂停使用不可用的代理，已被其他连接暂停时不重复报告
+func (p *proxyPool) markDown(px *pooledProxy, err error) {
+	px.mu.Lock()
+	if time.Now().Before(px.downUntil) {
+		px.mu.Unlock()
+		return
+	}
+	px.downUntil = time.Now().Add(proxyRetryAfter)
+	d := px.dialer
+	px.dialer = nil
+	px.mu.Unlock()
+
+	if d != nil {
+		closeDialer(d)
+	}
+	if p.onDown != nil {
+		p.onDown(px.url.Redacted(), err, proxyRetryAfter)
+	}
+}
+
+// DialContext 经下一个可用代理建立连接。代理本身不可用时暂停该代理并换用下一个，
+// 代理报告的目标错误(端口关闭、超时等)直接返回
+func (p *proxyPool) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	for i := 0; i < len(p.proxies); i++ {
+		px := p.pick()
+		if px == nil {
+			break
+		}
+		d, err := p.dialerFor(px)
+		if err == nil {
+			var conn net.Conn
+			conn, err = d.DialContext(ctx, network, addr)
+			if err == nil || !isProxyFailure(err) {
+				if err == nil {
+					atomic.AddInt64(&px.conns, 1)
+				}
+				return conn, err
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		p.markDown(px, err)
+	}
+	return nil, errNoProxyAvailable
+}
+
+// connections 返回经每个代理建立的连接数，键为隐去密码的代理地址，p为nil时返回nil
+func (p *proxyPool) connections() map[string]int64 {
+	if p == nil {
+		return nil
+	}
+	counts := make(map[string]int64, len(p.proxies))
+	for _, px := range p.proxies {
+		counts[px.url.Redacted()] = atomic.LoadInt64(&px.conns)
+	}
+	return counts
+}
+
+// close 关闭代理池持有的SSH会话，p为nil时忽略
+func (p *proxyPool) close() {
+	if p == nil {
+		return
+	}
+	for _, px := range p.proxies {
+		px.mu.Lock()
+		if px.dialer != nil {
+			closeDialer(px.dialer)
+			px.dialer = nil
+		}
+		px.mu.Unlock()
+	}
+}
+
+// proxyForward 连接SOCKS5代理服务器的拨号器，将连接失败标记为errProxyUnreachable
+type proxyForward struct {
+	timeout time.Duration
+}
+
+func (f proxyForward) Dial(network, addr string) (net.Conn, error) {
+	return f.DialContext(context.Background(), network, addr)
+}
+
+func (f proxyForward) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := net.Dialer{Timeout: f.timeout}
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errProxyUnreachable, err)
+	}
+	return conn, nil
+}
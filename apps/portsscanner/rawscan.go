@@ -0,0 +1,219 @@
+package portsscanner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+)
+
+// 扫描类型
+//
+// FIN/NULL/XMAS扫描依据RFC 793：关闭的端口收到不含SYN/RST/ACK的报文时回复RST，
+// 开放的端口静默丢弃。因此没有响应既可能是端口开放，也可能是被防火墙过滤，
+// 结果只能标记为 open|filtered；Windows等不遵循RFC的协议栈对所有端口都回复RST，
+// 会全部显示为关闭。
+//
+// UDP扫描不需要原始套接字，每个端口发送对应服务的探测载荷(见udpPayloads)，
+// 收到响应为开放，收到ICMP端口不可达为关闭，多次尝试均无响应为 open|filtered。
+const (
+	ScanTypeConnect = "connect"
+	ScanTypeSYN     = "syn"
+	ScanTypeFIN     = "fin"
+	ScanTypeNull    = "null"
+	ScanTypeXmas    = "xmas"
+	ScanTypeIdle    = "idle"
+	ScanTypeUDP     = "udp"
+	ScanTypeBoth    = "both" // 每个端口依次进行UDP探测和TCP连接扫描
+)
+
+// TCP标志位
+const (
+	tcpFIN = 0x01
+	tcpSYN = 0x02
+	tcpRST = 0x04
+	tcpPSH = 0x08
+	tcpACK = 0x10
+	tcpURG = 0x20
+)
+
+var errRawUnsupported = errors.New("raw socket scanning is not supported on this platform")
+
+// rawReply 原始套接字收到的目标响应
+type rawReply struct {
+	Flags uint8  // TCP标志位
+	IPID  uint16 // 响应包的IP ID
+
+	ICMP *icmpReply // 收到的是ICMP目标不可达或TTL超时报文而不是TCP响应
+}
+
+// rawPacket 构造探测包所需的参数
+type rawPacket struct {
+	Src     net.IP
+	Dst     net.IP
+	SrcPort uint16
+	DstPort uint16
+	Seq     uint32
+	Flags   uint8
+	TTL     uint8
+	TOS     uint8
+	IPID    uint16
+}
+
+// isRawScanType 判断扫描类型是否需要原始套接字
+func isRawScanType(scanType string) bool {
+	switch scanType {
+	case ScanTypeSYN, ScanTypeFIN, ScanTypeNull, ScanTypeXmas, ScanTypeIdle:
+		return true
+	}
+	return false
+}
+
+// rawScanFlags 返回各原始套接字扫描类型探测包的TCP标志位
+func rawScanFlags(scanType string) uint8 {
+	switch scanType {
+	case ScanTypeFIN:
+		return tcpFIN
+	case ScanTypeNull:
+		return 0
+	case ScanTypeXmas:
+		return tcpFIN | tcpPSH | tcpURG
+	}
+	return tcpSYN
+}
+
+// validateRawConfig 检查扫描类型和原始套接字扫描相关配置，诱饵扫描必须显式确认
+func validateRawConfig(config ScanConfig) ([]net.IP, error) {
+	if config.ScanType != ScanTypeConnect && config.ScanType != ScanTypeUDP && config.ScanType != ScanTypeBoth && !isRawScanType(config.ScanType) {
+		return nil, fmt.Errorf("unsupported scan type %q", config.ScanType)
+	}
+	if err := validateICMPHandling(config); err != nil {
+		return nil, err
+	}
+	if config.dialer != nil && config.ScanType != ScanTypeConnect {
+		return nil, errSimulatedScanType
+	}
+	if config.ScanType == ScanTypeIdle {
+		if config.ZombieHost == "" {
+			return nil, errors.New("idle scanning requires a zombie host (ZombieHost)")
+		}
+		if len(config.Decoys) > 0 {
+			return nil, errors.New("decoys cannot be combined with idle scanning")
+		}
+		if !config.AcknowledgeDecoys {
+			return nil, errors.New("idle scanning sends packets spoofed from the zombie host and must be acknowledged explicitly (AcknowledgeDecoys)")
+		}
+	}
+	if config.ProbeTTL != 0 {
+		if !isRawScanType(config.ScanType) || config.ScanType == ScanTypeIdle {
+			return nil, errors.New("probe TTL requires a raw socket scan type (syn, fin, null or xmas)")
+		}
+		if config.ProbeTTL < 1 || config.ProbeTTL > 255 {
+			return nil, fmt.Errorf("probe TTL %d must be between 1 and 255", config.ProbeTTL)
+		}
+	}
+	if config.Fragment {
+		if !isRawScanType(config.ScanType) || config.ScanType == ScanTypeIdle {
+			return nil, errors.New("IP fragmentation requires a raw socket scan type (syn, fin, null or xmas)")
+		}
+		if config.FragmentSize < 0 || config.FragmentSize%8 != 0 {
+			return nil, fmt.Errorf("fragment size %d must be a positive multiple of 8", config.FragmentSize)
+		}
+	}
+	if len(config.Decoys) == 0 {
+		return nil, nil
+	}
+	if !isRawScanType(config.ScanType) {
+		return nil, fmt.Errorf("decoy scanning requires a raw socket scan type (syn, fin, null or xmas)")
+	}
+	if !config.AcknowledgeDecoys {
+		return nil, errors.New("decoy scanning sends spoofed packets and must be acknowledged explicitly (AcknowledgeDecoys)")
+	}
+
+	decoys := make([]net.IP, 0, len(config.Decoys))
+	for _, d := range config.Decoys {
+		ip := net.ParseIP(d).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid decoy address %q: only IPv4 is supported", d)
+		}
+		decoys = append(decoys, ip)
+	}
+	return decoys, nil
+}
+
+// buildTCPPacket 构造带IP头的TCP探测包(IPv4，无选项)
+func buildTCPPacket(p rawPacket) []byte {
+	ttl := p.TTL
+	if ttl == 0 {
+		ttl = 64
+	}
+
+	pkt := make([]byte, 40)
+	ip := pkt[:20]
+	tcp := pkt[20:]
+
+	ip[0] = 0x45
+	ip[1] = p.TOS
+	binary.BigEndian.PutUint16(ip[2:], uint16(len(pkt)))
+	binary.BigEndian.PutUint16(ip[4:], p.IPID)
+	ip[8] = ttl
+	ip[9] = 6 // TCP
+	copy(ip[12:16], p.Src.To4())
+	copy(ip[16:20], p.Dst.To4())
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip))
+
+	binary.BigEndian.PutUint16(tcp[0:], p.SrcPort)
+	binary.BigEndian.PutUint16(tcp[2:], p.DstPort)
+	binary.BigEndian.PutUint32(tcp[4:], p.Seq)
+	tcp[12] = 5 << 4
+	tcp[13] = p.Flags
+	binary.BigEndian.PutUint16(tcp[14:], 1024)
+	binary.BigEndian.PutUint16(tcp[16:], tcpChecksum(p.Src.To4(), p.Dst.To4(), tcp))
+
+	return pkt
+}
+
+func tcpChecksum(src, dst net.IP, tcp []byte) uint16 {
+	pseudo := make([]byte, 12+len(tcp))
+	copy(pseudo[0:4], src)
+	copy(pseudo[4:8], dst)
+	pseudo[9] = 6
+	binary.BigEndian.PutUint16(pseudo[10:], uint16(len(tcp)))
+	copy(pseudo[12:], tcp)
+	return checksum(pseudo)
+}
+
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
+
+// decoySources 返回本次探测的源地址序列，真实源地址随机插入诱饵之间
+func decoySources(real net.IP, decoys []net.IP) []net.IP {
+	sources := make([]net.IP, 0, len(decoys)+1)
+	sources = append(sources, decoys...)
+	pos := rand.Intn(len(sources) + 1)
+	sources = append(sources, nil)
+	copy(sources[pos+1:], sources[pos:])
+	sources[pos] = real
+	return sources
+}
+
+// routeSource 通过路由选择确定访问目标时使用的本地IPv4源地址
+func routeSource(dst net.IP) (net.IP, error) {
+	ip, err := egressIP(dst.String())
+	if err != nil {
+		return nil, err
+	}
+	return net.ParseIP(ip).To4(), nil
+}
@@ -0,0 +1,270 @@
+//go:build linux
+
+package portsscanner
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// rawSocketAvailable 尝试创建原始TCP套接字以判断是否具备SYN扫描所需权限
+func rawSocketAvailable() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_TCP)
+	if err != nil {
+		return err
+	}
+	syscall.Close(fd)
+	return nil
+}
+
+type rawKey struct {
+	ip   [4]byte
+	port uint16
+}
+
+// rawScanner 基于原始套接字的TCP探测器，发送自构造的数据包并统一接收响应
+type rawScanner struct {
+	sendFd  int
+	recvFd  int
+	srcPort uint16
+	decoys  []net.IP
+
+	fragment int   // 探测包IP分片的载荷大小，0表示不分片
+	ttl      uint8 // 探测包的IP TTL，0表示默认值
+	tos      uint8 // 探测包的IP ToS字节(DSCP<<2)
+
+	icmp *icmpListener // 设置时同时等待与探测对应的ICMP目标不可达/超时报文
+
+	mu      sync.Mutex
+	waiters map[rawKey]chan rawReply
+	sources map[[4]byte]net.IP
+	mtus    map[[4]byte]int // 源地址所在网卡的MTU
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newRawScanner(decoys []net.IP, fragment, ttl, tos int, icmp *icmpListener) (*rawScanner, error) {
+	sendFd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_RAW)
+	if err != nil {
+		return nil, fmt.Errorf("raw socket scanning requires root or CAP_NET_RAW: %w", err)
+	}
+	recvFd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_TCP)
+	if err != nil {
+		syscall.Close(sendFd)
+		return nil, fmt.Errorf("raw socket scanning requires root or CAP_NET_RAW: %w", err)
+	}
+	// 设置读取超时，保证关闭时接收协程能及时退出
+	tv := syscall.NsecToTimeval(int64(200 * time.Millisecond))
+	syscall.SetsockoptTimeval(recvFd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+
+	r := &rawScanner{
+		sendFd:   sendFd,
+		recvFd:   recvFd,
+		srcPort:  uint16(40000 + rand.Intn(20000)),
+		decoys:   decoys,
+		fragment: fragment,
+		ttl:      uint8(ttl),
+		tos:      uint8(tos),
+		icmp:     icmp,
+		waiters:  make(map[rawKey]chan rawReply),
+		sources:  make(map[[4]byte]net.IP),
+		mtus:     make(map[[4]byte]int),
+		done:     make(chan struct{}),
+	}
+	go r.receiveLoop()
+	return r, nil
+}
+
+// Close 关闭原始套接字并停止接收协程
+func (r *rawScanner) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		syscall.Close(r.sendFd)
+		syscall.Close(r.recvFd)
+	})
+}
+
+// probe 向目标端口发送指定标志位的探测包(同时从诱饵地址发送)，等待响应或超时。
+// 设置了ICMP监听器时，收到对应的ICMP目标不可达或TTL超时报文同样视为响应，reply.ICMP不为nil
+func (r *rawScanner) probe(ctx context.Context, dst net.IP, port int, flags uint8, timeout time.Duration) (rawReply, bool, error) {
+	dst4 := dst.To4()
+	if dst4 == nil {
+		return rawReply{}, false, fmt.Errorf("raw socket scanning only supports IPv4 targets: %s", dst)
+	}
+	src, err := r.sourceFor(dst4)
+	if err != nil {
+		return rawReply{}, false, err
+	}
+
+	var key rawKey
+	copy(key.ip[:], dst4)
+	key.port = uint16(port)
+
+	ch := make(chan rawReply, 1)
+	r.mu.Lock()
+	r.waiters[key] = ch
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.waiters, key)
+		r.mu.Unlock()
+	}()
+	icmpCh, unwatch := r.icmp.watch(dst4, ipProtoTCP, int(r.srcPort), port)
+	defer unwatch()
+
+	for _, s := range decoySources(src, r.decoys) {
+		pkt := buildTCPPacket(rawPacket{
+			Src:     s,
+			Dst:     dst4,
+			SrcPort: r.srcPort,
+			DstPort: uint16(port),
+			Seq:     rand.Uint32(),
+			Flags:   flags,
+			TTL:     r.ttl,
+			TOS:     r.tos,
+			IPID:    uint16(rand.Intn(65536)),
+		})
+		if err := r.sendProbe(src, dst4, pkt); err != nil {
+			return rawReply{}, false, err
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return rawReply{}, false, ctx.Err()
+	case reply := <-ch:
+		return reply, true, nil
+	case icmp := <-icmpCh:
+		return rawReply{ICMP: &icmp}, true, nil
+	case <-timer.C:
+		return rawReply{}, false, nil
+	}
+}
+
+// spoof 以伪造的源地址发送单个探测包，不等待响应(空闲扫描使用)
+func (r *rawScanner) spoof(src, dst net.IP, port int, flags uint8) error {
+	dst4 := dst.To4()
+	if dst4 == nil {
+		return fmt.Errorf("raw socket scanning only supports IPv4 targets: %s", dst)
+	}
+	pkt := buildTCPPacket(rawPacket{
+		Src:     src.To4(),
+		Dst:     dst4,
+		SrcPort: r.srcPort,
+		DstPort: uint16(port),
+		Seq:     rand.Uint32(),
+		Flags:   flags,
+		TOS:     r.tos,
+		IPID:    uint16(rand.Intn(65536)),
+	})
+	return r.send(dst4, pkt)
+}
+
+// sendProbe 发送探测包，开启分片时按出口网卡MTU拆分后逐片发送
+func (r *rawScanner) sendProbe(src, dst net.IP, pkt []byte) error {
+	if r.fragment <= 0 {
+		return r.send(dst, pkt)
+	}
+	size := fragmentSizeFor(r.fragment, r.mtuFor(src))
+	for _, frag := range fragmentPacket(pkt, size) {
+		if err := r.send(dst, frag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mtuFor 按源地址缓存网卡MTU，诱饵地址不属于本机时返回0(不限制)
+func (r *rawScanner) mtuFor(src net.IP) int {
+	var key [4]byte
+	copy(key[:], src.To4())
+
+	r.mu.Lock()
+	mtu, ok := r.mtus[key]
+	r.mu.Unlock()
+	if ok {
+		return mtu
+	}
+	mtu = interfaceMTU(src)
+	r.mu.Lock()
+	r.mtus[key] = mtu
+	r.mu.Unlock()
+	return mtu
+}
+
+func (r *rawScanner) send(dst net.IP, pkt []byte) error {
+	var addr syscall.SockaddrInet4
+	copy(addr.Addr[:], dst)
+	return syscall.Sendto(r.sendFd, pkt, 0, &addr)
+}
+
+// sourceFor 按目标缓存路由源地址，多目标扫描时不同目标可能走不同网卡
+func (r *rawScanner) sourceFor(dst net.IP) (net.IP, error) {
+	var key [4]byte
+	copy(key[:], dst)
+
+	r.mu.Lock()
+	src, ok := r.sources[key]
+	r.mu.Unlock()
+	if ok {
+		return src, nil
+	}
+
+	src, err := routeSource(dst)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine source address for %s: %w", dst, err)
+	}
+	r.mu.Lock()
+	r.sources[key] = src
+	r.mu.Unlock()
+	return src, nil
+}
+
+func (r *rawScanner) receiveLoop() {
+	buf := make([]byte, 65535)
+	for {
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+
+		n, _, err := syscall.Recvfrom(r.recvFd, buf, 0)
+		if err != nil || n < 20 {
+			continue
+		}
+		ihl := int(buf[0]&0x0f) * 4
+		if buf[9] != 6 || n < ihl+20 {
+			continue
+		}
+		tcp := buf[ihl:n]
+		if binary.BigEndian.Uint16(tcp[2:4]) != r.srcPort {
+			continue
+		}
+
+		var key rawKey
+		copy(key.ip[:], buf[12:16])
+		key.port = binary.BigEndian.Uint16(tcp[0:2])
+
+		r.mu.Lock()
+		ch, ok := r.waiters[key]
+		r.mu.Unlock()
+		if !ok {
+			continue
+		}
+		select {
+		case ch <- rawReply{Flags: tcp[13], IPID: binary.BigEndian.Uint16(buf[4:6])}:
+		default:
+		}
+	}
+}
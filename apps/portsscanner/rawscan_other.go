@@ -0,0 +1,32 @@
+//go:build !linux
+
+package portsscanner
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// rawSocketAvailable 原始套接字扫描只在Linux上实现。macOS和BSD上即使能创建原始套接字也无法使用，
+// Windows自XP SP2起禁止通过原始套接字发送TCP数据包
+func rawSocketAvailable() error {
+	return errRawUnsupported
+}
+
+// rawScanner 非Linux平台不支持原始套接字扫描
+type rawScanner struct{}
+
+func newRawScanner(decoys []net.IP, fragment, ttl, tos int, icmp *icmpListener) (*rawScanner, error) {
+	return nil, errRawUnsupported
+}
+
+func (r *rawScanner) Close() {}
+
+func (r *rawScanner) probe(ctx context.Context, dst net.IP, port int, flags uint8, timeout time.Duration) (rawReply, bool, error) {
+	return rawReply{}, false, errRawUnsupported
+}
+
+func (r *rawScanner) spoof(src, dst net.IP, port int, flags uint8) error {
+	return errRawUnsupported
+}
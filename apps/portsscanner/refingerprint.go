@@ -0,0 +1,213 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// updateResult 用新的识别结果替换同一端口的已有结果，找不到时返回false
+func (r *scanRecord) updateResult(info PortInfo) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.results {
+		if r.results[i].Host == info.Host && r.results[i].Port == info.Port && r.results[i].Protocol == info.Protocol {
+			r.results[i] = info
+			r.addEvidence(info)
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprintTargets 选出可补充识别的结果：已确认开放的TCP端口，open|filtered 等未完成连接的不包括在内
+func fingerprintTargets(results []PortInfo) []PortInfo {
+	var targets []PortInfo
+	for _, r := range results {
+		if r.Protocol == "tcp" && r.State == "" {
+			targets = append(targets, r)
+		}
+	}
+	return targets
+}
+
+// FingerprintExisting 对已有扫描中的开放端口只运行指纹识别，不重新探测端口状态，
+// 适用于先以 connect 流水线快速发现端口、再对结果深入识别的两步流程。
+// 识别在后台进行，每个端口完成后更新存储的结果并发送 port-updated 事件，全部完成后发送 fingerprint-complete，
+// 可通过StopScan中断
+func (a *App) FingerprintExisting(scanID string) error {
+	if a == nil || a.ctx == nil {
+		return fmt.Errorf("app context is not initialized")
+	}
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return err
+	}
+	summary, config, results := record.snapshot()
+	if summary.Archived {
+		return archivedError(scanID)
+	}
+	targets := fingerprintTargets(results)
+	if len(targets) == 0 {
+		return fmt.Errorf("scan %q has no open TCP ports to fingerprint", scanID)
+	}
+	return a.startRefingerprint(record, summary, config, targets, func(ctx context.Context, s *portScanner, h scanTarget, info PortInfo) {
+		s.identify(ctx, h, info.Port, &info)
+	})
+}
+
+// startRefingerprint 在后台对targets逐个调用probe，probe通过s.callback报告更新后的结果
+func (a *App) startRefingerprint(record *scanRecord, summary ScanSummary, config ScanConfig, targets []PortInfo, probe func(ctx context.Context, s *portScanner, h scanTarget, info PortInfo)) error {
+	scanID := summary.ID
+	scanMutex.Lock()
+	if summary.Status == "running" && currentScan != nil && currentScan.record == record {
+		scanMutex.Unlock()
+		return fmt.Errorf("scan %q is still running", scanID)
+	}
+	if fingerprintCancel != nil {
+		scanMutex.Unlock()
+		return fmt.Errorf("fingerprinting is already in progress")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	fingerprintCancel = cancel
+	scanMutex.Unlock()
+
+	// 原扫描可能关闭了fingerprint阶段，这里总是按默认流水线识别
+	config.pipeline = nil
+	config.dialer = nil
+	applyScanDefaults(&config)
+	clientCert, err := loadClientCertificate(config)
+	if err != nil {
+		scanMutex.Lock()
+		fingerprintCancel = nil
+		scanMutex.Unlock()
+		cancel()
+		return err
+	}
+	config.clientCert = clientCert
+	if config.gentle, err = newGentlePolicy(config.GentleHosts); err != nil {
+		scanMutex.Lock()
+		fingerprintCancel = nil
+		scanMutex.Unlock()
+		cancel()
+		return err
+	}
+	config.bandwidth = newBandwidthMeter(config.MaxBandwidth)
+	config.emit = a.emitEvent
+	config.logf = record.log.add
+
+	var identified int32
+	s := &portScanner{
+		config: config,
+		callback: func(info PortInfo) {
+			if info.Service != "" {
+				atomic.AddInt32(&identified, 1)
+			}
+			if record.updateResult(info) {
+				a.emitEvent("port-updated", info)
+			}
+		},
+	}
+
+	_, _, results := record.snapshot()
+	s.seedGentle(results)
+
+	record.log.add(logInfo, "对 %d 个开放端口补充指纹识别", len(targets))
+	go func() {
+		defer func() {
+			scanMutex.Lock()
+			fingerprintCancel = nil
+			scanMutex.Unlock()
+			cancel()
+		}()
+
+		var wg sync.WaitGroup
+		semaphore := make(chan struct{}, config.MaxThreads)
+		for i := range targets {
+			if ctx.Err() != nil {
+				break
+			}
+			semaphore <- struct{}{}
+			wg.Add(1)
+			go func(info PortInfo) {
+				defer func() {
+					wg.Done()
+					<-semaphore
+					if r := recover(); r != nil {
+						s.portPanicked(scanTarget{IP: info.Host, Names: info.Aliases}, info.Port, StageFingerprint, r, nil)
+					}
+				}()
+				probe(ctx, s, scanTarget{IP: info.Host, Names: info.Aliases}, info)
+			}(targets[i])
+		}
+		wg.Wait()
+
+		if err := saveScanRecord(record); err != nil {
+			fmt.Printf("保存扫描记录失败: %v\n", err)
+		}
+		status := "completed"
+		if ctx.Err() != nil {
+			status = "cancelled"
+			record.log.add(logInfo, "补充指纹识别已取消，识别出 %d 个服务", atomic.LoadInt32(&identified))
+		} else {
+			record.log.add(logInfo, "补充指纹识别完成，识别出 %d/%d 个服务", atomic.LoadInt32(&identified), len(targets))
+		}
+		a.emitEvent("fingerprint-complete", map[string]interface{}{
+			"scan_id":    scanID,
+			"status":     status,
+			"ports":      len(targets),
+			"identified": atomic.LoadInt32(&identified),
+		})
+	}()
+	return nil
+}
+
+// 可信度低于该值的识别视为没有可靠结果：未匹配任何指纹时最高只有port等次要依据
+const refingerprintConfidence = confidenceFingerprint
+
+// unidentifiedTargets 选出没有可靠识别且有已加载主动自定义探测适用的开放TCP端口，
+// 被动横幅规则需要完整横幅，已存储的截断横幅无法重新匹配
+func unidentifiedTargets(results []PortInfo) []PortInfo {
+	var targets []PortInfo
+	for _, r := range fingerprintTargets(results) {
+		if r.Service != "" && !r.Unidentified && r.Confidence >= refingerprintConfidence {
+			continue
+		}
+		for _, probe := range customProbesFor("tcp", r.Port) {
+			if !probe.passive() {
+				targets = append(targets, r)
+				break
+			}
+		}
+	}
+	return targets
+}
+
+// RefingerprintUnidentified 用当前加载的自定义探测(见LoadCustomProbes)重新识别已有扫描中
+// 未识别或可信度低的开放端口，不重新扫描。识别成功的端口更新存储的结果并发送 port-updated，
+// 全部完成后发送 fingerprint-complete，可通过StopScan中断
+func (a *App) RefingerprintUnidentified(scanID string) error {
+	if a == nil || a.ctx == nil {
+		return fmt.Errorf("app context is not initialized")
+	}
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return err
+	}
+	summary, config, results := record.snapshot()
+	if summary.Archived {
+		return archivedError(scanID)
+	}
+	targets := unidentifiedTargets(results)
+	if len(targets) == 0 {
+		return fmt.Errorf("scan %q has no unidentified TCP ports matching the loaded custom probes", scanID)
+	}
+	return a.startRefingerprint(record, summary, config, targets, func(ctx context.Context, s *portScanner, h scanTarget, info PortInfo) {
+		if s.fingerprintCustom(ctx, h, info.Port, &info) {
+			canonicalizeService(&info)
+			scoreConfidence(&info)
+			s.callback(info)
+		}
+	})
+}
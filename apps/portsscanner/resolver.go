@@ -0,0 +1,204 @@
+package portsscanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsServerAddr 补全DNS服务器地址的默认端口
+func dnsServerAddr(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(server, "53")
+}
+
+// newResolver 创建将所有查询发往指定DNS服务器的解析器，用于分离式DNS环境
+func newResolver(server string) *net.Resolver {
+	addr := dnsServerAddr(server)
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: 5 * time.Second}
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// 并发解析目标主机名的数量上限
+const maxConcurrentLookups = 16
+
+// resolveTargets 展开CIDR/IP范围后解析并去重目标列表。每个主机名单独受DNSTimeout限制，
+// 解析失败的主机名被跳过并随第三个返回值报告，全部目标都无法解析时返回错误
+func resolveTargets(ctx context.Context, config ScanConfig) ([]scanTarget, int, []UnresolvedTarget, error) {
+	inputs, err := expandTargets(splitTargetList(config.Target), config.MaxHosts)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, config.DNSTimeout)
+	defer cancel()
+
+	switch {
+	case config.DoHEndpoint != "":
+		endpoint, err := parseDoHEndpoint(config.DoHEndpoint)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if err := checkResolver(checkCtx, newDoHResolver(endpoint), "DoH endpoint "+endpoint.String()); err != nil {
+			return nil, 0, nil, err
+		}
+	case config.DNSServer != "":
+		if err := checkResolver(checkCtx, newResolver(config.DNSServer), "DNS server "+dnsServerAddr(config.DNSServer)); err != nil {
+			return nil, 0, nil, err
+		}
+	}
+
+	resolved, failed, err := lookupTargets(ctx, config, config.resolver(), inputs)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	pick := func(host string) []string {
+		return []string{preferredAddress(resolved[host])}
+	}
+	var selections map[string]AddressSelection
+	switch {
+	case config.DualStackCompare:
+		pick = func(host string) []string {
+			return dualStackAddresses(resolved[host])
+		}
+	case config.HappyEyeballs:
+		if selections, err = happyEyeballsLookup(ctx, config, resolved); err != nil {
+			return nil, 0, nil, err
+		}
+		pick = func(host string) []string {
+			return []string{selections[host].Address}
+		}
+	}
+	lookup := func(_ context.Context, host string) ([]string, error) {
+		if err, ok := failed[host]; ok {
+			return nil, err
+		}
+		return pick(host), nil
+	}
+
+	targets, duplicates, unresolved, err := normalizeTargets(ctx, inputs, lookup)
+	for i := range unresolved {
+		unresolved[i].Retried = config.RetryUnresolved
+	}
+	if err != nil {
+		return nil, 0, unresolved, err
+	}
+	for i := range targets {
+		for _, name := range targets[i].Names {
+			if sel, ok := selections[name]; ok {
+				targets[i].Selections = append(targets[i].Selections, sel)
+			}
+		}
+	}
+	return targets, duplicates, unresolved, nil
+}
+
+// reportUnresolved 为每个解析失败而被跳过的目标发送 target-resolve-failed 事件
+func (a *App) reportUnresolved(config ScanConfig, unresolved []UnresolvedTarget) {
+	for _, u := range unresolved {
+		a.emitEvent("target-resolve-failed", map[string]interface{}{
+			"target":  config.Target,
+			"host":    u.Host,
+			"error":   u.Error,
+			"retried": u.Retried,
+		})
+	}
+	if len(unresolved) > 0 {
+		fmt.Printf("%d 个目标主机名解析失败，已跳过\n", len(unresolved))
+	}
+}
+
+// lookupTargets 并发解析目标列表中的全部主机名，一个主机名解析失败或超时不会中断或拖慢其他主机名。
+// 设置RetryUnresolved时，在其余主机名解析完成后对失败的主机名重试一次。
+// 返回解析到的地址和失败原因，解析被中断时返回context.Canceled
+func lookupTargets(ctx context.Context, config ScanConfig, resolver *net.Resolver, inputs []string) (map[string][]net.IPAddr, map[string]error, error) {
+	var hosts []string
+	for _, input := range inputs {
+		if _, ok := canonicalIP(input); !ok && !containsString(hosts, input) {
+			hosts = append(hosts, input)
+		}
+	}
+	resolved := make(map[string][]net.IPAddr, len(hosts))
+	failed := make(map[string]error)
+	var mu sync.Mutex
+	lookupAll := func(hosts []string) {
+		var wg sync.WaitGroup
+		semaphore := make(chan struct{}, maxConcurrentLookups)
+		for _, host := range hosts {
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func(host string) {
+				defer func() {
+					<-semaphore
+					wg.Done()
+				}()
+				lookupCtx, cancel := context.WithTimeout(ctx, config.DNSTimeout)
+				defer cancel()
+				addrs, err := lookupHost(lookupCtx, resolver, host)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					failed[host] = err
+					return
+				}
+				delete(failed, host)
+				resolved[host] = addrs
+			}(host)
+		}
+		wg.Wait()
+	}
+
+	lookupAll(hosts)
+	if config.RetryUnresolved && len(failed) > 0 && ctx.Err() == nil {
+		retry := make([]string, 0, len(failed))
+		for _, host := range hosts {
+			if _, ok := failed[host]; ok {
+				retry = append(retry, host)
+			}
+		}
+		lookupAll(retry)
+	}
+	if ctx.Err() != nil {
+		return nil, nil, context.Canceled
+	}
+	return resolved, failed, nil
+}
+
+// resolver 返回本次扫描使用的解析器：优先DoHEndpoint，其次DNSServer，都未配置时使用系统解析器
+func (c ScanConfig) resolver() *net.Resolver {
+	if c.DoHEndpoint != "" {
+		if endpoint, err := parseDoHEndpoint(c.DoHEndpoint); err == nil {
+			return newDoHResolver(endpoint)
+		}
+	}
+	if c.DNSServer == "" {
+		return net.DefaultResolver
+	}
+	return newResolver(c.DNSServer)
+}
+
+// checkResolver 扫描前确认自定义解析服务可达，能返回应答(包括NXDOMAIN)即视为可用
+func checkResolver(ctx context.Context, r *net.Resolver, name string) error {
+	_, err := r.LookupNS(ctx, ".")
+	if err == nil {
+		return nil
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s did not answer in time: %w", name, ErrDNSTimeout)
+	}
+	return fmt.Errorf("%s is not reachable: %w", name, err)
+}
@@ -0,0 +1,79 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// restartMutex 串行化RestartScan，两次重启不会交错地停止和启动扫描
+var restartMutex sync.Mutex
+
+// 等待目标解析阶段被中断时的轮询间隔
+const restartPollInterval = 50 * time.Millisecond
+
+// stopAndWait 停止正在进行的扫描(包括尚在解析目标的扫描)，等待扫描协程完成收尾。
+// 返回被停止的扫描ID，没有扫描时为空
+func (a *App) stopAndWait(timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	stopped := ""
+	for {
+		scanMutex.Lock()
+		scan, resolving := currentScan, resolveCancel != nil
+		scanMutex.Unlock()
+
+		switch {
+		case scan != nil:
+			stopped = scan.record.summary.ID
+			if err := a.StopScan(); err != nil {
+				return stopped, err
+			}
+			select {
+			case <-scan.done:
+			case <-time.After(time.Until(deadline)):
+				return stopped, fmt.Errorf("scan did not stop within %v", timeout)
+			}
+		case resolving:
+			// 解析目标时被取消的扫描由其调用方返回错误，解析刚好结束的扫描会在下一轮作为currentScan停止
+			if err := a.StopScan(); err != nil {
+				return stopped, err
+			}
+			if time.Now().After(deadline) {
+				return stopped, fmt.Errorf("scan did not stop within %v", timeout)
+			}
+			time.Sleep(restartPollInterval)
+		default:
+			return stopped, nil
+		}
+	}
+}
+
+// RestartScan 以新配置重新扫描：停止当前扫描(如有)，等待其记录保存和状态清理完成后再启动新扫描，
+// 成功后发送 scan-restarted 事件。没有扫描在进行时等同于 ScanWithConfig
+func (a *App) RestartScan(config ScanConfig) error {
+	if a == nil || a.ctx == nil {
+		return fmt.Errorf("app context is not initialized")
+	}
+	restartMutex.Lock()
+	defer restartMutex.Unlock()
+
+	previous, err := a.stopAndWait(stopExportTimeout)
+	if err != nil {
+		return err
+	}
+	if err := a.ScanWithConfig(config); err != nil {
+		return err
+	}
+
+	scanID := ""
+	scanMutex.Lock()
+	if currentScan != nil {
+		scanID = currentScan.record.summary.ID
+	}
+	scanMutex.Unlock()
+	a.emitEvent("scan-restarted", map[string]interface{}{
+		"previous_scan_id": previous,
+		"scan_id":          scanID, // 扫描窗口未开放而排队时为空
+	})
+	return nil
+}
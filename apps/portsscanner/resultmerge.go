@@ -0,0 +1,137 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// MergedHost 按IP合并结果时被合并的主机，Names为指向该IP的全部名称
+type MergedHost struct {
+	IP         string   `json:"ip"`
+	Names      []string `json:"names"`
+	Duplicates int      `json:"duplicates"` // 被合并掉的重复结果数
+}
+
+// hostResolver 将结果中的主机名解析为规范化IP
+type hostResolver func(ctx context.Context, host string) (string, error)
+
+// mergeResultsByIP 将指向同一IP的结果合并为同一台主机：主机名先解析为IP，所有名称合并到Aliases，
+// 同一IP、端口和协议只保留一条结果，优先保留有服务识别结果的那条。无法解析的主机名保持不变。
+// 返回合并后的结果和发生合并的主机(按IP排序)
+func mergeResultsByIP(ctx context.Context, results []PortInfo, resolve hostResolver) ([]PortInfo, []MergedHost) {
+	resolved := make(map[string]string)
+	ipOf := func(host string) string {
+		if ip, ok := canonicalIP(host); ok {
+			return ip
+		}
+		if ip, ok := resolved[host]; ok {
+			return ip
+		}
+		ip, err := resolve(ctx, host)
+		if err != nil {
+			ip = host
+		}
+		resolved[host] = ip
+		return ip
+	}
+
+	merged := make([]PortInfo, 0, len(results))
+	index := make(map[resultKey]int)
+	names := make(map[string][]string)   // 每个IP的全部名称
+	origins := make(map[string][]string) // 每个IP在结果中出现的原始Host写法
+	duplicates := make(map[string]int)   // 每个IP被合并掉的重复结果数
+	for _, r := range results {
+		ip := ipOf(r.Host)
+		if !containsString(origins[ip], r.Host) {
+			origins[ip] = append(origins[ip], r.Host)
+		}
+		add := r.Aliases
+		if _, isIP := canonicalIP(r.Host); !isIP && ip != r.Host {
+			add = append([]string{r.Host}, add...)
+		}
+		for _, name := range add {
+			if !containsString(names[ip], name) {
+				names[ip] = append(names[ip], name)
+			}
+		}
+		r.Host = ip
+
+		key := resultKey{ip, r.Port, r.Protocol}
+		if i, exists := index[key]; exists {
+			duplicates[ip]++
+			if merged[i].Service == "" && r.Service != "" {
+				merged[i] = r
+			}
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, r)
+	}
+
+	var hosts []MergedHost
+	for ip, from := range origins {
+		if len(from) > 1 || duplicates[ip] > 0 {
+			hosts = append(hosts, MergedHost{IP: ip, Names: names[ip], Duplicates: duplicates[ip]})
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool { return compareHosts(hosts[i].IP, hosts[j].IP) < 0 })
+	for i := range merged {
+		merged[i].Aliases = names[merged[i].Host]
+	}
+	return merged, hosts
+}
+
+// replaceResults 用合并后的结果替换扫描记录中的结果
+func (r *scanRecord) replaceResults(results []PortInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = results
+	r.summary.OpenPorts = countOpen(results)
+}
+
+// mergeRecordResults 按IP合并扫描记录中的结果，发生合并时发送 results-merged 事件，返回被合并掉的结果数
+func (a *App) mergeRecordResults(record *scanRecord) int {
+	_, config, results := record.snapshot()
+	applyScanDefaults(&config)
+	resolver := config.resolver()
+	ctx, cancel := context.WithTimeout(context.Background(), config.DNSTimeout)
+	defer cancel()
+	merged, hosts := mergeResultsByIP(ctx, results, func(ctx context.Context, host string) (string, error) {
+		return resolveHost(ctx, resolver, host)
+	})
+	if len(hosts) == 0 {
+		return 0
+	}
+	record.replaceResults(merged)
+	removed := len(results) - len(merged)
+	record.log.add(logInfo, "按IP合并结果: %d 台主机，去掉 %d 条重复结果", len(hosts), removed)
+	a.emitEvent("results-merged", map[string]interface{}{
+		"scan_id": record.summary.ID,
+		"hosts":   hosts,
+		"removed": removed,
+		"results": len(merged),
+	})
+	return removed
+}
+
+// MergeResultsByIP 将已有扫描(包括导入的扫描)中指向同一IP的不同主机名的结果合并为一台主机，
+// 保留所有名称，同一端口只保留一条结果。修改会立即保存，返回被合并掉的结果数
+func (a *App) MergeResultsByIP(scanID string) (int, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return 0, err
+	}
+	summary, _, _ := record.snapshot()
+	if summary.Archived {
+		return 0, archivedError(scanID)
+	}
+	if summary.Status == "running" {
+		return 0, fmt.Errorf("scan %q is still running", scanID)
+	}
+	removed := a.mergeRecordResults(record)
+	if err := saveScanRecord(record); err != nil {
+		return removed, fmt.Errorf("failed to save scan %q: %w", scanID, err)
+	}
+	return removed, nil
+}
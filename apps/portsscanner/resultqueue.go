@@ -0,0 +1,74 @@
+package portsscanner
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 扫描线程与结果分发之间的默认缓冲大小
+	defaultResultBuffer = 1024
+	// results-backpressure 警告的最短间隔
+	backpressureWarnInterval = time.Second
+)
+
+// resultQueue 扫描线程与结果分发(前端事件、结果流文件)之间的有界队列。
+// 缓冲区写满时扫描线程阻塞等待，宁可降低扫描速度也不丢弃结果
+type resultQueue struct {
+	ch      chan PortInfo
+	done    chan struct{}
+	deliver func(PortInfo)
+
+	// onBackpressure 缓冲区写满时回调，按backpressureWarnInterval限频
+	onBackpressure func(buffered, capacity int, stalls int64)
+	lastWarn       int64 // 上次回调的UnixNano时间
+	stalls         int64 // 累计阻塞次数
+
+	closeOnce sync.Once
+}
+
+// newResultQueue 创建结果队列并启动唯一的分发协程，size<=0时使用默认缓冲大小
+func newResultQueue(size int, deliver func(PortInfo), onBackpressure func(buffered, capacity int, stalls int64)) *resultQueue {
+	if size <= 0 {
+		size = defaultResultBuffer
+	}
+	q := &resultQueue{
+		ch:             make(chan PortInfo, size),
+		done:           make(chan struct{}),
+		deliver:        deliver,
+		onBackpressure: onBackpressure,
+	}
+	go func() {
+		defer close(q.done)
+		for info := range q.ch {
+			q.deliver(info)
+		}
+	}()
+	return q
+}
+
+// push 由扫描线程调用，缓冲区已满时阻塞直到分发协程腾出空间
+func (q *resultQueue) push(info PortInfo) {
+	select {
+	case q.ch <- info:
+		return
+	default:
+	}
+
+	stalls := atomic.AddInt64(&q.stalls, 1)
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&q.lastWarn)
+	if q.onBackpressure != nil && now-last >= int64(backpressureWarnInterval) && atomic.CompareAndSwapInt64(&q.lastWarn, last, now) {
+		q.onBackpressure(len(q.ch), cap(q.ch), stalls)
+	}
+	q.ch <- info
+}
+
+// close 停止接收结果并等待已缓冲的结果全部分发完毕，可重复调用
+func (q *resultQueue) close() {
+	q.closeOnce.Do(func() {
+		close(q.ch)
+	})
+	<-q.done
+}
@@ -0,0 +1,153 @@
+package portsscanner
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strings"
+)
+
+// resultLess 各排序键的比较函数，返回 a<b 的结果；缺少对应数据的结果总是排在最后
+type resultLess func(a, b PortInfo) (less, ok bool)
+
+var resultSortKeys = map[string]resultLess{
+	"port": func(a, b PortInfo) (bool, bool) {
+		return a.Port < b.Port, a.Port != b.Port
+	},
+	"host": func(a, b PortInfo) (bool, bool) {
+		c := compareHosts(a.Host, b.Host)
+		return c < 0, c != 0
+	},
+	"service": func(a, b PortInfo) (bool, bool) {
+		sa, sb := strings.ToLower(a.Service), strings.ToLower(b.Service)
+		return sa < sb, sa != sb
+	},
+	"latency": func(a, b PortInfo) (bool, bool) {
+		return a.Latency < b.Latency, a.Latency != b.Latency
+	},
+	"tls-expiry": func(a, b PortInfo) (bool, bool) {
+		return a.TLSExpiry.Before(b.TLSExpiry), !a.TLSExpiry.Equal(b.TLSExpiry)
+	},
+	"confidence": func(a, b PortInfo) (bool, bool) {
+		return a.Confidence < b.Confidence, a.Confidence != b.Confidence
+	},
+	"score": func(a, b PortInfo) (bool, bool) {
+		return a.Score < b.Score, a.Score != b.Score
+	},
+}
+
+// missingSortValue 判断结果是否缺少排序键对应的数据，例如非TLS端口没有证书到期时间
+func missingSortValue(by string, r PortInfo) bool {
+	switch by {
+	case "service":
+		return r.Service == ""
+	case "latency":
+		return r.Latency == 0
+	case "tls-expiry":
+		return r.TLSExpiry.IsZero()
+	}
+	return false
+}
+
+// compareHosts 按IP数值比较主机地址，无法解析的地址按字符串比较并排在IP之后
+func compareHosts(a, b string) int {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	switch {
+	case ipA != nil && ipB != nil:
+		return bytes.Compare(ipA.To16(), ipB.To16())
+	case ipA != nil:
+		return -1
+	case ipB != nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// sortResults 按指定键原地稳定排序，相同键值按主机、端口排列；未知的键按主机、端口排序
+func sortResults(results []PortInfo, by string, ascending bool) {
+	by = strings.ToLower(strings.TrimSpace(by))
+	less, known := resultSortKeys[by]
+	host, port := resultSortKeys["host"], resultSortKeys["port"]
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if known {
+			if ma, mb := missingSortValue(by, a), missingSortValue(by, b); ma != mb {
+				return mb
+			} else if !ma {
+				if l, ok := less(a, b); ok {
+					return l == ascending
+				}
+			}
+		}
+		if l, ok := host(a, b); ok {
+			return l
+		}
+		l, _ := port(a, b)
+		return l
+	})
+}
+
+// SortResults 返回按 port、host、service、latency、tls-expiry、confidence 或 score 排序的扫描结果，
+// 在Go层排序以避免前端每次点击表头时重排大量结果；扫描不存在时返回空列表。
+// 返回前按当前评分规则计算Score，按 score 降序排列即为优先处理的顺序
+func (a *App) SortResults(scanID string, by string, ascending bool) []PortInfo {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return []PortInfo{}
+	}
+	_, _, results := record.snapshot()
+	scoreResults(results)
+	sortResults(results, by, ascending)
+	return results
+}
+
+// ResultQuery QueryResults 的筛选条件，零值字段不参与筛选
+type ResultQuery struct {
+	Host          string `json:"host"`
+	Service       string `json:"service"`        // 规范化后的服务名，不区分大小写
+	Protocol      string `json:"protocol"`       // tcp 或 udp
+	MinConfidence int    `json:"min_confidence"` // 只返回识别可信度不低于该值的结果
+	SortBy        string `json:"sort_by"`        // 同SortResults，为空时按主机、端口排序
+	Ascending     bool   `json:"ascending"`
+
+	MinScore int `json:"min_score"` // 只返回评分不低于该值的结果，0不筛选
+}
+
+// matches 判断结果是否满足筛选条件
+func (q ResultQuery) matches(r PortInfo) bool {
+	switch {
+	case q.Host != "" && r.Host != q.Host && !containsString(r.Aliases, q.Host):
+		return false
+	case q.Service != "" && !strings.EqualFold(r.Service, canonicalServiceName(q.Service)):
+		return false
+	case q.Protocol != "" && !strings.EqualFold(r.Protocol, q.Protocol):
+		return false
+	}
+	if q.MinScore != 0 && r.Score < q.MinScore {
+		return false
+	}
+	return r.Confidence >= q.MinConfidence
+}
+
+// QueryResults 按主机、服务、协议、最低识别可信度和最低评分筛选扫描结果并排序，
+// 例如 MinConfidence 设为 80 只看可靠的识别，按 confidence 升序排列则优先人工复核可疑的识别，
+// 按 score 降序排列得到分诊顺序
+func (a *App) QueryResults(scanID string, query ResultQuery) ([]PortInfo, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return nil, err
+	}
+	if ip, ok := canonicalIP(query.Host); ok {
+		query.Host = ip
+	}
+	_, _, results := record.snapshot()
+	scoreResults(results)
+	matched := make([]PortInfo, 0, len(results))
+	for _, r := range results {
+		if query.matches(r) {
+			matched = append(matched, r)
+		}
+	}
+	sortResults(matched, query.SortBy, query.Ascending)
+	return matched, nil
+}
@@ -0,0 +1,320 @@
+package portsscanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrScanNotFound 存储后端中没有指定的扫描记录
+var ErrScanNotFound = errors.New("scan not found in result store")
+
+// StoredRecord 存储后端保存的单次扫描：Summary用于列表、查询和清理，
+// Data为完整记录(结果、配置、检查点、证据和时间线)的JSON，后端不需要理解其结构
+type StoredRecord struct {
+	Summary ScanSummary
+	Data    []byte
+}
+
+// ScanQuery 查询扫描历史的条件，零值字段不作限制。Target按子串匹配，Since/Until限制开始时间
+type ScanQuery struct {
+	Project string    `json:"project,omitempty"`
+	Status  string    `json:"status,omitempty"`
+	Target  string    `json:"target,omitempty"`
+	Since   time.Time `json:"since,omitempty"`
+	Until   time.Time `json:"until,omitempty"`
+	Limit   int       `json:"limit,omitempty"`
+}
+
+// ResultStore 扫描记录的存储后端。内置 file(默认，~/GlideWay/scans下每次扫描一个JSON文件)、memory(只保存在内存中)
+// 以及 sqlite(内置驱动，默认为~/GlideWay/scans.db)和 postgres(database/sql，需要在构建中链接驱动)，集成方可通过RegisterResultStore注册其他后端。
+// Get和Delete在记录不存在时返回包装了ErrScanNotFound的错误；List和Query按开始时间倒序返回；
+// Prune按保留策略(见expiredScans)删除过期记录并返回删除的扫描ID。实现需要支持并发调用
+type ResultStore interface {
+	Save(record StoredRecord) error
+	List() ([]ScanSummary, error)
+	Get(id string) (StoredRecord, error)
+	Query(q ScanQuery) ([]ScanSummary, error)
+	Prune(policy RetentionPolicy, now time.Time) ([]string, error)
+	Delete(id string) error
+}
+
+// ResultStoreOpener 按DSN打开存储后端，DSN的含义由后端决定(file为目录，sqlite为数据库文件，postgres为连接串)
+type ResultStoreOpener func(dsn string) (ResultStore, error)
+
+// 存储配置的文件名，保存在 ~/GlideWay 下
+const storageFile = "storage.json"
+
+// 默认的存储后端。保持file：已有的扫描历史都在scans目录中，OutputDir导出和外部工具也按文件读取记录，
+// 需要SQL查询时通过SetStorageBackend切换到sqlite
+const defaultStorageBackend = "file"
+
+// StorageConfig 存储后端配置，启动时读取，修改后重启生效
+type StorageConfig struct {
+	Backend string `json:"backend"`
+	DSN     string `json:"dsn,omitempty"`
+}
+
+var (
+	storeOpenersMu sync.RWMutex
+	storeOpeners   = map[string]ResultStoreOpener{
+		"file":     openFileStore,
+		"memory":   openMemoryStore,
+		"sqlite":   openSQLStore("sqlite"),
+		"postgres": openSQLStore("postgres"),
+	}
+
+	resultStoreMu sync.RWMutex
+	resultStore   ResultStore
+	storageConfig = StorageConfig{Backend: defaultStorageBackend}
+)
+
+// RegisterResultStore 注册存储后端，名称不区分大小写，同名时替换内置后端。需要在应用启动前调用
+func RegisterResultStore(name string, open ResultStoreOpener) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || open == nil {
+		return
+	}
+	storeOpenersMu.Lock()
+	storeOpeners[name] = open
+	storeOpenersMu.Unlock()
+}
+
+// storeOpener 返回已注册的存储后端
+func storeOpener(name string) (ResultStoreOpener, error) {
+	storeOpenersMu.RLock()
+	defer storeOpenersMu.RUnlock()
+	open, ok := storeOpeners[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(storeOpeners))
+		for n := range storeOpeners {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown result store backend %q (want one of %s)", name, strings.Join(names, ", "))
+	}
+	return open, nil
+}
+
+// loadStorageConfig 读取存储配置，文件不存在时使用默认的file后端
+func loadStorageConfig() (StorageConfig, error) {
+	config := StorageConfig{Backend: defaultStorageBackend}
+	path, err := dataFilePath(storageFile)
+	if err != nil {
+		return config, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read storage config: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return StorageConfig{Backend: defaultStorageBackend}, fmt.Errorf("failed to parse storage config %s: %w", path, err)
+	}
+	if config.Backend == "" {
+		config.Backend = defaultStorageBackend
+	}
+	return config, nil
+}
+
+// openStorage 启动时按存储配置打开后端，配置无效或打开失败时回退到file后端
+func openStorage() error {
+	config, err := loadStorageConfig()
+	if err == nil {
+		var open ResultStoreOpener
+		if open, err = storeOpener(config.Backend); err == nil {
+			var store ResultStore
+			if store, err = open(config.DSN); err == nil {
+				setResultStore(store, config)
+				return nil
+			}
+		}
+	}
+	store, fileErr := openFileStore("")
+	if fileErr != nil {
+		return fileErr
+	}
+	setResultStore(store, StorageConfig{Backend: defaultStorageBackend})
+	return fmt.Errorf("failed to open %s result store, using file store: %w", config.Backend, err)
+}
+
+func setResultStore(store ResultStore, config StorageConfig) {
+	resultStoreMu.Lock()
+	defer resultStoreMu.Unlock()
+	resultStore = store
+	storageConfig = config
+}
+
+// activeStore 返回当前的存储后端，启动前调用时使用file后端
+func activeStore() (ResultStore, error) {
+	resultStoreMu.RLock()
+	store := resultStore
+	resultStoreMu.RUnlock()
+	if store != nil {
+		return store, nil
+	}
+	store, err := openFileStore("")
+	if err != nil {
+		return nil, err
+	}
+	resultStoreMu.Lock()
+	defer resultStoreMu.Unlock()
+	if resultStore == nil {
+		resultStore = store
+	}
+	return resultStore, nil
+}
+
+// matchScanQuery 判断扫描是否满足查询条件，供不能在后端内过滤的存储使用
+func matchScanQuery(s ScanSummary, q ScanQuery) bool {
+	switch {
+	case q.Project != "" && s.Project != q.Project:
+		return false
+	case q.Status != "" && s.Status != q.Status:
+		return false
+	case q.Target != "" && !strings.Contains(s.Target, q.Target):
+		return false
+	case !q.Since.IsZero() && s.StartedAt.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && s.StartedAt.After(q.Until):
+		return false
+	}
+	return true
+}
+
+// queryScans 在List的结果中按条件过滤
+func queryScans(store ResultStore, q ScanQuery) ([]ScanSummary, error) {
+	summaries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	matched := []ScanSummary{}
+	for _, s := range summaries {
+		if !matchScanQuery(s, q) {
+			continue
+		}
+		matched = append(matched, s)
+		if q.Limit > 0 && len(matched) >= q.Limit {
+			break
+		}
+	}
+	return matched, nil
+}
+
+// pruneByPolicy 按保留策略逐个删除过期记录，删除失败的记录保留，下次清理时重试
+func pruneByPolicy(store ResultStore, policy RetentionPolicy, now time.Time) ([]string, error) {
+	summaries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	var errs []error
+	for _, id := range expiredScans(summaries, policy, now) {
+		if err := store.Delete(id); err != nil && !errors.Is(err, ErrScanNotFound) {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, id)
+	}
+	if len(errs) > 0 {
+		return removed, fmt.Errorf("failed to remove %d scan record(s): %w", len(errs), errs[0])
+	}
+	return removed, nil
+}
+
+// sortSummaries 按开始时间倒序排列
+func sortSummaries(summaries []ScanSummary) {
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt.After(summaries[j].StartedAt)
+	})
+}
+
+// GetStorageBackend 返回当前使用的存储后端配置
+func (a *App) GetStorageBackend() StorageConfig {
+	resultStoreMu.RLock()
+	defer resultStoreMu.RUnlock()
+	return storageConfig
+}
+
+// SetStorageBackend 保存存储后端配置(file、memory、sqlite、postgres或已注册的后端)，下次启动时生效。
+// 保存前会尝试打开一次后端，无法打开时不保存并返回错误
+func (a *App) SetStorageBackend(backend, dsn string) error {
+	backend = strings.ToLower(strings.TrimSpace(backend))
+	if backend == "" {
+		backend = defaultStorageBackend
+	}
+	open, err := storeOpener(backend)
+	if err != nil {
+		return err
+	}
+	store, err := open(dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open %s result store: %w", backend, err)
+	}
+	if closer, ok := store.(io.Closer); ok {
+		closer.Close()
+	}
+	path, err := dataFilePath(storageFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(StorageConfig{Backend: backend, DSN: dsn}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write storage config: %w", err)
+	}
+	fmt.Printf("存储后端已设置为 %s，重启后生效\n", backend)
+	return nil
+}
+
+// QueryScans 直接在存储后端中查询扫描历史，共享后端时包括其他成员保存的扫描
+func (a *App) QueryScans(query ScanQuery) ([]ScanSummary, error) {
+	store, err := activeStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Query(query)
+}
+
+// DeleteScan 从历史和存储后端中删除已结束的扫描，已归档的扫描需要先取消归档
+func (a *App) DeleteScan(scanID string) error {
+	record, err := getScanRecord(scanID)
+	if err == nil {
+		summary, _, _ := record.snapshot()
+		if summary.Archived {
+			return archivedError(scanID)
+		}
+		if summary.Status == "running" {
+			return fmt.Errorf("scan %q is still running", scanID)
+		}
+	}
+	store, err := activeStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(scanID); err != nil {
+		if !errors.Is(err, ErrScanNotFound) {
+			return fmt.Errorf("failed to delete scan %q: %w", scanID, err)
+		}
+		if record == nil {
+			return fmt.Errorf("scan %q not found", scanID)
+		}
+	}
+	storeMutex.Lock()
+	delete(scanStore, scanID)
+	if latestResults == scanID {
+		latestResults = ""
+	}
+	storeMutex.Unlock()
+	return nil
+}
@@ -0,0 +1,151 @@
+package portsscanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 保留策略的文件名，保存在 ~/GlideWay 下
+const retentionFile = "retention.json"
+
+// RetentionPolicy 扫描历史的保留策略，MaxScans为最多保留的扫描数，MaxAge为扫描开始后最长保留多久，
+// 为0表示不限制。两者都为0时不自动清理
+type RetentionPolicy struct {
+	MaxScans int           `json:"max_scans"`
+	MaxAge   time.Duration `json:"max_age"`
+}
+
+// retentionSettings 当前的保留策略，首次使用时从磁盘读取
+type retentionSettings struct {
+	mu     sync.Mutex
+	loaded bool
+	policy RetentionPolicy
+}
+
+var retention retentionSettings
+
+// get 返回当前策略，文件不存在时为不限制
+func (r *retentionSettings) get() (RetentionPolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loaded {
+		return r.policy, nil
+	}
+	path, err := dataFilePath(retentionFile)
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return RetentionPolicy{}, fmt.Errorf("failed to read retention policy: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &r.policy); err != nil {
+			return RetentionPolicy{}, fmt.Errorf("failed to parse retention policy %s: %w", path, err)
+		}
+	}
+	r.loaded = true
+	return r.policy, nil
+}
+
+// set 保存新的策略，写入失败时保持原策略
+func (r *retentionSettings) set(policy RetentionPolicy) error {
+	path, err := dataFilePath(retentionFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write retention policy: %w", err)
+	}
+	r.policy = policy
+	r.loaded = true
+	return nil
+}
+
+// expiredScans 按开始时间从新到旧排列，返回超出MaxScans或早于MaxAge的扫描ID。
+// 正在运行的扫描不会被清理，但计入保留数量；已归档的扫描不会被清理，也不计入保留数量
+func expiredScans(summaries []ScanSummary, policy RetentionPolicy, now time.Time) []string {
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt.After(summaries[j].StartedAt)
+	})
+	var expired []string
+	i := -1
+	for _, summary := range summaries {
+		if summary.Archived {
+			continue
+		}
+		i++
+		if summary.Status == "running" {
+			continue
+		}
+		tooMany := policy.MaxScans > 0 && i >= policy.MaxScans
+		tooOld := policy.MaxAge > 0 && now.Sub(summary.StartedAt) > policy.MaxAge
+		if tooMany || tooOld {
+			expired = append(expired, summary.ID)
+		}
+	}
+	return expired
+}
+
+// pruneStore 按保留策略删除存储后端中过期的扫描记录并移出历史，返回删除的数量。
+// 删除失败的扫描保留在历史中，下次清理时重试
+func pruneStore() (int, error) {
+	policy, err := retention.get()
+	if err != nil {
+		return 0, err
+	}
+	if policy.MaxScans <= 0 && policy.MaxAge <= 0 {
+		return 0, nil
+	}
+	store, err := activeStore()
+	if err != nil {
+		return 0, err
+	}
+
+	removed, err := store.Prune(policy, time.Now())
+	storeMutex.Lock()
+	for _, id := range removed {
+		delete(scanStore, id)
+		if latestResults == id {
+			latestResults = ""
+		}
+	}
+	storeMutex.Unlock()
+	if len(removed) > 0 {
+		fmt.Printf("按保留策略清理 %d 条扫描记录\n", len(removed))
+	}
+	return len(removed), err
+}
+
+// SetRetention 设置扫描历史的保留策略：最多保留maxScans次扫描，删除开始时间早于maxAge的扫描，
+// 为0表示不限制。策略保存在磁盘上，启动时和每次扫描结束后自动清理
+func (a *App) SetRetention(maxScans int, maxAge time.Duration) error {
+	if maxScans < 0 {
+		return fmt.Errorf("invalid max scans %d", maxScans)
+	}
+	if maxAge < 0 {
+		return fmt.Errorf("invalid max age %s", maxAge)
+	}
+	return retention.set(RetentionPolicy{MaxScans: maxScans, MaxAge: maxAge})
+}
+
+// GetRetention 返回当前的保留策略
+func (a *App) GetRetention() (RetentionPolicy, error) {
+	return retention.get()
+}
+
+// PruneStore 立即按保留策略清理扫描历史，返回删除的扫描数量
+func (a *App) PruneStore() (int, error) {
+	return pruneStore()
+}
@@ -0,0 +1,309 @@
+package portsscanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RiskModel 主机风险评分模型。主机得分为四类因素之和，每类有上限，总分截断到0-100：
+//
+//   - open-ports：每个开放端口OpenPortPoints分，最多OpenPortCap分，反映暴露面
+//   - services：每个端口按服务名取ServicePoints中的分数，未列出的已识别服务为DefaultServicePoints，
+//     无法识别的服务为UnidentifiedPoints，最多ServiceCap分
+//   - outdated：产品名包含OutdatedVersions中的键且版本号低于对应值时OutdatedPoints分，最多OutdatedCap分
+//   - tls：证书已过期TLSExpiredPoints、即将到期TLSExpiringPoints、自签名SelfSignedPoints，最多TLSCap分
+//
+// 疑似蜜罐的端口不计分。默认模型见defaultRiskModel，LoadRiskModel可覆盖任意字段
+type RiskModel struct {
+	OpenPortPoints int `json:"open_port_points"`
+	OpenPortCap    int `json:"open_port_cap"`
+
+	ServicePoints        map[string]int `json:"service_points"` // 按规范化后的服务名
+	DefaultServicePoints int            `json:"default_service_points"`
+	UnidentifiedPoints   int            `json:"unidentified_points"`
+	ServiceCap           int            `json:"service_cap"`
+
+	OutdatedVersions map[string]string `json:"outdated_versions"` // 产品名子串(不区分大小写)到最低可接受版本
+	OutdatedPoints   int               `json:"outdated_points"`
+	OutdatedCap      int               `json:"outdated_cap"`
+
+	TLSExpiredPoints  int `json:"tls_expired_points"`
+	TLSExpiringPoints int `json:"tls_expiring_points"`
+	SelfSignedPoints  int `json:"self_signed_points"`
+	TLSCap            int `json:"tls_cap"`
+}
+
+// defaultRiskModel 默认模型：明文登录和远程管理协议、常被未授权访问的数据库分数最高，
+// 暴露面和证书问题权重较低，单类因素最多占总分的40%
+func defaultRiskModel() RiskModel {
+	return RiskModel{
+		OpenPortPoints: 2,
+		OpenPortCap:    20,
+
+		ServicePoints: map[string]int{
+			"telnet": 25, "rlogin": 25, "rsh": 25, "rexec": 25,
+			"ftp": 15, "tftp": 15, "snmp": 10,
+			"ms-wbt-server": 15, "vnc": 20, "x11": 20,
+			"microsoft-ds": 20, "netbios-ssn": 15, "nfs": 15,
+			"mysql": 15, "postgresql": 15, "ms-sql-s": 15, "oracle": 15,
+			"mongodb": 20, "redis": 20, "elasticsearch": 20, "memcached": 20, "docker": 25,
+			"ssh": 5, "http": 3, "https": 2,
+		},
+		DefaultServicePoints: 3,
+		UnidentifiedPoints:   5,
+		ServiceCap:           40,
+
+		OutdatedVersions: map[string]string{
+			"openssh":       "8.0",
+			"apache httpd":  "2.4.50",
+			"nginx":         "1.20",
+			"microsoft iis": "10.0",
+			"vsftpd":        "3.0",
+			"proftpd":       "1.3.6",
+			"exim":          "4.94",
+			"mysql":         "5.7",
+			"openssl":       "1.1.1",
+		},
+		OutdatedPoints: 15,
+		OutdatedCap:    30,
+
+		TLSExpiredPoints:  10,
+		TLSExpiringPoints: 4,
+		SelfSignedPoints:  3,
+		TLSCap:            15,
+	}
+}
+
+// RiskFactor 主机得分中的一类因素，Items为各端口的具体贡献，如 "23/tcp telnet +25"
+type RiskFactor struct {
+	Factor string   `json:"factor"`
+	Points int      `json:"points"` // 应用上限后计入总分的分数
+	Capped bool     `json:"capped,omitempty"`
+	Items  []string `json:"items,omitempty"`
+}
+
+// HostRisk 单台主机的风险评分和构成
+type HostRisk struct {
+	Host    string       `json:"host"`
+	Aliases []string     `json:"aliases,omitempty"`
+	Score   int          `json:"score"` // 0-100
+	Factors []RiskFactor `json:"factors"`
+}
+
+var (
+	riskModel   = defaultRiskModel()
+	riskModelMu sync.RWMutex
+)
+
+// currentRiskModel 返回当前生效的模型
+func currentRiskModel() RiskModel {
+	riskModelMu.RLock()
+	defer riskModelMu.RUnlock()
+	return riskModel
+}
+
+// riskAccumulator 单类因素的累计分数
+type riskAccumulator struct {
+	name   string
+	limit  int
+	points int
+	items  []string
+}
+
+func (f *riskAccumulator) add(r PortInfo, what string, points int) {
+	if points == 0 {
+		return
+	}
+	f.points += points
+	f.items = append(f.items, fmt.Sprintf("%d/%s %s %+d", r.Port, r.Protocol, what, points))
+}
+
+func (f *riskAccumulator) factor() RiskFactor {
+	points := min(f.points, f.limit)
+	return RiskFactor{Factor: f.name, Points: max(points, 0), Capped: f.points > f.limit, Items: f.items}
+}
+
+// outdatedProduct 返回结果命中的过旧产品规则，未命中时返回空
+func (m RiskModel) outdatedProduct(r PortInfo) (string, string) {
+	version := versionNumbers(r.Version)
+	if len(version) == 0 {
+		return "", ""
+	}
+	product := strings.ToLower(r.ProductName)
+	// 按键排序，多个键同时命中时结果稳定
+	keys := make([]string, 0, len(m.OutdatedVersions))
+	for k := range m.OutdatedVersions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if k != "" && strings.Contains(product, strings.ToLower(k)) && versionLess(version, versionNumbers(m.OutdatedVersions[k])) {
+			return k, m.OutdatedVersions[k]
+		}
+	}
+	return "", ""
+}
+
+// scoreHost 按模型计算一台主机的风险评分
+func (m RiskModel) scoreHost(host string, aliases []string, results []PortInfo, now time.Time) HostRisk {
+	exposure := &riskAccumulator{name: "open-ports", limit: m.OpenPortCap}
+	services := &riskAccumulator{name: "services", limit: m.ServiceCap}
+	outdated := &riskAccumulator{name: "outdated", limit: m.OutdatedCap}
+	tlsHealth := &riskAccumulator{name: "tls", limit: m.TLSCap}
+
+	for _, r := range results {
+		if r.LikelyHoneypot {
+			continue
+		}
+		exposure.add(r, "open", m.OpenPortPoints)
+
+		service := canonicalServiceName(r.Service)
+		switch points, listed := m.ServicePoints[service]; {
+		case listed:
+			services.add(r, service, points)
+		case service == "" || r.Unidentified:
+			services.add(r, "unidentified", m.UnidentifiedPoints)
+		default:
+			services.add(r, service, m.DefaultServicePoints)
+		}
+
+		if product, minVersion := m.outdatedProduct(r); product != "" {
+			outdated.add(r, fmt.Sprintf("%s %s < %s", r.ProductName, r.Version, minVersion), m.OutdatedPoints)
+		}
+
+		switch {
+		case !r.TLSExpiry.IsZero() && r.TLSExpiry.Before(now):
+			tlsHealth.add(r, "certificate expired", m.TLSExpiredPoints)
+		case tlsExpiringSoon(r.TLSExpiry, now):
+			tlsHealth.add(r, "certificate expiring", m.TLSExpiringPoints)
+		}
+		if r.SelfSigned {
+			tlsHealth.add(r, "self-signed certificate", m.SelfSignedPoints)
+		}
+	}
+
+	risk := HostRisk{Host: host, Aliases: aliases}
+	for _, f := range []*riskAccumulator{exposure, services, outdated, tlsHealth} {
+		factor := f.factor()
+		risk.Score += factor.Points
+		risk.Factors = append(risk.Factors, factor)
+	}
+	risk.Score = min(max(risk.Score, 0), 100)
+	return risk
+}
+
+// hostRisks 按主机计算风险评分(只计开放的端口)，按分数从高到低排列，分数相同时按主机排序
+func hostRisks(results []PortInfo, now time.Time) []HostRisk {
+	model := currentRiskModel()
+	byHost := make(map[string][]PortInfo)
+	var order []string
+	for _, r := range results {
+		if r.State != "" {
+			continue
+		}
+		if _, ok := byHost[r.Host]; !ok {
+			order = append(order, r.Host)
+		}
+		byHost[r.Host] = append(byHost[r.Host], r)
+	}
+	risks := make([]HostRisk, 0, len(order))
+	for _, host := range order {
+		ports := byHost[host]
+		risks = append(risks, model.scoreHost(host, ports[0].Aliases, ports, now))
+	}
+	sort.Slice(risks, func(i, j int) bool {
+		if risks[i].Score != risks[j].Score {
+			return risks[i].Score > risks[j].Score
+		}
+		return compareHosts(risks[i].Host, risks[j].Host) < 0
+	})
+	return risks
+}
+
+// GetHostRiskScores 返回扫描中每台主机的风险评分(0-100)，扫描不存在时返回空
+func (a *App) GetHostRiskScores(scanID string) map[string]int {
+	scores := make(map[string]int)
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return scores
+	}
+	_, _, results := record.snapshot()
+	for _, risk := range hostRisks(results, time.Now()) {
+		scores[risk.Host] = risk.Score
+	}
+	return scores
+}
+
+// GetHostRisks 返回扫描中每台主机的风险评分及各因素的构成，按分数从高到低排列，可直接作为修复优先级列表
+func (a *App) GetHostRisks(scanID string) ([]HostRisk, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return nil, err
+	}
+	_, _, results := record.snapshot()
+	return hostRisks(results, time.Now()), nil
+}
+
+// LoadRiskModel 从JSON文件(格式见RiskModel)加载风险评分模型，文件中未出现的字段保持默认值，
+// service_points和outdated_versions中的条目与默认条目合并(分数设为0即可忽略某个服务)
+func (a *App) LoadRiskModel(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read risk model %s: %w", path, err)
+	}
+	model := defaultRiskModel()
+	if err := json.Unmarshal(data, &model); err != nil {
+		return fmt.Errorf("failed to parse risk model %s: %w", path, err)
+	}
+	for name, points := range model.ServicePoints {
+		if canonical := canonicalServiceName(name); canonical != name {
+			delete(model.ServicePoints, name)
+			model.ServicePoints[canonical] = points
+		}
+	}
+	for product, version := range model.OutdatedVersions {
+		if len(versionNumbers(version)) == 0 {
+			return fmt.Errorf("risk model %s: invalid version %q for %q", path, version, product)
+		}
+	}
+	for name, limit := range map[string]int{"open_port_cap": model.OpenPortCap, "service_cap": model.ServiceCap, "outdated_cap": model.OutdatedCap, "tls_cap": model.TLSCap} {
+		if limit < 0 {
+			return fmt.Errorf("risk model %s: %s must not be negative", path, name)
+		}
+	}
+
+	riskModelMu.Lock()
+	riskModel = model
+	riskModelMu.Unlock()
+	fmt.Printf("已加载风险评分模型 %s\n", path)
+	return nil
+}
+
+// GetRiskModel 返回当前生效的风险评分模型
+func (a *App) GetRiskModel() RiskModel {
+	return currentRiskModel()
+}
+
+// writeRiskMarkdown 写出主机风险评分表
+func writeRiskMarkdown(sb *strings.Builder, risks []HostRisk) {
+	if len(risks) == 0 {
+		return
+	}
+	sb.WriteString("\n| Host | Risk | Breakdown |\n")
+	sb.WriteString("|---|---|---|\n")
+	for _, risk := range risks {
+		var parts []string
+		for _, f := range risk.Factors {
+			if f.Points > 0 {
+				parts = append(parts, f.Factor+" "+strconv.Itoa(f.Points))
+			}
+		}
+		fmt.Fprintf(sb, "| %s | %d | %s |\n", markdownCell(textHostName(risk.Host, risk.Aliases)), risk.Score, markdownCell(strings.Join(parts, ", ")))
+	}
+}
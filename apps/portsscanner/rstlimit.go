@@ -0,0 +1,246 @@
+package portsscanner
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RST限速检测：主机先快速回应大量RST，随后连续多个端口超时，说明超时很可能是RST被限速丢弃，
+// 而不是端口真的被过滤
+const (
+	rstMinResets     = 20                     // 判定前主机至少已回应的RST数
+	rstTimeoutBurst  = 8                      // 连续超时的端口数
+	rstBurstWindow   = 2 * time.Second        // 连续超时开始时距最近一次RST的最长间隔
+	rstSlowInterval  = 20 * time.Millisecond  // AdaptRSTRateLimit降速后同一主机相邻两次探测的最小间隔
+	rstRetestDelay   = 200 * time.Millisecond // AdaptRSTRateLimit复测时同一主机相邻两次探测的间隔
+	rstMaxAmbiguous  = 4096                   // 每台主机最多记录的待复测端口数
+	rstRecentTimeout = rstTimeoutBurst * 2    // 判定前记录的最近超时端口数，判定时一并复测
+)
+
+// rstHostState 单台主机的RST/超时统计
+type rstHostState struct {
+	resets    int
+	lastReset time.Time
+	burst     int       // 最近一次RST之后连续超时的端口数
+	burstFrom time.Time // 连续超时开始的时间
+	limited   bool
+	ambiguous []int     // 判定限速后超时的端口，主扫描结束后低速复测
+	next      time.Time // 降速后下一次允许探测的时间
+}
+
+// rstTracker 按主机检测RST限速，开启AdaptRSTRateLimit时对限速主机降速并记录待复测端口
+type rstTracker struct {
+	mu    sync.Mutex
+	hosts map[string]*rstHostState
+	adapt bool
+}
+
+func newRSTTracker(adapt bool) *rstTracker {
+	return &rstTracker{hosts: make(map[string]*rstHostState), adapt: adapt}
+}
+
+// tracksRSTRateLimit 状态依据RST和超时区分关闭与过滤的扫描方式才需要检测
+func (c ScanConfig) tracksRSTRateLimit() bool {
+	switch c.ScanType {
+	case ScanTypeConnect, ScanTypeSYN, ScanTypeBoth:
+		return true
+	}
+	return false
+}
+
+func (t *rstTracker) host(ip string) *rstHostState {
+	st := t.hosts[ip]
+	if st == nil {
+		st = &rstHostState{}
+		t.hosts[ip] = st
+	}
+	return st
+}
+
+// observe 记录一次探测结果，主机第一次被判定为RST限速时返回true
+func (t *rstTracker) observe(ip string, p int, state string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.host(ip)
+	switch state {
+	case portClosed:
+		st.resets++
+		st.lastReset = now
+		st.burst = 0
+		if !st.limited {
+			st.ambiguous = st.ambiguous[:0]
+		}
+		return false
+	case portFiltered:
+	default:
+		return false
+	}
+
+	if st.burst == 0 {
+		st.burstFrom = now
+	}
+	st.burst++
+	if len(st.ambiguous) < rstMaxAmbiguous && (st.limited || len(st.ambiguous) < rstRecentTimeout) {
+		st.ambiguous = append(st.ambiguous, p)
+	}
+	if st.limited || st.resets < rstMinResets || st.burst < rstTimeoutBurst {
+		return false
+	}
+	if st.burstFrom.Sub(st.lastReset) > rstBurstWindow {
+		return false
+	}
+	st.limited = true
+	return true
+}
+
+// stats 返回主机已回应的RST数和当前连续超时数
+func (t *rstTracker) stats(ip string) (int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := t.host(ip)
+	return st.resets, st.burst
+}
+
+// pace 开启AdaptRSTRateLimit时，限速主机的相邻两次探测至少间隔rstSlowInterval，扫描被取消时返回false
+func (t *rstTracker) pace(ctx context.Context, ip string) bool {
+	if !t.adapt {
+		return true
+	}
+	t.mu.Lock()
+	st := t.hosts[ip]
+	if st == nil || !st.limited {
+		t.mu.Unlock()
+		return true
+	}
+	now := time.Now()
+	if st.next.Before(now) {
+		st.next = now
+	}
+	wait := st.next.Sub(now)
+	st.next = st.next.Add(rstSlowInterval)
+	t.mu.Unlock()
+	if wait <= 0 {
+		return true
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(wait):
+		return true
+	}
+}
+
+// retestTargets 返回各限速主机待复测的端口(去重排序)
+func (t *rstTracker) retestTargets() map[string][]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	targets := make(map[string][]int)
+	for ip, st := range t.hosts {
+		if !st.limited || len(st.ambiguous) == 0 {
+			continue
+		}
+		ports := append([]int(nil), st.ambiguous...)
+		sort.Ints(ports)
+		unique := ports[:0]
+		for i, p := range ports {
+			if i == 0 || p != ports[i-1] {
+				unique = append(unique, p)
+			}
+		}
+		targets[ip] = unique
+	}
+	return targets
+}
+
+// observeRST 记录TCP探测结果，主机第一次被判定为RST限速时发送 rst-ratelimit-detected 事件
+func (s *portScanner) observeRST(h scanTarget, p int, state string) {
+	if s.rst == nil || !s.rst.observe(h.IP, p, state, time.Now()) {
+		return
+	}
+	resets, burst := s.rst.stats(h.IP)
+	if s.rst.adapt {
+		s.log(logWarn, "%s 回应 %d 个RST后连续 %d 个端口超时，疑似RST限速，降低探测速率并在扫描结束后复测超时端口", h.IP, resets, burst)
+	} else {
+		s.log(logWarn, "%s 回应 %d 个RST后连续 %d 个端口超时，疑似RST限速，部分filtered结果可能实际为关闭", h.IP, resets, burst)
+	}
+	s.emit("rst-ratelimit-detected", map[string]interface{}{
+		"host":     h.IP,
+		"aliases":  h.Names,
+		"resets":   resets,
+		"timeouts": burst,
+		"adapting": s.rst.adapt,
+	})
+}
+
+// retestRateLimited 主扫描结束后依次低速复测限速主机上超时的端口，按复测结果重新分类：
+// 开放的进行指纹识别并报告，其余只计入 rst-ratelimit-retested 事件
+func (s *portScanner) retestRateLimited(ctx context.Context, hosts []scanTarget) {
+	if s.rst == nil || !s.rst.adapt {
+		return
+	}
+	targets := s.rst.retestTargets()
+	if len(targets) == 0 {
+		return
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(s.config.connectThreads(), 1))
+	for _, h := range hosts {
+		ports := targets[h.IP]
+		if len(ports) == 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(h scanTarget, ports []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.retestHost(ctx, h, ports)
+		}(h, ports)
+	}
+	wg.Wait()
+}
+
+// retestHost 对单台主机的待复测端口逐个探测，相邻两次探测间隔rstRetestDelay
+func (s *portScanner) retestHost(ctx context.Context, h scanTarget, ports []int) {
+	s.log(logInfo, "%s 低速复测 %d 个超时端口", h.IP, len(ports))
+	counts := map[string]int{}
+	for i, p := range ports {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(rstRetestDelay):
+			}
+		}
+		state, _ := s.probePort(ctx, h, p)
+		if ctx.Err() != nil {
+			return
+		}
+		counts[state]++
+		if state != portOpen {
+			continue
+		}
+		s.log(logInfo, "%s:%d 复测为开放", h.IP, p)
+		s.identify(ctx, h, p, &PortInfo{
+			Host:     h.IP,
+			Aliases:  h.Names,
+			Port:     p,
+			Endpoint: s.config.endpointName(h.IP, p),
+			Protocol: "tcp",
+		})
+	}
+	s.log(logInfo, "%s 复测完成: %d 个开放，%d 个关闭，%d 个仍然超时", h.IP, counts[portOpen], counts[portClosed], counts[portFiltered])
+	s.emit("rst-ratelimit-retested", map[string]interface{}{
+		"host":     h.IP,
+		"retested": len(ports),
+		"open":     counts[portOpen],
+		"closed":   counts[portClosed],
+		"filtered": counts[portFiltered],
+	})
+}
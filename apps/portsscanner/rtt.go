@@ -0,0 +1,77 @@
+package portsscanner
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 收集到这么多次响应后才启用自适应超时，之前使用配置的Timeout
+	adaptiveTimeoutMinSamples = 20
+	// 计算中位数使用的最近响应数，网络状况变化后超时随之调整
+	adaptiveTimeoutWindow = 200
+	// 每收到这么多次响应重新计算一次超时
+	adaptiveTimeoutRecalc = 10
+	// 超时默认取RTT中位数的倍数
+	defaultAdaptiveTimeoutFactor = 4.0
+	// 自适应超时的下限，避免RTT极低时调度抖动造成误判
+	adaptiveTimeoutFloor = 50 * time.Millisecond
+)
+
+// rttEstimator 根据有响应的探测(开放或拒绝连接)的耗时估计连接超时：
+// 取最近样本RTT中位数的factor倍，限制在 adaptiveTimeoutFloor 和配置的Timeout之间
+type rttEstimator struct {
+	mu      sync.Mutex
+	samples []time.Duration // 环形缓冲
+	next    int
+	count   int
+	factor  float64
+	max     time.Duration
+
+	current int64 // 当前生效的超时(纳秒)，原子读写
+}
+
+func newRTTEstimator(max time.Duration, factor float64) *rttEstimator {
+	if factor <= 1 {
+		factor = defaultAdaptiveTimeoutFactor
+	}
+	return &rttEstimator{
+		samples: make([]time.Duration, adaptiveTimeoutWindow),
+		factor:  factor,
+		max:     max,
+		current: int64(max),
+	}
+}
+
+// observe 记录一次有响应探测的耗时
+func (e *rttEstimator) observe(rtt time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.samples[e.next] = rtt
+	e.next = (e.next + 1) % len(e.samples)
+	e.count++
+	if e.count < adaptiveTimeoutMinSamples || e.count%adaptiveTimeoutRecalc != 0 {
+		return
+	}
+
+	window := append([]time.Duration(nil), e.samples[:min(e.count, len(e.samples))]...)
+	sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+	timeout := time.Duration(float64(window[len(window)/2]) * e.factor)
+	timeout = max(adaptiveTimeoutFloor, min(timeout, e.max))
+	atomic.StoreInt64(&e.current, int64(timeout))
+}
+
+// timeout 返回当前生效的连接超时
+func (e *rttEstimator) timeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&e.current))
+}
+
+// connectTimeout 端口状态探测使用的超时，启用AdaptiveTimeout时随观察到的RTT调整
+func (c ScanConfig) connectTimeout() time.Duration {
+	if c.rtt != nil {
+		return c.rtt.timeout()
+	}
+	return c.Timeout
+}
@@ -0,0 +1,153 @@
+package portsscanner
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ErrUnauthorizedTarget 安全模式下目标超出允许范围且未确认，前端据此弹出确认对话框
+var ErrUnauthorizedTarget = errors.New("unauthorized-target")
+
+// 安全模式默认允许的地址范围：RFC 1918私有地址、回环、链路本地和IPv6唯一本地地址
+var defaultAllowedRanges = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+// 错误信息中最多列出的越界目标数
+const maxUnauthorizedListed = 5
+
+// SafeModeSettings 安全模式设置。启用时扫描允许范围之外的目标需要在ScanConfig中设置Acknowledged
+type SafeModeSettings struct {
+	Enabled       bool     `json:"enabled"`
+	AllowedRanges []string `json:"allowed_ranges"` // CIDR列表，为空时使用默认的私有和回环地址段
+}
+
+// safeModePolicy App中保存的安全模式设置，零值即为启用且使用默认范围
+type safeModePolicy struct {
+	disabled bool
+	ranges   []netip.Prefix // nil时使用defaultAllowedRanges
+}
+
+// parseAllowedRanges 解析CIDR列表，单个地址视为/32或/128
+func parseAllowedRanges(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed range %q: %w", s, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed range %q: %w", s, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// allowedRanges 返回生效的允许范围
+func (p safeModePolicy) allowedRanges() []netip.Prefix {
+	if p.ranges != nil {
+		return p.ranges
+	}
+	prefixes, _ := parseAllowedRanges(defaultAllowedRanges)
+	return prefixes
+}
+
+// unauthorizedHosts 返回不在允许范围内的目标
+func (p safeModePolicy) unauthorizedHosts(hosts []scanTarget) []string {
+	ranges := p.allowedRanges()
+	var outside []string
+	for _, h := range hosts {
+		addr, err := netip.ParseAddr(h.IP)
+		if err != nil {
+			outside = append(outside, h.IP)
+			continue
+		}
+		addr = addr.Unmap().WithZone("")
+		allowed := false
+		for _, r := range ranges {
+			if r.Contains(addr) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			outside = append(outside, h.IP)
+		}
+	}
+	return outside
+}
+
+// SetSafeMode 修改安全模式设置，AllowedRanges可改为只允许内部网段的部署专用范围
+func (a *App) SetSafeMode(settings SafeModeSettings) error {
+	policy := safeModePolicy{disabled: !settings.Enabled}
+	if len(settings.AllowedRanges) > 0 {
+		ranges, err := parseAllowedRanges(settings.AllowedRanges)
+		if err != nil {
+			return err
+		}
+		if len(ranges) == 0 {
+			return fmt.Errorf("allowed ranges must contain at least one CIDR")
+		}
+		policy.ranges = ranges
+	}
+	scanMutex.Lock()
+	a.safeMode = policy
+	scanMutex.Unlock()
+	return nil
+}
+
+// GetSafeMode 返回当前的安全模式设置，未自定义时列出默认范围
+func (a *App) GetSafeMode() SafeModeSettings {
+	scanMutex.Lock()
+	policy := a.safeMode
+	scanMutex.Unlock()
+	settings := SafeModeSettings{Enabled: !policy.disabled, AllowedRanges: []string{}}
+	for _, r := range policy.allowedRanges() {
+		settings.AllowedRanges = append(settings.AllowedRanges, r.String())
+	}
+	return settings
+}
+
+// checkSafeMode 安全模式下拒绝未确认的越界目标，并发送 unauthorized-target 事件供前端请求确认。
+// 模拟网络不会产生真实流量，不受限制
+func (a *App) checkSafeMode(config ScanConfig, hosts []scanTarget) error {
+	scanMutex.Lock()
+	policy := a.safeMode
+	scanMutex.Unlock()
+	if policy.disabled || config.Acknowledged || config.dialer != nil {
+		return nil
+	}
+	outside := policy.unauthorizedHosts(hosts)
+	if len(outside) == 0 {
+		return nil
+	}
+	a.emitEvent("unauthorized-target", map[string]interface{}{
+		"target": config.Target,
+		"hosts":  outside,
+	})
+	listed := outside
+	if len(listed) > maxUnauthorizedListed {
+		listed = listed[:maxUnauthorizedListed]
+	}
+	return fmt.Errorf("%w: %d target(s) outside the allowed ranges (%s); set Acknowledged to scan them",
+		ErrUnauthorizedTarget, len(outside), strings.Join(listed, ", "))
+}
@@ -0,0 +1,95 @@
+package portsscanner
+
+import (
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// 扫描错误的分类，GetErrorSummary 和 error-summary 事件以这些名称为键
+const (
+	ErrorConnectionRefused  = "connection-refused"  // 端口关闭，正常扫描中占绝大多数
+	ErrorTimeout            = "timeout"             // 无响应，端口被过滤或主机不在线
+	ErrorNetworkUnreachable = "network-unreachable" // 没有到目标网络的路由
+	ErrorHostUnreachable    = "host-unreachable"    // 路由器或本机报告主机不可达
+	ErrorConnectionReset    = "connection-reset"
+	ErrorResourceExhausted  = "resource-exhausted" // 本机打开的文件数或套接字达到上限
+	ErrorPermissionDenied   = "permission-denied"  // 被本机防火墙拒绝或缺少原始套接字权限
+	ErrorDNS                = "dns"
+	ErrorOther              = "other"
+)
+
+// error-summary 事件的最小发送间隔
+const errorSummaryInterval = time.Second
+
+// classifyError 将拨号或探测错误归入固定的分类
+func classifyError(err error) string {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	switch {
+	case isConnRefused(err):
+		return ErrorConnectionRefused
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case isNetUnreachable(err):
+		return ErrorNetworkUnreachable
+	case isHostUnreachable(err):
+		return ErrorHostUnreachable
+	case isConnReset(err):
+		return ErrorConnectionReset
+	case isFDExhausted(err):
+		return ErrorResourceExhausted
+	case errors.Is(err, os.ErrPermission):
+		return ErrorPermissionDenied
+	}
+	return ErrorOther
+}
+
+// countError 记录一次探测错误，未设置回调时忽略
+func (s *portScanner) countError(err error) {
+	if s.config.onError != nil {
+		s.config.onError(classifyError(err))
+	}
+}
+
+// countError 累加错误计数，距上次发送超过节流间隔时返回最新快照
+func (c *scanControl) countError(category string) (map[string]int, bool) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	if c.errorCounts == nil {
+		c.errorCounts = make(map[string]int)
+	}
+	c.errorCounts[category]++
+
+	if time.Since(c.lastErrorsEmit) < errorSummaryInterval {
+		return nil, false
+	}
+	c.lastErrorsEmit = time.Now()
+	return copyCounts(c.errorCounts), true
+}
+
+// errorCountsSnapshot 返回错误计数的副本
+func (c *scanControl) errorCountsSnapshot() map[string]int {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return copyCounts(c.errorCounts)
+}
+
+// GetErrorSummary 返回当前扫描(或保留期内刚结束的扫描)中各类探测错误的数量，
+// 以connection-refused为主说明扫描正常，network-unreachable等大量出现则多为网络问题
+func (a *App) GetErrorSummary() map[string]int {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+
+	switch {
+	case currentScan != nil:
+		return currentScan.errorCountsSnapshot()
+	case lastScan != nil:
+		return lastScan.control.errorCountsSnapshot()
+	}
+	return map[string]int{}
+}
@@ -0,0 +1,65 @@
+package portsscanner
+
+import (
+	"fmt"
+)
+
+// 调用方指定的扫描ID和关联ID的最大长度
+const maxScanIdentifierLength = 128
+
+// EventContext 扫描进行中随每个事件附带的标识，作为事件的第二个参数发送，
+// 外部系统据此将事件与自己的记录关联
+type EventContext struct {
+	ScanID        string `json:"scan_id"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// validateScanIdentifier 只允许字母、数字和 . _ - : ，保证ID可以安全地用作文件名和HTTP头
+func validateScanIdentifier(kind, id string) error {
+	if len(id) > maxScanIdentifierLength {
+		return fmt.Errorf("%s is longer than %d characters", kind, maxScanIdentifierLength)
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.' || r == '_' || r == '-' || r == ':':
+		default:
+			return fmt.Errorf("%s %q contains invalid character %q (allowed: letters, digits, . _ - :)", kind, id, r)
+		}
+	}
+	return nil
+}
+
+// validateScanIDs 校验ScanConfig中指定的ID，扫描ID不能与内存或存储目录中已有的扫描重复
+func validateScanIDs(config ScanConfig) error {
+	if err := validateScanIdentifier("correlation id", config.CorrelationID); err != nil {
+		return err
+	}
+	if config.ScanID == "" {
+		return nil
+	}
+	if err := validateScanIdentifier("scan id", config.ScanID); err != nil {
+		return err
+	}
+	if config.ScanID == "." || config.ScanID == ".." {
+		return fmt.Errorf("scan id %q is reserved", config.ScanID)
+	}
+	storeMutex.RLock()
+	_, exists := scanStore[config.ScanID]
+	storeMutex.RUnlock()
+	if !exists {
+		if store, err := activeStore(); err == nil {
+			_, getErr := store.Get(config.ScanID)
+			exists = getErr == nil
+		}
+	}
+	if exists {
+		return fmt.Errorf("scan id %q is already in use", config.ScanID)
+	}
+	return nil
+}
+
+// setEventContext 设置之后事件附带的扫描标识，传入nil停止附带
+func (a *App) setEventContext(ctx *EventContext) {
+	a.eventContext.Store(ctx)
+}
@@ -0,0 +1,75 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 日志级别
+const (
+	logDebug = "debug"
+	logInfo  = "info"
+	logWarn  = "warn"
+	logError = "error"
+)
+
+// 单次扫描最多保留的日志条数，超出后丢弃最早的记录
+const maxScanLogEntries = 10000
+
+// LogEntry 扫描过程中的一条日志
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// LogFunc 扫描过程中写入日志的回调
+type LogFunc func(level, format string, args ...interface{})
+
+// scanLog 单次扫描的日志，保存在扫描记录中便于事后排查
+type scanLog struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	dropped int
+}
+
+func (l *scanLog) add(level, format string, args ...interface{}) {
+	entry := LogEntry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) >= maxScanLogEntries {
+		l.entries = append(l.entries[:0], l.entries[1:]...)
+		l.dropped++
+	}
+	l.entries = append(l.entries, entry)
+}
+
+func (l *scanLog) snapshot() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := make([]LogEntry, 0, len(l.entries)+1)
+	if l.dropped > 0 {
+		entries = append(entries, LogEntry{
+			Time:    l.entries[0].Time,
+			Level:   logWarn,
+			Message: fmt.Sprintf("日志超过 %d 条，已丢弃最早的 %d 条", maxScanLogEntries, l.dropped),
+		})
+	}
+	return append(entries, l.entries...)
+}
+
+// GetScanLog 返回指定扫描的详细日志(拨号、错误、限流和取消等决策)
+func (a *App) GetScanLog(scanID string) ([]LogEntry, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return nil, err
+	}
+	return record.log.snapshot(), nil
+}
@@ -0,0 +1,91 @@
+package portsscanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 原始套接字扫描权限不足且未设置ScanTypeFallback时回退到的扫描类型
+var defaultScanTypeFallback = []string{ScanTypeConnect}
+
+// ScanModeSelection 实际使用的扫描类型及选择原因，随 scan-mode-selected 事件发送
+type ScanModeSelection struct {
+	Requested string   `json:"requested"`
+	Selected  string   `json:"selected"`
+	FellBack  bool     `json:"fell_back"`
+	Reason    string   `json:"reason"`
+	Chain     []string `json:"chain"` // 按顺序尝试的扫描类型
+	Strict    bool     `json:"strict"`
+}
+
+// isKnownScanType 判断扫描类型名是否有效
+func isKnownScanType(scanType string) bool {
+	switch scanType {
+	case ScanTypeConnect, ScanTypeUDP, ScanTypeBoth:
+		return true
+	}
+	return isRawScanType(scanType)
+}
+
+// scanTypeChain 返回依次尝试的扫描类型：请求的类型在前，之后是ScanTypeFallback(未设置时为connect)，去掉重复项
+func scanTypeChain(config ScanConfig) ([]string, error) {
+	fallback := config.ScanTypeFallback
+	if len(fallback) == 0 {
+		fallback = defaultScanTypeFallback
+	}
+	chain := []string{config.ScanType}
+	for _, t := range fallback {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !isKnownScanType(t) {
+			return nil, fmt.Errorf("unsupported scan type %q in fallback chain", t)
+		}
+		if !containsString(chain, t) {
+			chain = append(chain, t)
+		}
+	}
+	return chain, nil
+}
+
+// selectScanType 按回退顺序选出当前权限下可用的扫描类型，available检查原始套接字权限。
+// StrictScanType，或配置了诱饵、分片、空闲扫描这些依赖原始套接字的选项时不回退，权限不足直接返回错误
+func selectScanType(config ScanConfig, available func() error) (ScanModeSelection, error) {
+	selection := ScanModeSelection{Requested: config.ScanType, Strict: config.StrictScanType}
+	if !isRawScanType(config.ScanType) {
+		selection.Selected = config.ScanType
+		selection.Chain = []string{config.ScanType}
+		selection.Reason = "scan type does not require raw socket privileges"
+		return selection, nil
+	}
+
+	chain, err := scanTypeChain(config)
+	if err != nil {
+		return selection, err
+	}
+	rawErr := available()
+	if rawErr == nil {
+		selection.Selected = config.ScanType
+		selection.Chain = []string{config.ScanType}
+		selection.Reason = "raw socket privileges available"
+		return selection, nil
+	}
+	requiredErr := fmt.Errorf("scan type %q requires raw socket privileges: %w", config.ScanType, rawErr)
+	if config.StrictScanType {
+		return selection, requiredErr
+	}
+	if len(config.Decoys) > 0 || config.Fragment || config.ProbeTTL > 0 || config.ScanType == ScanTypeIdle {
+		return selection, fmt.Errorf("%w; decoy, fragmented, TTL-limited and idle scans are never downgraded", requiredErr)
+	}
+
+	selection.Chain = chain
+	for _, t := range chain[1:] {
+		if isRawScanType(t) {
+			// 原始套接字权限对所有原始扫描类型相同，不可用时跳过
+			continue
+		}
+		selection.Selected = t
+		selection.FellBack = true
+		selection.Reason = fmt.Sprintf("%s scan requires raw socket privileges (%v), fell back to %s", config.ScanType, rawErr, t)
+		return selection, nil
+	}
+	return selection, fmt.Errorf("%w; no scan type in the fallback chain %s works without raw sockets", requiredErr, strings.Join(chain, " -> "))
+}
@@ -2,8 +2,13 @@ package portsscanner
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"math/rand"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,109 +17,1302 @@ import (
 )
 
 type ScanConfig struct {
-	Target     string
-	StartPort  int
-	EndPort    int
-	MaxThreads int
-	Timeout    time.Duration
+	Target    string // 单个目标或以逗号/换行分隔的目标列表
+	Project   string // 所属项目/客户名称，用于按项目归档扫描结果
+	StartPort int
+	EndPort   int
+	Ports     []int // 指定端口列表，非空时代替 StartPort-EndPort 范围
+	TopPorts  int   // 扫描最常见的前N个端口(最多100)，Ports为空时代替 StartPort-EndPort 范围
+
+	// ScanID 调用方指定的扫描ID，为空时自动生成；CorrelationID 外部系统的关联ID。
+	// 只允许字母、数字和 . _ - : ，最长128个字符，扫描ID不能与已有扫描重复。
+	// 两者保存在扫描记录中，随事件、导出文件、syslog和webhook一起发送
+	ScanID        string
+	CorrelationID string
+
+	// TwoPass 先快速扫描端口列表中最常见的 TwoPassTopPorts(默认100)个端口并发送 fast-pass-complete 事件，
+	// 再在同一次扫描中继续扫描其余端口
+	TwoPass         bool
+	TwoPassTopPorts int
+	MaxThreads      int
+	Timeout         time.Duration
+	Jitter          time.Duration // 每次探测前由扫描线程随机等待0..Jitter，打乱固定速率的流量特征
+	// AbruptClose 探测和识别连接结束时发送RST直接断开，默认正常发送FIN关闭。
+	// RST更快释放连接，但部分服务会将其记录为异常断开；gonmap自行建立的连接不受此项影响
+	AbruptClose bool
+	// VirtualHosts 对HTTPS端口逐个以这些主机名作为SNI和Host头访问，记录证书、标题或状态码与直接访问IP不同的虚拟主机。
+	// DiscoverVirtualHosts 额外尝试默认证书SAN和反向解析得到的名称。每个端口最多尝试MaxVirtualHosts个(默认16，上限128)
+	VirtualHosts         []string
+	DiscoverVirtualHosts bool
+	MaxVirtualHosts      int
+	// ProbeTimeouts 按服务名或端口号("3306")覆盖指纹识别阶段的超时，未列出的服务使用Timeout。
+	// 邮件、LDAP和常见数据库端口默认使用更长的超时，见defaultProbeTimeouts
+	ProbeTimeouts map[string]time.Duration
+
+	// ConnectThreads 和 FingerprintThreads 任一大于0时启用两段式扫描：
+	// 连接线程只判断端口状态，开放端口交给独立的指纹线程池，慢速指纹不会拖慢端口发现。
+	// ConnectThreads 为0时使用MaxThreads，FingerprintThreads 为0时与连接并发相同
+	ConnectThreads     int
+	FingerprintThreads int
+	PortCount          int // 只读，解析后每台主机实际扫描的端口数，由GetEffectiveConfig返回
+
+	Discover        bool // 扫描前通过TCP连接探测存活主机，只扫描在线的主机
+	EmitHostSummary bool // 扫描完成时为每台主机发送一条 host-summary 事件
+
+	// EmitHostEvents 每台主机开始扫描时发送 host-scan-started、全部端口完成时发送 host-scan-complete 事件，
+	// 扫描结束(完成或取消)时发送按主机汇总全部结果的 scan-aggregate 事件
+	EmitHostEvents bool
+
+	// SilentCompletion 扫描结束(完成、出错或取消)时不发送 scan-complete-alert 事件，适用于无人值守的自动化扫描。
+	// 默认每次扫描结束发送一次，携带结束状态、提示类别(success、failure、cancelled)和扫描概要，前端可据此播放提示音
+	SilentCompletion bool
+
+	// DetectHoneypots 扫描完成后检查疑似蜜罐/tarpit的主机：开放端口比例超过 HoneypotOpenRatio(默认0.8，
+	// 至少扫描20个端口时才判断)，或相同横幅出现在至少 HoneypotBannerPorts(默认5)个端口上
+	DetectHoneypots     bool
+	HoneypotOpenRatio   float64
+	HoneypotBannerPorts int
+
+	// CollapsePortRanges 扫描完成后将同一主机上指纹相同的连续端口合并，每个范围发送一次 port-range-found 事件，
+	// 单个端口的结果仍然保留，可通过ExpandPortRange展开。至少CollapseThreshold(默认5，最小3)个端口才合并，
+	// 相邻开放端口的间隔不超过CollapseMaxGap(默认1，即严格连续)
+	CollapsePortRanges bool
+	CollapseThreshold  int
+	CollapseMaxGap     int
+
+	SkipInterceptionCheck bool // 跳过扫描前的强制门户/透明代理检测，适用于已知干净的网络
+	Acknowledged          bool // 已确认有权扫描安全模式允许范围之外的目标(见SetSafeMode)
+
+	// PrioritizePorts 优先派发高价值端口，尽早得到可用结果：所有主机的优先端口按优先顺序跨主机先行派发，
+	// 之后再逐台派发各主机的其余端口。启用PerHostTimeout时，主机的计时从其第一个优先端口派发时开始
+	PrioritizePorts bool
+	PriorityPorts   []int // 自定义优先端口顺序，为空时使用内置列表
+
+	UserAgent string            // HTTP(S)指纹探测使用的User-Agent，为空时使用默认值
+	Headers   map[string]string // HTTP(S)指纹探测附加的请求头
+
+	ScanType          string   // 扫描类型：connect(默认)、udp、both(TCP和UDP)，syn/fin/null/xmas/idle(需要原始套接字权限)
+	ScanTypeFallback  []string // 原始套接字权限不足时依次尝试的扫描类型，默认回退到connect
+	StrictScanType    bool     // 权限不足时返回错误而不回退
+	Decoys            []string // 诱饵源地址，仅SYN扫描可用
+	AcknowledgeDecoys bool     // 诱饵扫描和空闲扫描会发送伪造源地址的数据包，必须显式确认
+	ZombieHost        string   // 空闲扫描使用的僵尸主机，其IP ID需全局递增
+	ZombiePort        int      // 探测僵尸主机IP ID的端口，默认80
+	UDPAttempts       int      // UDP扫描每个探测载荷的发送次数，默认2
+
+	// UDPGrace UDP扫描全部尝试超时后继续监听迟到响应的时间，与连接超时分开设置，默认0。
+	// 响应较慢的服务会因此被识别为开放而不是 open|filtered，每个无响应的端口相应地多等待这么久
+	UDPGrace time.Duration
+
+	// Fragment 将原始套接字扫描(syn/fin/null/xmas)的探测包拆分为IP分片，FragmentSize为每片载荷字节数
+	// (8的倍数，默认8，受出口网卡MTU限制)。多数现代协议栈和防火墙会先重组再过滤，结果可能与不分片相同
+	Fragment     bool
+	FragmentSize int
+
+	// ProbeTTL 原始套接字扫描(syn/fin/null/xmas)探测包的IP TTL(1-255)，0为默认64。
+	// TTL在途中耗尽时为每次探测发送ttl-exceeded事件，报告回复超时报文的路由器，可据此判断防火墙位于第几跳
+	ProbeTTL int
+
+	// DSCP 探测流量的DSCP标记(0-63，如46为EF、10为AF11)，0为不标记。连接扫描、UDP扫描、存活探测和识别探测
+	// 通过IP_TOS/IPV6_TCLASS设置，原始套接字扫描写入探测包的IP头；gonmap指纹探针和经代理的连接不受影响。
+	// Windows默认忽略应用设置的ToS，需要配置QoS组策略
+	DSCP int
+
+	// ICMPHandling 原始套接字扫描和UDP扫描对ICMP目标不可达报文的处理：ignore(默认)、interpret 或 report。
+	// interpret 按报文判断状态(UDP端口不可达为closed，管理禁止等为filtered)，report 还将被过滤的端口作为结果报告。需要原始套接字权限
+	ICMPHandling string
+
+	UseFingerprintCache bool // 命中缓存时跳过指纹探测，直接复用之前的服务识别结果
+
+	CaptureResponse bool // 在结果中附带服务响应原始字节的hex/ascii转储
+	CaptureSize     int  // 抓取的响应字节数，默认256，最大4096
+
+	// CaptureEvidence 为每个开放端口保存原始横幅、TLS证书(PEM)和HTTP响应头，通过GetEvidence获取。
+	// 单项证据保留EvidenceSize字节(默认4096，最大64KB)，单次扫描总量不超过16MB
+	CaptureEvidence bool
+	EvidenceSize    int
+
+	// BannerReadSize 横幅抓取、自定义探测和HTTP探测读取的最大字节数。自定义探测的Match、被动横幅规则
+	// (Payload为空的自定义探测，见CustomProbe)和HTTP标题都在完整读取的内容上匹配。
+	// 未设置时横幅读取10240字节(与gonmap探针一致)、HTTP响应体读取64KB，上限1MB。
+	// gonmap内置指纹规则使用其固定的10240字节读取，不受此项影响
+	BannerReadSize int
+
+	TLSClientCert string // mTLS探测使用的客户端证书(PEM文件路径)，普通握手被拒绝时才使用
+	TLSClientKey  string // 客户端证书对应的私钥(PEM文件路径)
+
+	DNSServer   string        // 自定义DNS服务器(host[:port])，为空时使用系统解析器
+	DoHEndpoint string        // DNS over HTTPS地址(如 https://1.1.1.1/dns-query)，设置后代替DNSServer和系统解析器
+	DNSTimeout  time.Duration // 每个目标主机名解析的超时时间，默认5秒
+
+	// RetryUnresolved 解析失败的主机名会被跳过(发送 target-resolve-failed 事件)，其余目标照常扫描；
+	// 设置后在其余主机名解析完成后对失败的主机名重试一次
+	RetryUnresolved bool
+
+	AdaptiveThrottle bool // 超时率突增时自动降低并发，恢复后逐步回升
+
+	// AutoConcurrency 按打开文件数上限和CPU核心数自动确定并发数，忽略MaxThreads和ConnectThreads，
+	// 扫描中按超时率调整(同AdaptiveThrottle)，遇到 too many open files 时立即减半并不再回升到原值
+	AutoConcurrency bool
+
+	// AdaptiveTimeout 根据有响应端口的RTT中位数自动设置连接超时(中位数的AdaptiveTimeoutFactor倍，默认4倍)，
+	// 随扫描持续调整，不超过Timeout；收集到足够样本前使用Timeout。只影响端口状态探测，不影响指纹识别
+	AdaptiveTimeout       bool
+	AdaptiveTimeoutFactor float64
+
+	// MaxBandwidth 所有探测连接合计的吞吐上限(字节/秒)，0为不限制。可与AdaptiveThrottle同时使用。
+	// gonmap自行建立连接，其指纹探针的流量不受限制也不计入统计
+	MaxBandwidth int
+
+	// SourceIPs 本机的多个源地址，每次连接按目标地址族轮流选择一个作为源地址，各地址的连接数记录在扫描概要中。
+	// 每个地址必须属于本机网络接口；只作用于TCP连接扫描、UDP扫描、存活探测和识别探测，gonmap指纹探针使用系统默认源地址
+	SourceIPs []string
+
+	// SourcePortRange 连接使用的本机源端口范围(如 "40000-40999" 或单个端口)，每次连接在范围内轮流选择，
+	// 端口暂时不可用(TIME_WAIT等)时换用下一个。作用范围同SourceIPs，经代理的连接不受限制。
+	// 低于1024的特权端口需要同时设置AllowPrivilegedSourcePorts(通常还需要管理员权限)
+	SourcePortRange            string
+	AllowPrivilegedSourcePorts bool
+
+	// Proxies 代理地址列表(格式同TestProxy)，每个连接轮流经由下一个代理，只支持TCP连接扫描。
+	// 无法连接的代理暂停使用30秒并发送 proxy-unavailable 事件。gonmap指纹探针不能经代理连接，
+	// 设置代理时跳过，只进行横幅、STARTTLS和HTTP等经代理的识别探测
+	Proxies []string
+
+	TreatResetAsAmbiguous bool // 收到RST时延迟复核，结果不一致的端口标记为possibly-filtered，会降低扫描速度
+
+	// AdaptRSTRateLimit 主机疑似对RST限速(快速回应大量RST后连续超时，见rstlimit.go)时降低对其的探测速率，
+	// 主扫描结束后低速复测超时的端口并重新分类。未开启时只发送 rst-ratelimit-detected 事件
+	AdaptRSTRateLimit bool
+
+	PerHostTimeout time.Duration // 单台主机的总扫描时间上限，超时后放弃其剩余端口并发送host-timeout事件，其余主机继续
+
+	// MergeByIP 扫描完成后将指向同一IP的不同主机名的结果合并为一台主机(Aliases记录全部名称)，
+	// 发生合并时发送 results-merged 事件。已有扫描可通过MergeResultsByIP合并
+	MergeByIP bool
+
+	MaxHosts int // CIDR/IP范围展开后允许的最大主机数，默认65536，需要扫描更大范围时显式调高
+
+	UnthrottledEvents bool // 关闭进度合并和port-found批量发送，适合结果较少的小规模扫描
+
+	StreamToFile string // 扫描过程中将每个开放端口以JSONL格式实时追加到该文件
+	ResultBuffer int    // 扫描线程与结果分发之间的缓冲大小，写满时扫描降速而不丢弃结果，0为默认1024
+
+	// CompletedRetention 扫描结束后继续返回其结束状态、进度和服务计数的时长，之后才变为idle，
+	// 便于前端在状态清空前取回最终结果。默认10秒，小于0时结束后立即变为idle
+	CompletedRetention time.Duration
+
+	SyslogTarget string // 以RFC 5424格式转发开放端口和扫描状态的syslog服务器(host:port，可加udp://或tcp://前缀，默认UDP)
+	// FindingWebhook 每发现一个开放端口即异步POST其PortInfo JSON的http(s)地址，失败时退避重试，
+	// 重试耗尽后发送 webhook-failed 事件
+	FindingWebhook string
+	// PostScanCommand 扫描完成后执行的命令，扫描ID和结果文件路径依次追加为最后两个参数(非file存储后端为
+	// 命令结束后删除的临时JSON文件)，输出通过 post-scan-output 事件发送。最长运行PostScanTimeout(默认5分钟)，超时后终止
+	PostScanCommand string
+	PostScanTimeout time.Duration
+
+	// AutoExportFormats 扫描完成后自动导出的格式(json、csv、text、html、markdown)，写入OutputDir
+	// (默认~/GlideWay/exports)下以扫描ID和开始时间命名的子目录，完成后发送 auto-export-complete 事件
+	AutoExportFormats []string
+	OutputDir         string
+
+	// SkipStartTLS 不对识别为SMTP、IMAP、POP3、FTP、PostgreSQL的明文端口尝试STARTTLS升级，
+	// 默认升级后记录服务端证书的到期时间
+	SkipStartTLS bool
+
+	// RandomizeProbeOrder 打乱每个端口自定义TCP探测和UDP载荷的发送顺序，避免固定的探测序列成为扫描器的特征。
+	// 只在端口对应的探测之间打乱，之后的通用探测(横幅读取、空UDP数据包)顺序不变；gonmap内置探针、
+	// STARTTLS和温和识别每个端口只有一种交互，不受影响。ProbeOrderSeed 为0时在扫描开始时随机生成并记录在配置中，
+	// 相同种子的扫描对每个端口使用相同的顺序，便于复现
+	RandomizeProbeOrder bool
+	ProbeOrderSeed      int64
+
+	// PauseOnNetworkChange 扫描期间到目标的出口地址或网卡IPv4地址变化(如Wi-Fi切换到VPN)时暂停派发新的探测，
+	// 发送 network-changed 事件，由ContinueScan继续或StopScan中止
+	PauseOnNetworkChange bool
+
+	// ProbeAttempts 每个端口的状态探测次数(1-10)，默认1。多次探测结果不一致时按StatePolicy决定最终状态，
+	// 结果标记为Flapping并发送port-flapping事件。StatePolicy默认prefer-open，可选prefer-last和majority(见flapping.go)
+	ProbeAttempts int
+	StatePolicy   string
+
+	// VerifyOpen 主扫描结束后重新连接所有开放的TCP端口，未能再次连接的标记为Unstable，
+	// 结果通过 port-verified/port-unstable 事件发送。会增加一轮连接，默认关闭，不支持空闲扫描
+	VerifyOpen bool
+
+	// FastOpen 对开放的TCP端口尝试TCP Fast Open，结果记录在PortInfo.FastOpen。
+	// 目前只支持Linux(需要内核启用TFO客户端)，其他平台记录为unsupported
+	FastOpen bool
+
+	// MaxResults 所有主机合计发现这么多个开放端口后停止扫描并发送 max-results-reached 事件，
+	// 扫描按完成处理，已发现的结果照常保存。0为不限制
+	MaxResults int
+
+	// DualStackCompare 同时有A和AAAA记录的主机名分别扫描IPv4和IPv6地址，扫描完成后
+	// 为每个这样的主机名发送 dualstack-diff 事件，列出只在其中一个地址族开放的端口
+	DualStackCompare bool
+
+	// HappyEyeballs 主机名解析到多个地址时按RFC 8305交替两个地址族，每隔HappyEyeballsDelay向下一个地址
+	// 发起连接而不等前一个超时，扫描最先响应(连接成功或被拒绝)的地址，并发送 address-selected 事件；
+	// 全部无响应时按默认规则优先IPv4。开启DualStackCompare时不生效
+	HappyEyeballs      bool
+	HappyEyeballsDelay time.Duration // 相邻两次连接尝试的间隔，默认250毫秒
+
+	// StopOnMatch 识别结果匹配时发送 match-found 事件并停止扫描，格式为 "服务名或产品名 [版本条件]"，
+	// 如 "OpenSSH 7.x"、"ssh <7.4"、"nginx >=1.18,<1.21"。名称不区分大小写，与服务名相同或包含在产品名中即可；
+	// 版本条件以逗号分隔，可用 < <= > >= = 比较，不带运算符时按前缀匹配(7.x 与 7 相同)
+	StopOnMatch     string
+	ContinueOnMatch bool // 为true时匹配只发送match-found事件，不停止扫描
+
+	// GentleFingerprint 对所有主机只进行温和识别：建立连接后读取服务端主动发送的横幅，不发送gonmap探针、
+	// 自定义探测、TLS/STARTTLS握手或HTTP请求，避免脆弱的嵌入式/工控设备崩溃。GentleHosts只对列出的地址、
+	// CIDR或主机名启用。另外默认在主机开放工控协议端口(Modbus、S7comm、DNP3等)或识别结果显示为工控/嵌入式设备时
+	// 自动对该主机之后的端口启用，SkipGentleDetection关闭自动启用。每台主机启用时发送 gentle-mode-applied 事件，
+	// 结果的Gentle和GentleReason记录是否及为何启用。只作用于TCP服务识别，并发识别时自动启用前已开始的探测不受影响
+	GentleFingerprint   bool
+	GentleHosts         []string
+	SkipGentleDetection bool
+
+	// FollowUpProbes 主扫描结束后按条件探测规则(见LoadFollowUpRules)，对已发现开放端口的主机补充探测相关端口
+	FollowUpProbes bool
+
+	emit        EventFunc // 扫描过程中的附加事件回调
+	logf        LogFunc   // 扫描日志回调，写入对应的扫描记录
+	probeEvents bool      // 每次探测后发送probe-result事件(verbose详细程度)
+
+	pipeline  *scanPipeline   // SetPipeline设置的扫描阶段，nil为默认流水线
+	dialer    contextDialer   // 替代系统网络的拨号器(模拟网络)，nil时直接连接
+	bandwidth *bandwidthMeter // 探测连接的流量计量和限速
+	sources   *sourcePool     // SourceIPs/SourcePortRange/DSCP 的本机连接设置，nil时使用系统默认值
+	proxies   *proxyPool      // Proxies 轮询的代理，nil时直接连接
+	rtt       *rttEstimator   // AdaptiveTimeout的RTT统计，nil时使用固定超时
+	phases    *phaseTracker   // 多阶段进度统计，nil时不统计
+
+	limiter *adaptiveLimiter // AdaptiveThrottle/AutoConcurrency的并发控制，与进度查询共享
+	pause   *pauseGate       // PauseScan和PauseOnNetworkChange的暂停控制，nil时不会暂停
+
+	clientCert *tls.Certificate // 由TLSClientCert/TLSClientKey加载的客户端证书
+	gentle     *gentlePolicy    // 由GentleHosts解析的温和识别范围
+
+	checkpoint *scanCheckpoint // 记录每台主机已完成的端口，用于中断后续扫
+	resume     *resumeState    // ResumeScan 续扫时沿用的原扫描进度
+
+	onHostTimeout func(host string, skipped int) // 主机超时后由上层调整进度并发送事件
+	onError       func(category string)          // 探测错误按分类计数
+	registry      *hostRegistry                  // 多目标扫描的主机级取消控制
+
+	hosts []scanTarget // 规范化去重后的主机列表
+
+	endpoints     []scanEndpoint    // ScanEndpoints 传入的 host:port 列表
+	hostPorts     map[string][]int  // 端点扫描时每台主机各自的端口
+	endpointNames map[string]string // 端点扫描时 ip:port 对应的原始输入
 }
 
 type PortInfo struct {
-	Port            int    `json:"port"`
-	Protocol        string `json:"protocol"`
-	Service         string `json:"service"`
-	ProductName     string `json:"product_name"`
-	Version         string `json:"version"`
-	Info            string `json:"info"`
-	Hostname        string `json:"hostname"`
-	OperatingSystem string `json:"operating_system"`
-	DeviceType      string `json:"device_type"`
-	ProbeName       string `json:"probe_name"`
-	TLS             bool   `json:"tls"`
+	Host            string        `json:"host"`
+	Aliases         []string      `json:"aliases,omitempty"` // 指向该主机的所有输入名称
+	Port            int           `json:"port"`
+	Endpoint        string        `json:"endpoint,omitempty"` // 端点扫描时对应的原始 host:port 输入
+	Protocol        string        `json:"protocol"`
+	Service         string        `json:"service"`               // 规范化后的服务名
+	RawService      string        `json:"raw_service,omitempty"` // 指纹或探针识别出的原始服务名
+	ProductName     string        `json:"product_name"`
+	Version         string        `json:"version"`
+	Info            string        `json:"info"`
+	Hostname        string        `json:"hostname"`
+	OperatingSystem string        `json:"operating_system"`
+	DeviceType      string        `json:"device_type"`
+	ProbeName       string        `json:"probe_name"`
+	TLS             bool          `json:"tls"`
+	MutualTLS       bool          `json:"mutual_tls,omitempty"` // 使用配置的客户端证书才完成TLS握手
+	HTTPTitle       string        `json:"http_title,omitempty"`
+	HTTPServer      string        `json:"http_server,omitempty"`
+	TLSExpiry       time.Time     `json:"tls_expiry"`    // HTTPS或STARTTLS服务证书的到期时间，未获取到证书时为零值
+	CPE             string        `json:"cpe,omitempty"` // 无法确定映射时为空
+	RawResponse     string        `json:"raw_response,omitempty"`
+	Unidentified    bool          `json:"unidentified,omitempty"`    // 端口开放但没有指纹匹配，Info中记录横幅
+	Process         *ProcessInfo  `json:"process,omitempty"`         // 扫描本机时占用该端口的进程
+	VirtualHosts    []VirtualHost `json:"virtual_hosts,omitempty"`   // 按SNI访问时响应与IP不同的虚拟主机
+	State           string        `json:"state,omitempty"`           // 为空表示开放；FIN/NULL/XMAS扫描无响应时为 open|filtered，RST复核不一致时为 possibly-filtered，ICMPHandling为report时可为 filtered
+	LikelyHoneypot  bool          `json:"likely_honeypot,omitempty"` // 所属主机疑似蜜罐/tarpit
+	Imported        bool          `json:"imported,omitempty"`        // 来自导入文件，不是本次会话扫描得到的
+	Latency         time.Duration `json:"latency,omitempty"`         // 判断端口开放的那次探测的耗时
+
+	Confidence        int      `json:"confidence"`                   // 服务识别的可信度(0-100)，计算方法见confidence.go
+	ConfidenceSignals []string `json:"confidence_signals,omitempty"` // 支持该识别的依据
+
+	StartTLS bool `json:"starttls,omitempty"` // 明文服务支持通过STARTTLS升级，TLSExpiry为升级后获取的证书到期时间
+
+	Unstable bool `json:"unstable,omitempty"` // 启用VerifyOpen时复核未能再次连接，可能是网络抖动造成的误报
+
+	Flapping bool `json:"flapping,omitempty"` // ProbeAttempts多次探测中时而开放时而未开放，状态按StatePolicy判定
+
+	FastOpen string `json:"fast_open,omitempty"` // 启用FastOpen时的TFO探测结果：accepted、rejected 或 unsupported
+
+	SelfSigned bool `json:"self_signed,omitempty"` // HTTPS或STARTTLS服务的证书为自签名证书
+
+	ALPN  string `json:"alpn,omitempty"`  // HTTPS探测时经ALPN协商出的协议，如 h2、http/1.1；服务端不支持ALPN时为空
+	HTTP2 bool   `json:"http2,omitempty"` // 服务端通过ALPN选择了HTTP/2
+	HTTP3 bool   `json:"http3,omitempty"` // Alt-Svc响应头声明了HTTP/3(经QUIC访问，未实际验证)
+
+	Score      int      `json:"score"`                 // 按评分规则计算的优先级，查询和排序时计算，见scoring.go
+	ScoreRules []string `json:"score_rules,omitempty"` // 匹配的评分规则名
+
+	TriggeredBy string `json:"triggered_by,omitempty"` // 由条件探测发现或识别时的规则和触发端口，如 "smb-netbios: 445/tcp"
+
+	ICMP string `json:"icmp,omitempty"` // 判定端口状态的ICMP报文类型/代码和来源，如 "3/13 admin-prohibited from 192.0.2.1"
+
+	Gentle       bool   `json:"gentle,omitempty"`        // 以温和识别处理：只读取了横幅，没有发送探测载荷
+	GentleReason string `json:"gentle_reason,omitempty"` // 启用温和识别的原因，如 "industrial protocol port 502/tcp (Modbus)"
+
+	BehindCDN   bool   `json:"behind_cdn,omitempty"` // 地址位于CDN地址段内或响应头/证书符合CDN特征，结果反映的是边缘节点而不是源站
+	CDN         string `json:"cdn,omitempty"`        // 识别出的CDN名称，如 Cloudflare
+	cdnEvidence string // CDN识别依据，见cdn.go
+
+	evidence *Evidence // 启用CaptureEvidence时收集的原始证据，由扫描记录单独保存
 }
 
 type PortCallback func(PortInfo)
 
+// EventFunc 扫描核心向上层发送事件的回调
+type EventFunc func(name string, data interface{})
+
+// 端口探测状态
+const (
+	portOpen         = "open"
+	portClosed       = "closed"
+	portFiltered     = "filtered"
+	portOpenFiltered = "open|filtered" // FIN/NULL/XMAS扫描无响应，无法区分开放与过滤
+
+	portPossiblyFiltered = "possibly-filtered" // 收到RST但复核结果不一致，疑似防火墙注入RST
+)
+
+// 开启TreatResetAsAmbiguous时RST复核的次数和间隔
+const (
+	resetVerifyAttempts = 2
+	resetVerifyDelay    = 300 * time.Millisecond
+)
+
+// portList 返回本次扫描的端口列表
+func (c ScanConfig) portList() []int {
+	if len(c.Ports) > 0 {
+		return c.Ports
+	}
+	if c.TopPorts > 0 {
+		return topPorts(c.TopPorts)
+	}
+	if c.EndPort < c.StartPort {
+		return nil
+	}
+	ports := make([]int, 0, c.EndPort-c.StartPort+1)
+	for port := c.StartPort; port <= c.EndPort; port++ {
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// unitsPerPort 每个端口计入进度的探测数，both 扫描的TCP和UDP各算一个
+func (c ScanConfig) unitsPerPort() int64 {
+	if c.ScanType == ScanTypeBoth {
+		return 2
+	}
+	return 1
+}
+
+// applyScanDefaults 为未设置的配置项填充默认值
+func applyScanDefaults(config *ScanConfig) {
+	if config.Timeout <= 0 {
+		config.Timeout = time.Second * 2
+	}
+	if config.MaxThreads <= 0 {
+		config.MaxThreads = 500
+	}
+	if config.AutoConcurrency {
+		config.MaxThreads = detectConcurrency().Concurrency
+		config.ConnectThreads = 0
+	}
+	if config.ScanType == "" {
+		config.ScanType = ScanTypeConnect
+	}
+	if config.DNSTimeout <= 0 {
+		config.DNSTimeout = 5 * time.Second
+	}
+	if config.HappyEyeballsDelay <= 0 {
+		config.HappyEyeballsDelay = defaultHappyEyeballsDelay
+	}
+	if config.MaxHosts <= 0 {
+		config.MaxHosts = defaultMaxHosts
+	}
+	if config.CompletedRetention == 0 {
+		config.CompletedRetention = defaultCompletedRetention
+	}
+	if config.RandomizeProbeOrder && config.ProbeOrderSeed == 0 {
+		config.ProbeOrderSeed = time.Now().UnixNano()
+	}
+}
+
+// connectThreads 返回端口探测的并发数，ConnectThreads未设置时使用MaxThreads
+func (c ScanConfig) connectThreads() int {
+	if c.ConnectThreads > 0 {
+		return c.ConnectThreads
+	}
+	return c.MaxThreads
+}
+
+// portScanner 单次扫描任务共享的探测状态
+type portScanner struct {
+	config   ScanConfig
+	raw      *rawScanner
+	icmp     *icmpListener
+	idle     *idleScanner
+	limiter  *adaptiveLimiter
+	callback PortCallback
+
+	cdnHosts    sync.Map    // 已发送 cdn-detected 事件的主机
+	gentleHosts sync.Map    // 已启用温和识别的主机及原因
+	rst         *rstTracker // connect/SYN扫描的RST限速检测
+
+	fpQueue     chan fingerprintJob // 两段式扫描时待指纹识别的开放端口
+	fpWG        sync.WaitGroup
+	fpCloseOnce sync.Once
+}
+
+// emit 发送附加事件，未设置回调时忽略
+func (s *portScanner) emit(name string, data interface{}) {
+	if s.config.emit != nil {
+		s.config.emit(name, data)
+	}
+}
+
+// log 写入扫描日志，未设置回调时忽略
+func (s *portScanner) log(level, format string, args ...interface{}) {
+	if s.config.logf != nil {
+		s.config.logf(level, format, args...)
+	}
+}
+
 func ScanPortsCombined(ctx context.Context, config ScanConfig, callback PortCallback) error {
 	if callback == nil {
 		return fmt.Errorf("callback function cannot be nil")
 	}
+	if config.bandwidth == nil {
+		config.bandwidth = newBandwidthMeter(config.MaxBandwidth)
+	}
+	if config.sources == nil {
+		sources, err := newSourcePool(config)
+		if err != nil {
+			return err
+		}
+		config.sources = sources
+	}
+	if config.gentle == nil {
+		gentle, err := newGentlePolicy(config.GentleHosts)
+		if err != nil {
+			return err
+		}
+		config.gentle = gentle
+	}
+	if config.proxies == nil {
+		proxies, err := newProxyPool(config.Proxies, config.Timeout)
+		if err != nil {
+			return err
+		}
+		config.proxies = proxies
+		defer proxies.close()
+	}
+	if config.AdaptiveTimeout && config.rtt == nil {
+		applyScanDefaults(&config)
+		config.rtt = newRTTEstimator(config.Timeout, config.AdaptiveTimeoutFactor)
+	}
+	if config.TwoPass {
+		return scanTwoPass(ctx, config, callback)
+	}
+
+	applyScanDefaults(&config)
+	hosts := config.hosts
+	if len(hosts) == 0 {
+		var err error
+		hosts, _, _, err = resolveTargets(ctx, config)
+		if err != nil {
+			return err
+		}
+	}
+
+	decoys, err := validateRawConfig(config)
+	if err != nil {
+		return err
+	}
+	if config.clientCert == nil {
+		if config.clientCert, err = loadClientCertificate(config); err != nil {
+			return err
+		}
+	}
+
+	s := &portScanner{config: config}
+	// 结果回调前补充CDN标记
+	s.callback = func(info PortInfo) {
+		if info.Protocol != "progress" {
+			s.reportCDN(&info)
+		}
+		callback(info)
+	}
+
+	if types := config.icmpTypes(); len(types) > 0 {
+		s.icmp, err = newICMPListener(types...)
+		if err != nil {
+			return err
+		}
+		defer s.icmp.Close()
+	}
+
+	if isRawScanType(config.ScanType) {
+		fragment := 0
+		if config.Fragment {
+			fragment = fragmentSizeFor(config.FragmentSize, 0)
+		}
+		s.raw, err = newRawScanner(decoys, fragment, config.ProbeTTL, dscpToS(config.DSCP), s.icmp)
+		if err != nil {
+			return err
+		}
+		defer s.raw.Close()
+	}
+
+	if config.ScanType == ScanTypeIdle {
+		var status zombieStatus
+		s.idle, status, err = newIdleScanner(ctx, s.raw, config)
+		if err != nil {
+			return err
+		}
+		s.log(logInfo, "僵尸主机 %s:%d IP ID采样 %v，平均RTT %v", s.idle.zombie, s.idle.port, status.IPIDs, status.RTT)
+		if status.Sequence != zombieIncremental {
+			fmt.Printf("僵尸主机 %s 的IP ID不是稳定递增(%s)，空闲扫描结果可能不准确\n", s.idle.zombie, status.Sequence)
+			s.log(logWarn, "僵尸主机 %s 的IP ID不是稳定递增(%s)，空闲扫描结果可能不准确", s.idle.zombie, status.Sequence)
+			s.emit("zombie-warning", map[string]interface{}{
+				"zombie":   s.idle.zombie.String(),
+				"port":     s.idle.port,
+				"sequence": status.Sequence,
+				"ip_ids":   status.IPIDs,
+			})
+		}
+	}
+
+	if config.tracksRSTRateLimit() {
+		s.rst = newRSTTracker(config.AdaptRSTRateLimit)
+	}
+
+	connectThreads := config.connectThreads()
+	if config.AutoConcurrency {
+		decision := detectConcurrency()
+		s.log(logInfo, "自动并发: %d (打开文件数上限 %d，CPU %d 核)", connectThreads, decision.FDLimit, decision.CPUs)
+		s.emit("auto-concurrency", decision)
+	}
+	if config.AdaptiveThrottle || config.AutoConcurrency {
+		s.limiter = config.limiter
+		if s.limiter == nil {
+			s.limiter = newAdaptiveLimiter(connectThreads, nil)
+		}
+		s.limiter.onChange = func(limit, previous int, ratio float64, reason string) {
+			fmt.Printf("自适应限流: 并发 %d -> %d (超时率 %.0f%%)\n", previous, limit, ratio*100)
+			s.log(logWarn, "自适应限流(%s): 并发 %d -> %d，超时率 %.0f%%", reason, previous, limit, ratio*100)
+			s.emit("auto-throttle", map[string]interface{}{
+				"concurrency":  limit,
+				"previous":     previous,
+				"timeout_rate": ratio,
+				"reason":       reason,
+			})
+		}
+	}
 
 	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, config.MaxThreads)
-	var scanned int32
+	semaphore := make(chan struct{}, connectThreads)
+	var scanned int64
+
+	ports := config.portList()
+	if config.PrioritizePorts {
+		ports = prioritizePorts(ports, config.PriorityPorts)
+	}
+	registry := config.registry
+	if registry == nil {
+		registry = newHostRegistry()
+	}
+	if len(registeredHostHooks()) > 0 || config.EmitHostEvents {
+		callback := s.callback
+		s.callback = func(info PortInfo) {
+			if info.Protocol != "progress" {
+				registry.recordResult(info)
+			}
+			callback(info)
+		}
+	}
+	var timers []*time.Timer
+	defer func() {
+		for _, t := range timers {
+			t.Stop()
+		}
+	}()
 
-	// 创建gonmap实例
-	scanner := gonmap.New()
-	scanner.SetTimeout(config.Timeout)
+	s.log(logInfo, "开始扫描 %d 台主机，每台 %d 个端口，扫描方式 %s，并发 %d，超时 %v，阶段 %s",
+		len(hosts), len(ports), config.ScanType, connectThreads, config.Timeout, config.stageNames())
+	if config.RandomizeProbeOrder {
+		s.log(logInfo, "探测顺序随机化，种子 %d", config.ProbeOrderSeed)
+	}
+	if (config.ConnectThreads > 0 || config.FingerprintThreads > 0) && config.runsFingerprint() {
+		fpThreads := config.FingerprintThreads
+		if fpThreads <= 0 {
+			fpThreads = connectThreads
+		}
+		s.startFingerprintPool(registry, fpThreads)
+		s.log(logInfo, "两段式扫描: 连接并发 %d，指纹识别并发 %d", connectThreads, fpThreads)
+	}
+	// 提前返回(取消)时同样等待进行中的连接线程和指纹识别结束再返回：它们仍会报告已确认的开放端口，
+	// 调用方在返回后才关闭结果队列
+	defer func() {
+		wg.Wait()
+		s.closeFingerprintPool()
+	}()
+	var priority map[int]bool
+	if config.PrioritizePorts {
+		priority = prioritySet(config.PriorityPorts)
+	}
+	newDispatch := func(host scanTarget) *hostDispatch {
+		d := &hostDispatch{host: host, ports: config.portsFor(host.IP, ports)}
+		if priority != nil {
+			if config.hostPorts != nil {
+				d.ports = prioritizePorts(d.ports, config.PriorityPorts)
+			}
+			d.priority = priorityPrefix(d.ports, priority)
+		}
+		return d
+	}
+	// startHost 主机派发第一个端口前登记，没有端口的主机直接完成
+	startHost := func(d *hostDispatch) {
+		if d.ctx != nil {
+			return
+		}
+		d.ctx = registry.start(ctx, d.host.IP, len(d.ports))
+		s.hostStarted(registry, d.host)
+		if results, done := registry.completeEmpty(d.host.IP); done {
+			s.hostFinished(d.host, results)
+		}
+		if config.PerHostTimeout > 0 {
+			ip := d.host.IP
+			timers = append(timers, time.AfterFunc(config.PerHostTimeout, func() {
+				s.hostTimedOut(registry, ip)
+			}))
+		}
+	}
+	// dispatchPort 派发主机的一个端口，整个扫描被取消时返回错误；主机被单独取消或超时时标记stopped
+	dispatchPort := func(d *hostDispatch, port int) error {
+		startHost(d)
+		if ctx.Err() != nil {
+			return context.Canceled
+		}
 
-	for port := config.StartPort; port <= config.EndPort; port++ {
-		select {
-		case <-ctx.Done():
+		if !config.pause.wait(ctx) {
 			return context.Canceled
-		default:
-			wg.Add(1)
-			semaphore <- struct{}{}
-
-			go func(p int) {
-				defer func() {
-					wg.Done()
-					<-semaphore
-					if r := recover(); r != nil {
-						fmt.Printf("Recovered from panic in port scan goroutine: %v\n", r)
+		}
+		semaphore <- struct{}{}
+		if s.limiter != nil && !s.limiter.acquire(d.ctx) {
+			<-semaphore
+			if ctx.Err() != nil {
+				return context.Canceled
+			}
+			d.stopped = true
+			return nil
+		}
+		// 主机已被单独取消时跳过其剩余端口
+		if !registry.dispatch(d.host.IP) {
+			if s.limiter != nil {
+				s.limiter.release()
+			}
+			<-semaphore
+			s.log(logInfo, "主机 %s 已被取消，跳过剩余端口", d.host.IP)
+			d.stopped = true
+			return nil
+		}
+
+		wg.Add(1)
+		go func(h scanTarget, p int, hostCtx context.Context) {
+			handedOff := false
+			var open *PortInfo
+			defer func() {
+				// 单个端口的panic只放弃该端口，其余端口继续扫描
+				r := recover()
+				if r != nil {
+					phase := StageConnect
+					if open != nil {
+						phase = StageFingerprint
+					} else {
+						s.config.phases.connected(false)
 					}
-				}()
-
-				// 更新进度
-				atomic.AddInt32(&scanned, 1)
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					callback(PortInfo{
-						Port:     p,
-						Protocol: "progress",
-					})
+					s.portPanicked(h, p, phase, r, open)
+				}
+				if open != nil && !handedOff {
+					s.config.phases.fingerprinted()
+				}
+				if s.limiter != nil {
+					s.limiter.release()
 				}
+				if !handedOff {
+					s.portDone(registry, hostCtx, h, p, r != nil)
+				}
+				wg.Done()
+				<-semaphore
+			}()
+
+			// 更新进度
+			atomic.AddInt64(&scanned, 1)
+			open = s.scanPort(hostCtx, h, p)
+			s.config.phases.connected(open != nil)
+			if open == nil {
+				return
+			}
+			if s.fpQueue != nil {
+				// 开放端口交给指纹线程池，连接线程立即继续探测下一个端口
+				handedOff = true
+				s.fpQueue <- fingerprintJob{ctx: hostCtx, host: h, port: p, info: open}
+				return
+			}
+			s.identify(hostCtx, h, p, open)
+		}(d.host, port, d.ctx)
+		return nil
+	}
+	// dispatchRest 逐个派发主机从from开始的剩余端口
+	dispatchRest := func(d *hostDispatch, from int) error {
+		startHost(d)
+		for _, port := range d.ports[from:] {
+			if d.stopped {
+				break
+			}
+			if err := dispatchPort(d, port); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if priority == nil {
+		for _, host := range hosts {
+			if err := dispatchRest(newDispatch(host), 0); err != nil {
+				return err
+			}
+		}
+	} else {
+		// 优先端口跨主机派发：按优先顺序逐个端口派发给所有主机，全部主机的优先端口派发后再逐台派发其余端口
+		pending := make([]*hostDispatch, len(hosts))
+		for i, host := range hosts {
+			pending[i] = newDispatch(host)
+		}
+		for i, more := 0, true; more; i++ {
+			more = false
+			for _, d := range pending {
+				if d.stopped || i >= d.priority {
+					continue
+				}
+				more = true
+				if err := dispatchPort(d, d.ports[i]); err != nil {
+					return err
+				}
+			}
+		}
+		for _, d := range pending {
+			if err := dispatchRest(d, d.priority); err != nil {
+				return err
+			}
+		}
+	}
+
+	wg.Wait()
+	s.retestRateLimited(ctx, hosts)
+	s.closeFingerprintPool()
+	// 全部端口派发后才取消(如停在最后一批的指纹识别)同样视为取消，进行中的结果已经报告
+	if ctx.Err() != nil {
+		return context.Canceled
+	}
+	return nil
+}
 
-				address := fmt.Sprintf("%s:%d", config.Target, p)
-				conn, err := net.DialTimeout("tcp", address, config.Timeout)
+// hostDispatch 一台主机的端口派发状态
+type hostDispatch struct {
+	host     scanTarget
+	ports    []int
+	priority int             // ports开头的优先端口数，启用PrioritizePorts时这些端口跨主机先行派发
+	ctx      context.Context // 主机开始派发后由hostRegistry分配
+	stopped  bool            // 主机已被取消或超时，剩余端口不再派发
+}
 
-				if err == nil && conn != nil {
-					conn.Close()
+// portDone 端口的探测和指纹识别全部结束后调用，被取消或发生panic的端口不计入检查点，续扫时重新探测
+func (s *portScanner) portDone(registry *hostRegistry, ctx context.Context, h scanTarget, p int, errored bool) {
+	counted := ctx.Err() == nil && !errored
+	if results, done := registry.complete(h.IP, counted); done {
+		s.hostFinished(h, results)
+	}
+	if s.config.checkpoint != nil && counted {
+		s.config.checkpoint.markDone(h.IP, p)
+	}
+}
 
-					// 对开放端口进行指纹识别
-					status, response := scanner.ScanTimeout(config.Target, p, config.Timeout)
+// portPanicked 记录单个端口探测或识别中的panic并发送 port-error 事件。
+// 端口已确认开放时仍然报告，panic可能发生在回调之后，重复的报告由结果分发去重；
+// panic来自回调本身时再次报告同样会panic，此时只记录日志
+func (s *portScanner) portPanicked(h scanTarget, p int, phase string, r interface{}, open *PortInfo) {
+	fmt.Printf("Recovered from panic in %s goroutine: %v\n", phase, r)
+	s.log(logError, "%s:%d 在%s阶段发生panic: %v", h.IP, p, phase, r)
+	s.emit("port-error", map[string]interface{}{
+		"host":  h.IP,
+		"port":  p,
+		"phase": phase,
+		"error": fmt.Sprint(r),
+	})
+	if open != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				s.log(logError, "%s:%d 已确认开放但无法报告结果: %v", h.IP, p, r)
+			}
+		}()
+		s.callback(*open)
+	}
+}
 
-					portInfo := PortInfo{
-						Port:     p,
-						Protocol: "tcp",
-					}
+// hostTimedOut 主机超过PerHostTimeout时放弃其剩余端口，其余主机继续扫描
+func (s *portScanner) hostTimedOut(registry *hostRegistry, ip string) {
+	skipped, ok := registry.expire(ip)
+	if !ok {
+		return
+	}
+	s.log(logWarn, "主机 %s 超过单主机时间上限 %v，放弃剩余 %d 个端口", ip, s.config.PerHostTimeout, skipped)
+	if s.config.onHostTimeout != nil {
+		s.config.onHostTimeout(ip, skipped)
+		return
+	}
+	s.emit("host-timeout", map[string]interface{}{
+		"host":          ip,
+		"timeout":       s.config.PerHostTimeout.Seconds(),
+		"skipped_ports": skipped,
+	})
+}
 
-					if status == gonmap.Matched && response != nil {
-						fp := response.FingerPrint
-						portInfo.Service = fp.Service
-						portInfo.ProductName = fp.ProductName
-						portInfo.Version = fp.Version
-						portInfo.Info = fp.Info
-						portInfo.Hostname = fp.Hostname
-						portInfo.OperatingSystem = fp.OperatingSystem
-						portInfo.DeviceType = fp.DeviceType
-						portInfo.ProbeName = fp.ProbeName
-						portInfo.TLS = response.TLS
-					}
+// scanPort 探测单个主机端口的状态，无需指纹识别的结果直接回调；
+// 端口开放时返回待识别的结果，由调用方在当前线程或指纹线程池中执行identify
+func (s *portScanner) scanPort(ctx context.Context, h scanTarget, p int) *PortInfo {
+	config := s.config
 
-					select {
-					case <-ctx.Done():
-						return
-					default:
-						callback(portInfo)
-					}
-				}
-			}(port)
+	// 已派发的端口总是计入进度，即使所属主机随后被取消。
+	// both 扫描的TCP和UDP分别计入进度，UDP的进度标记以Service区分
+	s.callback(PortInfo{
+		Host:     h.IP,
+		Port:     p,
+		Protocol: "progress",
+	})
+	if config.ScanType == ScanTypeBoth {
+		s.callback(PortInfo{
+			Host:     h.IP,
+			Port:     p,
+			Protocol: "progress",
+			Service:  ScanTypeUDP,
+		})
+	}
+	if ctx.Err() != nil || !sleepJitter(ctx, config.Jitter) {
+		return nil
+	}
+	switch config.ScanType {
+	case ScanTypeUDP:
+		s.scanUDPPort(ctx, h, p)
+		return nil
+	case ScanTypeBoth:
+		s.scanUDPPort(ctx, h, p)
+		if ctx.Err() != nil {
+			return nil
 		}
 	}
 
-	wg.Wait()
+	if s.rst != nil && !s.rst.pace(ctx, h.IP) {
+		return nil
+	}
+	state, icmp, latency, flapping := s.probeAttempts(ctx, h, p)
+	if s.rst != nil && icmp == nil && ctx.Err() == nil {
+		s.observeRST(h, p, state)
+	}
+	resetSuspect := false
+	if state == portClosed && config.TreatResetAsAmbiguous {
+		state, resetSuspect = s.verifyReset(ctx, h, p)
+	}
+	if s.limiter != nil {
+		s.limiter.record(state == portFiltered)
+	}
+	if config.rtt != nil && (state == portOpen || state == portClosed) && s.idle == nil {
+		config.rtt.observe(latency)
+	}
+	if config.probeEvents {
+		probeState := state
+		if resetSuspect {
+			probeState = portPossiblyFiltered
+		}
+		event := map[string]interface{}{
+			"host":  h.IP,
+			"port":  p,
+			"state": probeState,
+		}
+		if icmp != nil {
+			event["icmp"] = icmp.String()
+		}
+		s.emit("probe-result", event)
+	}
+	if icmp != nil && icmp.Type == icmpTimeExceeded {
+		s.reportTTLExceeded(h, p, *icmp)
+	}
+	if icmp != nil && state == portFiltered && config.ICMPHandling == ICMPReport {
+		s.reportICMPFiltered(ctx, h, p, "tcp", *icmp)
+		return nil
+	}
+	if state == portOpenFiltered || (state == portOpen && s.idle != nil) || (resetSuspect && state != portOpen) {
+		// 不进行指纹识别，完整连接会失去这类扫描绕过防火墙或隐藏源地址的意义
+		s.log(logInfo, "%s:%d %s", h.IP, p, state)
+		info := PortInfo{
+			Host:     h.IP,
+			Aliases:  h.Names,
+			Port:     p,
+			Endpoint: config.endpointName(h.IP, p),
+			Protocol: "tcp",
+			Flapping: flapping,
+		}
+		if state == portOpenFiltered {
+			info.State = state
+		}
+		if resetSuspect {
+			info.State = portPossiblyFiltered
+		}
+		// 已确认开放的端口即使扫描随后被取消也要报告
+		if ctx.Err() == nil || state == portOpen {
+			s.callback(info)
+		}
+		return nil
+	}
+	if state != portOpen {
+		return nil
+	}
+
+	portInfo := PortInfo{
+		Host:     h.IP,
+		Aliases:  h.Names,
+		Port:     p,
+		Endpoint: config.endpointName(h.IP, p),
+		Protocol: "tcp",
+		Latency:  latency,
+		Flapping: flapping,
+	}
+	if resetSuspect {
+		portInfo.State = portPossiblyFiltered
+	}
+	s.log(logInfo, "%s:%d 开放", h.IP, p)
+	return &portInfo
+}
+
+// identify 对开放端口进行指纹识别(或复用指纹缓存)、关联本机进程并回调结果。
+// 端口已确认开放，识别前或识别中扫描被取消时仍然报告，只是指纹信息可能为空或不完整
+func (s *portScanner) identify(ctx context.Context, h scanTarget, p int, portInfo *PortInfo) {
+	config := s.config
+	state := portInfo.State
+
+	// 端口已确认开放，指纹缓存命中时直接复用服务识别结果
+	cacheKey := fingerprintKey(h.IP, p)
+	var cached PortInfo
+	hit := false
+	if config.UseFingerprintCache {
+		cached, hit = fpCache.Get(cacheKey)
+	}
+	gentle := ""
+	if config.runsFingerprint() {
+		gentle = s.gentleReason(h, p)
+	}
+	switch {
+	case !config.runsFingerprint():
+		// 流水线不包含fingerprint阶段，只报告端口开放
+	case hit:
+		copyFingerprint(portInfo, cached)
+		s.log(logDebug, "%s:%d 命中指纹缓存: %s", h.IP, p, cached.Service)
+	case ctx.Err() != nil:
+		s.log(logInfo, "%s:%d 扫描已取消，跳过指纹识别", h.IP, p)
+	case gentle != "":
+		// 温和识别的结果只有横幅，不写入缓存
+		s.fingerprintGentle(ctx, h, p, portInfo)
+	default:
+		s.fingerprint(ctx, h, p, portInfo)
+		// 被取消打断的识别结果不完整，不写入缓存
+		if ctx.Err() != nil {
+			s.log(logInfo, "%s:%d 指纹识别被取消，报告已获取的部分信息", h.IP, p)
+		} else if portInfo.Service != "" {
+			fpCache.Put(cacheKey, *portInfo)
+		}
+	}
+	if gentle != "" {
+		portInfo.Gentle = true
+		portInfo.GentleReason = gentle
+	} else if config.runsFingerprint() {
+		s.detectEmbedded(h, portInfo)
+	}
+	portInfo.State = state
+	canonicalizeService(portInfo)
+	scoreConfidence(portInfo)
+	if isLoopbackTarget(h.IP) {
+		if proc, err := lookupListener(p); err == nil {
+			portInfo.Process = proc
+		} else {
+			s.log(logDebug, "%s:%d 未找到占用端口的进程: %v", h.IP, p, err)
+		}
+	}
+
+	s.callback(*portInfo)
+}
+
+// sleepJitter 随机等待0..jitter，扫描被取消时立即返回false
+func sleepJitter(ctx context.Context, jitter time.Duration) bool {
+	if jitter <= 0 {
+		return true
+	}
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(jitter) + 1)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// verifyReset 首次探测收到RST时延迟复核，复核结果不同说明RST可能是防火墙伪造的，
+// 返回复核后的状态以及是否出现了不一致
+func (s *portScanner) verifyReset(ctx context.Context, h scanTarget, p int) (string, bool) {
+	for i := 0; i < resetVerifyAttempts; i++ {
+		select {
+		case <-ctx.Done():
+			return portClosed, false
+		case <-time.After(resetVerifyDelay):
+		}
+		if state := s.probeState(ctx, h, p); state != portClosed && ctx.Err() == nil {
+			s.log(logWarn, "%s:%d 首次探测收到RST，复核结果为 %s，可能存在RST注入", h.IP, p, state)
+			return state, true
+		}
+	}
+	return portClosed, false
+}
+
+// dialTCP 建立TCP连接，设置了替代拨号器时经由该拨号器
+func dialTCP(ctx context.Context, d contextDialer, sources *sourcePool, address string, timeout time.Duration) (net.Conn, error) {
+	if d == nil {
+		return sources.dial(ctx, timeout, "tcp", address, nil)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return d.DialContext(ctx, "tcp", address)
+}
+
+// connectDialer 端口探测和存活探测使用的拨号器：模拟网络或代理池，都未设置时为nil(直接连接)
+func (c ScanConfig) connectDialer() contextDialer {
+	if c.dialer != nil {
+		return c.dialer
+	}
+	if c.proxies != nil {
+		return c.proxies
+	}
 	return nil
 }
+
+// dialProbe 建立识别探测使用的TCP连接，按AbruptClose设置关闭方式，并计入流量统计和限速
+func (c ScanConfig) dialProbe(ctx context.Context, network, address string) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if c.proxies != nil {
+		dialCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+		conn, err = c.proxies.DialContext(dialCtx, network, address)
+		cancel()
+	} else {
+		conn, err = c.sources.dial(ctx, c.Timeout, network, address, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	applyClosePolicy(conn, c.AbruptClose)
+	return c.bandwidth.wrap(ctx, conn), nil
+}
+
+// applyClosePolicy abrupt为true时将SO_LINGER设为0，关闭连接直接发送RST而不是FIN
+func applyClosePolicy(conn net.Conn, abrupt bool) {
+	if tcp, ok := conn.(*net.TCPConn); ok && abrupt {
+		tcp.SetLinger(0)
+	}
+}
+
+// fingerprint 对开放端口进行指纹识别
+func (s *portScanner) fingerprint(ctx context.Context, h scanTarget, p int, portInfo *PortInfo) {
+	if sim, ok := s.config.dialer.(*SimulatedNetwork); ok {
+		sim.identify(ctx, h.IP, p, portInfo)
+		normalizeProduct(portInfo)
+		return
+	}
+
+	// gonmap实例会记录已使用的探针且不会重置，多个端口共用一个实例时
+	// 后续端口会跳过探针，并发使用还存在数据竞争，因此每次识别单独创建(浅拷贝，开销很小)
+	status, response := gonmap.Status(gonmap.Unknown), (*gonmap.Response)(nil)
+	// gonmap直接连接目标，经代理扫描时跳过，由下面经代理的横幅和协议探测识别
+	if s.config.proxies == nil {
+		nmap := gonmap.New()
+		timeout := s.config.probeTimeout(p)
+		nmap.SetTimeout(timeout)
+		status, response = nmap.ScanTimeout(nmapHost(h.IP), p, timeout)
+	}
+
+	if status == gonmap.Matched && response != nil {
+		fp := response.FingerPrint
+		portInfo.Service = fp.Service
+		portInfo.ProductName = fp.ProductName
+		portInfo.Version = fp.Version
+		portInfo.Info = fp.Info
+		portInfo.Hostname = fp.Hostname
+		portInfo.OperatingSystem = fp.OperatingSystem
+		portInfo.DeviceType = fp.DeviceType
+		portInfo.ProbeName = fp.ProbeName
+		portInfo.TLS = response.TLS
+		s.captureBanner(portInfo, []byte(response.Raw))
+		s.log(logDebug, "%s:%d 指纹识别: %s %s %s (探针 %s)", h.IP, p, fp.Service, fp.ProductName, fp.Version, fp.ProbeName)
+	} else if !s.fingerprintCustom(ctx, h, p, portInfo) {
+		// 没有指纹匹配时记录服务端主动发送的横幅，便于人工识别或编写新指纹
+		portInfo.Unidentified = true
+		size := bannerReadLimit(s.config.BannerReadSize, defaultBannerReadSize)
+		if banner, err := grabBanner(ctx, s.config, h.IP, p, size); err == nil {
+			if s.config.CaptureResponse && portInfo.RawResponse == "" {
+				portInfo.RawResponse = dumpResponse(string(banner), s.config.CaptureSize)
+			}
+			s.captureBanner(portInfo, banner)
+			s.matchBanner(h, p, banner, portInfo)
+			if len(banner) > unidentifiedBannerSize {
+				banner = banner[:unidentifiedBannerSize]
+			}
+			portInfo.Info = escapeBanner(banner)
+		}
+		s.log(logDebug, "%s:%d 指纹未匹配 (状态 %v)，横幅: %q", h.IP, p, status, portInfo.Info)
+		// gonmap不支持客户端证书，要求mTLS的服务握手失败后无法识别
+		if s.config.clientCert != nil {
+			s.fingerprintMTLS(ctx, h, p, portInfo)
+		}
+	}
+
+	if s.config.CaptureResponse && response != nil && response.Raw != "" {
+		portInfo.RawResponse = dumpResponse(response.Raw, s.config.CaptureSize)
+	}
+
+	// 邮件、FTP和数据库等明文服务尝试STARTTLS升级，获取证书信息
+	s.probeStartTLS(ctx, h, p, portInfo)
+	s.probeFastOpen(ctx, h, p, portInfo)
+
+	// HTTP(S)服务额外获取页面标题和Server头
+	if isHTTPService(portInfo.Service) {
+		useTLS := portInfo.TLS || strings.EqualFold(portInfo.Service, "https")
+		if result, err := probeHTTP(ctx, s.config, h.IP, p, useTLS); err == nil {
+			portInfo.HTTPTitle = result.Title
+			portInfo.HTTPServer = result.Server
+			portInfo.MutualTLS = result.MutualTLS
+			portInfo.TLSExpiry = result.TLSExpiry
+			portInfo.SelfSigned = isSelfSigned(result.Certificate)
+			s.captureHTTP(portInfo, result)
+			applyALPN(portInfo, result)
+			labelCDN(portInfo, result)
+			if useTLS && s.config.virtualHostsEnabled() {
+				s.probeVirtualHosts(ctx, h, p, result, portInfo)
+			}
+		} else {
+			s.log(logDebug, "%s:%d HTTP探测失败: %v", h.IP, p, err)
+		}
+	}
+
+	normalizeProduct(portInfo)
+}
+
+// nmapHost gonmap内部以 "%s:%d" 拼接地址，IPv6地址(含区域标识)需要预先加上方括号
+func nmapHost(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "[" + ip + "]"
+	}
+	return ip
+}
+
+// copyFingerprint 复制指纹相关字段，不改变主机、端口信息和本次探测的耗时与状态是否反复
+func copyFingerprint(dst *PortInfo, src PortInfo) {
+	host, aliases, port, endpoint, protocol, latency := dst.Host, dst.Aliases, dst.Port, dst.Endpoint, dst.Protocol, dst.Latency
+	flapping := dst.Flapping
+	*dst = src
+	dst.Host, dst.Aliases, dst.Port, dst.Endpoint, dst.Protocol, dst.Latency = host, aliases, port, endpoint, protocol, latency
+	dst.Flapping = flapping
+}
+
+// probeState 判断端口状态：原始套接字扫描根据响应标志位判断，否则进行完整TCP连接
+func (s *portScanner) probeState(ctx context.Context, h scanTarget, p int) string {
+	state, _ := s.probePort(ctx, h, p)
+	return state
+}
+
+// probePort 同probeState，原始套接字扫描收到ICMP目标不可达报文时一并返回该报文
+func (s *portScanner) probePort(ctx context.Context, h scanTarget, p int) (string, *icmpReply) {
+	if s.idle != nil {
+		state, step, err := s.idle.probe(ctx, net.ParseIP(h.IP), p)
+		if err != nil && ctx.Err() == nil {
+			s.log(logWarn, "%s:%d 空闲扫描探测失败: %v", h.IP, p, err)
+		} else if state == portFiltered {
+			s.log(logWarn, "%s:%d 僵尸主机IP ID增量异常(%d)，僵尸主机可能不够空闲", h.IP, p, step)
+		}
+		return state, nil
+	}
+	if s.raw != nil {
+		flags := rawScanFlags(s.config.ScanType)
+		reply, ok, err := s.raw.probe(ctx, net.ParseIP(h.IP), p, flags, s.config.connectTimeout())
+		if err != nil {
+			s.log(logWarn, "%s:%d 发送%s探测包失败: %v", h.IP, p, strings.ToUpper(s.config.ScanType), err)
+			s.countError(err)
+			return portFiltered, nil
+		}
+		if flags != tcpSYN {
+			// FIN/NULL/XMAS：RST表示关闭，无响应为open|filtered，收到ICMP目标不可达为filtered
+			switch {
+			case !ok:
+				return portOpenFiltered, nil
+			case reply.ICMP != nil:
+				return icmpPortState(*reply.ICMP, "tcp"), reply.ICMP
+			case reply.Flags&tcpRST != 0:
+				return portClosed, nil
+			}
+			return portFiltered, nil
+		}
+		if !ok {
+			return portFiltered, nil
+		}
+		if reply.ICMP != nil {
+			return icmpPortState(*reply.ICMP, "tcp"), reply.ICMP
+		}
+		if reply.Flags&(tcpSYN|tcpACK) == tcpSYN|tcpACK {
+			return portOpen, nil
+		}
+		return portClosed, nil
+	}
+
+	address := net.JoinHostPort(h.IP, strconv.Itoa(p))
+	conn, err := dialTCP(ctx, s.config.connectDialer(), s.config.sources, address, s.config.connectTimeout())
+	if err != nil {
+		if ctx.Err() == nil {
+			s.countError(err)
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return portFiltered, nil
+		}
+		if isFDExhausted(err) && s.limiter != nil {
+			s.log(logWarn, "拨号 %s 失败，本机打开的文件数已达上限: %v", address, err)
+			s.limiter.exhausted()
+		} else if ctx.Err() == nil && !isConnRefused(err) {
+			s.log(logDebug, "拨号 %s 失败: %v", address, err)
+		}
+		return portClosed, nil
+	}
+	applyClosePolicy(conn, s.config.AbruptClose)
+	conn.Close()
+	return portOpen, nil
+}
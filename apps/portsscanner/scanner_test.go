@@ -0,0 +1,216 @@
+package portsscanner
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHugeRangeHeapStaysBounded(t *testing.T) {
+	if testing.Short() {
+		t.Skip("scans a synthetic /16 for a few seconds")
+	}
+	ports := make([]int, 0, 65535)
+	for p := 1; p <= 65535; p++ {
+		ports = append(ports, p)
+	}
+	// 65536台主机×65535个端口，预先生成任务列表需要数十GB；模拟网络中不存在的主机立即超时
+	config := simConfig(t, nil, "198.18.0.0/16", ports...)
+	config.MaxThreads = 64
+	config.pause = &pauseGate{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	dedup := newResultDedup()
+	scanned := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- ScanPortsCombined(ctx, config, func(info PortInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			if info.Protocol == "progress" && dedup.firstProgress(info) {
+				scanned++
+			}
+		})
+	}()
+
+	// 第一次采样前扫描已开始(指纹库等一次性初始化已完成)，之后扫描的端口数增加，存活的堆内存不应随之增长。
+	// 采样时暂停派发，等进行中的探测结束后再统计，避免把探测的临时分配算进去
+	liveHeap := func() (int64, int) {
+		time.Sleep(time.Second)
+		config.pause.pause()
+		defer config.pause.resume()
+		time.Sleep(100 * time.Millisecond)
+		var m runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		mu.Lock()
+		defer mu.Unlock()
+		return int64(m.HeapAlloc), scanned
+	}
+	heap1, scanned1 := liveHeap()
+	heap2, scanned2 := liveHeap()
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	if scanned2 <= scanned1 {
+		t.Fatalf("scan made no progress between samples (%d, %d ports)", scanned1, scanned2)
+	}
+	const limit = 8 << 20
+	if grown := heap2 - heap1; grown > limit {
+		t.Fatalf("live heap grew by %d MB while scanning %d more ports, want under %d MB",
+			grown>>20, scanned2-scanned1, limit>>20)
+	}
+}
+
+func TestCancelDuringFingerprintKeepsOpenPort(t *testing.T) {
+	hosts := []SimulatedHost{{IP: "198.18.2.1", Ports: []SimulatedPort{
+		{Port: 22, Service: "ssh", Product: "OpenSSH", IdentifyLatency: time.Minute},
+	}}}
+	for _, fpThreads := range []int{0, 2} {
+		config := simConfig(t, hosts, "198.18.2.1", 22)
+		// FingerprintThreads大于0时识别在单独的线程池中进行
+		config.FingerprintThreads = fpThreads
+
+		ctx, cancel := context.WithCancel(context.Background())
+		scan := &simScan{}
+		done := make(chan error, 1)
+		go func() {
+			done <- ScanPortsCombined(ctx, config, scan.callback)
+		}()
+		// 模拟网络的连接立即建立，之后识别一直阻塞到取消
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-done:
+			if err != context.Canceled {
+				t.Fatalf("fingerprint threads %d: err = %v, want context.Canceled", fpThreads, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("fingerprint threads %d: scan did not stop after cancel", fpThreads)
+		}
+		// ScanPortsCombined返回后不应再有回调
+		scan.mu.Lock()
+		if len(scan.results) != 1 {
+			t.Fatalf("fingerprint threads %d: results = %+v, want the open port reported once", fpThreads, scan.results)
+		}
+		if r := scan.results[0]; r.Port != 22 || r.State != "" || r.Service != "" {
+			t.Fatalf("fingerprint threads %d: result = %+v, want open 22 without a fingerprint", fpThreads, r)
+		}
+		scan.mu.Unlock()
+	}
+}
+
+func TestSummarizeHostsCountsOnlyOpenPorts(t *testing.T) {
+	hosts := []scanTarget{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}}
+	results := []PortInfo{
+		{Host: "10.0.0.1", Port: 443, Service: "https"},
+		{Host: "10.0.0.1", Port: 22, Service: "SSH"},
+		{Host: "10.0.0.1", Port: 53, Protocol: "udp", State: "open|filtered"},
+		{Host: "10.0.0.2", Port: 161, Protocol: "udp", State: "open|filtered"},
+	}
+	summaries := summarizeHosts(hosts, results)
+	if len(summaries) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(summaries))
+	}
+	if got := summaries[0]; len(got.OpenPorts) != 2 || got.OpenPorts[0] != 22 || got.Services[0] != "22/ssh" {
+		t.Errorf("10.0.0.1 summary = %+v, want only 22 and 443", got)
+	}
+	if got := summaries[1]; len(got.OpenPorts) != 0 {
+		t.Errorf("10.0.0.2 summary = %+v, want no open ports", got)
+	}
+}
+
+func TestPriorityPortsDispatchedAcrossHostsFirst(t *testing.T) {
+	var hosts []SimulatedHost
+	for _, ip := range []string{"198.18.5.1", "198.18.5.2", "198.18.5.3"} {
+		hosts = append(hosts, SimulatedHost{IP: ip, Ports: []SimulatedPort{{Port: 22, Service: "ssh"}, {Port: 1001}}})
+	}
+	config := simConfig(t, hosts, "198.18.5.1-198.18.5.3", 1000, 22, 1001, 80)
+	config.MaxThreads = 1
+	config.PrioritizePorts = true
+	config.PriorityPorts = []int{80, 22}
+	scan, err := runSimScan(t, context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, p := range scan.progress {
+		order = append(order, p.Host+":"+strconv.Itoa(p.Port))
+	}
+	want := []string{
+		"198.18.5.1:80", "198.18.5.2:80", "198.18.5.3:80",
+		"198.18.5.1:22", "198.18.5.2:22", "198.18.5.3:22",
+		"198.18.5.1:1000", "198.18.5.1:1001",
+		"198.18.5.2:1000", "198.18.5.2:1001",
+		"198.18.5.3:1000", "198.18.5.3:1001",
+	}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("dispatch order = %v, want %v", order, want)
+	}
+	if len(scan.results) != 6 {
+		t.Errorf("got %d open results, want 6", len(scan.results))
+	}
+}
+
+func TestRandomizedProbeOrderIsSeeded(t *testing.T) {
+	var probes []customProbe
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		probe, err := parseCustomProbe(CustomProbe{Name: name, Protocol: "tcp", Ports: []int{9999}, Service: name, Payload: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		probes = append(probes, probe)
+	}
+	customProbesMu.Lock()
+	saved := customProbes
+	customProbes = probes
+	customProbesMu.Unlock()
+	defer func() {
+		customProbesMu.Lock()
+		customProbes = saved
+		customProbesMu.Unlock()
+	}()
+
+	names := func(config ScanConfig, host string) string {
+		var s string
+		for _, p := range config.orderedCustomProbes(host, 9999) {
+			s += p.Name
+		}
+		return s
+	}
+	if got := names(ScanConfig{ProbeOrderSeed: 1}, "10.0.0.1"); got != "abcde" {
+		t.Errorf("order without RandomizeProbeOrder = %s, want abcde", got)
+	}
+	config := ScanConfig{RandomizeProbeOrder: true}
+	applyScanDefaults(&config)
+	if config.ProbeOrderSeed == 0 {
+		t.Fatal("no probe order seed generated")
+	}
+	orders := make(map[string]bool)
+	for seed := int64(1); seed <= 20; seed++ {
+		config.ProbeOrderSeed = seed
+		first := names(config, "10.0.0.1")
+		if again := names(config, "10.0.0.1"); again != first {
+			t.Fatalf("seed %d gave %s then %s", seed, first, again)
+		}
+		sorted := []byte(first)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		if string(sorted) != "abcde" {
+			t.Fatalf("seed %d order %s is not a permutation of the probes", seed, first)
+		}
+		orders[first] = true
+	}
+	if len(orders) < 2 {
+		t.Errorf("20 seeds produced only %v", orders)
+	}
+}
@@ -0,0 +1,108 @@
+package portsscanner
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// HostProgress 单台主机在快照时刻的扫描进度
+type HostProgress struct {
+	Host       string `json:"host"`
+	Total      int    `json:"total"`      // 需要扫描的端口数
+	Dispatched int    `json:"dispatched"` // 已派发的端口数
+	Completed  int    `json:"completed"`  // 已完成探测和识别的端口数
+	InFlight   int    `json:"in_flight"`  // 已派发但尚未完成的端口数
+	Cancelled  bool   `json:"cancelled"`  // 被CancelTarget取消或超过PerHostTimeout
+	Incomplete bool   `json:"incomplete"` // 有端口的探测被取消
+}
+
+// ScanStateSnapshot 某一时刻扫描引擎内部状态的副本，与之后的扫描进展互不影响。
+// 没有进行中或保留期内的扫描时Status为idle，其余字段为零值
+type ScanStateSnapshot struct {
+	ScanID        string         `json:"scan_id,omitempty"`
+	Status        string         `json:"status"`
+	TakenAt       time.Time      `json:"taken_at"`
+	TotalPorts    int64          `json:"total_ports"`
+	Scanned       int64          `json:"scanned"`
+	OpenPorts     int            `json:"open_ports"`
+	ActiveWorkers int            `json:"active_workers"`        // 所有主机进行中的端口探测数
+	Concurrency   int            `json:"concurrency,omitempty"` // 启用AdaptiveThrottle或AutoConcurrency时当前允许的并发数
+	ServiceCounts map[string]int `json:"service_counts"`
+	ErrorCounts   map[string]int `json:"error_counts"`
+	Hosts         []HostProgress `json:"hosts"`
+}
+
+// progress 返回每台主机当前进度的副本，按主机地址排序
+func (r *hostRegistry) progress() []HostProgress {
+	if r == nil {
+		return []HostProgress{}
+	}
+	r.mu.Lock()
+	hosts := make([]HostProgress, 0, len(r.hosts))
+	for host, st := range r.hosts {
+		hosts = append(hosts, HostProgress{
+			Host:       host,
+			Total:      st.total,
+			Dispatched: st.dispatched,
+			Completed:  st.completed,
+			InFlight:   max(st.dispatched-st.completed, 0),
+			Cancelled:  st.cancelled,
+			Incomplete: st.incomplete,
+		})
+	}
+	r.mu.Unlock()
+
+	sort.Slice(hosts, func(i, j int) bool {
+		return compareHosts(hosts[i].Host, hosts[j].Host) < 0
+	})
+	return hosts
+}
+
+// openPorts 返回已记录的开放端口数
+func (r *scanRecord) openPorts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.summary.OpenPorts
+}
+
+// snapshotState 采集扫描状态，各部分分别加锁读取，不会阻塞扫描线程
+func (c *scanControl) snapshotState(status string) ScanStateSnapshot {
+	snap := ScanStateSnapshot{
+		ScanID:        c.record.summary.ID,
+		Status:        status,
+		TakenAt:       time.Now(),
+		TotalPorts:    atomic.LoadInt64(&c.totalPorts),
+		Scanned:       atomic.LoadInt64(&c.scanned),
+		OpenPorts:     c.record.openPorts(),
+		Concurrency:   c.concurrency(),
+		ServiceCounts: c.serviceCountsSnapshot(),
+		ErrorCounts:   c.errorCountsSnapshot(),
+		Hosts:         c.registry.progress(),
+	}
+	for _, h := range snap.Hosts {
+		snap.ActiveWorkers += h.InFlight
+	}
+	return snap
+}
+
+// SnapshotScanState 返回当前扫描(或保留期内刚结束的扫描)内部状态的副本，可在扫描进行中随时并发调用，
+// 用于排查扫描卡住或结果不稳定等问题而不依赖事件的时序
+func (a *App) SnapshotScanState() ScanStateSnapshot {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+
+	switch {
+	case currentScan != nil:
+		return currentScan.snapshotState(currentScan.status())
+	case lastScan != nil:
+		return lastScan.control.snapshotState(lastScan.status)
+	}
+	return ScanStateSnapshot{
+		Status:        "idle",
+		TakenAt:       time.Now(),
+		ServiceCounts: map[string]int{},
+		ErrorCounts:   map[string]int{},
+		Hosts:         []HostProgress{},
+	}
+}
@@ -0,0 +1,254 @@
+package portsscanner
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScoringRule 结果评分规则：结果满足全部已设置的条件时加上Score(可以为负数)，
+// 一个结果的分数为所有匹配规则之和。Flags 可用的条件见 scoringFlags
+type ScoringRule struct {
+	Name         string   `json:"name"`
+	Score        int      `json:"score"`
+	Ports        []int    `json:"ports,omitempty"`
+	Services     []string `json:"services,omitempty"`      // 任一服务名匹配即可，按规范化后的名称比较
+	Product      string   `json:"product,omitempty"`       // 产品名的正则表达式，不区分大小写
+	VersionBelow string   `json:"version_below,omitempty"` // 版本号低于该值，按数字段比较，版本未知时不匹配
+	Flags        []string `json:"flags,omitempty"`
+}
+
+// ScoringRules 评分规则文件结构，ReplaceDefaults 为false时规则追加在默认规则之后
+type ScoringRules struct {
+	ReplaceDefaults bool          `json:"replace_defaults"`
+	Rules           []ScoringRule `json:"rules"`
+}
+
+// scoringFlags 规则可以要求的结果标记
+var scoringFlags = map[string]func(r PortInfo, now time.Time) bool{
+	"tls":          func(r PortInfo, now time.Time) bool { return r.TLS || !r.TLSExpiry.IsZero() },
+	"tls-expired":  func(r PortInfo, now time.Time) bool { return !r.TLSExpiry.IsZero() && r.TLSExpiry.Before(now) },
+	"tls-expiring": func(r PortInfo, now time.Time) bool { return tlsExpiringSoon(r.TLSExpiry, now) },
+	"self-signed":  func(r PortInfo, now time.Time) bool { return r.SelfSigned },
+	"mutual-tls":   func(r PortInfo, now time.Time) bool { return r.MutualTLS },
+	"unidentified": func(r PortInfo, now time.Time) bool { return r.Unidentified },
+	"honeypot":     func(r PortInfo, now time.Time) bool { return r.LikelyHoneypot },
+	"unstable":     func(r PortInfo, now time.Time) bool { return r.Unstable },
+}
+
+// tlsExpiringSoon 证书尚未过期但将在 reportTLSWarnDays 天内过期
+func tlsExpiringSoon(expiry, now time.Time) bool {
+	return !expiry.IsZero() && !expiry.Before(now) && expiry.Sub(now) < reportTLSWarnDays*24*time.Hour
+}
+
+// defaultScoringRules 内置规则：远程管理和数据库等高价值服务、明文登录协议、证书问题和过旧版本加分，
+// 疑似蜜罐和复核不稳定的结果减分
+var defaultScoringRules = []ScoringRule{
+	{Name: "remote-desktop", Score: 10, Services: []string{"ms-wbt-server", "vnc"}},
+	{Name: "file-sharing", Score: 10, Services: []string{"microsoft-ds", "netbios-ssn", "nfs"}},
+	{Name: "cleartext-login", Score: 15, Services: []string{"telnet", "ftp", "rlogin", "rsh", "rexec"}},
+	{Name: "database", Score: 10, Services: []string{"mysql", "postgresql", "ms-sql-s", "mongodb", "redis", "elasticsearch", "memcached"}},
+	{Name: "outdated-openssh", Score: 20, Product: "openssh", VersionBelow: "7.4"},
+	{Name: "outdated-apache", Score: 20, Product: "apache httpd", VersionBelow: "2.4"},
+	{Name: "tls-expired", Score: 20, Flags: []string{"tls-expired"}},
+	{Name: "tls-expiring", Score: 5, Flags: []string{"tls-expiring"}},
+	{Name: "self-signed", Score: 5, Flags: []string{"self-signed"}},
+	{Name: "unidentified", Score: 3, Flags: []string{"unidentified"}},
+	{Name: "honeypot", Score: -20, Flags: []string{"honeypot"}},
+	{Name: "unstable", Score: -5, Flags: []string{"unstable"}},
+}
+
+// scoringRule 校验后的规则
+type scoringRule struct {
+	ScoringRule
+	services []string
+	product  *regexp.Regexp
+	below    []int
+}
+
+var (
+	scoringRules   []scoringRule
+	scoringRulesMu sync.RWMutex
+)
+
+func init() {
+	rules, err := compileScoringRules(defaultScoringRules)
+	if err != nil {
+		panic(err)
+	}
+	scoringRules = rules
+}
+
+var versionDigits = regexp.MustCompile(`\d+`)
+
+// versionNumbers 取出版本号中的数字段，例如 "7.4p1" 为 [7 4 1]
+func versionNumbers(v string) []int {
+	var nums []int
+	for _, s := range versionDigits.FindAllString(v, -1) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			break
+		}
+		nums = append(nums, n)
+	}
+	return nums
+}
+
+// versionLess 逐段比较版本号，较短的一方不足的段按0处理
+func versionLess(a, b []int) bool {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return false
+}
+
+// compileScoringRules 校验规则，条件格式错误时报告规则名
+func compileScoringRules(rules []ScoringRule) ([]scoringRule, error) {
+	compiled := make([]scoringRule, 0, len(rules))
+	for i, r := range rules {
+		if r.Name == "" {
+			r.Name = "rule-" + strconv.Itoa(i+1)
+		}
+		rule := scoringRule{ScoringRule: r}
+		for _, s := range r.Services {
+			rule.services = append(rule.services, canonicalServiceName(s))
+		}
+		for _, p := range r.Ports {
+			if p < 1 || p > 65535 {
+				return nil, fmt.Errorf("scoring rule %q: invalid port %d", r.Name, p)
+			}
+		}
+		if r.Product != "" {
+			re, err := regexp.Compile("(?i)" + r.Product)
+			if err != nil {
+				return nil, fmt.Errorf("scoring rule %q: invalid product pattern: %w", r.Name, err)
+			}
+			rule.product = re
+		}
+		if r.VersionBelow != "" {
+			if rule.below = versionNumbers(r.VersionBelow); len(rule.below) == 0 {
+				return nil, fmt.Errorf("scoring rule %q: invalid version %q", r.Name, r.VersionBelow)
+			}
+		}
+		for _, f := range r.Flags {
+			if _, ok := scoringFlags[f]; !ok {
+				return nil, fmt.Errorf("scoring rule %q: unknown flag %q", r.Name, f)
+			}
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+// matches 判断结果是否满足规则的全部条件
+func (r scoringRule) matches(info PortInfo, now time.Time) bool {
+	if len(r.Ports) > 0 && !containsInt(r.Ports, info.Port) {
+		return false
+	}
+	if len(r.services) > 0 && !containsString(r.services, canonicalServiceName(info.Service)) {
+		return false
+	}
+	if r.product != nil && !r.product.MatchString(info.ProductName) {
+		return false
+	}
+	if r.below != nil {
+		version := versionNumbers(info.Version)
+		if len(version) == 0 || !versionLess(version, r.below) {
+			return false
+		}
+	}
+	for _, f := range r.Flags {
+		if !scoringFlags[f](info, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsInt 判断列表是否包含n
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// scoreResults 按当前规则计算每个结果的Score和匹配的规则名
+func scoreResults(results []PortInfo) {
+	now := time.Now()
+	scoringRulesMu.RLock()
+	defer scoringRulesMu.RUnlock()
+	for i := range results {
+		results[i].Score = 0
+		results[i].ScoreRules = nil
+		for _, rule := range scoringRules {
+			if rule.matches(results[i], now) {
+				results[i].Score += rule.Score
+				results[i].ScoreRules = append(results[i].ScoreRules, rule.Name)
+			}
+		}
+	}
+}
+
+// isSelfSigned 判断证书是否由自身签发，cert为nil时返回false
+func isSelfSigned(cert *x509.Certificate) bool {
+	if cert == nil || !strings.EqualFold(cert.Issuer.String(), cert.Subject.String()) {
+		return false
+	}
+	return cert.CheckSignatureFrom(cert) == nil
+}
+
+// LoadScoringRules 从JSON文件(格式见ScoringRules)加载评分规则，返回生效的规则数。
+// 任一规则格式错误时保持原有规则不变
+func (a *App) LoadScoringRules(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read scoring rules %s: %w", path, err)
+	}
+	var file ScoringRules
+	if err := json.Unmarshal(data, &file); err != nil {
+		return 0, fmt.Errorf("failed to parse scoring rules %s: %w", path, err)
+	}
+	rules := file.Rules
+	if !file.ReplaceDefaults {
+		rules = append(append([]ScoringRule(nil), defaultScoringRules...), file.Rules...)
+	}
+	compiled, err := compileScoringRules(rules)
+	if err != nil {
+		return 0, err
+	}
+
+	scoringRulesMu.Lock()
+	scoringRules = compiled
+	scoringRulesMu.Unlock()
+	fmt.Printf("已加载 %d 条评分规则\n", len(compiled))
+	return len(compiled), nil
+}
+
+// GetScoringRules 返回当前生效的评分规则
+func (a *App) GetScoringRules() []ScoringRule {
+	scoringRulesMu.RLock()
+	defer scoringRulesMu.RUnlock()
+	rules := make([]ScoringRule, len(scoringRules))
+	for i, r := range scoringRules {
+		rules[i] = r.ScoringRule
+	}
+	return rules
+}
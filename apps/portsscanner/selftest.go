@@ -0,0 +1,113 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// 自检结果状态
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+const (
+	defaultSelfTestHost = "www.baidu.com"
+	defaultSelfTestAddr = "1.1.1.1:53"
+	// 打开文件数低于该值时大线程数扫描容易出现 "too many open files"
+	recommendedOpenFiles = 4096
+)
+
+// SelfTestCheck 单项自检结果
+type SelfTestCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// SelfTestResult 扫描环境自检结果
+type SelfTestResult struct {
+	Status string          `json:"status"` // 所有检查项中最差的状态
+	Checks []SelfTestCheck `json:"checks"`
+}
+
+// SetSelfTestTarget 设置自检时用于出站TCP连通性测试的地址(host:port)
+func (a *App) SetSelfTestTarget(addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("invalid self-test address %q: %w", addr, err)
+	}
+	a.selfTestAddr = addr
+	return nil
+}
+
+// SelfTest 在正式扫描前检查DNS解析、出站TCP、原始套接字权限和文件描述符上限
+func (a *App) SelfTest() SelfTestResult {
+	addr := a.selfTestAddr
+	if addr == "" {
+		addr = defaultSelfTestAddr
+	}
+
+	checks := []SelfTestCheck{
+		checkDNS(defaultSelfTestHost),
+		checkOutboundTCP(addr),
+		checkRawSocket(),
+		checkOpenFileLimit(),
+	}
+
+	result := SelfTestResult{Status: checkPass, Checks: checks}
+	for _, c := range checks {
+		if c.Status == checkFail {
+			result.Status = checkFail
+			break
+		}
+		if c.Status == checkWarn {
+			result.Status = checkWarn
+		}
+	}
+	return result
+}
+
+func checkDNS(host string) SelfTestCheck {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return SelfTestCheck{Name: "dns", Status: checkFail, Detail: fmt.Sprintf("解析 %s 失败: %v", host, err)}
+	}
+	return SelfTestCheck{Name: "dns", Status: checkPass, Detail: fmt.Sprintf("%s -> %v", host, addrs)}
+}
+
+func checkOutboundTCP(addr string) SelfTestCheck {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return SelfTestCheck{Name: "tcp", Status: checkFail, Detail: fmt.Sprintf("连接 %s 失败: %v", addr, err)}
+	}
+	conn.Close()
+	return SelfTestCheck{Name: "tcp", Status: checkPass, Detail: fmt.Sprintf("连接 %s 成功，耗时 %v", addr, time.Since(start).Round(time.Millisecond))}
+}
+
+func checkRawSocket() SelfTestCheck {
+	if err := rawSocketAvailable(); err != nil {
+		return SelfTestCheck{Name: "raw_socket", Status: checkWarn, Detail: fmt.Sprintf("原始套接字不可用，SYN扫描将无法使用: %v", err)}
+	}
+	return SelfTestCheck{Name: "raw_socket", Status: checkPass, Detail: "原始套接字可用"}
+}
+
+func checkOpenFileLimit() SelfTestCheck {
+	limit, err := openFileLimit()
+	if err != nil {
+		return SelfTestCheck{Name: "open_files", Status: checkWarn, Detail: fmt.Sprintf("无法获取打开文件数上限: %v", err)}
+	}
+	if limit == 0 {
+		return SelfTestCheck{Name: "open_files", Status: checkPass, Detail: "当前平台没有打开文件数限制"}
+	}
+	if limit < recommendedOpenFiles {
+		return SelfTestCheck{Name: "open_files", Status: checkWarn, Detail: fmt.Sprintf("打开文件数上限为 %d，建议不低于 %d 或降低线程数", limit, recommendedOpenFiles)}
+	}
+	return SelfTestCheck{Name: "open_files", Status: checkPass, Detail: fmt.Sprintf("打开文件数上限为 %d", limit)}
+}
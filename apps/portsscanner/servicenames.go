@@ -0,0 +1,92 @@
+package portsscanner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// serviceVocabulary 规范服务名及其常见别名。不同探针对同一服务的叫法不一致，
+// 统一后服务计数、分组和过滤不会因写法不同被拆散。规范名沿用nmap-services的命名
+var serviceVocabulary = map[string][]string{
+	"http":          {"www", "www-http", "http-alt", "httpd", "http-mgmt"},
+	"https":         {"ssl/http", "ssl/https", "https-alt", "http-ssl", "ssl/http-alt"},
+	"http-proxy":    {"squid-http", "http-proxy-alt", "proxy"},
+	"ssh":           {"ssh2", "openssh"},
+	"ftp":           {"ftpd"},
+	"ftps":          {"ssl/ftp", "ftp-ssl"},
+	"smtp":          {"mail", "esmtp"},
+	"smtps":         {"ssl/smtp", "submissions"},
+	"imap":          {"imap2", "imap4"},
+	"imaps":         {"ssl/imap"},
+	"pop3":          {"pop", "pop-3"},
+	"pop3s":         {"ssl/pop3"},
+	"domain":        {"dns"},
+	"microsoft-ds":  {"smb", "cifs"},
+	"ms-wbt-server": {"rdp", "ms-term-serv", "terminal-services"},
+	"ms-sql-s":      {"mssql", "ms-sql", "sqlserver"},
+	"mysql":         {"mariadb"},
+	"postgresql":    {"postgres", "pgsql"},
+	"mongodb":       {"mongod", "mongo"},
+	"redis":         {"redis-server"},
+	"ldap":          {"ldapd"},
+	"ldaps":         {"ssl/ldap"},
+	"vnc":           {"rfb", "vnc-server"},
+	"upnp":          {"ssdp"},
+	"telnet":        {"telnetd"},
+}
+
+var (
+	serviceAliases   map[string]string // 别名(小写) -> 规范名
+	serviceAliasesMu sync.RWMutex
+)
+
+func init() {
+	serviceAliases = make(map[string]string)
+	for canonical, aliases := range serviceVocabulary {
+		serviceAliases[canonical] = canonical
+		for _, alias := range aliases {
+			serviceAliases[alias] = canonical
+		}
+	}
+}
+
+// canonicalServiceName 返回规范服务名：统一为小写，去掉nmap表示推测结果的 "?" 后缀，
+// 再按别名表映射；不在表中的名称只做大小写和空白处理
+func canonicalServiceName(service string) string {
+	name := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(service)), "?")
+	serviceAliasesMu.RLock()
+	canonical, ok := serviceAliases[name]
+	serviceAliasesMu.RUnlock()
+	if ok {
+		return canonical
+	}
+	return name
+}
+
+// canonicalizeService 规范化结果的服务名，原始识别结果保留在RawService中
+func canonicalizeService(portInfo *PortInfo) {
+	if portInfo.Service == "" {
+		return
+	}
+	if portInfo.RawService == "" {
+		portInfo.RawService = portInfo.Service
+	}
+	portInfo.Service = canonicalServiceName(portInfo.Service)
+}
+
+// RegisterServiceAlias 添加或覆盖服务名别名，之后的扫描结果中alias统一显示为canonical
+func (a *App) RegisterServiceAlias(alias, canonical string) error {
+	alias = strings.ToLower(strings.TrimSpace(alias))
+	canonical = canonicalServiceName(canonical)
+	if alias == "" || canonical == "" {
+		return fmt.Errorf("service alias and canonical name must not be empty")
+	}
+	serviceAliasesMu.Lock()
+	defer serviceAliasesMu.Unlock()
+	if existing, ok := serviceAliases[alias]; ok && existing == alias && alias != canonical {
+		return fmt.Errorf("%q is a canonical service name and cannot be an alias", alias)
+	}
+	serviceAliases[alias] = canonical
+	return nil
+}
@@ -0,0 +1,245 @@
+package portsscanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SimulatedPort 模拟网络中一个开放端口的服务
+type SimulatedPort struct {
+	Port    int           `json:"port"`
+	Service string        `json:"service"`
+	Product string        `json:"product"`
+	Version string        `json:"version"`
+	Banner  string        `json:"banner"`  // 连接建立后服务端主动发送的数据
+	Latency time.Duration `json:"latency"` // 建立连接的耗时，为0时使用主机的Latency
+
+	// IdentifyLatency 指纹识别的耗时，模拟响应缓慢的服务。识别期间扫描被取消时不填充任何指纹
+	IdentifyLatency time.Duration `json:"identify_latency,omitempty"`
+
+	// Flap 模拟状态反复的端口：每次连接依次取一个字符决定结果，o为开放、c为拒绝、f为超时，用完后从头循环。为空时总是开放
+	Flap string `json:"flap,omitempty"`
+}
+
+// SimulatedHost 模拟网络中的一台主机
+type SimulatedHost struct {
+	IP       string          `json:"ip"`
+	OS       string          `json:"os"`
+	Latency  time.Duration   `json:"latency"`
+	Filtered bool            `json:"filtered"` // 未开放的端口超时而不是拒绝连接，模拟丢弃数据包的防火墙
+	Ports    []SimulatedPort `json:"ports"`
+}
+
+// SimulatedNetwork 按声明的主机和端口确定性地响应连接，不产生任何真实网络流量。
+// 用于离线测试和演示：开放端口返回内存管道连接并发送横幅，关闭端口立即拒绝，
+// 被过滤的端口和不存在的主机立即返回超时错误
+type SimulatedNetwork struct {
+	hosts map[string]*SimulatedHost
+
+	mu    sync.Mutex
+	dials map[string]int // 设置了Flap的端口已建立的连接次数
+}
+
+// NewSimulatedNetwork 根据主机声明创建模拟网络
+func NewSimulatedNetwork(hosts []SimulatedHost) (*SimulatedNetwork, error) {
+	n := &SimulatedNetwork{hosts: make(map[string]*SimulatedHost, len(hosts)), dials: make(map[string]int)}
+	for i := range hosts {
+		h := hosts[i]
+		ip, ok := canonicalIP(h.IP)
+		if !ok {
+			return nil, fmt.Errorf("invalid simulated host address %q", h.IP)
+		}
+		for _, p := range h.Ports {
+			if strings.Trim(p.Flap, "ocf") != "" {
+				return nil, fmt.Errorf("invalid flap pattern %q for simulated port %s:%d (use o, c and f)", p.Flap, ip, p.Port)
+			}
+		}
+		h.IP = ip
+		n.hosts[ip] = &h
+	}
+	return n, nil
+}
+
+// WithSimulatedNetwork 让扫描的所有TCP连接经过模拟网络，只支持connect扫描
+func WithSimulatedNetwork(config ScanConfig, n *SimulatedNetwork) ScanConfig {
+	config.dialer = n
+	return config
+}
+
+// simTimeoutError 模拟的连接超时
+type simTimeoutError struct{}
+
+func (simTimeoutError) Error() string   { return "simulated connection timed out" }
+func (simTimeoutError) Timeout() bool   { return true }
+func (simTimeoutError) Temporary() bool { return true }
+
+func (n *SimulatedNetwork) lookup(addr string) (*SimulatedHost, *SimulatedPort, int, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if ip, ok := canonicalIP(host); ok {
+		host = ip
+	}
+	h, ok := n.hosts[host]
+	if !ok {
+		return nil, nil, port, nil
+	}
+	for i := range h.Ports {
+		if h.Ports[i].Port == port {
+			return h, &h.Ports[i], port, nil
+		}
+	}
+	return h, nil, port, nil
+}
+
+// DialContext 实现拨号器接口
+func (n *SimulatedNetwork) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	h, p, _, err := n.lookup(addr)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	if h == nil || (p == nil && h.Filtered) {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: simTimeoutError{}}
+	}
+
+	latency := h.Latency
+	if p != nil && p.Latency > 0 {
+		latency = p.Latency
+	}
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &net.OpError{Op: "dial", Net: network, Err: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+	if p != nil && p.Flap != "" {
+		switch n.flapState(h.IP, p) {
+		case 'c':
+			p = nil
+		case 'f':
+			return nil, &net.OpError{Op: "dial", Net: network, Err: simTimeoutError{}}
+		}
+	}
+	if p == nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: os.NewSyscallError("connect", connRefusedErrno)}
+	}
+
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		if p.Banner != "" {
+			if _, err := io.WriteString(server, p.Banner); err != nil {
+				return
+			}
+		}
+		io.Copy(io.Discard, server)
+	}()
+	return client, nil
+}
+
+// flapState 返回设置了Flap的端口本次连接的结果字符
+func (n *SimulatedNetwork) flapState(ip string, p *SimulatedPort) byte {
+	key := net.JoinHostPort(ip, strconv.Itoa(p.Port))
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	i := n.dials[key]
+	n.dials[key] = i + 1
+	return p.Flap[i%len(p.Flap)]
+}
+
+// identify 按声明填充指纹。gonmap自行建立连接，无法经过拨号器，模拟网络直接给出识别结果
+func (n *SimulatedNetwork) identify(ctx context.Context, ip string, port int, portInfo *PortInfo) {
+	h, p, _, err := n.lookup(net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil || p == nil {
+		return
+	}
+	if p.IdentifyLatency > 0 {
+		timer := time.NewTimer(p.IdentifyLatency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+	portInfo.OperatingSystem = h.OS
+	if p.Service == "" {
+		portInfo.Unidentified = true
+		banner := []byte(p.Banner)
+		if len(banner) > unidentifiedBannerSize {
+			banner = banner[:unidentifiedBannerSize]
+		}
+		portInfo.Info = escapeBanner(banner)
+		return
+	}
+	portInfo.Service = p.Service
+	portInfo.ProductName = p.Product
+	portInfo.Version = p.Version
+	portInfo.ProbeName = "simulated"
+	portInfo.TLS = strings.EqualFold(p.Service, "https")
+}
+
+// demoHosts 演示模式扫描的虚构网络，地址位于RFC 2544基准测试保留段，不会与真实主机冲突
+var demoHosts = []SimulatedHost{
+	{IP: "198.18.0.10", OS: "Linux", Latency: 20 * time.Millisecond, Ports: []SimulatedPort{
+		{Port: 22, Service: "ssh", Product: "OpenSSH", Version: "8.9p1", Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6\r\n"},
+		{Port: 80, Service: "http", Product: "nginx", Version: "1.24.0"},
+		{Port: 443, Service: "https", Product: "nginx", Version: "1.24.0"},
+	}},
+	{IP: "198.18.0.11", OS: "Windows", Latency: 35 * time.Millisecond, Ports: []SimulatedPort{
+		{Port: 135, Service: "msrpc", Product: "Microsoft Windows RPC"},
+		{Port: 445, Service: "microsoft-ds", Product: "Microsoft Windows Server 2019 microsoft-ds"},
+		{Port: 3389, Service: "ms-wbt-server", Product: "Microsoft Terminal Services"},
+	}},
+	{IP: "198.18.0.12", OS: "Linux", Latency: 15 * time.Millisecond, Ports: []SimulatedPort{
+		{Port: 22, Service: "ssh", Product: "OpenSSH", Version: "9.2p1", Banner: "SSH-2.0-OpenSSH_9.2p1 Debian-2\r\n"},
+		{Port: 3306, Service: "mysql", Product: "MySQL", Version: "8.0.36"},
+		{Port: 5432, Service: "postgresql", Product: "PostgreSQL", Version: "15.6"},
+	}},
+	{IP: "198.18.0.13", Latency: 60 * time.Millisecond, Filtered: true, Ports: []SimulatedPort{
+		{Port: 443, Service: "https", Product: "Caddy"},
+	}},
+	{IP: "198.18.0.14", OS: "embedded", Latency: 40 * time.Millisecond, Ports: []SimulatedPort{
+		{Port: 80, Service: "http", Product: "HP LaserJet http config"},
+		{Port: 9100, Banner: "@PJL INFO STATUS\r\n"},
+	}},
+}
+
+// StartDemoScan 扫描内置的虚构网络，不发送任何真实数据包，用于在没有目标时演示界面
+func (a *App) StartDemoScan() error {
+	n, err := NewSimulatedNetwork(demoHosts)
+	if err != nil {
+		return err
+	}
+	targets := make([]string, 0, len(demoHosts))
+	for _, h := range demoHosts {
+		targets = append(targets, h.IP)
+	}
+	return a.ScanWithConfig(WithSimulatedNetwork(ScanConfig{
+		Target:                strings.Join(targets, ","),
+		Project:               "demo",
+		TopPorts:              100,
+		MaxThreads:            50,
+		Timeout:               time.Second,
+		SkipInterceptionCheck: true,
+	}, n))
+}
+
+// errSimulatedScanType 模拟网络只实现了TCP连接
+var errSimulatedScanType = errors.New("simulated networks only support connect scans")
@@ -0,0 +1,49 @@
+package portsscanner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// simScan 一次模拟扫描收集到的回调
+type simScan struct {
+	mu       sync.Mutex
+	results  []PortInfo
+	progress []PortInfo
+}
+
+func (s *simScan) callback(info PortInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info.Protocol == "progress" {
+		s.progress = append(s.progress, info)
+		return
+	}
+	s.results = append(s.results, info)
+}
+
+// simConfig 扫描模拟网络的基本配置，超时很短，跳过与模拟网络无关的检查
+func simConfig(t *testing.T, hosts []SimulatedHost, target string, ports ...int) ScanConfig {
+	t.Helper()
+	n, err := NewSimulatedNetwork(hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return WithSimulatedNetwork(ScanConfig{
+		Target:                target,
+		Ports:                 ports,
+		MaxThreads:            8,
+		Timeout:               200 * time.Millisecond,
+		SkipInterceptionCheck: true,
+	}, n)
+}
+
+// runSimScan 用ScanPortsCombined扫描模拟网络并收集全部回调
+func runSimScan(t *testing.T, ctx context.Context, config ScanConfig) (*simScan, error) {
+	t.Helper()
+	scan := &simScan{}
+	err := ScanPortsCombined(ctx, config, scan.callback)
+	return scan, err
+}
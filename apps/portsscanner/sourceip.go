@@ -0,0 +1,241 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// 源端口被占用(如仍处于TIME_WAIT)时最多换用的端口数
+const maxSourcePortAttempts = 16
+
+// sourcePool SourceIPs 配置的本机源地址，每次建立连接时按地址族轮流选择一个作为LocalAddr，
+// 并统计每个地址发起的连接数。设置SourcePortRange时同时在范围内轮流选择源端口，设置DSCP时标记每个连接
+type sourcePool struct {
+	v4, v6 []*sourceAddr
+	next4  uint64
+	next6  uint64
+
+	portLo, portHi int // 源端口范围，portLo为0时由系统分配
+	nextPort       uint64
+
+	tos int // 连接的IP ToS/流量类别字节(DSCP<<2)，0为不设置
+}
+
+// sourceAddr 单个源地址及其发起的连接数
+type sourceAddr struct {
+	ip    net.IP
+	conns int64
+}
+
+// parseSourcePortRange 解析 "40000-40999" 或单个端口，空字符串返回0, 0。
+// 低于1024的特权端口需要allowPrivileged
+func parseSourcePortRange(s string, allowPrivileged bool) (int, int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, 0, nil
+	}
+	lo, hi, isRange := strings.Cut(s, "-")
+	start, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid source port range %q", s)
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil {
+			return 0, 0, fmt.Errorf("invalid source port range %q", s)
+		}
+	}
+	if start < 1 || end > 65535 || start > end {
+		return 0, 0, fmt.Errorf("invalid source port range %q: out of range", s)
+	}
+	if start < 1024 && !allowPrivileged {
+		return 0, 0, fmt.Errorf("source port range %q includes privileged ports; set AllowPrivilegedSourcePorts to use them", s)
+	}
+	return start, end, nil
+}
+
+// newSourcePool 校验SourceIPs中的每个地址都属于本机网络接口，并解析SourcePortRange和DSCP，
+// 都未设置时返回nil(使用系统默认源地址和端口，不标记连接)
+func newSourcePool(config ScanConfig) (*sourcePool, error) {
+	portLo, portHi, err := parseSourcePortRange(config.SourcePortRange, config.AllowPrivilegedSourcePorts)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDSCP(config.DSCP); err != nil {
+		return nil, err
+	}
+	ips := config.SourceIPs
+	if len(ips) == 0 {
+		if portLo == 0 && config.DSCP == 0 {
+			return nil, nil
+		}
+		return &sourcePool{portLo: portLo, portHi: portHi, tos: dscpToS(config.DSCP)}, nil
+	}
+	ifaceAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local addresses: %w", err)
+	}
+	local := make(map[netip.Addr]bool, len(ifaceAddrs))
+	for _, a := range ifaceAddrs {
+		if ipNet, ok := a.(*net.IPNet); ok {
+			if addr, ok := netip.AddrFromSlice(ipNet.IP); ok {
+				local[addr.Unmap()] = true
+			}
+		}
+	}
+
+	pool := &sourcePool{portLo: portLo, portHi: portHi, tos: dscpToS(config.DSCP)}
+	seen := make(map[netip.Addr]bool)
+	for _, s := range ips {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source IP %q", s)
+		}
+		addr = addr.Unmap()
+		if !local[addr] {
+			return nil, fmt.Errorf("source IP %s is not assigned to a local interface", addr)
+		}
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		source := &sourceAddr{ip: net.IP(addr.AsSlice())}
+		if addr.Is4() {
+			pool.v4 = append(pool.v4, source)
+		} else {
+			pool.v6 = append(pool.v6, source)
+		}
+	}
+	return pool, nil
+}
+
+// pick 按目标地址族轮流选择源地址，没有同族的源地址时返回nil
+func (p *sourcePool) pick(target netip.Addr) *sourceAddr {
+	list, next := p.v4, &p.next4
+	if !target.Unmap().Is4() {
+		list, next = p.v6, &p.next6
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	source := list[(atomic.AddUint64(next, 1)-1)%uint64(len(list))]
+	atomic.AddInt64(&source.conns, 1)
+	return source
+}
+
+// port 在源端口范围内轮流选择下一个端口，未设置范围时返回0
+func (p *sourcePool) port() int {
+	if p.portLo == 0 {
+		return 0
+	}
+	n := uint64(p.portHi - p.portLo + 1)
+	return p.portLo + int((atomic.AddUint64(&p.nextPort, 1)-1)%n)
+}
+
+// portAttempts 返回一次拨号最多尝试的源端口数
+func (p *sourcePool) portAttempts() int {
+	if p == nil || p.portLo == 0 {
+		return 1
+	}
+	return min(p.portHi-p.portLo+1, maxSourcePortAttempts)
+}
+
+// dialer 返回连接address使用的拨号器，p为nil或没有与目标同族的源地址时使用系统默认源地址，
+// 未设置源端口范围时由系统分配源端口
+func (p *sourcePool) dialer(timeout time.Duration, network, address string) net.Dialer {
+	d := net.Dialer{Timeout: timeout}
+	if p == nil {
+		return d
+	}
+	var ip net.IP
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		if target, err := netip.ParseAddr(host); err == nil {
+			if source := p.pick(target); source != nil {
+				ip = source.ip
+			}
+		}
+	}
+	port := p.port()
+	if ip == nil && port == 0 {
+		return d
+	}
+	switch network {
+	case "udp", "udp4", "udp6":
+		d.LocalAddr = &net.UDPAddr{IP: ip, Port: port}
+	default:
+		d.LocalAddr = &net.TCPAddr{IP: ip, Port: port}
+	}
+	return d
+}
+
+// dial 使用dialer建立连接，control不为nil时设置为拨号器的Control，设置DSCP时连接前先标记套接字。
+// 选中的源端口暂时不可用(仍处于TIME_WAIT或正被其他连接使用)时换用范围内的下一个端口重试
+func (p *sourcePool) dial(ctx context.Context, timeout time.Duration, network, address string, control func(network, address string, c syscall.RawConn) error) (net.Conn, error) {
+	if p != nil && p.tos != 0 {
+		control = markControl(p.tos, control)
+	}
+	var conn net.Conn
+	var err error
+	for attempt := 0; attempt < p.portAttempts(); attempt++ {
+		d := p.dialer(timeout, network, address)
+		d.Control = control
+		conn, err = d.DialContext(ctx, network, address)
+		if err == nil || !isAddrInUse(err) || ctx.Err() != nil {
+			break
+		}
+	}
+	return conn, err
+}
+
+// validateDSCP 检查DSCP取值，DSCP占ToS字节的高6位
+func validateDSCP(dscp int) error {
+	if dscp < 0 || dscp > 63 {
+		return fmt.Errorf("DSCP %d must be between 0 and 63", dscp)
+	}
+	return nil
+}
+
+// dscpToS 返回DSCP对应的ToS/流量类别字节，ECN位保持为0
+func dscpToS(dscp int) int {
+	return dscp << 2
+}
+
+// markControl 返回在连接前设置IP_TOS(IPv6为IPV6_TCLASS)的Control，之后再调用next
+func markControl(tos int, next func(network, address string, c syscall.RawConn) error) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = setSocketToS(fd, strings.HasSuffix(network, "6"), tos)
+		}); err != nil {
+			return err
+		}
+		if sockErr != nil {
+			return fmt.Errorf("failed to set DSCP: %w", sockErr)
+		}
+		if next != nil {
+			return next(network, address, c)
+		}
+		return nil
+	}
+}
+
+// connections 返回每个源地址发起的连接数
+func (p *sourcePool) connections() map[string]int64 {
+	if p == nil {
+		return nil
+	}
+	counts := make(map[string]int64, len(p.v4)+len(p.v6))
+	for _, list := range [][]*sourceAddr{p.v4, p.v6} {
+		for _, source := range list {
+			counts[source.ip.String()] = atomic.LoadInt64(&source.conns)
+		}
+	}
+	return counts
+}
@@ -0,0 +1,112 @@
+package portsscanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 扫描阶段，按顺序执行，每个阶段处理上一阶段的输出：
+// discovery 筛选在线主机，connect 探测端口状态，fingerprint 识别开放端口上的服务
+const (
+	StageDiscovery   = "discovery"
+	StageConnect     = "connect"
+	StageFingerprint = "fingerprint"
+)
+
+// stageOrder 各阶段在流水线中的先后位置，自定义流水线可以省略阶段但不能改变依赖顺序
+var stageOrder = map[string]int{
+	StageDiscovery:   0,
+	StageConnect:     1,
+	StageFingerprint: 2,
+}
+
+// scanPipeline 本次扫描启用的阶段，为nil时使用默认流水线：
+// 由 ScanConfig.Discover 决定是否探测存活主机，连接后总是进行指纹识别
+type scanPipeline struct {
+	stages []string
+}
+
+// parsePipeline 校验自定义阶段列表：阶段名必须已知且不重复，必须包含connect，顺序需满足阶段间的数据依赖
+func parsePipeline(stages []string) (*scanPipeline, error) {
+	p := &scanPipeline{}
+	last := -1
+	for _, stage := range stages {
+		stage = strings.ToLower(strings.TrimSpace(stage))
+		order, ok := stageOrder[stage]
+		if !ok {
+			return nil, fmt.Errorf("unknown pipeline stage %q (available: %s, %s, %s)", stage, StageDiscovery, StageConnect, StageFingerprint)
+		}
+		if p.has(stage) {
+			return nil, fmt.Errorf("pipeline stage %q is listed more than once", stage)
+		}
+		if order < last {
+			return nil, fmt.Errorf("pipeline stage %q must come before %q", stage, p.stages[len(p.stages)-1])
+		}
+		last = order
+		p.stages = append(p.stages, stage)
+	}
+	if !p.has(StageConnect) {
+		return nil, fmt.Errorf("pipeline must include the %q stage", StageConnect)
+	}
+	return p, nil
+}
+
+func (p *scanPipeline) has(stage string) bool {
+	for _, s := range p.stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// runsDiscovery 是否在扫描前探测存活主机
+func (c ScanConfig) runsDiscovery() bool {
+	if c.pipeline == nil {
+		return c.Discover
+	}
+	return c.pipeline.has(StageDiscovery)
+}
+
+// runsFingerprint 是否对开放端口进行指纹识别
+func (c ScanConfig) runsFingerprint() bool {
+	return c.pipeline == nil || c.pipeline.has(StageFingerprint)
+}
+
+// stageNames 返回本次扫描实际执行的阶段，用于日志
+func (c ScanConfig) stageNames() string {
+	if c.pipeline != nil {
+		return strings.Join(c.pipeline.stages, " -> ")
+	}
+	stages := []string{StageConnect, StageFingerprint}
+	if c.Discover {
+		stages = append([]string{StageDiscovery}, stages...)
+	}
+	return strings.Join(stages, " -> ")
+}
+
+// SetPipeline 设置之后扫描使用的阶段列表，如 ["connect"] 只探测端口状态、跳过指纹识别；
+// 传入空列表恢复默认流水线。正在进行的扫描不受影响
+func (a *App) SetPipeline(stages []string) error {
+	var pipeline *scanPipeline
+	if len(stages) > 0 {
+		var err error
+		if pipeline, err = parsePipeline(stages); err != nil {
+			return err
+		}
+	}
+	scanMutex.Lock()
+	a.pipeline = pipeline
+	scanMutex.Unlock()
+	return nil
+}
+
+// GetPipeline 返回之后扫描使用的阶段列表，默认流水线返回空列表
+func (a *App) GetPipeline() []string {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+	if a.pipeline == nil {
+		return []string{}
+	}
+	return append([]string(nil), a.pipeline.stages...)
+}
@@ -0,0 +1,286 @@
+package portsscanner
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// STARTTLS 会话中单条响应最多读取的行数，防止服务端无限输出
+const startTLSMaxLines = 64
+
+// errNoStartTLS 服务端未声明支持STARTTLS
+var errNoStartTLS = errors.New("server does not advertise STARTTLS")
+
+// startTLSDialogue 在明文连接上完成协议相关的协商，返回后即可开始TLS握手
+type startTLSDialogue func(r *bufio.Reader, conn net.Conn) error
+
+// startTLSDialogues 按规范化服务名选择升级方式，只对识别出的服务发起对应协议的命令
+var startTLSDialogues = map[string]startTLSDialogue{
+	"smtp":       smtpStartTLS,
+	"submission": smtpStartTLS,
+	"imap":       imapStartTLS,
+	"pop3":       pop3StartTLS,
+	"ftp":        ftpStartTLS,
+	"postgresql": postgresStartTLS,
+}
+
+// probeStartTLS 对识别为明文但支持STARTTLS的服务升级连接，记录服务端证书的到期时间。
+// 已是隐式TLS、服务不在支持列表或服务端未声明STARTTLS时不做任何修改
+func (s *portScanner) probeStartTLS(ctx context.Context, h scanTarget, p int, portInfo *PortInfo) {
+	if s.config.SkipStartTLS || portInfo.TLS {
+		return
+	}
+	dialogue, ok := startTLSDialogues[canonicalServiceName(portInfo.Service)]
+	if !ok {
+		return
+	}
+
+	state, err := startTLS(ctx, s.config, h.IP, p, dialogue)
+	if err != nil {
+		s.log(logDebug, "%s:%d STARTTLS探测失败: %v", h.IP, p, err)
+		return
+	}
+	portInfo.StartTLS = true
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		portInfo.TLSExpiry = cert.NotAfter
+		portInfo.SelfSigned = isSelfSigned(cert)
+		s.captureCertificate(portInfo, cert)
+	}
+	s.log(logDebug, "%s:%d STARTTLS升级成功 (%s)", h.IP, p, tls.VersionName(state.Version))
+}
+
+// startTLS 建立明文连接，执行dialogue协商后完成TLS握手并返回握手结果
+func startTLS(ctx context.Context, config ScanConfig, host string, port int, dialogue startTLSDialogue) (tls.ConnectionState, error) {
+	conn, err := config.dialProbe(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(config.probeTimeout(port)))
+
+	if err := dialogue(bufio.NewReader(conn), conn); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	// 协商完成后服务端不会再发送明文数据，bufio中没有残留，可以直接在原连接上握手
+	client := tls.Client(conn, probeTLSConfig(nil))
+	if err := client.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, fmt.Errorf("tls handshake: %w", err)
+	}
+	return client.ConnectionState(), nil
+}
+
+// readReplyLines 读取一条响应，last判断某一行是否为响应的最后一行
+func readReplyLines(r *bufio.Reader, last func(line string) bool) ([]string, error) {
+	var lines []string
+	for len(lines) < startTLSMaxLines {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return lines, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		lines = append(lines, line)
+		if last(line) {
+			return lines, nil
+		}
+	}
+	return lines, errors.New("reply too long")
+}
+
+// readCodeReply 读取SMTP/FTP格式的响应("250-"为续行，"250 "为最后一行)，返回状态码和所有行
+func readCodeReply(r *bufio.Reader) (int, []string, error) {
+	lines, err := readReplyLines(r, func(line string) bool {
+		return len(line) < 4 || line[3] != '-'
+	})
+	if err != nil {
+		return 0, lines, err
+	}
+	last := lines[len(lines)-1]
+	if len(last) < 3 {
+		return 0, lines, fmt.Errorf("malformed reply %q", last)
+	}
+	code, err := strconv.Atoi(last[:3])
+	if err != nil {
+		return 0, lines, fmt.Errorf("malformed reply %q", last)
+	}
+	return code, lines, nil
+}
+
+// sendCommand 发送一行命令
+func sendCommand(conn net.Conn, command string) error {
+	_, err := conn.Write([]byte(command + "\r\n"))
+	return err
+}
+
+// containsCapability 判断响应中是否有一行(忽略大小写)包含capability
+func containsCapability(lines []string, capability string) bool {
+	for _, line := range lines {
+		if strings.Contains(strings.ToUpper(line), capability) {
+			return true
+		}
+	}
+	return false
+}
+
+// smtpStartTLS 读取220问候后发送EHLO，能力列表包含STARTTLS时发起升级
+func smtpStartTLS(r *bufio.Reader, conn net.Conn) error {
+	if code, _, err := readCodeReply(r); err != nil || code != 220 {
+		return unexpectedReply("greeting", code, err)
+	}
+	if err := sendCommand(conn, "EHLO glideway"); err != nil {
+		return err
+	}
+	code, lines, err := readCodeReply(r)
+	if err != nil || code != 250 {
+		return unexpectedReply("EHLO", code, err)
+	}
+	if !containsCapability(lines, "STARTTLS") {
+		return errNoStartTLS
+	}
+	if err := sendCommand(conn, "STARTTLS"); err != nil {
+		return err
+	}
+	if code, _, err := readCodeReply(r); err != nil || code != 220 {
+		return unexpectedReply("STARTTLS", code, err)
+	}
+	return nil
+}
+
+// ftpStartTLS 读取220问候后发送AUTH TLS(RFC 4217)，234表示可以开始握手
+func ftpStartTLS(r *bufio.Reader, conn net.Conn) error {
+	if code, _, err := readCodeReply(r); err != nil || code != 220 {
+		return unexpectedReply("greeting", code, err)
+	}
+	if err := sendCommand(conn, "AUTH TLS"); err != nil {
+		return err
+	}
+	code, _, err := readCodeReply(r)
+	if err != nil {
+		return err
+	}
+	if code != 234 {
+		// 500系列表示不支持该命令或未启用TLS
+		return errNoStartTLS
+	}
+	return nil
+}
+
+// imapStartTLS 问候中已带有能力列表时直接判断，否则先发送CAPABILITY
+func imapStartTLS(r *bufio.Reader, conn net.Conn) error {
+	greeting, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("unexpected greeting %q", strings.TrimSpace(greeting))
+	}
+	supported := strings.Contains(strings.ToUpper(greeting), "STARTTLS")
+	if !supported {
+		if err := sendCommand(conn, "a1 CAPABILITY"); err != nil {
+			return err
+		}
+		lines, err := readTaggedReply(r, "a1")
+		if err != nil {
+			return err
+		}
+		supported = containsCapability(lines, "STARTTLS")
+	}
+	if !supported {
+		return errNoStartTLS
+	}
+	if err := sendCommand(conn, "a2 STARTTLS"); err != nil {
+		return err
+	}
+	lines, err := readTaggedReply(r, "a2")
+	if err != nil {
+		return err
+	}
+	if status := lines[len(lines)-1]; !strings.HasPrefix(strings.ToUpper(status), "A2 OK") {
+		return fmt.Errorf("STARTTLS rejected: %q", status)
+	}
+	return nil
+}
+
+// readTaggedReply 读取IMAP响应直到带有tag的状态行
+func readTaggedReply(r *bufio.Reader, tag string) ([]string, error) {
+	prefix := strings.ToUpper(tag) + " "
+	return readReplyLines(r, func(line string) bool {
+		return strings.HasPrefix(strings.ToUpper(line), prefix)
+	})
+}
+
+// pop3StartTLS 通过CAPA确认支持STLS(RFC 2595)后发起升级
+func pop3StartTLS(r *bufio.Reader, conn net.Conn) error {
+	greeting, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return fmt.Errorf("unexpected greeting %q", strings.TrimSpace(greeting))
+	}
+	if err := sendCommand(conn, "CAPA"); err != nil {
+		return err
+	}
+	status, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(status, "+OK") {
+		// 不支持CAPA的旧服务器，无法确认是否支持STLS
+		return errNoStartTLS
+	}
+	lines, err := readReplyLines(r, func(line string) bool { return line == "." })
+	if err != nil {
+		return err
+	}
+	if !containsCapability(lines, "STLS") {
+		return errNoStartTLS
+	}
+	if err := sendCommand(conn, "STLS"); err != nil {
+		return err
+	}
+	reply, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(reply, "+OK") {
+		return fmt.Errorf("STLS rejected: %q", strings.TrimSpace(reply))
+	}
+	return nil
+}
+
+// PostgreSQL SSLRequest 消息：长度8，请求码80877103
+var postgresSSLRequest = []byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}
+
+// postgresStartTLS 发送SSLRequest，服务端回复'S'表示接受TLS，'N'表示未启用
+func postgresStartTLS(r *bufio.Reader, conn net.Conn) error {
+	if _, err := conn.Write(postgresSSLRequest); err != nil {
+		return err
+	}
+	reply, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch reply {
+	case 'S':
+		return nil
+	case 'N':
+		return errNoStartTLS
+	}
+	return fmt.Errorf("unexpected SSLRequest reply %q", reply)
+}
+
+// unexpectedReply 生成协商过程中状态码不符合预期的错误
+func unexpectedReply(step string, code int, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", step, err)
+	}
+	return fmt.Errorf("%s: unexpected reply code %d", step, code)
+}
@@ -0,0 +1,115 @@
+package portsscanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// versionConstraint 版本条件中的一项，op为空时按前缀匹配
+type versionConstraint struct {
+	op      string
+	version []int
+}
+
+// satisfied 判断版本号是否满足条件
+func (c versionConstraint) satisfied(v []int) bool {
+	switch c.op {
+	case "<":
+		return versionLess(v, c.version)
+	case "<=":
+		return !versionLess(c.version, v)
+	case ">":
+		return versionLess(c.version, v)
+	case ">=":
+		return !versionLess(v, c.version)
+	case "=":
+		return !versionLess(v, c.version) && !versionLess(c.version, v)
+	}
+	if len(v) < len(c.version) {
+		return false
+	}
+	for i, n := range c.version {
+		if v[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// resultMatcher StopOnMatch 解析后的匹配条件
+type resultMatcher struct {
+	pattern     string
+	name        string // 小写的服务名或产品名
+	constraints []versionConstraint
+}
+
+// isVersionToken 以比较运算符或数字开头的部分为版本条件，之前的部分为名称
+func isVersionToken(s string) bool {
+	return s != "" && (strings.ContainsAny(s[:1], "<>=") || (s[0] >= '0' && s[0] <= '9'))
+}
+
+// parseResultMatcher 解析 "名称 [版本条件]" 形式的匹配条件，名称可以包含空格(如 "Apache httpd 2.4.x")
+func parseResultMatcher(pattern string) (*resultMatcher, error) {
+	fields := strings.Fields(pattern)
+	split := len(fields)
+	for i, f := range fields {
+		if isVersionToken(f) {
+			split = i
+			break
+		}
+	}
+	if split == 0 {
+		return nil, fmt.Errorf("match pattern %q has no service or product name", pattern)
+	}
+	m := &resultMatcher{
+		pattern: strings.TrimSpace(pattern),
+		name:    strings.ToLower(strings.Join(fields[:split], " ")),
+	}
+
+	expr := strings.Join(fields[split:], "")
+	if expr == "" {
+		return m, nil
+	}
+	for _, part := range strings.Split(expr, ",") {
+		var c versionConstraint
+		for _, op := range []string{"<=", ">=", "==", "<", ">", "="} {
+			if strings.HasPrefix(part, op) {
+				c.op, part = op, part[len(op):]
+				break
+			}
+		}
+		if c.op == "==" {
+			c.op = "="
+		}
+		if c.version = versionNumbers(part); len(c.version) == 0 {
+			return nil, fmt.Errorf("match pattern %q: invalid version %q", pattern, part)
+		}
+		if c.op != "" && strings.ContainsAny(part, "xX*") {
+			return nil, fmt.Errorf("match pattern %q: wildcard version %q cannot be compared", pattern, part)
+		}
+		m.constraints = append(m.constraints, c)
+	}
+	return m, nil
+}
+
+// matches 名称与服务名相同或包含在产品名中(不区分大小写)，且版本满足全部条件。
+// 设置了版本条件时，没有识别出版本号的结果不匹配
+func (m *resultMatcher) matches(info PortInfo) bool {
+	if canonicalServiceName(info.Service) != canonicalServiceName(m.name) &&
+		!strings.Contains(strings.ToLower(info.ProductName), m.name) {
+		return false
+	}
+	if len(m.constraints) == 0 {
+		return true
+	}
+	version := versionNumbers(info.Version)
+	if len(version) == 0 {
+		return false
+	}
+	for _, c := range m.constraints {
+		if !c.satisfied(version) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,332 @@
+package portsscanner
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScanSummary 扫描任务概要信息
+type ScanSummary struct {
+	ID         string    `json:"id"`
+	Project    string    `json:"project"`
+	Target     string    `json:"target"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	OpenPorts  int       `json:"open_ports"`
+
+	ScannerHost string   `json:"scanner_host"` // 执行扫描的机器主机名
+	EgressIPs   []string `json:"egress_ips"`   // 按路由确定的本机出口地址
+
+	ResumedFrom   string `json:"resumed_from,omitempty"`   // 续扫时对应的原扫描ID
+	CorrelationID string `json:"correlation_id,omitempty"` // 调用方提供的外部关联ID
+
+	// ResultsTimestamp 结果对应的扫描时间：实时扫描为结束时间，导入或从文件加载的扫描保留原始扫描时间
+	ResultsTimestamp time.Time `json:"results_timestamp"`
+
+	// 探测连接实际收发的应用层字节数，用于核对MaxBandwidth是否生效
+	BytesSent     int64 `json:"bytes_sent"`
+	BytesReceived int64 `json:"bytes_received"`
+
+	SourceConnections map[string]int64 `json:"source_connections,omitempty"` // 配置SourceIPs时每个源地址发起的连接数
+	ProxyConnections  map[string]int64 `json:"proxy_connections,omitempty"`  // 配置Proxies时经每个代理建立的连接数
+
+	// Archived 已通过ArchiveScan归档：不会被保留策略清理，也不能被覆盖或修改，仍可查看和导出
+	Archived   bool       `json:"archived,omitempty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	ConfigHash string `json:"config_hash,omitempty"` // 实际配置的哈希(见configHash)，用于证明两次扫描参数相同，导入的扫描为空
+
+	Unresolved []UnresolvedTarget `json:"unresolved,omitempty"` // DNS解析失败、未扫描的目标主机名
+}
+
+// 结果超过该时长视为过期，加载时发送 results-stale 警告
+const staleResultsAge = 24 * time.Hour
+
+// resultsTime 返回结果对应的扫描时间，兼容没有ResultsTimestamp的旧记录
+func (s ScanSummary) resultsTime() time.Time {
+	switch {
+	case !s.ResultsTimestamp.IsZero():
+		return s.ResultsTimestamp
+	case !s.FinishedAt.IsZero():
+		return s.FinishedAt
+	}
+	return s.StartedAt
+}
+
+// scanRecord 单次扫描的配置和结果
+type scanRecord struct {
+	mu      sync.Mutex
+	summary ScanSummary
+	config  ScanConfig
+	results []PortInfo
+	log     scanLog
+
+	checkpoint *scanCheckpoint   // 每台主机的完成进度，为nil表示没有可续扫的进度
+	metadata   ScanMetadata      // 扫描开始时记录的运行环境
+	compliance *ComplianceReport // 最近一次合规检查的结果，不持久化
+
+	evidence      map[resultKey]*Evidence // CaptureEvidence 收集的原始证据
+	evidenceBytes int
+	evidenceFull  bool // 已达到证据总量上限
+
+	timeline scanTimeline // 扫描过程中的事件时间线
+
+	alerted bool // 已发送 scan-complete-alert
+}
+
+var (
+	scanStore  = make(map[string]*scanRecord)
+	storeMutex sync.RWMutex
+
+	// latestResults 界面当前展示结果的扫描ID：最近开始、加载或查看的扫描
+	latestResults string
+)
+
+// newScanID 生成形如 20060102-150405-a1b2c3 的扫描ID
+func newScanID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// createScanRecord 为新扫描创建记录并加入历史
+func createScanRecord(config ScanConfig) *scanRecord {
+	scannerHost, egressIPs := collectEgress(config.hosts)
+	id := config.ScanID
+	if id == "" {
+		id = newScanID()
+	}
+	record := &scanRecord{
+		summary: ScanSummary{
+			ID:          id,
+			Project:     strings.TrimSpace(config.Project),
+			Target:      config.Target,
+			Status:      "running",
+			StartedAt:   time.Now(),
+			ScannerHost: scannerHost,
+			EgressIPs:   egressIPs,
+		},
+		config:   config,
+		metadata: captureMetadata(config),
+	}
+
+	record.summary.ResultsTimestamp = record.summary.StartedAt
+	record.summary.CorrelationID = config.CorrelationID
+	record.timeline.start = record.summary.StartedAt
+
+	storeMutex.Lock()
+	scanStore[record.summary.ID] = record
+	latestResults = record.summary.ID
+	storeMutex.Unlock()
+	return record
+}
+
+// getScanRecord 返回扫描记录，不在内存中时从存储后端加载(共享后端中其他成员保存的扫描)
+func getScanRecord(scanID string) (*scanRecord, error) {
+	storeMutex.RLock()
+	record, ok := scanStore[scanID]
+	storeMutex.RUnlock()
+	if ok {
+		return record, nil
+	}
+
+	store, err := activeStore()
+	if err == nil {
+		record, err = loadStoredRecord(store, scanID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan %q not found", scanID)
+	}
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	if existing, ok := scanStore[scanID]; ok {
+		return existing, nil
+	}
+	scanStore[scanID] = record
+	return record, nil
+}
+
+func (r *scanRecord) addResult(info PortInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, info)
+	if info.State == "" {
+		r.summary.OpenPorts++
+	}
+	r.addEvidence(info)
+}
+
+// countOpen 统计确认开放的结果数，open|filtered 等状态不计入
+func countOpen(results []PortInfo) int {
+	n := 0
+	for _, r := range results {
+		if r.State == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// finish 记录扫描结束状态并持久化，重启后仍可查看，随后按保留策略清理历史
+func (r *scanRecord) finish(status string) {
+	r.mu.Lock()
+	r.summary.Status = status
+	r.summary.FinishedAt = time.Now()
+	if status != "imported" {
+		r.summary.ResultsTimestamp = r.summary.FinishedAt
+	}
+	if m := r.config.bandwidth; m != nil {
+		r.summary.BytesSent, r.summary.BytesReceived = m.totals()
+	}
+	r.summary.SourceConnections = r.config.sources.connections()
+	r.summary.ProxyConnections = r.config.proxies.connections()
+	r.mu.Unlock()
+	r.timeline.close(status)
+
+	if err := saveScanRecord(r); err != nil {
+		fmt.Printf("保存扫描记录失败: %v\n", err)
+	}
+	if _, err := pruneStore(); err != nil {
+		fmt.Printf("清理扫描记录失败: %v\n", err)
+	}
+}
+
+// setResultsTime 导入的结果使用源文件中的原始扫描时间
+func (r *scanRecord) setResultsTime(t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summary.ResultsTimestamp = t
+}
+
+// checkpointSnapshot 导出扫描进度，没有检查点时返回nil
+func (r *scanRecord) checkpointSnapshot() []HostCheckpoint {
+	r.mu.Lock()
+	checkpoint := r.checkpoint
+	r.mu.Unlock()
+	if checkpoint == nil {
+		return nil
+	}
+	return checkpoint.snapshot()
+}
+
+func (r *scanRecord) snapshot() (ScanSummary, ScanConfig, []PortInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	results := make([]PortInfo, len(r.results))
+	copy(results, r.results)
+	return r.summary, r.config, results
+}
+
+// ListScans 按开始时间倒序列出历史扫描
+func (a *App) ListScans() []ScanSummary {
+	storeMutex.RLock()
+	summaries := make([]ScanSummary, 0, len(scanStore))
+	for _, record := range scanStore {
+		summary, _, _ := record.snapshot()
+		summaries = append(summaries, summary)
+	}
+	storeMutex.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt.After(summaries[j].StartedAt)
+	})
+	return summaries
+}
+
+// ListProjects 列出所有出现过的项目名称
+func (a *App) ListProjects() []string {
+	seen := make(map[string]bool)
+	projects := []string{}
+	for _, summary := range a.ListScans() {
+		if summary.Project != "" && !seen[summary.Project] {
+			seen[summary.Project] = true
+			projects = append(projects, summary.Project)
+		}
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+// GetProjectScans 返回属于指定项目的扫描，按开始时间倒序
+func (a *App) GetProjectScans(project string) []ScanSummary {
+	scans := []ScanSummary{}
+	for _, summary := range a.ListScans() {
+		if summary.Project == project {
+			scans = append(scans, summary)
+		}
+	}
+	return scans
+}
+
+// GetScanResults 返回指定扫描发现的开放端口，该扫描成为 GetResultsAge 计算的对象
+func (a *App) GetScanResults(scanID string) ([]PortInfo, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return nil, err
+	}
+	storeMutex.Lock()
+	latestResults = scanID
+	storeMutex.Unlock()
+	_, _, results := record.snapshot()
+	return results, nil
+}
+
+// GetResultsAge 返回界面当前展示的结果距今多久，用于显示"3小时前扫描"并提示数据过期，
+// 没有任何结果时返回0
+func (a *App) GetResultsAge() time.Duration {
+	storeMutex.RLock()
+	record, ok := scanStore[latestResults]
+	storeMutex.RUnlock()
+	if !ok {
+		return 0
+	}
+	summary, _, _ := record.snapshot()
+	return time.Since(summary.resultsTime())
+}
+
+// warnIfStale 加载的结果已过期时发送 results-stale 事件
+func (a *App) warnIfStale(summary ScanSummary) {
+	age := time.Since(summary.resultsTime())
+	if age < staleResultsAge || a.ctx == nil {
+		return
+	}
+	fmt.Printf("扫描 %s 的结果来自 %s，已过期\n", summary.ID, summary.resultsTime().Format(time.RFC3339))
+	a.emitEvent("results-stale", map[string]interface{}{
+		"scan_id":           summary.ID,
+		"results_timestamp": summary.resultsTime(),
+		"age_seconds":       int64(age.Seconds()),
+	})
+}
+
+// ProfileFromScan 以历史扫描确认开放的端口生成新的扫描配置，用于快速复测，open|filtered 等结果不计入
+func (a *App) ProfileFromScan(scanID string) (ScanConfig, error) {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return ScanConfig{}, err
+	}
+	_, config, results := record.snapshot()
+	seen := make(map[int]bool)
+	ports := make([]int, 0, len(results))
+	for _, r := range results {
+		if r.State == "" && !seen[r.Port] {
+			seen[r.Port] = true
+			ports = append(ports, r.Port)
+		}
+	}
+	if len(ports) == 0 {
+		return ScanConfig{}, fmt.Errorf("scan %q has no open ports to profile", scanID)
+	}
+	sort.Ints(ports)
+
+	config.Ports = ports
+	config.StartPort = ports[0]
+	config.EndPort = ports[len(ports)-1]
+	config.hosts = nil
+	return config, nil
+}
@@ -0,0 +1,149 @@
+package portsscanner
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// storedRecordFor 按persist.go的格式生成记录，file后端从Data中读取摘要
+func storedRecordFor(t *testing.T, summary ScanSummary) StoredRecord {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"scan": summary, "results": []PortInfo{{Host: "10.0.0.1", Port: 22}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return StoredRecord{Summary: summary, Data: data}
+}
+
+func TestResultStoreRoundTrip(t *testing.T) {
+	for _, backend := range []string{"file", "memory", "sqlite"} {
+		t.Run(backend, func(t *testing.T) {
+			open, err := storeOpener(backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dsn := t.TempDir()
+			if backend == "sqlite" {
+				dsn = filepath.Join(dsn, "scans.db")
+			}
+			store, err := open(dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c, ok := store.(interface{ Close() error }); ok {
+				defer c.Close()
+			}
+
+			base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			older := ScanSummary{ID: "scan-1", Project: "alpha", Target: "10.0.0.0/24", Status: "completed", StartedAt: base}
+			newer := ScanSummary{ID: "scan-2", Project: "beta", Target: "192.168.1.1", Status: "stopped", StartedAt: base.Add(time.Hour)}
+			for _, s := range []ScanSummary{older, newer} {
+				if err := store.Save(storedRecordFor(t, s)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// 同一ID再次保存覆盖原记录
+			older.OpenPorts = 3
+			first := storedRecordFor(t, older)
+			if err := store.Save(first); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := store.Get("scan-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Summary.OpenPorts != 3 || !got.Summary.StartedAt.Equal(base) || string(got.Data) != string(first.Data) {
+				t.Errorf("Get returned %+v / %s, want the overwritten record", got.Summary, got.Data)
+			}
+
+			list, err := store.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 2 || list[0].ID != "scan-2" || list[1].ID != "scan-1" {
+				t.Errorf("List = %+v, want scan-2 then scan-1", list)
+			}
+			matched, err := store.Query(ScanQuery{Target: "10.0.0", Since: base.Add(-time.Minute)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(matched) != 1 || matched[0].ID != "scan-1" {
+				t.Errorf("Query = %+v, want scan-1", matched)
+			}
+
+			if err := store.Delete("scan-1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get("scan-1"); !errors.Is(err, ErrScanNotFound) {
+				t.Errorf("Get after Delete returned %v, want ErrScanNotFound", err)
+			}
+			if err := store.Delete("scan-1"); !errors.Is(err, ErrScanNotFound) {
+				t.Errorf("second Delete returned %v, want ErrScanNotFound", err)
+			}
+		})
+	}
+}
+
+func TestPostScanResultFileForMemoryStore(t *testing.T) {
+	store, _ := openMemoryStore("")
+	resultStoreMu.RLock()
+	savedStore, savedConfig := resultStore, storageConfig
+	resultStoreMu.RUnlock()
+	setResultStore(store, StorageConfig{Backend: "memory"})
+	defer setResultStore(savedStore, savedConfig)
+
+	if _, _, err := postScanResultFile("missing"); !errors.Is(err, ErrScanNotFound) {
+		t.Fatalf("missing scan returned %v, want ErrScanNotFound", err)
+	}
+	record := storedRecordFor(t, ScanSummary{ID: "scan-1", Target: "10.0.0.1", Status: "completed"})
+	if err := store.Save(record); err != nil {
+		t.Fatal(err)
+	}
+	path, cleanup, err := postScanResultFile("scan-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != string(record.Data) {
+		t.Fatalf("result file %q = %s (%v), want the stored record", path, data, err)
+	}
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("result file %q still exists after cleanup", path)
+	}
+}
+
+func TestOpenPortsAndProfileIgnoreUnconfirmedResults(t *testing.T) {
+	record := &scanRecord{summary: ScanSummary{ID: "profile-open-only"}, config: ScanConfig{Target: "10.0.0.1"}}
+	storeMutex.Lock()
+	scanStore[record.summary.ID] = record
+	storeMutex.Unlock()
+	defer func() {
+		storeMutex.Lock()
+		delete(scanStore, record.summary.ID)
+		storeMutex.Unlock()
+	}()
+
+	a := &App{}
+	record.addResult(PortInfo{Host: "10.0.0.1", Port: 161, Protocol: "udp", State: "open|filtered"})
+	if _, err := a.ProfileFromScan(record.summary.ID); err == nil {
+		t.Error("profiled a scan with no confirmed open ports")
+	}
+	record.addResult(PortInfo{Host: "10.0.0.1", Port: 443, Protocol: "tcp"})
+	record.addResult(PortInfo{Host: "10.0.0.1", Port: 22, Protocol: "tcp"})
+	if got := record.openPorts(); got != 2 {
+		t.Errorf("OpenPorts = %d, want 2", got)
+	}
+	config, err := a.ProfileFromScan(record.summary.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Ports) != 2 || config.Ports[0] != 22 || config.Ports[1] != 443 {
+		t.Errorf("profile ports = %v, want [22 443]", config.Ports)
+	}
+}
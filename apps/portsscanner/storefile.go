@@ -0,0 +1,228 @@
+package portsscanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileStore 默认的存储后端：每次扫描一个JSON文件，文件名为扫描ID
+type fileStore struct {
+	dir string
+
+	mu    sync.Mutex
+	paths map[string]string // List时发现的扫描ID到文件路径，文件名与ID不一致的旧记录也能找到
+}
+
+// openFileStore 打开目录(为空时为~/GlideWay/scans)中的扫描记录，旧格式的记录先升级到当前格式
+func openFileStore(dsn string) (ResultStore, error) {
+	dir := dsn
+	if dir == "" {
+		var err error
+		if dir, err = storeDir(); err != nil {
+			return nil, err
+		}
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create scan store directory: %w", err)
+	}
+	s := &fileStore{dir: dir, paths: make(map[string]string)}
+	if err := s.migrate(); err != nil {
+		fmt.Printf("迁移扫描记录失败: %v\n", err)
+	}
+	return s, nil
+}
+
+// path 返回扫描记录的文件路径
+func (s *fileStore) path(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.paths[id]; ok {
+		return p
+	}
+	return filepath.Join(s.dir, sanitizeFileName(id)+".json")
+}
+
+// files 列出存储目录中的扫描记录文件
+func (s *fileStore) files() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == storeSchemaFile || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		files = append(files, filepath.Join(s.dir, e.Name()))
+	}
+	return files, nil
+}
+
+// readSummary 只解码记录中的扫描摘要
+func readSummary(data []byte) (ScanSummary, error) {
+	var doc struct {
+		Scan ScanSummary `json:"scan"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return ScanSummary{}, err
+	}
+	if doc.Scan.ID == "" {
+		return ScanSummary{}, fmt.Errorf("missing scan id")
+	}
+	return doc.Scan, nil
+}
+
+func (s *fileStore) Save(record StoredRecord) error {
+	path := filepath.Join(s.dir, sanitizeFileName(record.Summary.ID)+".json")
+	if err := writeFileAtomic(path, record.Data); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.paths[record.Summary.ID] = path
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *fileStore) List() ([]ScanSummary, error) {
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]ScanSummary, 0, len(files))
+	paths := make(map[string]string, len(files))
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		summary, err := readSummary(data)
+		if err != nil {
+			fmt.Printf("跳过无法加载的扫描记录 %s: %v\n", path, err)
+			continue
+		}
+		if _, exists := paths[summary.ID]; exists {
+			continue
+		}
+		paths[summary.ID] = path
+		summaries = append(summaries, summary)
+	}
+	s.mu.Lock()
+	s.paths = paths
+	s.mu.Unlock()
+	sortSummaries(summaries)
+	return summaries, nil
+}
+
+func (s *fileStore) Get(id string) (StoredRecord, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return StoredRecord{}, fmt.Errorf("scan %q: %w", id, ErrScanNotFound)
+	}
+	if err != nil {
+		return StoredRecord{}, err
+	}
+	summary, err := readSummary(data)
+	if err != nil {
+		return StoredRecord{}, fmt.Errorf("invalid scan record %s: %w", id, err)
+	}
+	return StoredRecord{Summary: summary, Data: data}, nil
+}
+
+func (s *fileStore) Query(q ScanQuery) ([]ScanSummary, error) {
+	return queryScans(s, q)
+}
+
+func (s *fileStore) Prune(policy RetentionPolicy, now time.Time) ([]string, error) {
+	return pruneByPolicy(s, policy, now)
+}
+
+func (s *fileStore) Delete(id string) error {
+	path := s.path(id)
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("scan %q: %w", id, ErrScanNotFound)
+		}
+		return err
+	}
+	s.mu.Lock()
+	delete(s.paths, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// migrate 将存储目录中的旧记录升级到当前格式并记录格式版本
+func (s *fileStore) migrate() error {
+	schemaPath := filepath.Join(s.dir, storeSchemaFile)
+	var schema struct {
+		Version    int       `json:"version"`
+		MigratedAt time.Time `json:"migrated_at"`
+	}
+	if data, err := os.ReadFile(schemaPath); err == nil {
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return fmt.Errorf("invalid %s: %w", schemaPath, err)
+		}
+	}
+	if schema.Version == storeSchemaVersion {
+		return nil
+	}
+	if schema.Version > storeSchemaVersion {
+		return fmt.Errorf("scan store uses schema version %d, this build supports up to %d", schema.Version, storeSchemaVersion)
+	}
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+	migrated := 0
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		scan, upgraded, err := decodeStoredScan(data)
+		if err != nil {
+			fmt.Printf("跳过无法迁移的扫描记录 %s: %v\n", path, err)
+			continue
+		}
+		if !upgraded {
+			continue
+		}
+		out, err := json.Marshal(scan)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(path, out); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		migrated++
+	}
+
+	schema.Version = storeSchemaVersion
+	schema.MigratedAt = time.Now()
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(schemaPath, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", schemaPath, err)
+	}
+	fmt.Printf("扫描记录格式已升级到版本 %d，迁移 %d 条记录\n", storeSchemaVersion, migrated)
+	return nil
+}
+
+// storedScanPath 使用file后端时返回扫描记录的文件路径，其他后端没有对应文件时返回空
+func storedScanPath(id string) string {
+	store, err := activeStore()
+	if err != nil {
+		return ""
+	}
+	if fs, ok := store.(*fileStore); ok {
+		return fs.path(id)
+	}
+	return ""
+}
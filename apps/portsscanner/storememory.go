@@ -0,0 +1,64 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// memoryStore 只保存在内存中的存储后端，适用于不希望在本机留下扫描记录的临时使用，退出后全部丢失
+type memoryStore struct {
+	mu      sync.RWMutex
+	records map[string]StoredRecord
+}
+
+func openMemoryStore(string) (ResultStore, error) {
+	return &memoryStore{records: make(map[string]StoredRecord)}, nil
+}
+
+func (s *memoryStore) Save(record StoredRecord) error {
+	record.Data = append([]byte(nil), record.Data...)
+	s.mu.Lock()
+	s.records[record.Summary.ID] = record
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryStore) List() ([]ScanSummary, error) {
+	s.mu.RLock()
+	summaries := make([]ScanSummary, 0, len(s.records))
+	for _, r := range s.records {
+		summaries = append(summaries, r.Summary)
+	}
+	s.mu.RUnlock()
+	sortSummaries(summaries)
+	return summaries, nil
+}
+
+func (s *memoryStore) Get(id string) (StoredRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.records[id]
+	if !ok {
+		return StoredRecord{}, fmt.Errorf("scan %q: %w", id, ErrScanNotFound)
+	}
+	return record, nil
+}
+
+func (s *memoryStore) Query(q ScanQuery) ([]ScanSummary, error) {
+	return queryScans(s, q)
+}
+
+func (s *memoryStore) Prune(policy RetentionPolicy, now time.Time) ([]string, error) {
+	return pruneByPolicy(s, policy, now)
+}
+
+func (s *memoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[id]; !ok {
+		return fmt.Errorf("scan %q: %w", id, ErrScanNotFound)
+	}
+	delete(s.records, id)
+	return nil
+}
@@ -0,0 +1,216 @@
+package portsscanner
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // 纯Go的SQLite驱动，注册为 "sqlite"，不需要cgo
+)
+
+// 各SQL后端可用的database/sql驱动名，按顺序使用第一个已链接到构建中的驱动
+var sqlDriverNames = map[string][]string{
+	"sqlite":   {"sqlite", "sqlite3"},
+	"postgres": {"pgx", "postgres"},
+}
+
+// 默认的SQLite数据库文件名，保存在 ~/GlideWay 下
+const defaultSQLiteFile = "scans.db"
+
+const sqlStoreSchema = `CREATE TABLE IF NOT EXISTS glideway_scans (
+	id         TEXT PRIMARY KEY,
+	project    TEXT NOT NULL,
+	target     TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	started_at BIGINT NOT NULL,
+	summary    TEXT NOT NULL,
+	data       TEXT NOT NULL
+)`
+
+// sqlStore 基于database/sql的存储后端，SQLite和PostgreSQL共用同一张表，
+// 摘要单独成列，列表和查询不需要读取完整记录
+type sqlStore struct {
+	db      *sql.DB
+	dialect string
+}
+
+// openSQLStore 返回指定方言的打开函数。SQLite使用内置的 modernc.org/sqlite；PostgreSQL不内置驱动，
+// 构建时需要以空白导入链接(如 github.com/jackc/pgx/v5/stdlib 或 github.com/lib/pq)
+func openSQLStore(dialect string) ResultStoreOpener {
+	return func(dsn string) (ResultStore, error) {
+		driver, err := sqlDriverFor(dialect)
+		if err != nil {
+			return nil, err
+		}
+		if dsn == "" {
+			if dialect != "sqlite" {
+				return nil, fmt.Errorf("%s result store requires a DSN", dialect)
+			}
+			if dsn, err = dataFilePath(defaultSQLiteFile); err != nil {
+				return nil, err
+			}
+		}
+		db, err := sql.Open(driver, dsn)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to connect to %s: %w", dialect, err)
+		}
+		if _, err := db.Exec(sqlStoreSchema); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create scan table: %w", err)
+		}
+		if _, err := db.Exec("CREATE INDEX IF NOT EXISTS glideway_scans_started ON glideway_scans (started_at)"); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create scan index: %w", err)
+		}
+		return &sqlStore{db: db, dialect: dialect}, nil
+	}
+}
+
+// sqlDriverFor 返回方言对应的已注册驱动
+func sqlDriverFor(dialect string) (string, error) {
+	candidates := sqlDriverNames[dialect]
+	for _, name := range candidates {
+		for _, registered := range sql.Drivers() {
+			if name == registered {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no database/sql driver for %s is linked into this build (want one of %s)", dialect, strings.Join(candidates, ", "))
+}
+
+// bind 将查询中的 ? 占位符转换为方言的写法，PostgreSQL使用 $1、$2...
+func (s *sqlStore) bind(query string) string {
+	if s.dialect != "postgres" {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Close 关闭数据库连接
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqlStore) Save(record StoredRecord) error {
+	summary, err := json.Marshal(record.Summary)
+	if err != nil {
+		return err
+	}
+	r := record.Summary
+	_, err = s.db.Exec(s.bind(`INSERT INTO glideway_scans (id, project, target, status, started_at, summary, data)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET project = excluded.project, target = excluded.target, status = excluded.status,
+	started_at = excluded.started_at, summary = excluded.summary, data = excluded.data`),
+		r.ID, r.Project, r.Target, r.Status, r.StartedAt.UnixNano(), string(summary), string(record.Data))
+	return err
+}
+
+// querySummaries 执行返回summary列的查询
+func (s *sqlStore) querySummaries(query string, args ...interface{}) ([]ScanSummary, error) {
+	rows, err := s.db.Query(s.bind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	summaries := []ScanSummary{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var summary ScanSummary
+		if err := json.Unmarshal([]byte(data), &summary); err != nil {
+			return nil, fmt.Errorf("invalid scan summary: %w", err)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+func (s *sqlStore) List() ([]ScanSummary, error) {
+	return s.querySummaries("SELECT summary FROM glideway_scans ORDER BY started_at DESC")
+}
+
+func (s *sqlStore) Get(id string) (StoredRecord, error) {
+	var summary, data string
+	err := s.db.QueryRow(s.bind("SELECT summary, data FROM glideway_scans WHERE id = ?"), id).Scan(&summary, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return StoredRecord{}, fmt.Errorf("scan %q: %w", id, ErrScanNotFound)
+	}
+	if err != nil {
+		return StoredRecord{}, err
+	}
+	var record StoredRecord
+	if err := json.Unmarshal([]byte(summary), &record.Summary); err != nil {
+		return StoredRecord{}, fmt.Errorf("invalid scan summary %s: %w", id, err)
+	}
+	record.Data = []byte(data)
+	return record, nil
+}
+
+func (s *sqlStore) Query(q ScanQuery) ([]ScanSummary, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if q.Project != "" {
+		add("project = ?", q.Project)
+	}
+	if q.Status != "" {
+		add("status = ?", q.Status)
+	}
+	if q.Target != "" {
+		add("target LIKE ?", "%"+q.Target+"%")
+	}
+	if !q.Since.IsZero() {
+		add("started_at >= ?", q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		add("started_at <= ?", q.Until.UnixNano())
+	}
+	query := "SELECT summary FROM glideway_scans"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY started_at DESC"
+	if q.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(q.Limit)
+	}
+	return s.querySummaries(query, args...)
+}
+
+func (s *sqlStore) Prune(policy RetentionPolicy, now time.Time) ([]string, error) {
+	return pruneByPolicy(s, policy, now)
+}
+
+func (s *sqlStore) Delete(id string) error {
+	result, err := s.db.Exec(s.bind("DELETE FROM glideway_scans WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("scan %q: %w", id, ErrScanNotFound)
+	}
+	return nil
+}
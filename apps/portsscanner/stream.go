@@ -0,0 +1,44 @@
+package portsscanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// jsonlWriter 扫描过程中将每个发现的端口以一行JSON追加写入文件
+type jsonlWriter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openJSONLWriter(path string) (*jsonlWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream file %s: %w", path, err)
+	}
+	return &jsonlWriter{file: f}, nil
+}
+
+// Write 写入一条结果并立即落盘，扫描中途崩溃也能保留已发现的结果
+func (w *jsonlWriter) Write(info PortInfo) error {
+	line, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Write(line); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+func (w *jsonlWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
@@ -3,21 +3,168 @@ package portsscanner
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 type scanControl struct {
+	// totalPorts 和 scanned 原子读写，放在最前保证32位平台上64位对齐
+	totalPorts int64
+	scanned    int64
+	record     *scanRecord
+	registry   *hostRegistry
 	cancel     context.CancelFunc
-	totalPorts int32
-	scanned    int32
+	rtt        *rttEstimator // 启用AdaptiveTimeout时的RTT统计
+	phases     *phaseTracker // 各阶段进度
+	done       chan struct{} // 扫描协程完成收尾(记录已保存、currentScan已清空)后关闭
+
+	limiter *adaptiveLimiter // 启用AdaptiveThrottle或AutoConcurrency时的并发控制
+	pause   *pauseGate       // PauseScan和PauseOnNetworkChange的暂停控制
+
+	statsMu        sync.Mutex
+	serviceCounts  map[string]int // 服务名 -> 已发现数量
+	lastCountsEmit time.Time
+	errorCounts    map[string]int // 错误分类 -> 次数
+	lastErrorsEmit time.Time
+
+	topServices *topCounter // 服务排行榜，见topn.go
+	topHosts    *topCounter // 开放端口数的主机排行榜
+	lastTopEmit time.Time
+}
+
+// matchHost 将用户输入的主机名、别名或IP匹配为本次扫描中的规范IP
+func (c *scanControl) matchHost(host string) (string, bool) {
+	if ip, ok := canonicalIP(host); ok {
+		host = ip
+	}
+	for _, h := range c.record.config.hosts {
+		if h.IP == host || containsString(h.Names, host) {
+			return h.IP, true
+		}
+	}
+	return "", false
+}
+
+// service-counts 事件的最小发送间隔
+const serviceCountsInterval = time.Second
+
+// countService 累加服务计数，距上次发送超过节流间隔时返回最新快照
+func (c *scanControl) countService(service string) (map[string]int, bool) {
+	if service == "" {
+		service = "unknown"
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	if c.serviceCounts == nil {
+		c.serviceCounts = make(map[string]int)
+	}
+	c.serviceCounts[service]++
+
+	if time.Since(c.lastCountsEmit) < serviceCountsInterval {
+		return nil, false
+	}
+	c.lastCountsEmit = time.Now()
+	return copyCounts(c.serviceCounts), true
+}
+
+// serviceCountsSnapshot 返回服务计数的副本
+func (c *scanControl) serviceCountsSnapshot() map[string]int {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return copyCounts(c.serviceCounts)
+}
+
+func copyCounts(src map[string]int) map[string]int {
+	counts := make(map[string]int, len(src))
+	for k, v := range src {
+		counts[k] = v
+	}
+	return counts
+}
+
+// deferredScan 一次排队等待时间窗口的扫描，按指针区分：新的排队或StopScan替换后，
+// 旧的等待协程不再启动扫描，也不会清除新的排队
+type deferredScan struct {
+	cancel context.CancelFunc
 }
 
 var (
 	currentScan *scanControl
 	scanMutex   sync.Mutex
+
+	// 时间窗口外排队等待的扫描
+	deferred *deferredScan
+	// 扫描开始前正在进行的目标解析
+	resolveCancel context.CancelFunc
+	// FingerprintExisting 正在进行的补充识别
+	fingerprintCancel context.CancelFunc
+	// 当前或最近一次扫描实际使用的配置
+	effectiveConfig ScanConfig
+	// 刚结束的扫描，在保留期内仍可查询其状态、进度和服务计数
+	lastScan *finishedScan
 )
 
+// 扫描结束后保留其状态的默认时长，之后才标记为idle
+const defaultCompletedRetention = 10 * time.Second
+
+// finishedScan 已结束扫描的保留槽，避免前端取回最终结果之前状态就变为idle
+type finishedScan struct {
+	control *scanControl
+	status  string // completed、cancelled 或 error
+	timer   *time.Timer
+}
+
+// clearRetainedScan 新扫描开始时立即结束上一次扫描的保留期，调用方需持有scanMutex
+func clearRetainedScan() {
+	if lastScan != nil {
+		lastScan.timer.Stop()
+		lastScan = nil
+	}
+}
+
 type ScanProgress struct {
-	CurrentPort int32  `json:"current_port"`
-	TotalPorts  int32  `json:"total_ports"`
+	CurrentPort int64  `json:"current_port"`
+	TotalPorts  int64  `json:"total_ports"`
 	Status      string `json:"status"`
+	ScanID      string `json:"scan_id,omitempty"`
+	// EffectiveTimeout 启用AdaptiveTimeout时当前生效的连接超时(秒)
+	EffectiveTimeout float64 `json:"effective_timeout,omitempty"`
+	// Concurrency 启用AdaptiveThrottle或AutoConcurrency时当前允许的并发数
+	Concurrency int `json:"concurrency,omitempty"`
+	// Phases 各阶段进度和加权整体进度，与 scan-phase-progress 事件的内容相同
+	Phases *ScanPhaseProgress `json:"phases,omitempty"`
+}
+
+// phaseProgress 各阶段进度的快照，未统计时返回nil
+func (c *scanControl) phaseProgress() *ScanPhaseProgress {
+	if c.phases == nil {
+		return nil
+	}
+	progress := c.phases.snapshot()
+	return &progress
+}
+
+// status 进行中扫描的状态：running，或因网络变化暂停时为 paused
+func (c *scanControl) status() string {
+	if c.pause.paused() {
+		return "paused"
+	}
+	return "running"
+}
+
+// concurrency 当前允许的并发数，未启用自适应并发时为0
+func (c *scanControl) concurrency() int {
+	if c.limiter == nil {
+		return 0
+	}
+	return c.limiter.current()
+}
+
+// effectiveTimeout 当前生效的自适应连接超时(秒)，未启用时为0
+func (c *scanControl) effectiveTimeout() float64 {
+	if c.rtt == nil {
+		return 0
+	}
+	return c.rtt.timeout().Seconds()
 }
@@ -0,0 +1,58 @@
+//go:build !windows
+
+package portsscanner
+
+import (
+	"errors"
+	"syscall"
+)
+
+// openFileLimit 返回当前进程的打开文件数软限制
+func openFileLimit() (uint64, error) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, err
+	}
+	return uint64(rlimit.Cur), nil
+}
+
+// connRefusedErrno 连接被拒绝的错误码，供模拟网络构造与真实拨号一致的错误
+const connRefusedErrno = syscall.ECONNREFUSED
+
+// isConnRefused 判断拨号错误是否为目标端口关闭(收到RST)
+func isConnRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// isNetUnreachable 判断拨号错误是否为没有到目标网络的路由
+func isNetUnreachable(err error) bool {
+	return errors.Is(err, syscall.ENETUNREACH)
+}
+
+// isHostUnreachable 判断拨号错误是否为目标主机不可达
+func isHostUnreachable(err error) bool {
+	return errors.Is(err, syscall.EHOSTUNREACH)
+}
+
+// isConnReset 判断错误是否为连接被对端重置
+func isConnReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET)
+}
+
+// isAddrInUse 判断拨号错误是否为本机源地址或端口不可用(已被占用或仍处于TIME_WAIT)
+func isAddrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE) || errors.Is(err, syscall.EADDRNOTAVAIL)
+}
+
+// setSocketToS 设置套接字的IP_TOS，IPv6套接字设置IPV6_TCLASS
+func setSocketToS(fd uintptr, ipv6 bool, tos int) error {
+	if ipv6 {
+		return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, tos)
+	}
+	return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+}
+
+// isPortUnreachable 判断UDP读取错误是否为收到ICMP端口不可达
+func isPortUnreachable(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,69 @@
+//go:build windows
+
+package portsscanner
+
+import (
+	"errors"
+	"syscall"
+)
+
+// openFileLimit Windows没有类似ulimit的打开文件数限制，返回0表示不限制
+func openFileLimit() (uint64, error) {
+	return 0, nil
+}
+
+// WSAECONNREFUSED、WSAECONNRESET、WSAENETUNREACH、WSAEHOSTUNREACH、WSAEADDRINUSE、WSAEADDRNOTAVAIL，
+// syscall包未导出这些常量
+const (
+	wsaeConnRefused  syscall.Errno = 10061
+	wsaeConnReset    syscall.Errno = 10054
+	wsaeNetUnreach   syscall.Errno = 10051
+	wsaeHostUnreach  syscall.Errno = 10065
+	wsaeAddrInUse    syscall.Errno = 10048
+	wsaeAddrNotAvail syscall.Errno = 10049
+
+	// connRefusedErrno 连接被拒绝的错误码，供模拟网络构造与真实拨号一致的错误
+	connRefusedErrno = wsaeConnRefused
+)
+
+// isConnRefused 判断拨号错误是否为目标端口关闭(收到RST)
+func isConnRefused(err error) bool {
+	return errors.Is(err, wsaeConnRefused)
+}
+
+// isNetUnreachable 判断拨号错误是否为没有到目标网络的路由
+func isNetUnreachable(err error) bool {
+	return errors.Is(err, wsaeNetUnreach)
+}
+
+// isHostUnreachable 判断拨号错误是否为目标主机不可达
+func isHostUnreachable(err error) bool {
+	return errors.Is(err, wsaeHostUnreach)
+}
+
+// isConnReset 判断错误是否为连接被对端重置
+func isConnReset(err error) bool {
+	return errors.Is(err, wsaeConnReset)
+}
+
+// isAddrInUse 判断拨号错误是否为本机源地址或端口不可用(已被占用或仍处于TIME_WAIT)
+func isAddrInUse(err error) bool {
+	return errors.Is(err, wsaeAddrInUse) || errors.Is(err, wsaeAddrNotAvail)
+}
+
+// IPV6_TCLASS，syscall包未导出
+const ipv6TClass = 39
+
+// setSocketToS 设置套接字的IP_TOS，IPv6套接字设置IPV6_TCLASS。
+// Windows默认忽略应用设置的ToS，需要通过组策略的QoS策略才会生效
+func setSocketToS(fd uintptr, ipv6 bool, tos int) error {
+	if ipv6 {
+		return syscall.SetsockoptInt(syscall.Handle(fd), syscall.IPPROTO_IPV6, ipv6TClass, tos)
+	}
+	return syscall.SetsockoptInt(syscall.Handle(fd), syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+}
+
+// isPortUnreachable 判断UDP读取错误是否为收到ICMP端口不可达，Windows上报告为WSAECONNRESET
+func isPortUnreachable(err error) bool {
+	return errors.Is(err, wsaeConnReset)
+}
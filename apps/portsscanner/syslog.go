@@ -0,0 +1,203 @@
+package portsscanner
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 等待发送的syslog消息上限，syslog服务器跟不上时丢弃新消息而不阻塞扫描
+	syslogQueueSize = 1024
+	// 连接或发送syslog的超时时间
+	syslogTimeout = 3 * time.Second
+	// 连接失败后暂停重连的时间，期间的消息直接丢弃
+	syslogRetryDelay = 10 * time.Second
+	// RFC 5424 APP-NAME 和结构化数据ID
+	syslogAppName = "GlideWay"
+	syslogSDID    = "glideway@32473"
+)
+
+// syslog 设施和级别(RFC 5424)
+const (
+	syslogFacilityLocal0 = 16
+	syslogSeverityErr    = 3
+	syslogSeverityWarn   = 4
+	syslogSeverityNotice = 5
+	syslogSeverityInfo   = 6
+)
+
+// syslogForwarder 将扫描发现和生命周期事件以RFC 5424格式转发到syslog服务器，
+// 尽力投递：队列写满或发送失败时丢弃消息，不影响扫描
+type syslogForwarder struct {
+	network       string // udp 或 tcp
+	addr          string
+	hostname      string
+	scanID        string
+	correlationID string
+
+	queue   chan string
+	done    chan struct{}
+	dropped int64
+}
+
+// parseSyslogTarget 解析 host:port、udp://host:port 或 tcp://host:port，默认UDP
+func parseSyslogTarget(target string) (string, string, error) {
+	network := "udp"
+	addr := strings.TrimSpace(target)
+	if scheme, rest, ok := strings.Cut(addr, "://"); ok {
+		network, addr = strings.ToLower(scheme), rest
+	}
+	if network != "udp" && network != "tcp" {
+		return "", "", fmt.Errorf("unsupported syslog transport %q", network)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", "", fmt.Errorf("invalid syslog target %q: %w", target, err)
+	}
+	return network, addr, nil
+}
+
+// newSyslogForwarder 创建转发器并启动发送协程
+func newSyslogForwarder(target, scanID, correlationID string) (*syslogForwarder, error) {
+	network, addr, err := parseSyslogTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	f := &syslogForwarder{
+		network:       network,
+		addr:          addr,
+		hostname:      hostname,
+		scanID:        scanID,
+		correlationID: correlationID,
+		queue:         make(chan string, syslogQueueSize),
+		done:          make(chan struct{}),
+	}
+	go f.loop()
+	return f, nil
+}
+
+func (f *syslogForwarder) loop() {
+	defer close(f.done)
+	var conn net.Conn
+	var retryAt time.Time
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	for msg := range f.queue {
+		if conn == nil {
+			if time.Now().Before(retryAt) {
+				atomic.AddInt64(&f.dropped, 1)
+				continue
+			}
+			c, err := net.DialTimeout(f.network, f.addr, syslogTimeout)
+			if err != nil {
+				atomic.AddInt64(&f.dropped, 1)
+				retryAt = time.Now().Add(syslogRetryDelay)
+				continue
+			}
+			conn = c
+		}
+		// TCP使用RFC 6587的八位组计数分帧
+		if f.network == "tcp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			atomic.AddInt64(&f.dropped, 1)
+			conn.Close()
+			conn = nil
+		}
+	}
+}
+
+// send 格式化并投递一条消息，队列已满时直接丢弃
+func (f *syslogForwarder) send(severity int, msgID string, params [][2]string, text string) {
+	ids := [][2]string{{"scan_id", f.scanID}}
+	if f.correlationID != "" {
+		ids = append(ids, [2]string{"correlation_id", f.correlationID})
+	}
+	msg := formatSyslog(syslogFacilityLocal0*8+severity, time.Now(), f.hostname, msgID, append(ids, params...), text)
+	select {
+	case f.queue <- msg:
+	default:
+		atomic.AddInt64(&f.dropped, 1)
+	}
+}
+
+// finding 转发一个开放端口
+func (f *syslogForwarder) finding(info PortInfo) {
+	params := [][2]string{
+		{"host", info.Host},
+		{"port", strconv.Itoa(info.Port)},
+		{"protocol", info.Protocol},
+		{"service", info.Service},
+	}
+	if info.ProductName != "" {
+		params = append(params, [2]string{"product", info.ProductName})
+	}
+	if info.Version != "" {
+		params = append(params, [2]string{"version", info.Version})
+	}
+	if info.State != "" {
+		params = append(params, [2]string{"state", info.State})
+	}
+	f.send(syslogSeverityNotice, "port-found", params,
+		fmt.Sprintf("open port %s:%d %s", info.Host, info.Port, info.Service))
+}
+
+// lifecycle 转发扫描开始、完成、取消和出错
+func (f *syslogForwarder) lifecycle(status, target, detail string) {
+	severity := syslogSeverityInfo
+	switch status {
+	case "error":
+		severity = syslogSeverityErr
+	case "cancelled":
+		severity = syslogSeverityWarn
+	}
+	text := "scan " + status
+	if detail != "" {
+		text += ": " + detail
+	}
+	f.send(severity, "scan-"+status, [][2]string{{"status", status}, {"target", target}}, text)
+}
+
+// close 停止接收消息并等待已排队的消息发送完毕，syslog服务器无响应时最多等待两个发送超时
+func (f *syslogForwarder) close() {
+	close(f.queue)
+	select {
+	case <-f.done:
+	case <-time.After(2 * syslogTimeout):
+	}
+	if dropped := atomic.LoadInt64(&f.dropped); dropped > 0 {
+		fmt.Printf("syslog转发丢弃了 %d 条消息\n", dropped)
+	}
+}
+
+// formatSyslog 生成RFC 5424消息，时间戳最多6位小数，结构化数据中的 "、\ 和 ] 需要转义
+func formatSyslog(pri int, t time.Time, hostname, msgID string, params [][2]string, text string) string {
+	var sd strings.Builder
+	sd.WriteString("[" + syslogSDID)
+	for _, p := range params {
+		if p[1] == "" {
+			continue
+		}
+		sd.WriteString(" " + p[0] + `="` + escapeSDValue(p[1]) + `"`)
+	}
+	sd.WriteString("]")
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		pri, t.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), hostname, syslogAppName, os.Getpid(), msgID, sd.String(), text)
+}
+
+func escapeSDValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,179 @@
+package portsscanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ErrDNSTimeout 目标主机名解析超时
+var ErrDNSTimeout = errors.New("dns-timeout")
+
+// scanTarget 去重后的单台物理主机
+type scanTarget struct {
+	IP    string   // 规范化后的IP地址，实际拨号使用
+	Names []string // 指向该IP的所有原始输入(主机名或IP)
+
+	Selections []AddressSelection // HappyEyeballs为指向该IP的主机名选择地址的过程
+}
+
+// UnresolvedTarget 解析失败、扫描时被跳过的目标主机名
+type UnresolvedTarget struct {
+	Host    string `json:"host"`
+	Error   string `json:"error"`
+	Retried bool   `json:"retried,omitempty"` // 已在其余目标解析完成后重试过一次(RetryUnresolved)
+}
+
+// hostLookup 将主机名解析为要扫描的规范化IP
+type hostLookup func(ctx context.Context, host string) ([]string, error)
+
+// splitTargetList 将用户粘贴的目标列表按逗号、空白和换行拆分
+func splitTargetList(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	targets := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			targets = append(targets, f)
+		}
+	}
+	return targets
+}
+
+// canonicalIP 将IP字符串规范化，IPv4映射的IPv6地址还原为IPv4，
+// IPv6链路本地地址的区域标识(fe80::1%eth0)会被保留，不同网卡上的同一地址视为不同主机
+func canonicalIP(s string) (string, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return "", false
+	}
+	return addr.Unmap().String(), true
+}
+
+// lookupHost 解析主机名的全部地址，超时报告为ErrDNSTimeout
+func lookupHost(ctx context.Context, resolver *net.Resolver, host string) ([]net.IPAddr, error) {
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &dnsErr) && dnsErr.IsTimeout) {
+			return nil, fmt.Errorf("解析主机名 %s 超时: %w", host, ErrDNSTimeout)
+		}
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, context.Canceled
+		}
+		return nil, fmt.Errorf("解析主机名 %s 失败: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("主机名 %s 没有解析到任何地址", host)
+	}
+	return addrs, nil
+}
+
+// resolveHost 将主机名解析为规范化IP，优先使用IPv4地址
+func resolveHost(ctx context.Context, resolver *net.Resolver, host string) (string, error) {
+	addrs, err := lookupHost(ctx, resolver, host)
+	if err != nil {
+		return "", err
+	}
+	return preferredAddress(addrs), nil
+}
+
+// preferredAddress 从解析结果中选择要扫描的地址，优先使用IPv4地址
+func preferredAddress(addrs []net.IPAddr) string {
+	chosen := addrs[0]
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			chosen = a
+			break
+		}
+	}
+	// IPAddr.String 带有链路本地地址的区域标识(fe80::1%eth0)，拨号时需要保留
+	ip, _ := canonicalIP(chosen.String())
+	return ip
+}
+
+// normalizeTargets 扫描前的规范化处理：解析主机名、规范化IP并去重，
+// 保证每台物理主机只扫描一次，同时记录所有指向它的名称。主机名由lookup解析，
+// 可能得到多个地址(见DualStackCompare)。解析失败的主机名被跳过，不影响其余目标，
+// 全部目标都无法解析或解析被中断时返回错误。
+// 返回去重后的目标列表、被合并掉的重复项数量以及解析失败的主机名。
+func normalizeTargets(ctx context.Context, inputs []string, lookup hostLookup) ([]scanTarget, int, []UnresolvedTarget, error) {
+	if len(inputs) == 0 {
+		return nil, 0, nil, fmt.Errorf("target list is empty")
+	}
+
+	targets := make([]scanTarget, 0, len(inputs))
+	index := make(map[string]int)
+	duplicates := 0
+	var unresolved []UnresolvedTarget
+	var firstErr error
+
+	for _, input := range inputs {
+		var ips []string
+		if ip, ok := canonicalIP(input); ok {
+			ips = []string{ip}
+		} else {
+			resolved, err := lookup(ctx, input)
+			if errors.Is(err, context.Canceled) {
+				return nil, 0, nil, err
+			}
+			if err != nil {
+				if !containsUnresolved(unresolved, input) {
+					unresolved = append(unresolved, UnresolvedTarget{Host: input, Error: err.Error()})
+				}
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			ips = resolved
+		}
+
+		merged := true
+		for _, ip := range ips {
+			if i, exists := index[ip]; exists {
+				if !containsString(targets[i].Names, input) {
+					targets[i].Names = append(targets[i].Names, input)
+				}
+				continue
+			}
+			merged = false
+			index[ip] = len(targets)
+			targets = append(targets, scanTarget{IP: ip, Names: []string{input}})
+		}
+		if merged {
+			duplicates++
+		}
+	}
+
+	if len(targets) == 0 {
+		if len(unresolved) == 1 {
+			return nil, 0, unresolved, firstErr
+		}
+		return nil, 0, unresolved, fmt.Errorf("none of the %d target host(s) could be resolved: %w", len(unresolved), firstErr)
+	}
+	return targets, duplicates, unresolved, nil
+}
+
+// containsUnresolved 判断主机名是否已记录为解析失败，重复输入的主机名只记录一次
+func containsUnresolved(list []UnresolvedTarget, host string) bool {
+	for _, u := range list {
+		if u.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
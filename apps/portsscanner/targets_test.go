@@ -0,0 +1,47 @@
+package portsscanner
+
+import (
+	"context"
+	"testing"
+)
+
+func TestZonedLinkLocalTarget(t *testing.T) {
+	// 同一链路本地地址在两块网卡上是不同的主机，拨号丢失区域标识时连不到lo上的端口
+	hosts := []SimulatedHost{
+		{IP: "fe80::1%lo", Ports: []SimulatedPort{{Port: 22, Service: "ssh"}}},
+		{IP: "fe80::1%br-lan", Ports: []SimulatedPort{{Port: 80, Service: "http"}}},
+	}
+	config := simConfig(t, hosts, "fe80::1%lo", 22, 80)
+	applyScanDefaults(&config)
+
+	resolved, duplicates, unresolved, err := resolveTargets(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 1 || resolved[0].IP != "fe80::1%lo" || duplicates != 0 || len(unresolved) != 0 {
+		t.Fatalf("resolveTargets = %+v (duplicates %d, unresolved %v), want fe80::1%%lo", resolved, duplicates, unresolved)
+	}
+
+	scan, err := runSimScan(t, context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scan.results) != 1 {
+		t.Fatalf("results = %+v, want only 22 on fe80::1%%lo", scan.results)
+	}
+	if r := scan.results[0]; r.Host != "fe80::1%lo" || r.Port != 22 || r.Service != "ssh" {
+		t.Fatalf("result = %+v, want ssh on fe80::1%%lo port 22", r)
+	}
+}
+
+func TestZonedTargetWithHyphenIsNotARange(t *testing.T) {
+	config := simConfig(t, nil, "fe80::1%br-lan")
+	applyScanDefaults(&config)
+	resolved, _, _, err := resolveTargets(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 1 || resolved[0].IP != "fe80::1%br-lan" {
+		t.Fatalf("resolveTargets = %+v, want the single zoned address", resolved)
+	}
+}
@@ -0,0 +1,95 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// textHostName 主机段标题中的名称：扫描时输入了主机名时写作 "主机名 (IP)"
+func textHostName(host string, aliases []string) string {
+	for _, alias := range aliases {
+		if _, isIP := canonicalIP(alias); !isIP && alias != host {
+			return fmt.Sprintf("%s (%s)", alias, host)
+		}
+	}
+	return host
+}
+
+// writeText 写出类似nmap默认输出的纯文本摘要：每台主机一段，标题为主机名和扫描时间，
+// 每个端口一行 "port/proto state service version"，按端口号排序
+func writeText(sb *strings.Builder, summary ScanSummary, results []PortInfo) error {
+	byHost := make(map[string][]PortInfo)
+	var hosts []string
+	for _, r := range results {
+		if _, seen := byHost[r.Host]; !seen {
+			hosts = append(hosts, r.Host)
+		}
+		byHost[r.Host] = append(byHost[r.Host], r)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return compareHosts(hosts[i], hosts[j]) < 0 })
+
+	scanned := summary.resultsTime().Format("2006-01-02 15:04:05 MST")
+	fmt.Fprintf(sb, "# GlideWay scan %s of %s: %d ports on %d hosts\n", summary.ID, summary.Target, len(results), len(hosts))
+	if summary.ConfigHash != "" {
+		fmt.Fprintf(sb, "# Config hash %s\n", summary.ConfigHash)
+	}
+	for _, host := range hosts {
+		ports := byHost[host]
+		sort.Slice(ports, func(i, j int) bool {
+			if ports[i].Port != ports[j].Port {
+				return ports[i].Port < ports[j].Port
+			}
+			return ports[i].Protocol < ports[j].Protocol
+		})
+
+		fmt.Fprintf(sb, "\nScan report for %s, scanned %s\n", textHostName(host, ports[0].Aliases), scanned)
+		var table strings.Builder
+		w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PORT\tSTATE\tSERVICE\tVERSION")
+		for _, r := range ports {
+			state, service := r.State, r.Service
+			if state == "" {
+				state = "open"
+			}
+			if service == "" {
+				service = "unknown"
+			}
+			version := strings.TrimSpace(r.ProductName + " " + r.Version)
+			fmt.Fprintf(w, "%s/%s\t%s\t%s\t%s\n", strconv.Itoa(r.Port), r.Protocol, state, service, version)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		// 没有版本信息的行末尾会留下对齐用的空格
+		for _, line := range strings.SplitAfter(table.String(), "\n") {
+			if line != "" {
+				sb.WriteString(strings.TrimRight(line, " \n") + "\n")
+			}
+		}
+	}
+	return nil
+}
+
+// ExportText 将扫描结果以纯文本摘要(格式见writeText)写入path，便于直接粘贴到笔记或工单
+func (a *App) ExportText(scanID, path string) error {
+	if path == "" {
+		return fmt.Errorf("export path is empty")
+	}
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return err
+	}
+	summary, _, results := record.snapshot()
+	data, err := encodeResults("text", summary, nil, results, nil)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("%d 个结果已导出到 %s\n", len(results), path)
+	return nil
+}
@@ -0,0 +1,110 @@
+package portsscanner
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	throttleWindow       = 100 // 每统计多少次探测评估一次超时率
+	throttleMinRatio     = 0.5 // 超时率低于该值时不视为限流
+	throttleSpikeFactor  = 2.0 // 超时率达到基线的倍数视为突增
+	throttleRecoverSlack = 0.1 // 超时率回落到基线附近后逐步恢复并发
+)
+
+// adaptiveLimiter 根据超时率动态调整并发数：检测到目标开始丢弃连接时减半，恢复后逐步回升
+type adaptiveLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	max    int
+	limit  int
+	active int
+
+	samples  int
+	timeouts int
+	baseline float64 // 首个统计窗口的超时率，-1表示尚未建立
+	cooldown int     // 资源耗尽降低并发后，还需统计多少次探测才允许再次因此降低
+
+	onChange func(limit, previous int, ratio float64, reason string)
+}
+
+func newAdaptiveLimiter(max int, onChange func(limit, previous int, ratio float64, reason string)) *adaptiveLimiter {
+	l := &adaptiveLimiter{max: max, limit: max, baseline: -1, onChange: onChange}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire 等待可用并发名额，ctx取消时返回false
+func (l *adaptiveLimiter) acquire(ctx context.Context) bool {
+	stop := context.AfterFunc(ctx, func() {
+		l.mu.Lock()
+		l.cond.Broadcast()
+		l.mu.Unlock()
+	})
+	defer stop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= l.limit {
+		if ctx.Err() != nil {
+			return false
+		}
+		l.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	l.active++
+	return true
+}
+
+func (l *adaptiveLimiter) release() {
+	l.mu.Lock()
+	l.active--
+	l.cond.Signal()
+	l.mu.Unlock()
+}
+
+// record 记录一次探测结果，每个统计窗口结束时评估是否需要调整并发
+func (l *adaptiveLimiter) record(timedOut bool) {
+	l.mu.Lock()
+	l.samples++
+	if l.cooldown > 0 {
+		l.cooldown--
+	}
+	if timedOut {
+		l.timeouts++
+	}
+	if l.samples < throttleWindow {
+		l.mu.Unlock()
+		return
+	}
+
+	ratio := float64(l.timeouts) / float64(l.samples)
+	l.samples, l.timeouts = 0, 0
+	previous := l.limit
+	reason := ""
+
+	switch {
+	case l.baseline < 0:
+		l.baseline = ratio
+	case ratio >= throttleMinRatio && ratio >= l.baseline*throttleSpikeFactor:
+		if l.limit > 1 {
+			l.limit /= 2
+			reason = "backoff"
+		}
+	case ratio <= l.baseline+throttleRecoverSlack && l.limit < l.max:
+		l.limit += l.max/10 + 1
+		if l.limit > l.max {
+			l.limit = l.max
+		}
+		l.cond.Broadcast()
+		reason = "recover"
+	}
+	limit := l.limit
+	l.mu.Unlock()
+
+	if reason != "" && l.onChange != nil {
+		l.onChange(limit, previous, ratio, reason)
+	}
+}
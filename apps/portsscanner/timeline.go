@@ -0,0 +1,183 @@
+package portsscanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 单次扫描最多保留的时间线条目，超出后不再记录
+	maxTimelineEntries = 50000
+	// scan-progress 写入时间线的最短间隔，足以看出扫描何时变慢
+	timelineProgressInterval = time.Second
+)
+
+// timelineDetailKeys 写入时间线Detail的事件字段
+var timelineDetailKeys = []string{"status", "scanned", "total_ports", "effective_timeout", "reason", "error"}
+
+// TimelineEntry 扫描时间线中的一条事件
+type TimelineEntry struct {
+	Time     time.Time     `json:"time"`
+	Elapsed  time.Duration `json:"elapsed"` // 距扫描开始的时间
+	Event    string        `json:"event"`
+	Host     string        `json:"host,omitempty"`
+	Port     int           `json:"port,omitempty"`
+	Protocol string        `json:"protocol,omitempty"`
+	Detail   string        `json:"detail,omitempty"`
+}
+
+// timelineEntry 时间线的紧凑存储格式：时间为距扫描开始的毫秒数，字段名缩写
+type timelineEntry struct {
+	Offset   int64  `json:"t"`
+	Event    string `json:"e"`
+	Host     string `json:"h,omitempty"`
+	Port     int    `json:"p,omitempty"`
+	Protocol string `json:"r,omitempty"`
+	Detail   string `json:"d,omitempty"`
+}
+
+// storedTimeline 持久化的时间线
+type storedTimeline struct {
+	Start   time.Time       `json:"start"`
+	Entries []timelineEntry `json:"entries"`
+	Dropped int             `json:"dropped,omitempty"`
+}
+
+// scanTimeline 扫描进行中发送的事件及其时间，扫描结束时关闭
+type scanTimeline struct {
+	mu           sync.Mutex
+	start        time.Time
+	entries      []timelineEntry
+	dropped      int
+	lastProgress time.Time
+	closed       bool
+}
+
+// append 追加一条记录，已满时只计数
+func (t *scanTimeline) append(e timelineEntry) {
+	if len(t.entries) >= maxTimelineEntries {
+		t.dropped++
+		return
+	}
+	t.entries = append(t.entries, e)
+}
+
+// add 将事件转换为时间线条目：端口结果记录主机和端口，批量发现的每个端口各记一条，
+// 其他事件记录主机、端口和timelineDetailKeys中的字段
+func (t *scanTimeline) add(name string, data interface{}) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	if name == "scan-progress" {
+		if now.Sub(t.lastProgress) < timelineProgressInterval {
+			return
+		}
+		t.lastProgress = now
+	}
+
+	entry := timelineEntry{Offset: now.Sub(t.start).Milliseconds(), Event: name}
+	switch v := data.(type) {
+	case PortInfo:
+		entry.Host, entry.Port, entry.Protocol, entry.Detail = v.Host, v.Port, v.Protocol, v.Service
+	case []PortInfo:
+		for _, info := range v {
+			t.append(timelineEntry{Offset: entry.Offset, Event: "port-found",
+				Host: info.Host, Port: info.Port, Protocol: info.Protocol, Detail: info.Service})
+		}
+		return
+	case map[string]interface{}:
+		entry.Host, _ = v["host"].(string)
+		entry.Port, _ = v["port"].(int)
+		entry.Protocol, _ = v["protocol"].(string)
+		var details []string
+		for _, key := range timelineDetailKeys {
+			if value, ok := v[key]; ok {
+				details = append(details, fmt.Sprintf("%s=%v", key, value))
+			}
+		}
+		entry.Detail = strings.Join(details, " ")
+	case string:
+		entry.Detail = v
+	}
+	t.append(entry)
+}
+
+// close 记录扫描的最终状态，之后的事件不再写入
+func (t *scanTimeline) close(status string) {
+	t.add("scan-finished", status)
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+}
+
+// stored 返回持久化使用的副本，没有任何记录时为nil
+func (t *scanTimeline) stored() *storedTimeline {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.entries) == 0 {
+		return nil
+	}
+	return &storedTimeline{
+		Start:   t.start,
+		Entries: append([]timelineEntry(nil), t.entries...),
+		Dropped: t.dropped,
+	}
+}
+
+// restore 从存储记录恢复时间线，恢复后不再追加
+func (t *scanTimeline) restore(s *storedTimeline) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	if s == nil {
+		return
+	}
+	t.start, t.entries, t.dropped = s.Start, s.Entries, s.Dropped
+}
+
+// snapshot 按时间顺序返回完整条目，有丢弃时在末尾附加一条说明
+func (t *scanTimeline) snapshot() []TimelineEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list := make([]TimelineEntry, 0, len(t.entries)+1)
+	for _, e := range t.entries {
+		elapsed := time.Duration(e.Offset) * time.Millisecond
+		list = append(list, TimelineEntry{
+			Time:     t.start.Add(elapsed),
+			Elapsed:  elapsed,
+			Event:    e.Event,
+			Host:     e.Host,
+			Port:     e.Port,
+			Protocol: e.Protocol,
+			Detail:   e.Detail,
+		})
+	}
+	// 批量发现与同一时刻的其他事件可能交错写入，按时间稳定排序
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Elapsed < list[j].Elapsed })
+	if t.dropped > 0 && len(list) > 0 {
+		last := list[len(list)-1]
+		list = append(list, TimelineEntry{
+			Time:    last.Time,
+			Elapsed: last.Elapsed,
+			Event:   "timeline-truncated",
+			Detail:  fmt.Sprintf("时间线超过 %d 条，之后的 %d 条未记录", maxTimelineEntries, t.dropped),
+		})
+	}
+	return list
+}
+
+// GetScanTimeline 返回扫描过程中各事件的时间线：端口何时发现、状态何时变化、进度何时放缓，
+// 比结果列表保留了发现顺序。扫描不存在或没有时间线(如导入的扫描)时返回空列表
+func (a *App) GetScanTimeline(scanID string) []TimelineEntry {
+	record, err := getScanRecord(scanID)
+	if err != nil {
+		return []TimelineEntry{}
+	}
+	return record.timeline.snapshot()
+}
@@ -0,0 +1,184 @@
+package portsscanner
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+const (
+	// topCounterCapacity 每个排行榜跟踪的最多条目数，超过后按Space-Saving算法替换计数最小的条目
+	topCounterCapacity = 1000
+	// defaultTopN top-n-updated 事件和n不大于0时返回的条目数
+	defaultTopN = 10
+	// topNInterval top-n-updated 事件的最小发送间隔
+	topNInterval = time.Second
+)
+
+// TopEntry 排行榜中的一项。条目数超过跟踪上限后计数是近似值，
+// Count 为上界，Count-Error 为下界；Error为0时计数是准确的
+type TopEntry struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+	Error int    `json:"error,omitempty"`
+}
+
+// TopNUpdate top-n-updated 事件的内容
+type TopNUpdate struct {
+	Services []TopEntry `json:"services"`
+	Hosts    []TopEntry `json:"hosts"`
+}
+
+// topItem 最小堆中的条目，index为其在堆中的位置
+type topItem struct {
+	TopEntry
+	index int
+}
+
+type topHeap []*topItem
+
+func (h topHeap) Len() int           { return len(h) }
+func (h topHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h topHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *topHeap) Push(x interface{}) {
+	item := x.(*topItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+func (h *topHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// topCounter 流式Top-N计数(Space-Saving)：最多跟踪capacity个键，内存不随结果数量增长。
+// 新键在已满时替换当前计数最小的键，继承其计数并记为误差
+type topCounter struct {
+	capacity int
+	items    map[string]*topItem
+	heap     topHeap
+}
+
+func newTopCounter(capacity int) *topCounter {
+	return &topCounter{capacity: capacity, items: make(map[string]*topItem)}
+}
+
+// add 将键的计数加一
+func (c *topCounter) add(key string) {
+	if item, ok := c.items[key]; ok {
+		item.Count++
+		heap.Fix(&c.heap, item.index)
+		return
+	}
+	if len(c.heap) < c.capacity {
+		item := &topItem{TopEntry: TopEntry{Name: key, Count: 1}}
+		c.items[key] = item
+		heap.Push(&c.heap, item)
+		return
+	}
+	item := c.heap[0]
+	delete(c.items, item.Name)
+	item.Name = key
+	item.Error = item.Count
+	item.Count++
+	c.items[key] = item
+	heap.Fix(&c.heap, 0)
+}
+
+// top 返回计数最大的n项，计数相同时按名称排序
+func (c *topCounter) top(n int) []TopEntry {
+	entries := make([]TopEntry, 0, len(c.heap))
+	for _, item := range c.heap {
+		entries = append(entries, item.TopEntry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	if n < len(entries) {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// topNLimit 规范化调用方请求的条目数
+func topNLimit(n int) int {
+	if n <= 0 {
+		return defaultTopN
+	}
+	return min(n, topCounterCapacity)
+}
+
+// countTop 将已确认开放的端口计入服务和主机排行榜，距上次发送超过节流间隔时返回最新的前defaultTopN项
+func (c *scanControl) countTop(info PortInfo) (TopNUpdate, bool) {
+	if info.State != "" {
+		return TopNUpdate{}, false
+	}
+	service := info.Service
+	if service == "" {
+		service = "unknown"
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	if c.topServices == nil {
+		c.topServices = newTopCounter(topCounterCapacity)
+		c.topHosts = newTopCounter(topCounterCapacity)
+	}
+	c.topServices.add(service)
+	c.topHosts.add(info.Host)
+
+	if time.Since(c.lastTopEmit) < topNInterval {
+		return TopNUpdate{}, false
+	}
+	c.lastTopEmit = time.Now()
+	return c.topSnapshotLocked(defaultTopN), true
+}
+
+// topSnapshot 返回服务和主机排行榜的前n项
+func (c *scanControl) topSnapshot(n int) TopNUpdate {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return c.topSnapshotLocked(n)
+}
+
+func (c *scanControl) topSnapshotLocked(n int) TopNUpdate {
+	update := TopNUpdate{Services: []TopEntry{}, Hosts: []TopEntry{}}
+	if c.topServices != nil {
+		update.Services = c.topServices.top(n)
+		update.Hosts = c.topHosts.top(n)
+	}
+	return update
+}
+
+// retainedTop 返回当前扫描(或保留期内刚结束的扫描)的排行榜
+func retainedTop(n int) TopNUpdate {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+
+	switch {
+	case currentScan != nil:
+		return currentScan.topSnapshot(topNLimit(n))
+	case lastScan != nil:
+		return lastScan.control.topSnapshot(topNLimit(n))
+	}
+	return TopNUpdate{Services: []TopEntry{}, Hosts: []TopEntry{}}
+}
+
+// GetTopServices 返回当前扫描(或保留期内刚结束的扫描)中发现最多的n个服务，n不大于0时返回前10个
+func (a *App) GetTopServices(n int) []TopEntry {
+	return retainedTop(n).Services
+}
+
+// GetTopHosts 返回当前扫描(或保留期内刚结束的扫描)中开放端口最多的n台主机，n不大于0时返回前10台
+func (a *App) GetTopHosts(n int) []TopEntry {
+	return retainedTop(n).Hosts
+}
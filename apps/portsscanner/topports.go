@@ -0,0 +1,25 @@
+package portsscanner
+
+// topTCPPorts 最常见的100个TCP端口，按nmap-services统计的开放频率从高到低排列
+var topTCPPorts = []int{
+	80, 23, 443, 21, 22, 25, 3389, 110, 445, 139,
+	143, 53, 135, 3306, 8080, 1723, 111, 995, 993, 5900,
+	1025, 587, 8888, 199, 1720, 465, 548, 113, 81, 6001,
+	10000, 514, 5060, 179, 1026, 2000, 8443, 8000, 32768, 554,
+	26, 1433, 49152, 2001, 515, 8008, 49154, 1027, 5666, 646,
+	5000, 5631, 631, 49153, 8081, 2049, 88, 79, 5800, 106,
+	2121, 1110, 49155, 6000, 513, 990, 5357, 427, 49156, 543,
+	544, 5101, 144, 7, 389, 8009, 3128, 9999, 5009, 7070,
+	5190, 3000, 5432, 1900, 3986, 13, 1029, 9, 5051, 6646,
+	49157, 1028, 873, 1755, 2717, 4899, 9100, 119, 37, 444,
+}
+
+// topPorts 返回最常见的前n个端口，n超过列表长度时返回整个列表
+func topPorts(n int) []int {
+	if n > len(topTCPPorts) {
+		n = len(topTCPPorts)
+	}
+	ports := make([]int, n)
+	copy(ports, topTCPPorts[:n])
+	return ports
+}
@@ -0,0 +1,106 @@
+package portsscanner
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// splitTwoPass 将每台主机的端口拆分为第一遍扫描的常见端口和第二遍扫描的其余端口，
+// 没有可拆分的端口(全部是或全部不是常见端口)时返回false
+func splitTwoPass(config ScanConfig, hosts []scanTarget, ports []int) (fast, rest map[string][]int, ok bool) {
+	n := config.TwoPassTopPorts
+	if n <= 0 {
+		n = len(topTCPPorts)
+	}
+	top := topPorts(n)
+
+	fast = make(map[string][]int, len(hosts))
+	rest = make(map[string][]int, len(hosts))
+	fastCount, restCount := 0, 0
+	for _, h := range hosts {
+		hostPorts := config.portsFor(h.IP, ports)
+		wanted := make(map[int]bool, len(hostPorts))
+		for _, p := range hostPorts {
+			wanted[p] = true
+		}
+		isTop := make(map[int]bool, len(top))
+		// 第一遍按常见程度排序，最可能开放的端口最先出结果
+		for _, p := range top {
+			if wanted[p] {
+				fast[h.IP] = append(fast[h.IP], p)
+				isTop[p] = true
+			}
+		}
+		for _, p := range hostPorts {
+			if !isTop[p] {
+				rest[h.IP] = append(rest[h.IP], p)
+			}
+		}
+		fastCount += len(fast[h.IP])
+		restCount += len(rest[h.IP])
+	}
+	return fast, rest, fastCount > 0 && restCount > 0
+}
+
+// scanTwoPass 先扫描常见端口并发送 fast-pass-complete 事件，再继续扫描其余端口
+func scanTwoPass(ctx context.Context, config ScanConfig, callback PortCallback) error {
+	applyScanDefaults(&config)
+	config.TwoPass = false
+	if len(config.hosts) == 0 {
+		hosts, _, _, err := resolveTargets(ctx, config)
+		if err != nil {
+			return err
+		}
+		config.hosts = hosts
+	}
+
+	fast, rest, ok := splitTwoPass(config, config.hosts, config.portList())
+	if !ok {
+		return ScanPortsCombined(ctx, config, callback)
+	}
+
+	// 两遍共用主机登记，主机钩子在两遍都扫描完该主机后才调用
+	if config.registry == nil {
+		config.registry = newHostRegistry()
+		for _, h := range config.hosts {
+			config.registry.register(h.IP, len(fast[h.IP])+len(rest[h.IP]))
+		}
+	}
+
+	var found int32
+	first := config
+	first.hostPorts = fast
+	err := ScanPortsCombined(ctx, first, func(info PortInfo) {
+		if info.Protocol != "progress" {
+			atomic.AddInt32(&found, 1)
+		}
+		callback(info)
+	})
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return context.Canceled
+	}
+
+	fastPorts, restPorts := 0, 0
+	for ip := range fast {
+		fastPorts += len(fast[ip])
+		restPorts += len(rest[ip])
+	}
+	if config.logf != nil {
+		config.logf(logInfo, "快速扫描完成: %d 个常见端口发现 %d 个开放，继续扫描剩余 %d 个端口", fastPorts, found, restPorts)
+	}
+	if config.emit != nil {
+		config.emit("fast-pass-complete", map[string]interface{}{
+			"hosts":           len(config.hosts),
+			"scanned_ports":   fastPorts,
+			"open_ports":      atomic.LoadInt32(&found),
+			"remaining_ports": restPorts,
+		})
+	}
+
+	second := config
+	second.hostPorts = rest
+	return ScanPortsCombined(ctx, second, callback)
+}
@@ -0,0 +1,235 @@
+package portsscanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+)
+
+// UDP扫描每个载荷的默认发送次数，UDP不保证送达，单次探测容易漏掉开放的服务
+const defaultUDPAttempts = 2
+
+// udpPayload 针对特定服务的UDP探测载荷：大多数UDP服务只响应格式正确的请求，
+// 空数据包得不到回复，端口只能显示为 open|filtered
+type udpPayload struct {
+	name    string // 报告在 PortInfo.ProbeName 中
+	service string
+	ports   []int
+	data    []byte
+}
+
+// udpEmptyPayload 没有专用载荷的端口发送空数据包
+var udpEmptyPayload = udpPayload{name: "empty"}
+
+var udpPayloads = []udpPayload{
+	{
+		// version.bind CH TXT 查询
+		name: "DNSVersionBindReq", service: "domain", ports: []int{53},
+		data: []byte("\x00\x06\x01\x00\x00\x01\x00\x00\x00\x00\x00\x00\x07version\x04bind\x00\x00\x10\x00\x03"),
+	},
+	{
+		// 查询 _services._dns-sd._udp.local PTR
+		name: "mDNSServices", service: "mdns", ports: []int{5353},
+		data: []byte("\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x09_services\x07_dns-sd\x04_udp\x05local\x00\x00\x0c\x00\x01"),
+	},
+	{
+		// 使用 public 团体名的SNMPv1 GetRequest，查询 sysDescr.0
+		name: "SNMPv1public", service: "snmp", ports: []int{161},
+		data: []byte("\x30\x29\x02\x01\x00\x04\x06public\xa0\x1c\x02\x04\x4c\x33\xa7\x56\x02\x01\x00\x02\x01\x00" +
+			"\x30\x0e\x30\x0c\x06\x08\x2b\x06\x01\x02\x01\x01\x01\x00\x05\x00"),
+	},
+	{
+		// NTPv4 客户端请求
+		name: "NTPRequest", service: "ntp", ports: []int{123},
+		data: append([]byte{0xe3}, make([]byte, 47)...),
+	},
+	{
+		// NetBIOS 通配名称状态查询
+		name: "NBTStat", service: "netbios-ns", ports: []int{137},
+		data: []byte("\x80\xf0\x00\x10\x00\x01\x00\x00\x00\x00\x00\x00\x20CKAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\x00\x00\x21\x00\x01"),
+	},
+	{
+		name: "SSDPSearch", service: "upnp", ports: []int{1900},
+		data: []byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: ssdp:all\r\n\r\n"),
+	},
+	{
+		// 读取不存在的文件，服务端回复错误包
+		name: "TFTPRead", service: "tftp", ports: []int{69},
+		data: []byte("\x00\x01glideway.txt\x00octet\x00"),
+	},
+	{
+		// portmapper NULL 调用
+		name: "RPCCheck", service: "rpcbind", ports: []int{111},
+		data: []byte("\x72\xfe\x1d\x13\x00\x00\x00\x00\x00\x00\x00\x02\x00\x01\x86\xa0\x00\x00\x00\x02" +
+			"\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"),
+	},
+	{
+		name: "memcached", service: "memcached", ports: []int{11211},
+		data: []byte("\x00\x01\x00\x00\x00\x01\x00\x00stats\r\n"),
+	},
+}
+
+// payloadsFor 返回端口对应的探测载荷(内置载荷在前，之后是自定义UDP探测)，没有专用载荷时只发送空数据包
+func payloadsFor(port int) []udpPayload {
+	var list []udpPayload
+	for _, pl := range udpPayloads {
+		for _, p := range pl.ports {
+			if p == port {
+				list = append(list, pl)
+				break
+			}
+		}
+	}
+	for _, probe := range customProbesFor("udp", port) {
+		list = append(list, udpPayload{name: probe.Name, service: probe.Service, ports: probe.Ports, data: probe.data})
+	}
+	if len(list) == 0 {
+		list = append(list, udpEmptyPayload)
+	}
+	return list
+}
+
+// udpResult 单个UDP端口的探测结果
+type udpResult struct {
+	state    string
+	payload  udpPayload // 得到响应的载荷
+	response []byte
+
+	icmp *icmpReply // 监听ICMP时判定状态的目标不可达报文
+}
+
+// probeUDP 依次发送端口对应的载荷(见orderedPayloads)，每个载荷最多尝试attempts次：
+// 收到响应为开放，收到ICMP端口不可达为关闭，全部超时为 open|filtered。
+// 设置了ICMP监听器时按收到的报文判断状态，内核不报告的主机不可达等报文也判定为filtered。
+// grace大于0时，全部尝试超时后继续等待grace接收迟到的响应，迟到的响应归于最后发送的载荷
+func probeUDP(ctx context.Context, ip string, port int, payloads []udpPayload, attempts int, timeout, grace time.Duration, meter *bandwidthMeter, sources *sourcePool, icmp *icmpListener) udpResult {
+	if attempts <= 0 {
+		attempts = defaultUDPAttempts
+	}
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	conn, err := sources.dial(ctx, timeout, "udp", address, nil)
+	if err != nil {
+		return udpResult{state: portFiltered}
+	}
+	var icmpCh <-chan icmpReply
+	if local, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		var unwatch func()
+		icmpCh, unwatch = icmp.watch(net.ParseIP(ip), ipProtoUDP, local.Port, port)
+		defer unwatch()
+	}
+	conn = meter.wrap(ctx, conn)
+	defer conn.Close()
+
+	buf := make([]byte, defaultBannerReadSize)
+	var last udpPayload
+	for _, pl := range payloads {
+		last = pl
+		for i := 0; i < attempts; i++ {
+			if ctx.Err() != nil {
+				return udpResult{state: portOpenFiltered}
+			}
+			if _, err := conn.Write(pl.data); err != nil {
+				if isPortUnreachable(err) {
+					return udpICMPResult(icmpCh, icmpGrace, portClosed)
+				}
+				return udpICMPResult(icmpCh, icmpGrace, portFiltered)
+			}
+			conn.SetReadDeadline(time.Now().Add(timeout))
+			n, err := conn.Read(buf)
+			if err == nil {
+				return udpResult{state: portOpen, payload: pl, response: append([]byte(nil), buf[:n]...)}
+			}
+			if isPortUnreachable(err) {
+				return udpICMPResult(icmpCh, icmpGrace, portClosed)
+			}
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				return udpICMPResult(icmpCh, icmpGrace, portFiltered)
+			}
+			if r, ok := waitICMP(icmpCh, 0); ok {
+				return udpResult{state: icmpPortState(r, "udp"), icmp: &r}
+			}
+		}
+	}
+	if grace > 0 && ctx.Err() == nil {
+		conn.SetReadDeadline(time.Now().Add(grace))
+		n, err := conn.Read(buf)
+		switch {
+		case err == nil:
+			return udpResult{state: portOpen, payload: last, response: append([]byte(nil), buf[:n]...)}
+		case isPortUnreachable(err):
+			return udpICMPResult(icmpCh, icmpGrace, portClosed)
+		}
+		if r, ok := waitICMP(icmpCh, 0); ok {
+			return udpResult{state: icmpPortState(r, "udp"), icmp: &r}
+		}
+	}
+	return udpResult{state: portOpenFiltered}
+}
+
+// udpICMPResult 套接字报错后等待对应的ICMP报文，收到时按报文判断状态，否则返回fallback
+func udpICMPResult(ch <-chan icmpReply, grace time.Duration, fallback string) udpResult {
+	if r, ok := waitICMP(ch, grace); ok {
+		return udpResult{state: icmpPortState(r, "udp"), icmp: &r}
+	}
+	return udpResult{state: fallback}
+}
+
+// scanUDPPort UDP扫描单个端口，开放和 open|filtered 的端口直接回调，不进行TCP指纹识别
+func (s *portScanner) scanUDPPort(ctx context.Context, h scanTarget, p int) {
+	config := s.config
+	result := probeUDP(ctx, h.IP, p, config.orderedPayloads(h.IP, p), config.UDPAttempts, config.Timeout, config.UDPGrace, config.bandwidth, config.sources, s.icmp)
+	if config.probeEvents {
+		event := map[string]interface{}{
+			"host":     h.IP,
+			"port":     p,
+			"protocol": "udp",
+			"state":    result.state,
+		}
+		if result.icmp != nil {
+			event["icmp"] = result.icmp.String()
+		}
+		s.emit("probe-result", event)
+	}
+	if result.icmp != nil && result.state == portFiltered && config.ICMPHandling == ICMPReport {
+		s.reportICMPFiltered(ctx, h, p, "udp", *result.icmp)
+		return
+	}
+	if result.state != portOpen && result.state != portOpenFiltered {
+		return
+	}
+
+	info := PortInfo{
+		Host:     h.IP,
+		Aliases:  h.Names,
+		Port:     p,
+		Endpoint: config.endpointName(h.IP, p),
+		Protocol: "udp",
+	}
+	if result.state == portOpenFiltered {
+		info.State = portOpenFiltered
+		s.log(logDebug, "%s:%d/udp open|filtered", h.IP, p)
+	} else {
+		info.Service = result.payload.service
+		info.ProbeName = result.payload.name
+		canonicalizeService(&info)
+		if config.CaptureResponse {
+			info.RawResponse = dumpResponse(string(result.response), config.CaptureSize)
+		}
+		if info.Service == "" {
+			info.Unidentified = true
+			banner := result.response
+			if len(banner) > unidentifiedBannerSize {
+				banner = banner[:unidentifiedBannerSize]
+			}
+			info.Info = escapeBanner(banner)
+		}
+		scoreConfidence(&info)
+		s.log(logInfo, "%s:%d/udp 开放 (载荷 %s)", h.IP, p, result.payload.name)
+	}
+	if ctx.Err() == nil {
+		s.callback(info)
+	}
+}
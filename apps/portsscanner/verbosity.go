@@ -0,0 +1,93 @@
+package portsscanner
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// 扫描事件流的详细程度
+const (
+	EventVerbosityMinimal = "minimal" // 只发送状态切换和最终结果
+	EventVerbosityNormal  = "normal"  // 默认，发送进度和端口发现等全部常规事件
+	EventVerbosityVerbose = "verbose" // 额外发送每次探测的结果
+)
+
+// eventVerbosity 详细程度的内部表示，零值为normal以保持原有行为
+type eventVerbosity int32
+
+const (
+	verbosityNormal eventVerbosity = iota
+	verbosityMinimal
+	verbosityVerbose
+)
+
+// minimalEvents minimal级别下仍然发送的事件
+var minimalEvents = map[string]bool{
+	"scan-status":         true,
+	"scan-started":        true,
+	"scan-complete":       true,
+	"scan-error":          true,
+	"scan-deferred":       true,
+	"scan-complete-alert": true,
+}
+
+// verboseEvents 只在verbose级别下发送的事件
+var verboseEvents = map[string]bool{
+	"probe-result": true,
+}
+
+// allows 判断当前详细程度下是否发送该事件
+func (v eventVerbosity) allows(name string) bool {
+	switch v {
+	case verbosityMinimal:
+		return minimalEvents[name]
+	case verbosityVerbose:
+		return true
+	}
+	return !verboseEvents[name]
+}
+
+// SetEventVerbosity 设置后端发送的扫描事件粒度：minimal、normal 或 verbose
+func (a *App) SetEventVerbosity(level string) error {
+	var v eventVerbosity
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case EventVerbosityMinimal:
+		v = verbosityMinimal
+	case EventVerbosityNormal, "":
+		v = verbosityNormal
+	case EventVerbosityVerbose:
+		v = verbosityVerbose
+	default:
+		return fmt.Errorf("unknown event verbosity %q", level)
+	}
+	atomic.StoreInt32(&a.verbosity, int32(v))
+	fmt.Printf("事件详细程度设置为 %s\n", level)
+	return nil
+}
+
+// eventLevel 返回当前的事件详细程度
+func (a *App) eventLevel() eventVerbosity {
+	return eventVerbosity(atomic.LoadInt32(&a.verbosity))
+}
+
+// emitEvent 按当前详细程度过滤后向前端发送事件，扫描进行中的事件(verbose级别的除外)同时写入时间线
+func (a *App) emitEvent(name string, data interface{}) {
+	if t := a.timeline.Load(); t != nil && !verboseEvents[name] {
+		t.add(name, data)
+	}
+	if !a.eventLevel().allows(name) {
+		return
+	}
+	ctx := a.eventContext.Load()
+	if r := a.recorder.Load(); r != nil {
+		r.record(name, data, ctx)
+	}
+	if ctx != nil {
+		runtime.EventsEmit(a.ctx, name, data, *ctx)
+		return
+	}
+	runtime.EventsEmit(a.ctx, name, data)
+}
@@ -0,0 +1,104 @@
+package portsscanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// VerifyOpen 复核时每个端口的连接次数和间隔，任意一次连接成功即视为确认开放
+const (
+	verifyOpenAttempts = 2
+	verifyOpenDelay    = 200 * time.Millisecond
+)
+
+// errVerifyIdle 空闲扫描不会从本机直接连接目标，复核会暴露扫描源地址
+var errVerifyIdle = errors.New("VerifyOpen is not supported with idle scans")
+
+// flagUnstable 标记复核时未能再次连接的端口
+func (r *scanRecord) flagUnstable(host string, port int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.results {
+		if r.results[i].Host == host && r.results[i].Port == port && r.results[i].Protocol == "tcp" {
+			r.results[i].Unstable = true
+		}
+	}
+}
+
+// reconfirmOpen 重新建立TCP连接确认端口仍然开放
+func reconfirmOpen(ctx context.Context, config ScanConfig, host string, port int) bool {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	for i := 0; i < verifyOpenAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(verifyOpenDelay):
+			}
+		}
+		conn, err := dialTCP(ctx, config.connectDialer(), config.sources, address, config.connectTimeout())
+		if err == nil {
+			applyClosePolicy(conn, config.AbruptClose)
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// verifyOpenPorts 主扫描结束后对所有已确认开放的TCP端口重新连接一次，
+// 确认的发送 port-verified 事件，未能确认的标记为Unstable并发送 port-unstable 事件。
+// 扫描被取消时返回ctx的错误
+func (a *App) verifyOpenPorts(ctx context.Context, config ScanConfig, record *scanRecord) error {
+	_, _, results := record.snapshot()
+	targets := fingerprintTargets(results)
+	if len(targets) == 0 {
+		return nil
+	}
+	record.log.add(logInfo, "复核 %d 个开放端口", len(targets))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	unstable := 0
+	sem := make(chan struct{}, max(config.connectThreads(), 1))
+	for _, target := range targets {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(r PortInfo) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ok := reconfirmOpen(ctx, config, r.Host, r.Port)
+			if ctx.Err() != nil {
+				return
+			}
+			event := map[string]interface{}{"host": r.Host, "port": r.Port}
+			if ok {
+				a.emitEvent("port-verified", event)
+				return
+			}
+			record.flagUnstable(r.Host, r.Port)
+			record.log.add(logWarn, "%s:%d 复核时无法再次连接，标记为不稳定", r.Host, r.Port)
+			mu.Lock()
+			unstable++
+			mu.Unlock()
+			a.emitEvent("port-unstable", event)
+		}(target)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	record.log.add(logInfo, "开放端口复核完成: %d 个确认，%d 个不稳定", len(targets)-unstable, unstable)
+	return nil
+}
@@ -0,0 +1,139 @@
+package portsscanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	// 每个HTTPS端口默认最多尝试的虚拟主机名数
+	defaultMaxVirtualHosts = 16
+	// MaxVirtualHosts 的上限，避免对单个端口发送过多请求
+	maxVirtualHostsLimit = 128
+	// 反向解析的超时时间
+	vhostReverseLookupTimeout = 2 * time.Second
+)
+
+// VirtualHost 以某个主机名作为SNI和Host头访问时，与直接访问IP不同的响应
+type VirtualHost struct {
+	Name        string    `json:"name"`
+	Status      int       `json:"status"`
+	Title       string    `json:"title,omitempty"`
+	Server      string    `json:"server,omitempty"`
+	CertSubject string    `json:"cert_subject,omitempty"`
+	CertNames   []string  `json:"cert_names,omitempty"` // 证书的DNS SAN
+	CertSHA256  string    `json:"cert_sha256,omitempty"`
+	TLSExpiry   time.Time `json:"tls_expiry"`
+}
+
+// virtualHostsEnabled 是否需要对HTTPS端口探测虚拟主机
+func (c ScanConfig) virtualHostsEnabled() bool {
+	return len(c.VirtualHosts) > 0 || c.DiscoverVirtualHosts
+}
+
+// virtualHostLimit 返回每个端口最多尝试的主机名数
+func (c ScanConfig) virtualHostLimit() int {
+	switch {
+	case c.MaxVirtualHosts <= 0:
+		return defaultMaxVirtualHosts
+	case c.MaxVirtualHosts > maxVirtualHostsLimit:
+		return maxVirtualHostsLimit
+	}
+	return c.MaxVirtualHosts
+}
+
+// newVirtualHost 由探测结果生成虚拟主机信息
+func newVirtualHost(name string, result *httpProbeResult) VirtualHost {
+	vh := VirtualHost{
+		Name:   name,
+		Status: result.Status,
+		Title:  result.Title,
+		Server: result.Server,
+	}
+	if cert := result.Certificate; cert != nil {
+		sum := sha256.Sum256(cert.Raw)
+		vh.CertSubject = cert.Subject.CommonName
+		vh.CertNames = cert.DNSNames
+		vh.CertSHA256 = hex.EncodeToString(sum[:])
+		vh.TLSExpiry = cert.NotAfter
+	}
+	return vh
+}
+
+// differsFrom 证书、标题或状态码与默认响应不同时视为独立的虚拟主机
+func (vh VirtualHost) differsFrom(base VirtualHost) bool {
+	return vh.CertSHA256 != base.CertSHA256 || vh.Title != base.Title || vh.Status != base.Status
+}
+
+// virtualHostCandidates 汇总要尝试的主机名：配置的列表、扫描输入中指向该IP的名称，
+// 启用DiscoverVirtualHosts时再加上默认证书SAN和反向解析结果。通配符名称无法直接访问，跳过
+func (s *portScanner) virtualHostCandidates(ctx context.Context, h scanTarget, base *httpProbeResult) []string {
+	limit := s.config.virtualHostLimit()
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+		if name == "" || strings.Contains(name, "*") || seen[name] || len(names) >= limit {
+			return
+		}
+		if _, ok := canonicalIP(name); ok {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	for _, name := range s.config.VirtualHosts {
+		add(name)
+	}
+	for _, name := range h.Names {
+		add(name)
+	}
+	if !s.config.DiscoverVirtualHosts {
+		return names
+	}
+	if base.Certificate != nil {
+		add(base.Certificate.Subject.CommonName)
+		for _, name := range base.Certificate.DNSNames {
+			add(name)
+		}
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, vhostReverseLookupTimeout)
+	defer cancel()
+	if ptrs, err := s.config.resolver().LookupAddr(lookupCtx, h.IP); err == nil {
+		for _, name := range ptrs {
+			add(name)
+		}
+	}
+	return names
+}
+
+// probeVirtualHosts 依次以每个候选主机名作为SNI访问HTTPS端口，
+// 与直接访问IP的响应不同的记录到结果中并发送 vhost-found 事件
+func (s *portScanner) probeVirtualHosts(ctx context.Context, h scanTarget, p int, base *httpProbeResult, portInfo *PortInfo) {
+	baseline := newVirtualHost("", base)
+	for _, name := range s.virtualHostCandidates(ctx, h, base) {
+		if ctx.Err() != nil {
+			return
+		}
+		result, err := probeHTTPName(ctx, s.config, h.IP, p, true, name)
+		if err != nil {
+			s.log(logDebug, "%s:%d 虚拟主机 %s 探测失败: %v", h.IP, p, name, err)
+			continue
+		}
+		vh := newVirtualHost(name, result)
+		if !vh.differsFrom(baseline) {
+			continue
+		}
+		portInfo.VirtualHosts = append(portInfo.VirtualHosts, vh)
+		s.log(logInfo, "%s:%d 发现虚拟主机 %s: %d %q", h.IP, p, name, vh.Status, vh.Title)
+		s.emit("vhost-found", map[string]interface{}{
+			"host":         h.IP,
+			"port":         p,
+			"virtual_host": vh,
+		})
+	}
+}
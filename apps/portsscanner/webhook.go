@@ -0,0 +1,201 @@
+package portsscanner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// 同时进行的webhook请求数
+	webhookWorkers = 4
+	// 单次POST的超时时间
+	webhookTimeout = 10 * time.Second
+	// 每个发现最多投递的次数(含首次)，之后放弃并发送 webhook-failed 事件
+	webhookMaxAttempts = 4
+	// 首次重试的等待时间，之后每次加倍
+	webhookRetryDelay = time.Second
+	// 扫描结束后等待未完成投递的最长时间
+	webhookDrainTimeout = 30 * time.Second
+)
+
+// webhookJob 等待投递的一个发现
+type webhookJob struct {
+	info     PortInfo
+	body     []byte
+	attempts int
+}
+
+// findingWebhook 将每个开放端口实时POST到收集端。投递在独立的协程中进行，
+// 队列不设上限，收集端故障或变慢时发现在内存中排队重试，既不阻塞扫描也不丢弃
+type findingWebhook struct {
+	url           string
+	scanID        string
+	correlationID string
+	client        *http.Client
+	onFailure     func(job webhookJob, err error)
+
+	mu      sync.Mutex
+	pending []webhookJob
+	signal  chan struct{}
+	stop    chan struct{}
+
+	inflight sync.WaitGroup // 尚未成功投递或放弃的发现
+	workers  sync.WaitGroup
+}
+
+// parseWebhookURL 校验webhook地址，只接受http和https
+func parseWebhookURL(target string) (*url.URL, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL %q: %w", target, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q: must be an http or https URL", target)
+	}
+	return u, nil
+}
+
+// newFindingWebhook 创建webhook投递器并启动投递协程
+func newFindingWebhook(target, scanID, correlationID string, onFailure func(job webhookJob, err error)) (*findingWebhook, error) {
+	u, err := parseWebhookURL(target)
+	if err != nil {
+		return nil, err
+	}
+	w := &findingWebhook{
+		url:           u.String(),
+		scanID:        scanID,
+		correlationID: correlationID,
+		client:        &http.Client{Timeout: webhookTimeout},
+		onFailure:     onFailure,
+		signal:        make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+	}
+	for i := 0; i < webhookWorkers; i++ {
+		w.workers.Add(1)
+		go w.loop()
+	}
+	return w, nil
+}
+
+// finding 将开放端口加入投递队列，立即返回
+func (w *findingWebhook) finding(info PortInfo) {
+	body, err := json.Marshal(info)
+	if err != nil {
+		return
+	}
+	w.inflight.Add(1)
+	w.enqueue(webhookJob{info: info, body: body})
+}
+
+func (w *findingWebhook) enqueue(job webhookJob) {
+	w.mu.Lock()
+	w.pending = append(w.pending, job)
+	w.mu.Unlock()
+	w.wake()
+}
+
+func (w *findingWebhook) wake() {
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// next 取出队首的发现，队列中还有剩余时唤醒其他投递协程
+func (w *findingWebhook) next() (webhookJob, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) == 0 {
+		return webhookJob{}, false
+	}
+	job := w.pending[0]
+	w.pending = w.pending[1:]
+	if len(w.pending) > 0 {
+		w.wake()
+	}
+	return job, true
+}
+
+func (w *findingWebhook) loop() {
+	defer w.workers.Done()
+	for {
+		job, ok := w.next()
+		if !ok {
+			select {
+			case <-w.signal:
+				continue
+			case <-w.stop:
+				return
+			}
+		}
+		w.deliver(job)
+	}
+}
+
+// deliver 投递一次，可重试的失败按指数退避重新排队，重试耗尽或收集端明确拒绝时放弃
+func (w *findingWebhook) deliver(job webhookJob) {
+	job.attempts++
+	retry, err := w.post(job.body)
+	if err == nil {
+		w.inflight.Done()
+		return
+	}
+	if retry && job.attempts < webhookMaxAttempts {
+		delay := webhookRetryDelay << (job.attempts - 1)
+		time.AfterFunc(delay, func() { w.enqueue(job) })
+		return
+	}
+	if w.onFailure != nil {
+		w.onFailure(job, err)
+	}
+	w.inflight.Done()
+}
+
+// post 发送一个发现，返回失败是否值得重试：网络错误、429和5xx重试，其余4xx不重试
+func (w *findingWebhook) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GlideWay-Scan-ID", w.scanID)
+	if w.correlationID != "" {
+		req.Header.Set("X-GlideWay-Correlation-ID", w.correlationID)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
+
+// close 等待排队和重试中的发现投递完毕后停止投递协程，最多等待webhookDrainTimeout，
+// 返回超时时仍未投递的发现数
+func (w *findingWebhook) close() int {
+	drained := make(chan struct{})
+	go func() {
+		w.inflight.Wait()
+		close(drained)
+	}()
+	left := 0
+	select {
+	case <-drained:
+	case <-time.After(webhookDrainTimeout):
+		w.mu.Lock()
+		left = len(w.pending)
+		w.mu.Unlock()
+	}
+	close(w.stop)
+	w.workers.Wait()
+	return left
+}
@@ -0,0 +1,167 @@
+package portsscanner
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ScanWindow 允许扫描的时间窗口(本地时间)，用于避免在业务高峰期扫描生产环境
+type ScanWindow struct {
+	Enabled            bool   `json:"enabled"`
+	Start              string `json:"start"` // 窗口开始时间 HH:MM
+	End                string `json:"end"`   // 窗口结束时间 HH:MM，小于开始时间表示跨午夜
+	Days               []int  `json:"days"`  // 允许的星期(0=周日)，为空表示每天
+	DeferOutsideWindow bool   `json:"defer_outside_window"`
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w ScanWindow) validate() error {
+	if !w.Enabled {
+		return nil
+	}
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("scan window start and end must differ")
+	}
+	for _, d := range w.Days {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("invalid weekday %d, expected 0-6", d)
+		}
+	}
+	return nil
+}
+
+func (w ScanWindow) dayAllowed(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, day := range w.Days {
+		if time.Weekday(day) == d {
+			return true
+		}
+	}
+	return false
+}
+
+// allows 判断给定时间是否处于窗口内，跨午夜的窗口以开始当天的星期为准
+func (w ScanWindow) allows(t time.Time) bool {
+	if !w.Enabled {
+		return true
+	}
+	start, _ := parseClock(w.Start)
+	end, _ := parseClock(w.End)
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+
+	if start < end {
+		return w.dayAllowed(t.Weekday()) && offset >= start && offset < end
+	}
+	if offset >= start {
+		return w.dayAllowed(t.Weekday())
+	}
+	return offset < end && w.dayAllowed(t.AddDate(0, 0, -1).Weekday())
+}
+
+// nextOpen 返回下一次窗口打开的时间
+func (w ScanWindow) nextOpen(t time.Time) time.Time {
+	start, _ := parseClock(w.Start)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for d := 0; d <= 7; d++ {
+		day := midnight.AddDate(0, 0, d)
+		candidate := day.Add(start)
+		if candidate.After(t) && w.dayAllowed(day.Weekday()) {
+			return candidate
+		}
+	}
+	return t
+}
+
+// SetScanWindow 设置允许扫描的时间窗口
+func (a *App) SetScanWindow(window ScanWindow) error {
+	if err := window.validate(); err != nil {
+		return err
+	}
+	scanMutex.Lock()
+	a.scanWindow = window
+	scanMutex.Unlock()
+	return nil
+}
+
+// GetScanWindow 返回当前的扫描时间窗口配置
+func (a *App) GetScanWindow() ScanWindow {
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+	return a.scanWindow
+}
+
+// checkScanWindow 在窗口外启动扫描时拒绝或排队，返回true表示扫描已排队等待
+func (a *App) checkScanWindow(config ScanConfig) (bool, error) {
+	scanMutex.Lock()
+	window := a.scanWindow
+	scanMutex.Unlock()
+
+	now := time.Now()
+	if window.allows(now) {
+		return false, nil
+	}
+	opensAt := window.nextOpen(now)
+	if !window.DeferOutsideWindow {
+		return false, fmt.Errorf("scan rejected: outside allowed scan window %s-%s (next opening %s)",
+			window.Start, window.End, opensAt.Format("2006-01-02 15:04"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	token := &deferredScan{cancel: cancel}
+	scanMutex.Lock()
+	if deferred != nil {
+		deferred.cancel()
+	}
+	deferred = token
+	scanMutex.Unlock()
+
+	a.emitEvent("scan-deferred", map[string]interface{}{
+		"target":   config.Target,
+		"opens_at": opensAt.Format(time.RFC3339),
+	})
+
+	go func() {
+		timer := time.NewTimer(time.Until(opensAt))
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		// 计时器与取消同时就绪时select可能选中计时器，以仍登记的排队为准
+		scanMutex.Lock()
+		current := deferred == token
+		if current {
+			deferred = nil
+		}
+		scanMutex.Unlock()
+		if !current {
+			return
+		}
+		if err := a.ScanWithConfig(config); err != nil {
+			a.emitEvent("scan-error", err.Error())
+		}
+	}()
+	return true, nil
+}